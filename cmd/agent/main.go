@@ -78,6 +78,17 @@ func startGateway(configPath string, logger zerolog.Logger) {
 	ctx := context.Background()
 	gw.StartScanner(ctx, 10*time.Second)
 
+	// Keep remote repos up to date, including ones added at runtime
+	gw.StartFetcher(ctx)
+
+	// Start working tree watchers for repos with watch enabled
+	gw.StartWatchers(ctx)
+
+	// Add, update and remove repos when the config file changes
+	if err := gw.WatchConfig(ctx, configPath); err != nil {
+		logger.Warn().Err(err).Msg("Config hot reload disabled")
+	}
+
 	// Start HTTP server for gateway
 	srv := server.NewGateway(gw, config.Port, logger)
 	if err := srv.Start(); err != nil {
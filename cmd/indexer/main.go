@@ -7,6 +7,9 @@ import (
 	"os"
 	"time"
 
+	"github.com/First008/mesh/internal/resilience"
+	"github.com/First008/mesh/internal/secrets"
+	"github.com/First008/mesh/internal/tokenizer"
 	"github.com/First008/mesh/internal/vectorstore"
 	"github.com/rs/zerolog"
 )
@@ -20,6 +23,9 @@ func main() {
 	openaiKey := flag.String("openai-key", "", "OpenAI API key (for openai provider)")
 	ollamaURL := flag.String("ollama-url", "http://localhost:11434", "Ollama API URL")
 	ollamaModel := flag.String("ollama-model", "nomic-embed-text", "Ollama model name")
+	secretMode := flag.String("secrets", "redact", "Files containing secrets: redact, skip, or off")
+	generated := flag.String("generated", "downweight", "Generated, vendored and minified files: downweight, skip, or off")
+	embedTemplate := flag.String("embed-template", "", "Go template for the text embedded per chunk (default: header, package and imports), or raw")
 	flag.Parse()
 
 	// Setup logger
@@ -37,6 +43,19 @@ func main() {
 		logger.Fatal().Msg("--name flag is required")
 	}
 
+	mode, err := secrets.ParseMode(*secretMode)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Invalid --secrets flag")
+	}
+	generatedMode, err := vectorstore.ParseGeneratedMode(*generated)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Invalid --generated flag")
+	}
+	embedding, err := vectorstore.ParseEmbedTemplate(*embedTemplate)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Invalid --embed-template flag")
+	}
+
 	logger.Info().
 		Str("repo_path", *repoPath).
 		Str("repo_name", *repoName).
@@ -46,7 +65,6 @@ func main() {
 
 	// Create embedding provider
 	var embeddingProvider vectorstore.EmbeddingProvider
-	var err error
 
 	switch *provider {
 	case "ollama":
@@ -73,6 +91,10 @@ func main() {
 		logger.Fatal().Str("provider", *provider).Msg("Unknown provider. Use 'ollama' or 'openai'")
 	}
 
+	// Retry transient embedding failures instead of dropping files
+	guard := resilience.Shared("embedding/"+*provider+"/"+embeddingProvider.GetModelName(), resilience.Policy{}, logger)
+	embeddingProvider = resilience.WrapEmbedding(embeddingProvider, guard)
+
 	logger.Info().
 		Str("model", embeddingProvider.GetModelName()).
 		Int("dimensions", embeddingProvider.GetDimensions()).
@@ -87,6 +109,10 @@ func main() {
 
 	// Create indexer
 	indexer := vectorstore.NewIndexer(store, *repoPath, logger)
+	indexer.SetTokenizer(tokenizer.ForEmbeddingModel(embeddingProvider.GetModelName()))
+	indexer.SetSecretMode(mode)
+	indexer.SetGeneratedMode(generatedMode)
+	indexer.SetEmbedTemplate(embedding)
 
 	// Index the repository
 	ctx := context.Background()
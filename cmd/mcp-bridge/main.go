@@ -8,7 +8,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"strings"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/rs/zerolog"
@@ -25,7 +27,12 @@ type HTTPAgent struct {
 
 // AskToolArgs defines the arguments for the ask tool (single repo)
 type AskToolArgs struct {
-	Question string `json:"question" jsonschema:"description:Question about the codebase"`
+	Question     string   `json:"question" jsonschema:"description:Question about the codebase"`
+	WorkingTree  bool     `json:"working_tree,omitempty" jsonschema:"description:Include uncommitted local edits (gateway repos with watch enabled)"`
+	Paths        []string `json:"paths,omitempty" jsonschema:"description:Only search files under these paths (e.g. internal/api/**)"`
+	Languages    []string `json:"languages,omitempty" jsonschema:"description:Only search files in these languages (e.g. go or typescript)"`
+	ExcludeTests bool     `json:"exclude_tests,omitempty" jsonschema:"description:Leave test files out of the search"`
+	DocTypes     []string `json:"doc_types,omitempty" jsonschema:"description:Only search these kinds of file: code or documentation (readme, changelog, adr, design, guide)"`
 }
 
 // AskRepoToolArgs defines the arguments for asking a specific repo in gateway mode
@@ -34,6 +41,34 @@ type AskRepoToolArgs struct {
 	Question   string `json:"question" jsonschema:"description:Question about the codebase"`
 }
 
+// SymbolToolArgs defines the arguments for the find_definition and
+// find_references tools (gateway mode)
+type SymbolToolArgs struct {
+	Repository string `json:"repository" jsonschema:"description:Repository name to search"`
+	Name       string `json:"name" jsonschema:"description:Identifier to look up, optionally qualified (e.g. NewServer or Server.Start)"`
+	Branch     string `json:"branch,omitempty" jsonschema:"description:Branch to search (defaults to the checked-out branch)"`
+}
+
+// SymbolResponse matches the gateway's GET /repos/:repo/symbols response
+type SymbolResponse struct {
+	Repo        string `json:"repo"`
+	Branch      string `json:"branch"`
+	Indexed     bool   `json:"indexed"`
+	Definitions []struct {
+		Name      string `json:"name"`
+		Kind      string `json:"kind"`
+		Path      string `json:"path"`
+		Line      int    `json:"line"`
+		Container string `json:"container"`
+		Signature string `json:"signature"`
+	} `json:"definitions"`
+	References []struct {
+		Path string `json:"path"`
+		Line int    `json:"line"`
+	} `json:"references"`
+	TotalReferences int `json:"total_references"`
+}
+
 // RepoInfo represents repository information from the gateway
 type RepoInfo struct {
 	Name   string `json:"name"`
@@ -43,7 +78,24 @@ type RepoInfo struct {
 
 // AskRequest matches the HTTP API request format
 type AskRequest struct {
-	Question string `json:"question"`
+	Question     string   `json:"question"`
+	WorkingTree  bool     `json:"working_tree,omitempty"`
+	Paths        []string `json:"paths,omitempty"`
+	Languages    []string `json:"languages,omitempty"`
+	ExcludeTests bool     `json:"exclude_tests,omitempty"`
+	DocTypes     []string `json:"doc_types,omitempty"`
+}
+
+// newAskRequest builds the HTTP request for a tool call
+func newAskRequest(args AskToolArgs) AskRequest {
+	return AskRequest{
+		Question:     args.Question,
+		WorkingTree:  args.WorkingTree,
+		Paths:        args.Paths,
+		Languages:    args.Languages,
+		ExcludeTests: args.ExcludeTests,
+		DocTypes:     args.DocTypes,
+	}
 }
 
 // AskResponse matches the HTTP API response format
@@ -176,6 +228,24 @@ func (h *HTTPAgent) registerGatewayTools(mcpServer *mcp.Server) error {
 	// Store repos for ask_all to use
 	h.repos = reposResp.Repos
 
+	// Register symbol lookup tools, which answer from the index without an LLM
+	mcp.AddTool(
+		mcpServer,
+		&mcp.Tool{
+			Name:        "find_definition",
+			Description: "Find where an identifier (function, method, type, class, constant) is defined in a repository. Returns file paths, line numbers and declaration lines. Use Type.Method to narrow down a method.",
+		},
+		h.handleFindDefinition,
+	)
+	mcp.AddTool(
+		mcpServer,
+		&mcp.Tool{
+			Name:        "find_references",
+			Description: "Find the lines where an identifier is used in a repository. References are matched by name, not resolved by type.",
+		},
+		h.handleFindReferences,
+	)
+
 	// Register a tool for each repository
 	for _, repo := range reposResp.Repos {
 		repoName := repo.Name
@@ -215,9 +285,7 @@ func (h *HTTPAgent) handleAsk(ctx context.Context, request *mcp.CallToolRequest,
 		Msg("MCP tool invoked, forwarding to HTTP agent")
 
 	// Build request
-	reqBody := AskRequest{
-		Question: args.Question,
-	}
+	reqBody := newAskRequest(args)
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
@@ -268,9 +336,7 @@ func (h *HTTPAgent) handleAskRepo(ctx context.Context, request *mcp.CallToolRequ
 		Msg("MCP tool invoked for repository, forwarding to gateway")
 
 	// Build request
-	reqBody := AskRequest{
-		Question: args.Question,
-	}
+	reqBody := newAskRequest(args)
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
@@ -349,7 +415,7 @@ func (h *HTTPAgent) handleAskAll(ctx context.Context, request *mcp.CallToolReque
 			result := repoResult{repo: repoName}
 
 			// Build request
-			reqBody := AskRequest{Question: args.Question}
+			reqBody := newAskRequest(args)
 			jsonData, err := json.Marshal(reqBody)
 			if err != nil {
 				result.err = fmt.Errorf("marshal error: %w", err)
@@ -443,3 +509,88 @@ func (h *HTTPAgent) handleAskAll(ctx context.Context, request *mcp.CallToolReque
 		},
 	}, nil, nil
 }
+
+// handleFindDefinition lists where an identifier is defined (gateway mode)
+func (h *HTTPAgent) handleFindDefinition(ctx context.Context, request *mcp.CallToolRequest, args SymbolToolArgs) (*mcp.CallToolResult, any, error) {
+	symbols, err := h.lookupSymbol(args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var text strings.Builder
+	if len(symbols.Definitions) == 0 {
+		fmt.Fprintf(&text, "No definition of %s found in %s (branch: %s).\n", args.Name, symbols.Repo, symbols.Branch)
+	}
+	for _, def := range symbols.Definitions {
+		kind := def.Kind
+		if def.Container != "" {
+			kind += " of " + def.Container
+		}
+		fmt.Fprintf(&text, "%s:%d (%s)\n    %s\n", def.Path, def.Line, kind, def.Signature)
+	}
+	if !symbols.Indexed {
+		text.WriteString("The branch has not been indexed yet.\n")
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: text.String()},
+		},
+	}, nil, nil
+}
+
+// handleFindReferences lists where an identifier is used (gateway mode)
+func (h *HTTPAgent) handleFindReferences(ctx context.Context, request *mcp.CallToolRequest, args SymbolToolArgs) (*mcp.CallToolResult, any, error) {
+	symbols, err := h.lookupSymbol(args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "%d references to %s in %s (branch: %s)", symbols.TotalReferences, args.Name, symbols.Repo, symbols.Branch)
+	if len(symbols.References) < symbols.TotalReferences {
+		fmt.Fprintf(&text, ", first %d shown", len(symbols.References))
+	}
+	text.WriteString(":\n")
+	for _, ref := range symbols.References {
+		fmt.Fprintf(&text, "%s:%d\n", ref.Path, ref.Line)
+	}
+	if !symbols.Indexed {
+		text.WriteString("The branch has not been indexed yet.\n")
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: text.String()},
+		},
+	}, nil, nil
+}
+
+// lookupSymbol fetches the definitions and references of a name from the gateway
+func (h *HTTPAgent) lookupSymbol(args SymbolToolArgs) (*SymbolResponse, error) {
+	h.logger.Info().
+		Str("repo", args.Repository).
+		Str("name", args.Name).
+		Msg("MCP symbol tool invoked, forwarding to gateway")
+
+	query := url.Values{"name": {args.Name}}
+	if args.Branch != "" {
+		query.Set("branch", args.Branch)
+	}
+	resp, err := http.Get(fmt.Sprintf("%s/repos/%s/symbols?%s", h.baseURL, url.PathEscape(args.Repository), query.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to call gateway: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("gateway error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var symbols SymbolResponse
+	if err := json.NewDecoder(resp.Body).Decode(&symbols); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &symbols, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/First008/mesh/internal/gateway"
+	"github.com/First008/mesh/internal/vectorstore"
+	"github.com/rs/zerolog"
+)
+
+func main() {
+	// Parse flags
+	configPath := flag.String("config", "configs/repos.yaml", "Path to gateway configuration file")
+	repoName := flag.String("repo", "", "Repository to verify (default: all repositories)")
+	branch := flag.String("branch", "", "Branch to verify (default: checked-out branch)")
+	repair := flag.Bool("repair", false, "Re-index missing/stale files and remove orphaned ones")
+	jsonOutput := flag.Bool("json", false, "Print reports as JSON")
+	verbose := flag.Bool("v", false, "Enable info logging")
+	flag.Parse()
+
+	// Setup logger (stderr, so stdout carries only the report)
+	level := zerolog.WarnLevel
+	if *verbose {
+		level = zerolog.InfoLevel
+	}
+	logger := zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}).
+		Level(level).
+		With().
+		Timestamp().
+		Logger()
+
+	config, err := gateway.LoadConfig(*configPath)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to load gateway configuration")
+	}
+
+	repos := []string{*repoName}
+	if *repoName == "" {
+		repos = repos[:0]
+		for _, repo := range config.Repos {
+			repos = append(repos, repo.Name)
+		}
+	}
+
+	ctx := context.Background()
+	exitCode := 0
+
+	for _, name := range repos {
+		report, err := gateway.VerifyBranch(ctx, config, name, *branch, *repair, logger)
+		if err != nil {
+			logger.Error().Err(err).Str("repo", name).Msg("Verification failed")
+			exitCode = 2
+			continue
+		}
+
+		if *jsonOutput {
+			data, _ := json.Marshal(report)
+			fmt.Println(string(data))
+		} else {
+			printReport(report)
+		}
+
+		if !report.Consistent() && (!report.Repaired || report.Errors > 0) && exitCode == 0 {
+			exitCode = 1
+		}
+	}
+
+	os.Exit(exitCode)
+}
+
+// printReport writes a human-readable verification report
+func printReport(report *vectorstore.VerifyReport) {
+	status := "✅ consistent"
+	if !report.Consistent() {
+		status = "❌ inconsistent"
+		if report.Repaired {
+			status = "🔧 repaired"
+		}
+	}
+
+	fmt.Printf("%s/%s @ %.8s: %s\n", report.Repo, report.Branch, report.Commit, status)
+	fmt.Printf("   Files: %d\n", report.Files)
+	printPaths("Missing", report.Missing)
+	printPaths("Stale", report.Stale)
+	printPaths("Orphaned", report.Orphaned)
+	if report.Unread > 0 {
+		fmt.Printf("   Unreadable: %d\n", report.Unread)
+	}
+	if report.Repaired {
+		fmt.Printf("   Re-indexed: %d, removed: %d, errors: %d\n", report.Reindexed, report.Removed, report.Errors)
+	}
+}
+
+// printPaths lists affected paths under a heading, if any
+func printPaths(label string, paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	fmt.Printf("   %s: %d\n", label, len(paths))
+	for _, path := range paths {
+		fmt.Printf("     - %s\n", path)
+	}
+}
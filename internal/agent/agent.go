@@ -7,12 +7,17 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 
 	contextbuilder "github.com/First008/mesh/internal/context"
 	"github.com/First008/mesh/internal/factory"
+	"github.com/First008/mesh/internal/filetypes"
 	"github.com/First008/mesh/internal/llm"
+	"github.com/First008/mesh/internal/models"
+	"github.com/First008/mesh/internal/tokenizer"
 	"github.com/First008/mesh/internal/vectorstore"
 	"github.com/First008/mesh/pkg/telemetry"
 	"github.com/rs/zerolog"
@@ -22,64 +27,34 @@ import (
 type Agent struct {
 	config         *Config
 	personality    *Personality
-	llmProvider    llm.LLMProvider
+	llmChain       []llmLink // Tried in order until one answers
 	contextBuilder *contextbuilder.Builder
 	costTracker    *telemetry.CostTracker
 	logger         zerolog.Logger
+
+	closeMu sync.RWMutex // Read-held while a question is answered; Close takes it
+	closed  bool
+
+	ownedMu sync.Mutex
+	store   vectorstore.VectorStore  // Closed with the agent
+	tree    *vectorstore.WorkingTree // Overlay closed with the agent, unless released
 }
 
+// ErrClosed is returned for questions asked after Close
+var ErrClosed = errors.New("agent closed")
+
 // New creates a new Agent instance
 func New(config *Config, logger zerolog.Logger) (*Agent, error) {
 	// Create personality
 	personality := NewPersonality(config.RepoName, config.Personality, config.FocusPaths)
 
-	// Create LLM provider using factory
-	llmProvider, err := factory.NewLLMProvider(
-		factory.LLMConfig{
-			Provider:     config.LLMProvider,
-			Model:        config.LLMModel,
-			AnthropicKey: config.AnthropicKey,
-			OpenAIKey:    config.OpenAIKey,
-			OllamaURL:    config.OllamaURL,
-		},
-		logger,
-	)
+	// Create the LLM fallback chain using factory, sized from the model registry
+	registry := models.NewRegistry(config.Models)
+	llmChain, err := newLLMChain(config, registry, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create LLM provider: %w", err)
 	}
-
-	// Pick context limits based on LLM provider
-	var maxRegularChars, maxChunksPerFile, maxChunkChars, maxCacheableLines int
-
-	// Simple string matching on provider/model
-	providerLower := strings.ToLower(config.LLMProvider)
-	modelLower := strings.ToLower(config.LLMModel)
-
-	if providerLower == "ollama" {
-		// Ollama local models: aggressive limits
-		maxRegularChars = 35000
-		maxChunksPerFile = 3
-		maxChunkChars = 1200
-		maxCacheableLines = 100 // Reduced from 200 for faster responses
-	} else if strings.Contains(modelLower, "sonnet") {
-		// Anthropic Sonnet: larger context OK
-		maxRegularChars = 80000
-		maxChunksPerFile = 5
-		maxChunkChars = 2000
-		maxCacheableLines = 500
-	} else if strings.Contains(modelLower, "haiku") {
-		// Anthropic Haiku: moderate
-		maxRegularChars = 50000
-		maxChunksPerFile = 4
-		maxChunkChars = 1500
-		maxCacheableLines = 300
-	} else {
-		// Fallback (OpenAI, unknown)
-		maxRegularChars = 50000
-		maxChunksPerFile = 3
-		maxChunkChars = 1500
-		maxCacheableLines = 300
-	}
+	limits := llmChain[0].limits
 
 	// Create context builder with limits
 	contextBuilder := contextbuilder.NewBuilderWithLimits(
@@ -89,12 +64,22 @@ func New(config *Config, logger zerolog.Logger) (*Agent, error) {
 		config.FocusPaths,
 		config.ExcludePatterns,
 		nil, // vectorStore will be set later if available
-		maxRegularChars,
-		maxChunksPerFile,
-		maxChunkChars,
-		maxCacheableLines,
+		limits.MaxRegularChars,
+		limits.MaxChunksPerFile,
+		limits.MaxChunkChars,
+		limits.MaxCacheableLines,
 		logger,
 	)
+	if config.Secrets != "" {
+		contextBuilder.SetSecretMode(config.Secrets)
+	}
+	if !config.FileTypes.Empty() {
+		types, err := filetypes.New(config.FileTypes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file_types: %w", err)
+		}
+		contextBuilder.SetFileTypes(types)
+	}
 
 	// Initialize vector store if configured (Phase 2+)
 	if config.QdrantURL != "" {
@@ -105,6 +90,7 @@ func New(config *Config, logger zerolog.Logger) (*Agent, error) {
 				OpenAIKey:   config.OpenAIKey,
 				OllamaURL:   config.OllamaURL,
 				OllamaModel: config.OllamaModel,
+				Resilience:  config.Resilience.Embedding,
 			},
 			logger,
 		)
@@ -123,6 +109,8 @@ func New(config *Config, logger zerolog.Logger) (*Agent, error) {
 			if err != nil {
 				logger.Warn().Err(err).Msg("Failed to initialize vector store, will use keyword search")
 			} else {
+				vectorStore.SetSearchConfig(vectorstore.SearchConfigForInputTokens(llmChain[0].caps.InputTokens()))
+				vectorStore.SetTokenizer(tokenizer.ForModel(llmChain[0].provider.GetModel()))
 				contextBuilder.SetVectorStore(vectorStore)
 				logger.Info().
 					Str("provider", embeddingProvider.GetModelName()).
@@ -139,61 +127,249 @@ func New(config *Config, logger zerolog.Logger) (*Agent, error) {
 		config.CostLimits.PerQueryMaxTokens,
 		logger,
 	)
+	costTracker.SetPricing(pricingLookup(registry))
 
 	return &Agent{
 		config:         config,
 		personality:    personality,
-		llmProvider:    llmProvider,
+		llmChain:       llmChain,
 		contextBuilder: contextBuilder,
 		costTracker:    costTracker,
 		logger:         logger,
 	}, nil
 }
 
+// llmLink is one provider in the agent's fallback chain
+type llmLink struct {
+	provider llm.LLMProvider
+	name     string                // Provider type, e.g. "anthropic"
+	caps     models.Capabilities   // Registry entry for the model
+	limits   contextbuilder.Limits // Context limits derived from caps
+	billed   bool                  // Counts against the daily cost budget
+}
+
+// caching reports whether prompt caching should be used for this link
+func (l llmLink) caching() bool {
+	return l.caps.SupportsCaching && l.provider.SupportsPromptCaching()
+}
+
+// label identifies the link in logs and errors
+func (l llmLink) label() string {
+	return l.name + "/" + l.provider.GetModel()
+}
+
+// newLLMChain creates a provider for every chain entry. Entries that cannot
+// be created are skipped so a missing key only removes that fallback.
+func newLLMChain(config *Config, registry *models.Registry, logger zerolog.Logger) ([]llmLink, error) {
+	entries := config.llmEntries()
+	chain := make([]llmLink, 0, len(entries))
+	var firstErr error
+
+	for _, entry := range entries {
+		name := strings.ToLower(entry.Provider)
+		if name == "" {
+			// Auto-detected by the factory; mirror its choice
+			name = detectedProvider(config)
+		}
+
+		provider, err := factory.NewLLMProvider(
+			factory.LLMConfig{
+				Provider:        entry.Provider,
+				Model:           entry.Model,
+				AnthropicKey:    config.AnthropicKey,
+				OpenAIKey:       config.OpenAIKey,
+				OllamaURL:       config.OllamaURL,
+				MaxOutputTokens: registry.Lookup(name, entry.Model).MaxOutputTokens,
+				Resilience:      config.Resilience.LLM,
+			},
+			logger,
+		)
+		if err != nil {
+			if len(entries) > 1 {
+				logger.Warn().
+					Err(err).
+					Str("provider", entry.Provider).
+					Str("model", entry.Model).
+					Msg("Skipping LLM provider in fallback chain")
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		caps := registry.Lookup(name, provider.GetModel())
+		chain = append(chain, llmLink{
+			provider: provider,
+			name:     name,
+			caps:     caps,
+			limits:   contextLimits(caps),
+			billed:   name != "ollama",
+		})
+	}
+
+	if len(chain) == 0 {
+		return nil, firstErr
+	}
+	return chain, nil
+}
+
+// detectedProvider mirrors the factory's provider auto-detection
+func detectedProvider(config *Config) string {
+	switch {
+	case config.OllamaURL != "":
+		return "ollama"
+	case config.AnthropicKey != "":
+		return "anthropic"
+	default:
+		return "openai"
+	}
+}
+
+const (
+	// charsPerToken converts token budgets to character limits
+	charsPerToken = 4
+
+	// maxRegularContextTokens caps retrieved code so answers stay fast even
+	// on models with very large windows
+	maxRegularContextTokens = 20000
+)
+
+// contextLimits derives context limits from a model's capabilities. Retrieved
+// code gets up to a third of the input window; chunk sizes and counts scale
+// with that budget. Models without prompt caching pay for static context on
+// every question, so they get fewer cacheable lines.
+func contextLimits(caps models.Capabilities) contextbuilder.Limits {
+	regularTokens := caps.InputTokens() / 3
+	if regularTokens > maxRegularContextTokens {
+		regularTokens = maxRegularContextTokens
+	}
+	regularChars := regularTokens * charsPerToken
+
+	chunksPerFile := 3
+	if regularChars >= 50000 {
+		chunksPerFile++
+	}
+	if regularChars >= 80000 {
+		chunksPerFile++
+	}
+
+	tokensPerLine := 100
+	if caps.SupportsCaching {
+		tokensPerLine = 40
+	}
+
+	return contextbuilder.Limits{
+		MaxRegularChars:   regularChars,
+		MaxChunksPerFile:  chunksPerFile,
+		MaxChunkChars:     clamp(regularChars/40, 1200, 2000),
+		MaxCacheableLines: clamp(regularTokens/tokensPerLine, 100, 500),
+	}
+}
+
+// clamp bounds v to [lo, hi]
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// pricingLookup adapts the model registry to the cost tracker
+func pricingLookup(registry *models.Registry) func(model string) (telemetry.PricingTable, bool) {
+	return func(model string) (telemetry.PricingTable, bool) {
+		caps, ok := registry.Find(model)
+		if !ok || !caps.Priced() {
+			return telemetry.PricingTable{}, false
+		}
+		return telemetry.PricingTable{
+			InputPricePerMToken:  caps.InputPricePerMToken,
+			OutputPricePerMToken: caps.OutputPricePerMToken,
+		}, true
+	}
+}
+
 // Ask asks the agent a question about the repository
 func (a *Agent) Ask(ctx context.Context, question string) (*llm.Response, error) {
+	return a.AskWithOptions(ctx, question, contextbuilder.QueryOptions{})
+}
+
+// AskWithOptions asks a question with per-query context options
+func (a *Agent) AskWithOptions(ctx context.Context, question string, opts contextbuilder.QueryOptions) (*llm.Response, error) {
+	a.closeMu.RLock()
+	defer a.closeMu.RUnlock()
+	if a.closed {
+		return nil, ErrClosed
+	}
+
 	a.logger.Info().
 		Str("repo", a.config.RepoName).
 		Str("question", question).
+		Bool("working_tree", opts.WorkingTree).
 		Msg("Received question")
 
-	// 1. Build context in layers (cacheable vs regular)
-	contextLayers, err := a.contextBuilder.BuildContextLayers(question)
-	if err != nil {
-		return nil, fmt.Errorf("failed to build context: %w", err)
-	}
-
-	// 2. Get system prompt from personality
+	// 1. Get system prompt from personality
 	systemPrompt := a.personality.GetSystemPrompt()
 
-	// 3. Call LLM provider with caching if supported
+	// 2. Walk the fallback chain until a provider answers. Context is built
+	// per distinct set of limits so a smaller fallback model gets less of it.
+	layersByLimits := make(map[contextbuilder.Limits]*contextbuilder.ContextLayers)
 	var response *llm.Response
-	if a.llmProvider.SupportsPromptCaching() && contextLayers.Cacheable != "" {
-		// Use caching for static content
-		response, err = a.llmProvider.AskWithCache(
-			ctx,
-			systemPrompt,
-			contextLayers.Cacheable,
-			contextLayers.Regular,
-			question,
-		)
-	} else {
-		// Fallback to regular Ask (no caching)
-		combinedContext := contextLayers.Cacheable + contextLayers.Regular
-		userPrompt := fmt.Sprintf(`Repository Context:
-%s
+	var failures []string
 
----
+	for i, link := range a.llmChain {
+		if link.billed && a.costTracker.BudgetExhausted() {
+			a.logger.Warn().
+				Str("provider", link.label()).
+				Msg("Daily budget exhausted, skipping LLM provider")
+			failures = append(failures, link.label()+": daily budget exhausted")
+			continue
+		}
 
-Question: %s`, combinedContext, question)
-		response, err = a.llmProvider.Ask(ctx, systemPrompt, userPrompt)
+		contextLayers, ok := layersByLimits[link.limits]
+		if !ok {
+			limits := link.limits
+			opts.Limits = &limits
+			layers, err := a.contextBuilder.BuildContextLayersWithOptions(question, opts)
+			if err != nil {
+				return nil, fmt.Errorf("failed to build context: %w", err)
+			}
+			layersByLimits[link.limits] = layers
+			contextLayers = layers
+		}
+
+		resp, err := askProvider(ctx, link, systemPrompt, contextLayers, question)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("LLM request failed: %w", err)
+			}
+			a.logger.Warn().
+				Err(err).
+				Str("provider", link.label()).
+				Msg("LLM provider failed")
+			failures = append(failures, fmt.Sprintf("%s: %v", link.label(), err))
+			continue
+		}
+
+		resp.Provider = link.name
+		if i > 0 {
+			a.logger.Info().
+				Str("provider", link.label()).
+				Int("position", i+1).
+				Msg("Question answered by fallback LLM provider")
+		}
+		response = resp
+		break
 	}
 
-	if err != nil {
-		return nil, fmt.Errorf("LLM request failed: %w", err)
+	if response == nil {
+		return nil, fmt.Errorf("LLM request failed: %s", strings.Join(failures, "; "))
 	}
 
-	// 5. Track costs
+	// 3. Track costs
 	cost, err := a.costTracker.RecordRequest(
 		response.Model,
 		response.InputTokens,
@@ -211,11 +387,75 @@ Question: %s`, combinedContext, question)
 		Int("output_tokens", response.OutputTokens).
 		Int("cached_tokens", response.CachedTokens).
 		Float64("cost_usd", cost).
+		Str("provider", response.Provider).
+		Str("model", response.Model).
 		Msg("Question answered")
 
 	return response, nil
 }
 
+// summarySystemPrompt frames the prompts of Summarize
+const summarySystemPrompt = "You write short, precise technical summaries of source code for engineers new to a codebase."
+
+// Summarize answers a prompt without repository context, walking the
+// fallback chain like Ask; it writes the package summaries of a repository
+// at index time (see vectorstore.Summarizer)
+func (a *Agent) Summarize(ctx context.Context, prompt string) (string, error) {
+	a.closeMu.RLock()
+	defer a.closeMu.RUnlock()
+	if a.closed {
+		return "", ErrClosed
+	}
+
+	var failures []string
+	for _, link := range a.llmChain {
+		if link.billed && a.costTracker.BudgetExhausted() {
+			failures = append(failures, link.label()+": daily budget exhausted")
+			continue
+		}
+
+		resp, err := link.provider.Ask(ctx, summarySystemPrompt, prompt)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", err
+			}
+			failures = append(failures, fmt.Sprintf("%s: %v", link.label(), err))
+			continue
+		}
+
+		if _, err := a.costTracker.RecordRequest(resp.Model, resp.InputTokens, resp.OutputTokens, resp.CachedTokens); err != nil {
+			a.logger.Error().Err(err).Msg("Cost tracking failed")
+		}
+		return resp.Content, nil
+	}
+	return "", fmt.Errorf("LLM request failed: %s", strings.Join(failures, "; "))
+}
+
+// askProvider sends the question to one provider, using prompt caching when supported
+func askProvider(ctx context.Context, link llmLink, systemPrompt string, contextLayers *contextbuilder.ContextLayers, question string) (*llm.Response, error) {
+	provider := link.provider
+	if link.caching() && contextLayers.Cacheable != "" {
+		// Use caching for static content
+		return provider.AskWithCache(
+			ctx,
+			systemPrompt,
+			contextLayers.Cacheable,
+			contextLayers.Regular,
+			question,
+		)
+	}
+
+	// Fallback to regular Ask (no caching)
+	combinedContext := contextLayers.Cacheable + contextLayers.Regular
+	userPrompt := fmt.Sprintf(`Repository Context:
+%s
+
+---
+
+Question: %s`, combinedContext, question)
+	return provider.Ask(ctx, systemPrompt, userPrompt)
+}
+
 // GetRepoName returns the repository name
 func (a *Agent) GetRepoName() string {
 	return a.config.RepoName
@@ -231,13 +471,80 @@ func (a *Agent) GetTotalStats() telemetry.TotalStats {
 	return a.costTracker.GetTotalStats()
 }
 
-// GetModel returns the LLM model being used
+// GetModel returns the model of the primary LLM provider
 func (a *Agent) GetModel() string {
-	return a.llmProvider.GetModel()
+	return a.llmChain[0].provider.GetModel()
+}
+
+// GetLLMChain returns the fallback chain as provider/model labels, in order
+func (a *Agent) GetLLMChain() []string {
+	labels := make([]string, len(a.llmChain))
+	for i, link := range a.llmChain {
+		labels[i] = link.label()
+	}
+	return labels
 }
 
 // SetVectorStore updates the agent's vector store for semantic search
 // Used by gateway to update the vector store after branch detection
 func (a *Agent) SetVectorStore(store vectorstore.VectorStore) {
+	if qs, ok := store.(*vectorstore.QdrantStore); ok {
+		qs.SetSearchConfig(vectorstore.SearchConfigForInputTokens(a.llmChain[0].caps.InputTokens()))
+		qs.SetTokenizer(tokenizer.ForModel(a.llmChain[0].provider.GetModel()))
+	}
 	a.contextBuilder.SetVectorStore(store)
+
+	a.ownedMu.Lock()
+	a.store = store
+	a.ownedMu.Unlock()
+}
+
+// SetBranchIndex sets the branch whose symbol index supplies definitions of
+// identifiers mentioned in questions, and whose repository summary orients
+// the model
+func (a *Agent) SetBranchIndex(branch string) {
+	a.contextBuilder.SetBranchIndex(branch)
+}
+
+// SetWorkingTree enables the uncommitted-changes overlay for queries that opt in
+func (a *Agent) SetWorkingTree(tree *vectorstore.WorkingTree) {
+	a.contextBuilder.SetWorkingTree(tree)
+
+	a.ownedMu.Lock()
+	a.tree = tree
+	a.ownedMu.Unlock()
+}
+
+// ReleaseWorkingTree hands the overlay over to another agent: it keeps
+// serving questions already being answered, but Close leaves it open
+func (a *Agent) ReleaseWorkingTree() {
+	a.ownedMu.Lock()
+	a.tree = nil
+	a.ownedMu.Unlock()
+}
+
+// Close waits for questions being answered to finish, then closes the
+// agent's vector store and working tree overlay. Later questions fail with
+// ErrClosed.
+func (a *Agent) Close() error {
+	a.closeMu.Lock()
+	defer a.closeMu.Unlock()
+	if a.closed {
+		return nil
+	}
+	a.closed = true
+
+	a.ownedMu.Lock()
+	store, tree := a.store, a.tree
+	a.store, a.tree = nil, nil
+	a.ownedMu.Unlock()
+
+	var errs []error
+	if store != nil {
+		errs = append(errs, store.Close())
+	}
+	if tree != nil {
+		errs = append(errs, tree.Store().Close())
+	}
+	return errors.Join(errs...)
 }
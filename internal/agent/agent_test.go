@@ -1,9 +1,19 @@
 package agent
 
 import (
+	"context"
+	"errors"
 	"io"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	contextbuilder "github.com/First008/mesh/internal/context"
+	"github.com/First008/mesh/internal/llm"
+	"github.com/First008/mesh/internal/models"
+	"github.com/First008/mesh/internal/vectorstore"
+	"github.com/First008/mesh/pkg/telemetry"
 	"github.com/rs/zerolog"
 )
 
@@ -37,8 +47,8 @@ func TestNew_Success(t *testing.T) {
 		t.Error("Agent config is nil")
 	}
 
-	if agent.llmProvider == nil {
-		t.Error("Agent LLM provider is nil")
+	if len(agent.llmChain) == 0 {
+		t.Error("Agent LLM chain is empty")
 	}
 
 	if agent.contextBuilder == nil {
@@ -359,3 +369,268 @@ func TestAsk_WithMockLLM(t *testing.T) {
 	}
 }
 */
+
+// stubLLM answers with its model name, or fails with err when set
+type stubLLM struct {
+	model string
+	err   error
+	calls int
+}
+
+func (s *stubLLM) Ask(ctx context.Context, systemPrompt, userPrompt string) (*llm.Response, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &llm.Response{Content: "answer from " + s.model, Model: s.model}, nil
+}
+
+func (s *stubLLM) AskWithCache(ctx context.Context, systemPrompt, cacheableContext, regularContext, question string) (*llm.Response, error) {
+	return s.Ask(ctx, systemPrompt, question)
+}
+
+func (s *stubLLM) CountTokens(text string) (int, error) { return len(text) / 4, nil }
+func (s *stubLLM) GetModel() string                     { return s.model }
+func (s *stubLLM) SupportsPromptCaching() bool          { return false }
+
+// testAgentWithChain builds an agent around the given fallback chain
+func testAgentWithChain(t *testing.T, costTracker *telemetry.CostTracker, chain ...llmLink) *Agent {
+	t.Helper()
+	return &Agent{
+		config:         &Config{RepoName: "test-repo"},
+		personality:    NewPersonality("test-repo", "", nil),
+		llmChain:       chain,
+		contextBuilder: contextbuilder.NewBuilder(t.TempDir(), "test-repo", nil, testLogger()),
+		costTracker:    costTracker,
+		logger:         testLogger(),
+	}
+}
+
+func TestAsk_FallsBackOnProviderError(t *testing.T) {
+	local := &stubLLM{model: "llama3.3:70b"}
+	agt := testAgentWithChain(t,
+		telemetry.NewCostTracker(10, 8, 100000, testLogger()),
+		llmLink{provider: &stubLLM{model: "claude-sonnet-4-5", err: errors.New("overloaded")}, name: "anthropic", billed: true, limits: contextLimits(models.NewRegistry(nil).Lookup("anthropic", "claude-sonnet-4-5"))},
+		llmLink{provider: local, name: "ollama", limits: contextLimits(models.NewRegistry(nil).Lookup("ollama", "llama3.3:70b"))},
+	)
+
+	response, err := agt.Ask(context.Background(), "What does this repo do?")
+	if err != nil {
+		t.Fatalf("Expected fallback to answer, got %v", err)
+	}
+	if response.Provider != "ollama" || response.Model != "llama3.3:70b" {
+		t.Errorf("Expected answer from ollama/llama3.3:70b, got %s/%s", response.Provider, response.Model)
+	}
+	if local.calls != 1 {
+		t.Errorf("Expected fallback to be called once, got %d", local.calls)
+	}
+}
+
+func TestAsk_SkipsBilledProvidersWhenBudgetExhausted(t *testing.T) {
+	costTracker := telemetry.NewCostTracker(0.10, 0.08, 100000, testLogger())
+	costTracker.RecordRequest("claude-sonnet-4-5-20250929", 10000, 5000, 0) // Refused: over the limit
+
+	paid := &stubLLM{model: "claude-sonnet-4-5-20250929"}
+	local := &stubLLM{model: "llama3.3:70b"}
+	agt := testAgentWithChain(t, costTracker,
+		llmLink{provider: paid, name: "anthropic", billed: true},
+		llmLink{provider: local, name: "ollama"},
+	)
+
+	response, err := agt.Ask(context.Background(), "What does this repo do?")
+	if err != nil {
+		t.Fatalf("Expected local provider to answer, got %v", err)
+	}
+	if paid.calls != 0 {
+		t.Errorf("Expected billed provider to be skipped, got %d calls", paid.calls)
+	}
+	if response.Provider != "ollama" {
+		t.Errorf("Expected answer from ollama, got %s", response.Provider)
+	}
+}
+
+func TestSummarize_FallsBackOnProviderError(t *testing.T) {
+	local := &stubLLM{model: "llama3.3:70b"}
+	agt := testAgentWithChain(t,
+		telemetry.NewCostTracker(10, 8, 100000, testLogger()),
+		llmLink{provider: &stubLLM{model: "claude-sonnet-4-5", err: errors.New("overloaded")}, name: "anthropic", billed: true},
+		llmLink{provider: local, name: "ollama"},
+	)
+
+	summary, err := agt.Summarize(context.Background(), "Summarize what the directory `api` does")
+	if err != nil {
+		t.Fatalf("Expected fallback to summarize, got %v", err)
+	}
+	if summary != "answer from llama3.3:70b" || local.calls != 1 {
+		t.Errorf("Expected one summary from the fallback, got %q after %d calls", summary, local.calls)
+	}
+}
+
+func TestAsk_AllProvidersFail(t *testing.T) {
+	agt := testAgentWithChain(t,
+		telemetry.NewCostTracker(10, 8, 100000, testLogger()),
+		llmLink{provider: &stubLLM{model: "claude-sonnet-4-5", err: errors.New("overloaded")}, name: "anthropic"},
+		llmLink{provider: &stubLLM{model: "llama3.3:70b", err: errors.New("connection refused")}, name: "ollama"},
+	)
+
+	_, err := agt.Ask(context.Background(), "What does this repo do?")
+	if err == nil {
+		t.Fatal("Expected error when every provider fails")
+	}
+	for _, want := range []string{"anthropic/claude-sonnet-4-5: overloaded", "ollama/llama3.3:70b: connection refused"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %q, got %v", want, err)
+		}
+	}
+}
+
+func TestContextLimits(t *testing.T) {
+	registry := models.NewRegistry([]models.Capabilities{
+		{Name: "qwen2.5-coder", Provider: "ollama", ContextWindow: 131072, MaxOutputTokens: 8192},
+	})
+
+	tests := []struct {
+		provider          string
+		model             string
+		maxRegularChars   int
+		maxChunksPerFile  int
+		maxCacheableLines int
+	}{
+		{"ollama", "llama3.3:70b", 38228, 3, 100},
+		{"ollama", "qwen2.5-coder:32b", 80000, 5, 200},
+		{"anthropic", "claude-sonnet-4-5-20250929", 80000, 5, 500},
+		{"anthropic", "claude-3-5-haiku-20241022", 80000, 5, 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			limits := contextLimits(registry.Lookup(tt.provider, tt.model))
+			if limits.MaxRegularChars != tt.maxRegularChars {
+				t.Errorf("Expected MaxRegularChars %d, got %d", tt.maxRegularChars, limits.MaxRegularChars)
+			}
+			if limits.MaxChunksPerFile != tt.maxChunksPerFile {
+				t.Errorf("Expected MaxChunksPerFile %d, got %d", tt.maxChunksPerFile, limits.MaxChunksPerFile)
+			}
+			if limits.MaxCacheableLines != tt.maxCacheableLines {
+				t.Errorf("Expected MaxCacheableLines %d, got %d", tt.maxCacheableLines, limits.MaxCacheableLines)
+			}
+		})
+	}
+}
+
+func TestPricingLookup(t *testing.T) {
+	lookup := pricingLookup(models.NewRegistry([]models.Capabilities{
+		{Name: "claude-sonnet-5", Provider: "anthropic", InputPricePerMToken: 4, OutputPricePerMToken: 20},
+	}))
+
+	if pricing, ok := lookup("claude-sonnet-4-5-20250929"); !ok || pricing.InputPricePerMToken != 3.00 {
+		t.Errorf("Expected built-in Sonnet 4.5 pricing, got %+v (%v)", pricing, ok)
+	}
+	if pricing, ok := lookup("claude-sonnet-5-20261001"); !ok || pricing.OutputPricePerMToken != 20 {
+		t.Errorf("Expected override pricing for new model, got %+v (%v)", pricing, ok)
+	}
+	if _, ok := lookup("llama3.3:70b"); ok {
+		t.Error("Expected local model to have no pricing")
+	}
+}
+
+func TestConfig_LLMEntries(t *testing.T) {
+	single := &Config{LLMProvider: "anthropic", LLMModel: "claude-sonnet-4-5-20250929"}
+	if entries := single.llmEntries(); len(entries) != 1 || entries[0].Provider != "anthropic" {
+		t.Errorf("Expected single entry from llm_provider, got %v", entries)
+	}
+
+	chain := &Config{
+		LLMProvider: "anthropic",
+		LLMChain:    []LLMEntry{{Provider: "anthropic", Model: "claude-haiku-4-5-20251001"}, {Provider: "ollama", Model: "llama3.3:70b"}},
+	}
+	if entries := chain.llmEntries(); len(entries) != 2 || entries[1].Provider != "ollama" {
+		t.Errorf("Expected llm_chain to take precedence, got %v", entries)
+	}
+}
+
+// blockingLLM answers once release is closed, signalling started first
+type blockingLLM struct {
+	stubLLM
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingLLM) Ask(ctx context.Context, systemPrompt, userPrompt string) (*llm.Response, error) {
+	close(b.started)
+	<-b.release
+	return b.stubLLM.Ask(ctx, systemPrompt, userPrompt)
+}
+
+func (b *blockingLLM) AskWithCache(ctx context.Context, systemPrompt, cacheableContext, regularContext, question string) (*llm.Response, error) {
+	return b.Ask(ctx, systemPrompt, question)
+}
+
+// closeTrackingStore is an empty vector store recording Close
+type closeTrackingStore struct {
+	closed atomic.Bool
+}
+
+func (s *closeTrackingStore) IndexFile(ctx context.Context, filePath, content string) error {
+	return nil
+}
+func (s *closeTrackingStore) Search(ctx context.Context, query string, limit int) ([]vectorstore.SearchResult, error) {
+	return nil, nil
+}
+func (s *closeTrackingStore) SearchWithAggregation(ctx context.Context, query string, limit int) ([]vectorstore.SearchResult, error) {
+	return nil, nil
+}
+func (s *closeTrackingStore) DeleteFile(ctx context.Context, filePath string) error { return nil }
+func (s *closeTrackingStore) ListIndexedChunks(ctx context.Context) (map[string]string, error) {
+	return nil, nil
+}
+func (s *closeTrackingStore) DeleteCollection(ctx context.Context) error { return nil }
+func (s *closeTrackingStore) GetStats(ctx context.Context) (*vectorstore.Stats, error) {
+	return &vectorstore.Stats{}, nil
+}
+func (s *closeTrackingStore) Close() error {
+	s.closed.Store(true)
+	return nil
+}
+
+func TestClose_WaitsForQuestionsThenClosesStore(t *testing.T) {
+	provider := &blockingLLM{stubLLM: stubLLM{model: "llama3.3:70b"}, started: make(chan struct{}), release: make(chan struct{})}
+	agt := testAgentWithChain(t, telemetry.NewCostTracker(10, 8, 100000, testLogger()), llmLink{provider: provider, name: "ollama"})
+
+	store := &closeTrackingStore{}
+	agt.SetVectorStore(store)
+
+	asked := make(chan error, 1)
+	go func() {
+		_, err := agt.Ask(context.Background(), "What does this repo do?")
+		asked <- err
+	}()
+	<-provider.started
+
+	closed := make(chan error, 1)
+	go func() { closed <- agt.Close() }()
+
+	select {
+	case <-closed:
+		t.Fatal("Expected Close to wait for the question being answered")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if store.closed.Load() {
+		t.Fatal("Expected store to stay open while a question is answered")
+	}
+
+	close(provider.release)
+	if err := <-asked; err != nil {
+		t.Errorf("Expected in-flight question to be answered, got %v", err)
+	}
+	if err := <-closed; err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if !store.closed.Load() {
+		t.Error("Expected Close to close the vector store")
+	}
+
+	if _, err := agt.Ask(context.Background(), "Still there?"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+}
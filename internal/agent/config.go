@@ -5,26 +5,49 @@ import (
 	"os"
 	"strings"
 
+	"github.com/First008/mesh/internal/filetypes"
+	"github.com/First008/mesh/internal/models"
+	"github.com/First008/mesh/internal/resilience"
+	"github.com/First008/mesh/internal/secrets"
 	"gopkg.in/yaml.v3"
 )
 
 // Config holds the configuration for a single agent instance
 type Config struct {
-	RepoPath          string     `yaml:"repo_path"`
-	RepoName          string     `yaml:"repo_name"`
-	FocusPaths        []string   `yaml:"focus_paths"`
-	Personality       string     `yaml:"personality"`
-	ExcludePatterns   []string   `yaml:"exclude_patterns"` // File patterns to exclude from search results
-	Port              int        `yaml:"port"`
-	AnthropicKey      string     `yaml:"anthropic_key"`
-	OpenAIKey         string     `yaml:"openai_key"`
-	QdrantURL         string     `yaml:"qdrant_url"`
-	EmbeddingProvider string     `yaml:"embedding_provider"` // "openai" or "ollama"
-	OllamaURL         string     `yaml:"ollama_url"`         // Ollama API endpoint
-	OllamaModel       string     `yaml:"ollama_model"`       // Ollama embedding model
-	LLMProvider       string     `yaml:"llm_provider"`       // "anthropic", "ollama", "openai"
-	LLMModel          string     `yaml:"llm_model"`          // LLM model to use (e.g. "claude-sonnet-4-5-20250929", "claude-haiku-4-5-20251001")
-	CostLimits        CostLimits `yaml:"cost_limits"`
+	RepoPath          string                `yaml:"repo_path"`
+	RepoName          string                `yaml:"repo_name"`
+	FocusPaths        []string              `yaml:"focus_paths"`
+	Personality       string                `yaml:"personality"`
+	ExcludePatterns   []string              `yaml:"exclude_patterns"` // File patterns to exclude from search results
+	Secrets           secrets.Mode          `yaml:"secrets"`          // Files with secrets: redact (default), skip, or off
+	FileTypes         filetypes.Config      `yaml:"file_types"`       // Extensions, file names and shebangs indexed as code, on top of the built-in ones
+	Port              int                   `yaml:"port"`
+	AnthropicKey      string                `yaml:"anthropic_key"`
+	OpenAIKey         string                `yaml:"openai_key"`
+	QdrantURL         string                `yaml:"qdrant_url"`
+	EmbeddingProvider string                `yaml:"embedding_provider"` // "openai" or "ollama"
+	OllamaURL         string                `yaml:"ollama_url"`         // Ollama API endpoint
+	OllamaModel       string                `yaml:"ollama_model"`       // Ollama embedding model
+	LLMProvider       string                `yaml:"llm_provider"`       // "anthropic", "ollama", "openai"
+	LLMModel          string                `yaml:"llm_model"`          // LLM model to use (e.g. "claude-sonnet-4-5-20250929", "claude-haiku-4-5-20251001")
+	LLMChain          []LLMEntry            `yaml:"llm_chain"`          // Ordered fallback chain; overrides llm_provider/llm_model when set
+	Models            []models.Capabilities `yaml:"models"`             // Model registry overrides and additions
+	CostLimits        CostLimits            `yaml:"cost_limits"`
+	Resilience        resilience.Config     `yaml:"resilience"` // Retry, rate limit and circuit breaker policies
+}
+
+// LLMEntry is one provider in an ordered LLM fallback chain
+type LLMEntry struct {
+	Provider string `yaml:"provider"` // "anthropic", "ollama", "openai"
+	Model    string `yaml:"model"`
+}
+
+// llmEntries returns the fallback chain, or the single configured provider
+func (c *Config) llmEntries() []LLMEntry {
+	if len(c.LLMChain) > 0 {
+		return c.LLMChain
+	}
+	return []LLMEntry{{Provider: c.LLMProvider, Model: c.LLMModel}}
 }
 
 // CostLimits defines cost constraints for the agent
@@ -79,6 +102,22 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	for i, entry := range c.LLMChain {
+		if entry.Provider == "" {
+			errors = append(errors, fmt.Sprintf("llm_chain[%d]: provider is required", i))
+		}
+	}
+
+	if mode, err := secrets.ParseMode(string(c.Secrets)); err != nil {
+		errors = append(errors, err.Error())
+	} else {
+		c.Secrets = mode
+	}
+
+	if err := c.FileTypes.Validate(); err != nil {
+		errors = append(errors, err.Error())
+	}
+
 	// Same for OpenAI key (optional for Phase 1)
 	if c.OpenAIKey == "" {
 		c.OpenAIKey = os.Getenv("OPENAI_API_KEY")
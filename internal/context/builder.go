@@ -14,24 +14,35 @@ import (
 	"strings"
 
 	"github.com/First008/mesh/internal/filetypes"
+	"github.com/First008/mesh/internal/secrets"
 	"github.com/First008/mesh/internal/vectorstore"
 	"github.com/rs/zerolog"
 )
 
 // Builder builds context for LLM queries from repository files
 type Builder struct {
-	repoPath          string
-	repoName          string
-	branch            string
-	focusPaths        []string
-	personality       string
-	excludePatterns   []string                // File patterns to exclude from results
-	vectorStore       vectorstore.VectorStore // Optional: for semantic search (Phase 2+)
-	logger            zerolog.Logger
-	maxRegularChars   int // Max chars for "regular" context layer
-	maxChunksPerFile  int // Max chunks to include per file
-	maxChunkChars     int // Max chars per individual chunk
-	maxCacheableLines int // Limit cacheable content (0 = no limit)
+	repoPath        string
+	repoName        string
+	branch          string
+	focusPaths      []string
+	personality     string
+	excludePatterns []string                 // File patterns to exclude from results
+	vectorStore     vectorstore.VectorStore  // Optional: for semantic search (Phase 2+)
+	workingTree     *vectorstore.WorkingTree // Optional: uncommitted changes overlay
+	secretMode      secrets.Mode             // How file content containing secrets is handled
+	fileTypes       *filetypes.Registry      // Which files are code (nil = built-in)
+	indexBranch     string                   // Branch whose symbol index and summary are used ("" = none)
+	logger          zerolog.Logger
+	limits          Limits // Default limits, overridable per query
+}
+
+// Limits bounds how much context is gathered for a question, sized to the
+// LLM that will answer it
+type Limits struct {
+	MaxRegularChars   int // Max chars for "regular" context layer
+	MaxChunksPerFile  int // Max chunks to include per file
+	MaxChunkChars     int // Max chars per individual chunk
+	MaxCacheableLines int // Limit cacheable content (0 = no limit)
 }
 
 // NewBuilder creates a new context builder (backward compatible)
@@ -54,26 +65,47 @@ func NewBuilderWithOptions(repoPath, repoName, branch string, focusPaths, exclud
 func NewBuilderWithLimits(repoPath, repoName, branch string, focusPaths, excludePatterns []string, vectorStore vectorstore.VectorStore,
 	maxRegularChars, maxChunksPerFile, maxChunkChars, maxCacheableLines int, logger zerolog.Logger) *Builder {
 	return &Builder{
-		repoPath:          repoPath,
-		repoName:          repoName,
-		branch:            branch,
-		focusPaths:        focusPaths,
-		excludePatterns:   excludePatterns,
-		vectorStore:       vectorStore,
-		maxRegularChars:   maxRegularChars,
-		maxChunksPerFile:  maxChunksPerFile,
-		maxChunkChars:     maxChunkChars,
-		maxCacheableLines: maxCacheableLines,
-		logger:            logger,
+		repoPath:        repoPath,
+		repoName:        repoName,
+		branch:          branch,
+		focusPaths:      focusPaths,
+		excludePatterns: excludePatterns,
+		vectorStore:     vectorStore,
+		secretMode:      secrets.ModeRedact,
+		limits: Limits{
+			MaxRegularChars:   maxRegularChars,
+			MaxChunksPerFile:  maxChunksPerFile,
+			MaxChunkChars:     maxChunkChars,
+			MaxCacheableLines: maxCacheableLines,
+		},
+		logger: logger,
 	}
 }
 
+// Limits returns the builder's default context limits
+func (b *Builder) Limits() Limits {
+	return b.limits
+}
+
 // SetVectorStore sets the vector store for semantic search
 func (b *Builder) SetVectorStore(store vectorstore.VectorStore) {
 	b.vectorStore = store
 	b.logger.Info().Msg("Vector store enabled for semantic search")
 }
 
+// SetBranchIndex sets the branch whose per-branch indexes are used: the symbol
+// index resolving identifiers in questions to their definitions, and the
+// repository summary of the cacheable layer ("" disables both)
+func (b *Builder) SetBranchIndex(branch string) {
+	b.indexBranch = branch
+}
+
+// SetWorkingTree enables the uncommitted-changes overlay for queries that opt in
+func (b *Builder) SetWorkingTree(tree *vectorstore.WorkingTree) {
+	b.workingTree = tree
+	b.logger.Info().Msg("Working tree overlay enabled")
+}
+
 // SetExcludePatterns sets file exclusion patterns
 func (b *Builder) SetExcludePatterns(patterns []string) {
 	b.excludePatterns = patterns
@@ -82,6 +114,18 @@ func (b *Builder) SetExcludePatterns(patterns []string) {
 	}
 }
 
+// SetSecretMode sets how file content containing secrets is handled before it
+// is sent to the LLM: redacted (the default), left out, or sent as-is
+func (b *Builder) SetSecretMode(mode secrets.Mode) {
+	b.secretMode = mode
+}
+
+// SetFileTypes sets which files are code and in which language, for keyword
+// search; nil means the built-in registry
+func (b *Builder) SetFileTypes(types *filetypes.Registry) {
+	b.fileTypes = types
+}
+
 // SetPersonality sets the custom personality for this repository's agent
 func (b *Builder) SetPersonality(personality string) {
 	b.personality = personality
@@ -94,16 +138,37 @@ type ContextLayers struct {
 	Regular   string // Dynamic content (code search results) - not cached
 }
 
+// QueryOptions adjusts how context is gathered for a single question
+type QueryOptions struct {
+	WorkingTree bool                     // Search uncommitted working-tree changes on top of the branch index
+	Limits      *Limits                  // Overrides the builder's limits (e.g. for a fallback LLM)
+	Filter      vectorstore.SearchFilter // Restricts code search by path, language and test files
+}
+
+// limitsFor returns the limits that apply to a query
+func (b *Builder) limitsFor(opts QueryOptions) Limits {
+	if opts.Limits != nil {
+		return *opts.Limits
+	}
+	return b.limits
+}
+
 // BuildContextLayers builds context in layers for prompt caching optimization
 func (b *Builder) BuildContextLayers(question string) (*ContextLayers, error) {
+	return b.BuildContextLayersWithOptions(question, QueryOptions{})
+}
+
+// BuildContextLayersWithOptions builds layered context with per-query options
+func (b *Builder) BuildContextLayersWithOptions(question string, opts QueryOptions) (*ContextLayers, error) {
 	var cacheableSB, regularSB strings.Builder
+	limits := b.limitsFor(opts)
 
 	// Layer 1 (Cacheable): CLAUDE.md - rarely changes
 	claudeMD, err := b.loadClaudeMD()
 	if err == nil && claudeMD != "" {
 		// Truncate for local models if limit is set
-		if b.maxCacheableLines > 0 {
-			claudeMD = truncateToLines(claudeMD, b.maxCacheableLines)
+		if limits.MaxCacheableLines > 0 {
+			claudeMD = truncateToLines(claudeMD, limits.MaxCacheableLines)
 		}
 		cacheableSB.WriteString("# Project Context (from CLAUDE.md)\n\n")
 		cacheableSB.WriteString(claudeMD)
@@ -115,8 +180,8 @@ func (b *Builder) BuildContextLayers(question string) (*ContextLayers, error) {
 	readme, err := b.loadReadme()
 	if err == nil && readme != "" {
 		// Truncate for local models if limit is set
-		if b.maxCacheableLines > 0 {
-			readme = truncateToLines(readme, b.maxCacheableLines)
+		if limits.MaxCacheableLines > 0 {
+			readme = truncateToLines(readme, limits.MaxCacheableLines)
 		}
 		cacheableSB.WriteString("# README\n\n")
 		cacheableSB.WriteString(readme)
@@ -124,17 +189,37 @@ func (b *Builder) BuildContextLayers(question string) (*ContextLayers, error) {
 		b.logger.Debug().Msg("Loaded README.md (cacheable)")
 	}
 
-	// Layer 1 (Cacheable): Repository structure
+	// Layer 1 (Cacheable): Repository structure - changes with the indexed commit
 	structure := b.getRepoStructure()
 	if structure != "" {
+		if limits.MaxCacheableLines > 0 {
+			structure = truncateToLines(structure, limits.MaxCacheableLines)
+		}
 		cacheableSB.WriteString("# Repository Structure\n\n")
 		cacheableSB.WriteString(structure)
 		cacheableSB.WriteString("\n\n")
 	}
 
+	// Layer 2 (Regular): Definitions of identifiers the question mentions
+	if definitions := b.findDefinitions(question, limits); len(definitions) > 0 {
+		names := make([]string, len(definitions))
+		for i, d := range definitions {
+			names[i] = d.RelPath
+		}
+		b.logger.Info().Strs("definitions", names).Msg("Definitions for LLM")
+
+		regularSB.WriteString("# Definitions of Identifiers in the Question\n\n")
+		for _, d := range definitions {
+			regularSB.WriteString(fmt.Sprintf("## %s\n\n", d.RelPath))
+			regularSB.WriteString("```" + d.Language + "\n")
+			regularSB.WriteString(d.Content)
+			regularSB.WriteString("\n```\n\n")
+		}
+	}
+
 	// Layer 2 (Regular): Code search results - changes per query
 	// Using 10 files for comprehensive context coverage
-	relevantFiles, err := b.findRelevantFiles(question, 10)
+	relevantFiles, err := b.findRelevantFiles(question, 10, b.searchStore(opts), limits, opts.Filter)
 	if err != nil {
 		b.logger.Warn().Err(err).Msg("Failed to find relevant files")
 	} else if len(relevantFiles) > 0 {
@@ -197,7 +282,7 @@ func (b *Builder) BuildContext(question string) (string, error) {
 
 	// 4. Find relevant files using vector search (or keyword fallback)
 	// Using 10 files for comprehensive context coverage
-	relevantFiles, err := b.findRelevantFiles(question, 10)
+	relevantFiles, err := b.findRelevantFiles(question, 10, b.vectorStore, b.limits, vectorstore.SearchFilter{})
 	if err != nil {
 		b.logger.Warn().Err(err).Msg("Failed to find relevant files")
 	} else if len(relevantFiles) > 0 {
@@ -233,7 +318,7 @@ func (b *Builder) loadClaudeMD() (string, error) {
 	for _, path := range paths {
 		content, err := os.ReadFile(path)
 		if err == nil {
-			return string(content), nil
+			return b.scrubContent(path, string(content)), nil
 		}
 	}
 
@@ -251,104 +336,110 @@ func (b *Builder) loadReadme() (string, error) {
 	for _, path := range paths {
 		content, err := os.ReadFile(path)
 		if err == nil {
-			return string(content), nil
+			return b.scrubContent(path, string(content)), nil
 		}
 	}
 
 	return "", fmt.Errorf("README not found")
 }
 
-// getRepoStructure returns a simple directory tree of the repository
-func (b *Builder) getRepoStructure() string {
-	var sb strings.Builder
-
-	sb.WriteString("```\n")
-	sb.WriteString(b.repoName + "/\n")
-
-	// Walk focus paths to show structure
-	seen := make(map[string]bool)
-	for _, focusPath := range b.focusPaths {
-		pattern := filepath.Join(b.repoPath, focusPath)
+// FileInfo holds information about a relevant file
+type FileInfo struct {
+	RelPath  string
+	Content  string
+	Language string
+}
 
-		// Simple directory listing (not full tree to keep context small)
-		dirs := b.listDirectories(pattern)
-		for _, dir := range dirs {
-			if !seen[dir] {
-				sb.WriteString("├── " + dir + "/\n")
-				seen[dir] = true
-			}
-		}
+// searchStore returns the store a query should search: the branch index, or
+// the branch index overlaid with working-tree changes when requested
+func (b *Builder) searchStore(opts QueryOptions) vectorstore.VectorStore {
+	if opts.WorkingTree && b.workingTree != nil {
+		return vectorstore.NewOverlayStore(b.vectorStore, b.workingTree)
 	}
-
-	sb.WriteString("```\n")
-
-	return sb.String()
+	return b.vectorStore
 }
 
-// listDirectories returns top-level directories matching pattern
-func (b *Builder) listDirectories(pattern string) []string {
-	var dirs []string
-
-	// Extract base path without wildcards
-	basePath := b.repoPath
+// findRelevantFiles finds files relevant to the question
+// Phase 2: Uses vector search if available, falls back to keyword matching
+func (b *Builder) findRelevantFiles(question string, limit int, store vectorstore.VectorStore, limits Limits, filter vectorstore.SearchFilter) ([]FileInfo, error) {
+	var files []FileInfo
+	var err error
 
-	// List directories in base path
-	entries, err := os.ReadDir(basePath)
+	// If vector store is available, use semantic search
+	if store != nil {
+		files, err = b.vectorSearch(question, limit, store, limits, filter)
+	} else {
+		// Fallback to keyword search (Phase 1)
+		files, err = b.keywordSearch(question, limit, filter)
+	}
 	if err != nil {
-		return dirs
+		return nil, err
 	}
 
-	for _, entry := range entries {
-		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
-			dirs = append(dirs, entry.Name())
+	// Keyword search reads files from disk, and collections indexed before
+	// scrubbing (or with it off) hold raw content
+	scrubbed := files[:0]
+	for _, file := range files {
+		if content := b.scrubContent(file.RelPath, file.Content); content != "" {
+			file.Content = content
+			scrubbed = append(scrubbed, file)
 		}
 	}
-
-	return dirs
+	return scrubbed, nil
 }
 
-// FileInfo holds information about a relevant file
-type FileInfo struct {
-	RelPath  string
-	Content  string
-	Language string
-}
-
-// findRelevantFiles finds files relevant to the question
-// Phase 2: Uses vector search if available, falls back to keyword matching
-func (b *Builder) findRelevantFiles(question string, limit int) ([]FileInfo, error) {
-	// If vector store is available, use semantic search
-	if b.vectorStore != nil {
-		return b.vectorSearch(question, limit)
+// scrubContent applies the secret mode to file content before it goes into
+// a prompt. Returns "" for content left out in skip mode.
+func (b *Builder) scrubContent(name, content string) string {
+	var findings []secrets.Finding
+	switch b.secretMode {
+	case secrets.ModeOff:
+		return content
+	case secrets.ModeSkip:
+		if findings = secrets.Scan(content); len(findings) > 0 {
+			content = ""
+		}
+	default:
+		content, findings = secrets.Redact(content)
 	}
 
-	// Fallback to keyword search (Phase 1)
-	return b.keywordSearch(question, limit)
+	if len(findings) > 0 {
+		rules := make([]string, len(findings))
+		for i, f := range findings {
+			rules[i] = f.Rule
+		}
+		b.logger.Warn().
+			Str("file", name).
+			Strs("rules", rules).
+			Str("mode", string(b.secretMode)).
+			Msg("Secrets scrubbed from context")
+	}
+	return content
 }
 
 // vectorSearch uses the vector store for semantic search
 // Returns top chunks only (not full files) for LLM context
-func (b *Builder) vectorSearch(question string, limit int) ([]FileInfo, error) {
+func (b *Builder) vectorSearch(question string, limit int, store vectorstore.VectorStore, limits Limits, filter vectorstore.SearchFilter) ([]FileInfo, error) {
 	// Use caller's context for proper cancellation/timeout
 	ctx := context.Background() // TODO: Should accept ctx as parameter in future refactor
 
-	// Get top relevant CHUNKS (not aggregated files)
-	chunks, err := b.vectorStore.Search(ctx, question, limit)
+	// Get top relevant CHUNKS (not aggregated files), filtered before ranking
+	chunks, err := vectorstore.SearchWithFilter(ctx, store, question, limit, filter)
 	if err != nil {
 		b.logger.Warn().Err(err).Msg("Vector search failed, falling back to keyword search")
-		return b.keywordSearch(question, limit)
+		return b.keywordSearch(question, limit, filter)
 	}
 
 	if len(chunks) == 0 {
 		b.logger.Debug().Msg("No chunks found, falling back to keyword search")
-		return b.keywordSearch(question, limit)
+		return b.keywordSearch(question, limit, filter)
 	}
 
 	// Group chunks by file
 	fileGroups := groupChunksByFile(chunks)
 
 	// Select top N chunks per file
-	files := b.buildFileInfoFromChunks(fileGroups, b.maxChunksPerFile, b.maxChunkChars)
+	files := b.buildFileInfoFromChunks(fileGroups, limits.MaxChunksPerFile, limits.MaxChunkChars)
 
 	// Apply exclude patterns
 	filteredFiles := []FileInfo{}
@@ -363,14 +454,14 @@ func (b *Builder) vectorSearch(question string, limit int) ([]FileInfo, error) {
 	}
 
 	// Apply character budget
-	finalFiles := applyCharacterBudget(filteredFiles, b.maxRegularChars)
+	finalFiles := applyCharacterBudget(filteredFiles, limits.MaxRegularChars)
 
 	b.logger.Debug().
 		Int("chunks", len(chunks)).
 		Int("file_groups", len(fileGroups)).
 		Int("after_budget", len(finalFiles)).
 		Int("total_chars", calculateTotalChars(finalFiles)).
-		Int("budget", b.maxRegularChars).
+		Int("budget", limits.MaxRegularChars).
 		Str("search_method", "chunk_based").
 		Msg("Found relevant files")
 
@@ -632,7 +723,7 @@ func matchDoubleStarPattern(filePath, pattern string) bool {
 }
 
 // keywordSearch performs simple keyword-based search
-func (b *Builder) keywordSearch(question string, limit int) ([]FileInfo, error) {
+func (b *Builder) keywordSearch(question string, limit int, filter vectorstore.SearchFilter) ([]FileInfo, error) {
 	keywords := b.extractKeywords(question)
 
 	var files []FileInfo
@@ -667,26 +758,34 @@ func (b *Builder) keywordSearch(question string, limit int) ([]FileInfo, error)
 				return nil
 			}
 
-			// Only process code files
-			if !b.isCodeFile(path) {
+			// Only process code files (scripts are told by their shebang)
+			if !b.fileTypes.MaybeCode(path) {
 				return nil
 			}
 
-			// Check if file content matches keywords
 			content, err := os.ReadFile(path)
 			if err != nil {
 				return nil
 			}
-
 			contentStr := string(content)
+			language := b.fileTypes.Detect(path, contentStr)
+			if language == "" {
+				return nil
+			}
+
+			relPath, _ := filepath.Rel(b.repoPath, path)
+			if !filter.MatchesLanguage(filepath.ToSlash(relPath), language) {
+				return nil
+			}
+
+			// Check if file content matches keywords
 			score := b.scoreContent(contentStr, keywords)
 
 			if score > 0 {
-				relPath, _ := filepath.Rel(b.repoPath, path)
 				files = append(files, FileInfo{
 					RelPath:  relPath,
 					Content:  contentStr,
-					Language: b.getLanguage(path),
+					Language: language,
 				})
 			}
 
@@ -754,19 +853,3 @@ func (b *Builder) scoreContent(content string, keywords []string) int {
 
 	return score
 }
-
-// isCodeFile checks if a file is a code file we should index
-// Delegates to filetypes package (single source of truth)
-func (b *Builder) isCodeFile(path string) bool {
-	return filetypes.IsCodeFile(path)
-}
-
-// getLanguage returns the language identifier for syntax highlighting
-// Delegates to filetypes package (single source of truth)
-func (b *Builder) getLanguage(path string) string {
-	lang := filetypes.GetLanguage(path)
-	if lang == "" {
-		return "text"
-	}
-	return lang
-}
@@ -0,0 +1,166 @@
+package context
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/First008/mesh/internal/symbols"
+	"github.com/First008/mesh/internal/vectorstore"
+)
+
+const (
+	maxQuestionIdentifiers = 8 // Identifiers of a question looked up
+	maxDefinitions         = 4 // Definitions added to a question's context
+	maxDefinitionsPerName  = 2 // Definitions of one (overloaded or common) name
+	defaultDefinitionLines = 40
+)
+
+var (
+	// questionToken matches identifiers in a question, qualified ones
+	// ("Server.Start", "db::connect") included, and a call's "()"
+	questionToken = regexp.MustCompile("`[^`]+`|[A-Za-z_][\\w]*(?:(?:\\.|::)[A-Za-z_]\\w*)*(?:\\(\\))?")
+
+	// mixedCase matches a lower-case letter followed by an upper-case one
+	mixedCase = regexp.MustCompile(`[a-z0-9][A-Z]|[A-Z]{2}[a-z]`)
+)
+
+// questionIdentifiers returns the code identifiers mentioned in a question:
+// anything in backticks, and words that only make sense as code (camelCase,
+// snake_case, qualified names, calls)
+func questionIdentifiers(question string) []string {
+	var identifiers []string
+	seen := make(map[string]bool)
+	for _, token := range questionToken.FindAllString(question, -1) {
+		quoted := strings.HasPrefix(token, "`")
+		token = strings.TrimSuffix(strings.Trim(token, "`"), "()")
+		if !quoted && !looksLikeCode(token) {
+			continue
+		}
+		if token == "" || strings.ContainsAny(token, " \t") || seen[token] {
+			continue
+		}
+		seen[token] = true
+		identifiers = append(identifiers, token)
+		if len(identifiers) == maxQuestionIdentifiers {
+			break
+		}
+	}
+	return identifiers
+}
+
+// looksLikeCode reports whether a word from a question is an identifier
+// rather than prose
+func looksLikeCode(word string) bool {
+	return strings.Contains(word, "_") || strings.Contains(word, ".") ||
+		strings.Contains(word, "::") || mixedCase.MatchString(word)
+}
+
+// findDefinitions returns the source of the definitions of identifiers
+// mentioned in the question, from the branch's symbol index
+func (b *Builder) findDefinitions(question string, limits Limits) []FileInfo {
+	if b.indexBranch == "" {
+		return nil
+	}
+	identifiers := questionIdentifiers(question)
+	if len(identifiers) == 0 {
+		return nil
+	}
+
+	table, err := vectorstore.LoadSymbolTable(b.repoName, b.indexBranch)
+	if err != nil {
+		b.logger.Warn().Err(err).Msg("Failed to load symbol index")
+		return nil
+	}
+	if table == nil {
+		return nil
+	}
+
+	var files []FileInfo
+	for _, identifier := range identifiers {
+		_, name := splitIdentifier(identifier)
+		added := 0
+		for _, def := range table.Definitions(identifier) {
+			// Only exact matches: prose words match names ignoring case
+			if def.Name != name || b.shouldExclude(def.Path) {
+				continue
+			}
+			file, ok := b.definitionSource(def, limits)
+			if !ok {
+				continue
+			}
+			files = append(files, file)
+			if added++; added == maxDefinitionsPerName || len(files) == maxDefinitions {
+				break
+			}
+		}
+		if len(files) == maxDefinitions {
+			break
+		}
+	}
+	return files
+}
+
+// splitIdentifier splits a qualified identifier into its qualifier and name
+func splitIdentifier(identifier string) (string, string) {
+	identifier = strings.ReplaceAll(identifier, "::", ".")
+	if i := strings.LastIndex(identifier, "."); i >= 0 {
+		return identifier[:i], identifier[i+1:]
+	}
+	return "", identifier
+}
+
+// definitionSource reads a definition from the working copy: its doc comment
+// and declaration, up to defaultDefinitionLines when the end isn't known and
+// MaxChunkChars in all
+func (b *Builder) definitionSource(def symbols.Definition, limits Limits) (FileInfo, bool) {
+	content, err := os.ReadFile(filepath.Join(b.repoPath, filepath.FromSlash(def.Path)))
+	if err != nil {
+		return FileInfo{}, false
+	}
+	lines := strings.Split(string(content), "\n")
+	if def.Line < 1 || def.Line > len(lines) {
+		return FileInfo{}, false // The index is behind the working copy
+	}
+
+	start := def.Line - 1
+	for start > 0 && isDocLine(lines[start-1]) {
+		start--
+	}
+	end := def.EndLine
+	if end < def.Line {
+		end = def.Line + defaultDefinitionLines - 1
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	source := strings.Join(lines[start:end], "\n")
+	if limits.MaxChunkChars > 0 && len(source) > limits.MaxChunkChars {
+		source = source[:limits.MaxChunkChars] + "\n... (truncated)"
+	}
+	source = b.scrubContent(def.Path, source)
+	if source == "" {
+		return FileInfo{}, false
+	}
+
+	return FileInfo{
+		RelPath:  fmt.Sprintf("%s:%d-%d", def.Path, start+1, end),
+		Content:  source,
+		Language: b.fileTypes.Detect(def.Path, string(content)),
+	}, true
+}
+
+// isDocLine reports whether a line is part of a comment or annotation above
+// a declaration
+func isDocLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	for _, prefix := range []string{"//", "/*", "*", "#", "@", "--"} {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return false
+}
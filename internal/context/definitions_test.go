@@ -0,0 +1,72 @@
+package context
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/First008/mesh/internal/symbols"
+	"github.com/First008/mesh/internal/vectorstore"
+)
+
+func TestQuestionIdentifiers(t *testing.T) {
+	tests := []struct {
+		question string
+		want     []string
+	}{
+		{"How does NewServer wire the routes?", []string{"NewServer"}},
+		{"What calls Server.Start() and load_config?", []string{"Server.Start", "load_config"}},
+		{"Where is `helper` used, and what does db::connect do?", []string{"helper", "db::connect"}},
+		{"How does the Server handle errors?", nil}, // Prose, even capitalized
+		{"Explain parseHTTPHeader and parseHTTPHeader again", []string{"parseHTTPHeader"}},
+	}
+
+	for _, tt := range tests {
+		got := questionIdentifiers(tt.question)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("questionIdentifiers(%q) = %v, want %v", tt.question, got, tt.want)
+		}
+	}
+}
+
+func TestBuildContextLayers_Definitions(t *testing.T) {
+	repoDir := t.TempDir()
+	source := "package api\n\nimport \"net/http\"\n\n// NewServer creates the API server\nfunc NewServer() *http.Server {\n\treturn &http.Server{}\n}\n\nfunc other() {}\n"
+	os.WriteFile(filepath.Join(repoDir, "server.go"), []byte(source), 0644)
+
+	// Symbol indexes live under .mesh in the working directory
+	originalWd, _ := os.Getwd()
+	os.Chdir(t.TempDir())
+	defer os.Chdir(originalWd)
+	err := vectorstore.SaveSymbolIndex(&vectorstore.SymbolIndex{
+		RepoName: "repo",
+		Branch:   "main",
+		Files:    map[string]*symbols.File{"server.go": symbols.Extract("server.go", "go", source)},
+	})
+	if err != nil {
+		t.Fatalf("SaveSymbolIndex failed: %v", err)
+	}
+
+	builder := NewBuilderWithBranch(repoDir, "repo", "main", nil, newMockVectorStore(), testLogger())
+	question := "What does NewServer return?"
+
+	layers, err := builder.BuildContextLayers(question)
+	if err != nil {
+		t.Fatalf("BuildContextLayers failed: %v", err)
+	}
+	if strings.Contains(layers.Regular, "# Definitions") {
+		t.Error("Expected no definitions without a symbol index set")
+	}
+
+	builder.SetBranchIndex("main")
+	layers, err = builder.BuildContextLayers(question)
+	if err != nil {
+		t.Fatalf("BuildContextLayers failed: %v", err)
+	}
+	// The doc comment and declaration, without the rest of the file
+	want := "## server.go:5-8\n\n```go\n// NewServer creates the API server\nfunc NewServer() *http.Server {\n\treturn &http.Server{}\n}\n```"
+	if !strings.Contains(layers.Regular, "# Definitions of Identifiers in the Question") || !strings.Contains(layers.Regular, want) {
+		t.Errorf("Expected the definition of NewServer in the regular layer, got:\n%s", layers.Regular)
+	}
+}
@@ -0,0 +1,206 @@
+package context
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/First008/mesh/internal/filetypes"
+	"github.com/First008/mesh/internal/vectorstore"
+)
+
+const (
+	maxTreeDepth          = 4     // Deeper directories are counted in their ancestors
+	maxTreeLines          = 150   // Directories shown in the tree
+	maxPackageSummaries   = 80    // Package summaries shown
+	maxStructureWalkFiles = 20000 // Files listed from the working copy without a summary
+)
+
+// treeNode is a directory of the repository tree, with the number of files
+// under it
+type treeNode struct {
+	name     string
+	files    int
+	children map[string]*treeNode
+}
+
+// getRepoStructure returns the repository map of the cacheable layer: a
+// directory tree with file counts, entry points and package summaries from
+// the branch's summary, or a tree of the working copy's code files when the
+// branch hasn't been summarized
+func (b *Builder) getRepoStructure() string {
+	if b.indexBranch != "" {
+		summary, err := vectorstore.CachedRepoSummary(b.repoName, b.indexBranch)
+		if err != nil {
+			b.logger.Warn().Err(err).Msg("Failed to load repository summary")
+		}
+		if summary != nil {
+			var files []string
+			for relPath := range summary.Files {
+				if !b.shouldExclude(relPath) {
+					files = append(files, relPath)
+				}
+			}
+			return renderRepoStructure(b.repoName, files, summary)
+		}
+	}
+	return renderRepoStructure(b.repoName, b.listRepoFiles(), nil)
+}
+
+// listRepoFiles returns the code files of the working copy within the focus
+// paths, minus excluded ones
+func (b *Builder) listRepoFiles() []string {
+	var files []string
+	filepath.WalkDir(b.repoPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip unreadable entries
+		}
+		if d.IsDir() {
+			if p != b.repoPath && filetypes.ShouldSkipDirectory(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if len(files) == maxStructureWalkFiles {
+			return filepath.SkipAll
+		}
+
+		rel, err := filepath.Rel(b.repoPath, p)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if b.fileTypes.MaybeCode(rel) && b.inFocus(rel) && !b.shouldExclude(rel) {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	return files
+}
+
+// inFocus reports whether a file matches the focus paths, if any are set
+func (b *Builder) inFocus(relPath string) bool {
+	if len(b.focusPaths) == 0 {
+		return true
+	}
+	for _, pattern := range b.focusPaths {
+		if matchGlobPattern(relPath, filepath.ToSlash(pattern)) {
+			return true
+		}
+	}
+	return false
+}
+
+// renderRepoStructure renders the directory tree of files, and the entry
+// points and package summaries of summary when there is one
+func renderRepoStructure(repoName string, files []string, summary *vectorstore.RepoSummary) string {
+	root := &treeNode{name: repoName, children: make(map[string]*treeNode)}
+	direct := make(map[string]int) // Directory -> files directly in it
+	for _, relPath := range files {
+		root.files++
+		direct[path.Dir(relPath)]++
+		node := root
+		dirs := strings.Split(path.Dir(relPath), "/")
+		for i, dir := range dirs {
+			if dir == "." || i == maxTreeDepth {
+				break
+			}
+			child, ok := node.children[dir]
+			if !ok {
+				child = &treeNode{name: dir, children: make(map[string]*treeNode)}
+				node.children[dir] = child
+			}
+			child.files++
+			node = child
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("```\n")
+	fmt.Fprintf(&sb, "%s/ (%s)\n", root.name, countFiles(root.files))
+	lines, omitted := 0, 0
+	writeTree(&sb, root, "", &lines, &omitted)
+	if omitted > 0 {
+		fmt.Fprintf(&sb, "... (%d more directories)\n", omitted)
+	}
+	sb.WriteString("```\n")
+
+	if summary == nil {
+		return sb.String()
+	}
+
+	if len(summary.EntryPoints) > 0 {
+		sb.WriteString("\n## Entry Points\n\n")
+		for _, entry := range summary.EntryPoints {
+			fmt.Fprintf(&sb, "- `%s`\n", entry)
+		}
+	}
+
+	var dirs []string
+	for dir, p := range summary.Packages {
+		if p.Summary != "" && direct[dir] > 0 {
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	if len(dirs) > 0 {
+		sb.WriteString("\n## Packages\n\n")
+		for i, dir := range dirs {
+			if i == maxPackageSummaries {
+				fmt.Fprintf(&sb, "- ... (%d more)\n", len(dirs)-i)
+				break
+			}
+			name := dir
+			if dir == "." {
+				name = repoName + " (root)"
+			}
+			fmt.Fprintf(&sb, "- `%s` (%s): %s\n", name, countFiles(direct[dir]), summary.Packages[dir].Summary)
+		}
+	}
+	return sb.String()
+}
+
+// writeTree writes the subdirectories of node, up to maxTreeLines in all,
+// counting the rest in omitted
+func writeTree(sb *strings.Builder, node *treeNode, prefix string, lines, omitted *int) {
+	names := make([]string, 0, len(node.children))
+	for name := range node.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		child := node.children[name]
+		if *lines == maxTreeLines {
+			*omitted += countDirs(child)
+			continue
+		}
+		*lines++
+
+		branch, indent := "├── ", "│   "
+		if i == len(names)-1 {
+			branch, indent = "└── ", "    "
+		}
+		fmt.Fprintf(sb, "%s%s%s/ (%s)\n", prefix, branch, child.name, countFiles(child.files))
+		writeTree(sb, child, prefix+indent, lines, omitted)
+	}
+}
+
+// countDirs returns the number of directories in a subtree, its root included
+func countDirs(node *treeNode) int {
+	n := 1
+	for _, child := range node.children {
+		n += countDirs(child)
+	}
+	return n
+}
+
+func countFiles(n int) string {
+	if n == 1 {
+		return "1 file"
+	}
+	return fmt.Sprintf("%d files", n)
+}
@@ -0,0 +1,96 @@
+package context
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/First008/mesh/internal/vectorstore"
+)
+
+func TestGetRepoStructure_WorkingCopy(t *testing.T) {
+	repoDir := t.TempDir()
+	for _, relPath := range []string{"cmd/api/main.go", "internal/store/store.go", "internal/store/cache.go", "node_modules/lib/index.js"} {
+		os.MkdirAll(filepath.Join(repoDir, filepath.Dir(relPath)), 0755)
+		os.WriteFile(filepath.Join(repoDir, relPath), []byte("package x\n"), 0644)
+	}
+
+	tests := []struct {
+		name       string
+		focusPaths []string
+		want       []string
+		notWant    []string
+	}{
+		{
+			name:    "all",
+			want:    []string{"(3 files)", "├── cmd/ (1 file)", "│   └── api/ (1 file)", "└── internal/ (2 files)", "    └── store/ (2 files)"},
+			notWant: []string{"node_modules", "## Packages"},
+		},
+		{
+			name:       "focused",
+			focusPaths: []string{"internal/**"},
+			want:       []string{"(2 files)", "└── internal/ (2 files)"},
+			notWant:    []string{"cmd/"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(repoDir, "repo", tt.focusPaths, testLogger())
+			got := builder.getRepoStructure()
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("Expected %q in structure:\n%s", want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("Unexpected %q in structure:\n%s", notWant, got)
+				}
+			}
+		})
+	}
+}
+
+func TestGetRepoStructure_Summary(t *testing.T) {
+	// Summaries live under .mesh in the working directory
+	originalWd, _ := os.Getwd()
+	os.Chdir(t.TempDir())
+	defer os.Chdir(originalWd)
+	err := vectorstore.SaveRepoSummary(&vectorstore.RepoSummary{
+		RepoName: "repo",
+		Branch:   "main",
+		Files:    map[string]string{"go.mod": "a", "cmd/api/main.go": "b", "internal/store/store.go": "c", "internal/store/secret.go": "d"},
+		Packages: map[string]*vectorstore.PackageSummary{
+			".":              {Hash: "1", Summary: "Module definition."},
+			"cmd/api":        {Hash: "2", Summary: "Starts the API server."},
+			"internal/store": {Hash: "3", Summary: "Persists users."},
+		},
+		EntryPoints: []string{"cmd/api/main.go"},
+	})
+	if err != nil {
+		t.Fatalf("SaveRepoSummary failed: %v", err)
+	}
+
+	builder := NewBuilderWithBranch(t.TempDir(), "repo", "main", nil, newMockVectorStore(), testLogger())
+	builder.SetExcludePatterns([]string{"**/secret.go"})
+	if got := builder.getRepoStructure(); strings.Contains(got, "## Entry Points") {
+		t.Errorf("Expected no summary without a branch index set, got:\n%s", got)
+	}
+
+	builder.SetBranchIndex("main")
+	got := builder.getRepoStructure()
+	for _, want := range []string{
+		"repo/ (3 files)",
+		"└── internal/ (1 file)",
+		"## Entry Points\n\n- `cmd/api/main.go`",
+		"- `repo (root)` (1 file): Module definition.",
+		"- `cmd/api` (1 file): Starts the API server.",
+		"- `internal/store` (1 file): Persists users.",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in structure:\n%s", want, got)
+		}
+	}
+}
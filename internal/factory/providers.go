@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	"github.com/First008/mesh/internal/llm"
+	"github.com/First008/mesh/internal/resilience"
 	"github.com/First008/mesh/internal/vectorstore"
 	"github.com/rs/zerolog"
 )
@@ -18,11 +19,14 @@ type EmbeddingConfig struct {
 	OpenAIKey   string
 	OllamaURL   string
 	OllamaModel string
+	Resilience  resilience.Policy // Retry, rate limit and circuit breaker settings
 }
 
 // NewEmbeddingProvider creates an embedding provider based on configuration.
 // This is the single source of truth for embedding provider creation,
 // eliminating duplication across agent.go and gateway.go.
+// The provider is wrapped in a resilience guard shared by every caller of
+// the same provider and model.
 func NewEmbeddingProvider(cfg EmbeddingConfig, logger zerolog.Logger) (vectorstore.EmbeddingProvider, error) {
 	providerType := cfg.Provider
 
@@ -37,16 +41,25 @@ func NewEmbeddingProvider(cfg EmbeddingConfig, logger zerolog.Logger) (vectorsto
 		}
 	}
 
+	var provider vectorstore.EmbeddingProvider
+	var err error
+
 	switch providerType {
 	case "ollama":
-		return newOllamaProvider(cfg, logger)
+		provider, err = newOllamaProvider(cfg, logger)
 
 	case "openai":
-		return newOpenAIProvider(cfg, logger)
+		provider, err = newOpenAIProvider(cfg, logger)
 
 	default:
 		return nil, fmt.Errorf("unsupported embedding provider: %s (supported: ollama, openai)", providerType)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	guard := resilience.Shared("embedding/"+providerType+"/"+provider.GetModelName(), cfg.Resilience, logger)
+	return resilience.WrapEmbedding(provider, guard), nil
 }
 
 func newOllamaProvider(cfg EmbeddingConfig, logger zerolog.Logger) (vectorstore.EmbeddingProvider, error) {
@@ -96,15 +109,19 @@ func newOpenAIProvider(cfg EmbeddingConfig, logger zerolog.Logger) (vectorstore.
 
 // LLMConfig holds configuration for creating an LLM provider
 type LLMConfig struct {
-	Provider     string // "anthropic" | "ollama" | "openai"
-	Model        string
-	AnthropicKey string
-	OpenAIKey    string
-	OllamaURL    string
+	Provider        string // "anthropic" | "ollama" | "openai"
+	Model           string
+	AnthropicKey    string
+	OpenAIKey       string
+	OllamaURL       string
+	MaxOutputTokens int               // Response token limit from the model registry (0 = provider default)
+	Resilience      resilience.Policy // Retry, rate limit and circuit breaker settings
 }
 
 // NewLLMProvider creates an LLM provider based on configuration.
 // This is the single source of truth for LLM provider creation.
+// The provider is wrapped in a resilience guard shared by every caller of
+// the same provider and model.
 func NewLLMProvider(cfg LLMConfig, logger zerolog.Logger) (llm.LLMProvider, error) {
 	providerType := cfg.Provider
 
@@ -121,12 +138,15 @@ func NewLLMProvider(cfg LLMConfig, logger zerolog.Logger) (llm.LLMProvider, erro
 		}
 	}
 
+	var provider llm.LLMProvider
+	var err error
+
 	switch providerType {
 	case "ollama":
-		return newOllamaLLMProvider(cfg, logger)
+		provider, err = newOllamaLLMProvider(cfg, logger)
 
 	case "anthropic":
-		return newAnthropicProvider(cfg, logger)
+		provider, err = newAnthropicProvider(cfg, logger)
 
 	case "openai":
 		// TODO: Implement OpenAI LLM provider
@@ -135,6 +155,12 @@ func NewLLMProvider(cfg LLMConfig, logger zerolog.Logger) (llm.LLMProvider, erro
 	default:
 		return nil, fmt.Errorf("unsupported LLM provider: %s (supported: ollama, anthropic)", providerType)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	guard := resilience.Shared("llm/"+providerType+"/"+provider.GetModel(), cfg.Resilience, logger)
+	return resilience.WrapLLM(provider, guard), nil
 }
 
 func newOllamaLLMProvider(cfg LLMConfig, logger zerolog.Logger) (llm.LLMProvider, error) {
@@ -170,7 +196,7 @@ func newAnthropicProvider(cfg LLMConfig, logger zerolog.Logger) (llm.LLMProvider
 		cfg.Model = "claude-sonnet-4-5-20250929" // Default model
 	}
 
-	provider, err := llm.NewAnthropicProvider(cfg.AnthropicKey, cfg.Model, logger)
+	provider, err := llm.NewAnthropicProviderWithMaxTokens(cfg.AnthropicKey, cfg.Model, cfg.MaxOutputTokens, logger)
 	if err != nil {
 		return nil, fmt.Errorf("create Anthropic LLM provider: %w", err)
 	}
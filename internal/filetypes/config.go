@@ -0,0 +1,215 @@
+package filetypes
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Config adds to or overrides the built-in tables for one repository.
+// Mapping a key to "" stops matching files from being indexed.
+type Config struct {
+	Extensions   map[string]string `yaml:"extensions,omitempty" json:"extensions,omitempty"`     // ".vue": "vue"
+	Filenames    map[string]string `yaml:"filenames,omitempty" json:"filenames,omitempty"`       // "Jenkinsfile": "groovy"
+	Interpreters map[string]string `yaml:"interpreters,omitempty" json:"interpreters,omitempty"` // Shebang "deno": "typescript"
+}
+
+// Empty reports whether the config changes nothing
+func (c Config) Empty() bool {
+	return len(c.Extensions) == 0 && len(c.Filenames) == 0 && len(c.Interpreters) == 0
+}
+
+// Validate checks the keys of the config
+func (c Config) Validate() error {
+	for ext := range c.Extensions {
+		if len(ext) < 2 || ext[0] != '.' || strings.ContainsAny(ext[1:], "./") {
+			return fmt.Errorf("file_types: extension %q must look like \".vue\"", ext)
+		}
+	}
+	for name := range c.Filenames {
+		if name == "" || strings.Contains(name, "/") {
+			return fmt.Errorf("file_types: filename %q must be a base name", name)
+		}
+	}
+	for interpreter := range c.Interpreters {
+		if interpreter == "" || strings.Contains(interpreter, "/") {
+			return fmt.Errorf("file_types: interpreter %q must be a command name, like \"python\"", interpreter)
+		}
+	}
+	return nil
+}
+
+// Registry decides which files are code and in which language, from their
+// extension, their file name, or the shebang of extension-less scripts.
+// A nil Registry is the built-in one.
+type Registry struct {
+	extensions   map[string]string // Lower-case extension -> language
+	filenames    map[string]string // Base name -> language
+	interpreters map[string]string // Shebang interpreter -> language
+	fingerprint  string            // Fingerprint of the overrides ("" for built-in)
+}
+
+// defaultRegistry holds the built-in tables
+var defaultRegistry = &Registry{
+	extensions:   builtinExtensions(),
+	filenames:    Filenames,
+	interpreters: Interpreters,
+}
+
+// builtinExtensions returns the indexed extensions with their languages
+func builtinExtensions() map[string]string {
+	extensions := make(map[string]string, len(Extensions))
+	for ext, indexed := range Extensions {
+		if indexed {
+			extensions[ext] = Languages[ext]
+		}
+	}
+	return extensions
+}
+
+// Default returns the built-in registry
+func Default() *Registry {
+	return defaultRegistry
+}
+
+// New returns the built-in registry with the overrides of cfg applied
+func New(cfg Config) (*Registry, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Empty() {
+		return defaultRegistry, nil
+	}
+
+	r := &Registry{
+		extensions:   merge(defaultRegistry.extensions, cfg.Extensions, strings.ToLower),
+		filenames:    merge(defaultRegistry.filenames, cfg.Filenames, nil),
+		interpreters: merge(defaultRegistry.interpreters, cfg.Interpreters, nil),
+	}
+
+	fingerprint := sha256.New()
+	for _, table := range []map[string]string{cfg.Extensions, cfg.Filenames, cfg.Interpreters} {
+		keys := make([]string, 0, len(table))
+		for key := range table {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(fingerprint, "%s\x00%s\x00", key, table[key])
+		}
+		fingerprint.Write([]byte{1})
+	}
+	r.fingerprint = fmt.Sprintf("%x", fingerprint.Sum(nil))
+	return r, nil
+}
+
+// merge copies base and applies overrides; "" deletes a key
+func merge(base, overrides map[string]string, normalize func(string) string) map[string]string {
+	merged := make(map[string]string, len(base)+len(overrides))
+	for key, language := range base {
+		merged[key] = language
+	}
+	for key, language := range overrides {
+		if normalize != nil {
+			key = normalize(key)
+		}
+		if language == "" {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = strings.ToLower(language)
+	}
+	return merged
+}
+
+// Fingerprint identifies the overrides, so a change can trigger re-indexing.
+// Returns "" for the built-in registry.
+func (r *Registry) Fingerprint() string {
+	if r == nil {
+		return ""
+	}
+	return r.fingerprint
+}
+
+// Language returns the language of a file from its name, or "" if it is not
+// a recognized code file. File names take precedence over extensions.
+func (r *Registry) Language(filePath string) string {
+	if r == nil {
+		r = defaultRegistry
+	}
+	base := path.Base(filepath.ToSlash(filePath))
+	if language, ok := r.filenames[base]; ok {
+		return language
+	}
+	return r.extensions[strings.ToLower(path.Ext(base))]
+}
+
+// IsCodeFile reports whether a file is code from its name alone
+func (r *Registry) IsCodeFile(filePath string) bool {
+	return r.Language(filePath) != ""
+}
+
+// MaybeCode reports whether a file is code or could be a script whose
+// shebang names a known interpreter; Detect tells once content is read
+func (r *Registry) MaybeCode(filePath string) bool {
+	return r.IsCodeFile(filePath) || r.maybeScript(filePath)
+}
+
+// Detect returns the language of a file from its name or, for extension-less
+// scripts, its shebang line. Returns "" if it is not code.
+func (r *Registry) Detect(filePath, content string) string {
+	if language := r.Language(filePath); language != "" {
+		return language
+	}
+	if !r.maybeScript(filePath) {
+		return ""
+	}
+	if r == nil {
+		r = defaultRegistry
+	}
+	interpreter := shebangInterpreter(content)
+	if language, ok := r.interpreters[interpreter]; ok {
+		return language
+	}
+	// Versioned interpreters: python3, python3.12, perl5
+	return r.interpreters[strings.TrimRight(interpreter, "0123456789.")]
+}
+
+// maybeScript reports whether a file could be a script: it has no extension
+// and isn't hidden
+func (r *Registry) maybeScript(filePath string) bool {
+	if r == nil {
+		r = defaultRegistry
+	}
+	base := path.Base(filepath.ToSlash(filePath))
+	return len(r.interpreters) > 0 && !strings.Contains(base, ".")
+}
+
+// shebangInterpreter returns the command a "#!" line runs, looking through
+// env: "python3" for both "#!/usr/bin/python3" and "#!/usr/bin/env python3"
+func shebangInterpreter(content string) string {
+	if !strings.HasPrefix(content, "#!") {
+		return ""
+	}
+	line, _, _ := strings.Cut(content[2:], "\n")
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	command := path.Base(fields[0])
+	if command != "env" {
+		return command
+	}
+	for _, arg := range fields[1:] {
+		// Skip env's options (-S) and variable assignments
+		if strings.HasPrefix(arg, "-") || strings.Contains(arg, "=") {
+			continue
+		}
+		return path.Base(arg)
+	}
+	return ""
+}
@@ -0,0 +1,95 @@
+package filetypes
+
+import "testing"
+
+func TestRegistry_Detect(t *testing.T) {
+	custom, err := New(Config{
+		Extensions:   map[string]string{".VUE": "vue3", ".tpl": "gotemplate", ".json": ""},
+		Filenames:    map[string]string{"Tiltfile": "starlark", "Makefile": ""},
+		Interpreters: map[string]string{"deno": "deno", "perl": ""},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		registry *Registry
+		path     string
+		content  string
+		want     string
+	}{
+		{"extension", Default(), "web/App.vue", "", "vue"},
+		{"extension case", Default(), "lib/Main.DART", "", "dart"},
+		{"filename", Default(), "deploy/Dockerfile", "FROM alpine\n", "dockerfile"},
+		{"filename over extension", Default(), "CMakeLists.txt", "", "cmake"},
+		{"unknown extension", Default(), "notes.txt", "", ""},
+		{"shebang", Default(), "bin/deploy", "#!/bin/bash\nset -e\n", "bash"},
+		{"shebang via env", Default(), "scripts/migrate", "#!/usr/bin/env python3\n", "python"},
+		{"shebang with env options", Default(), "bin/run", "#!/usr/bin/env -S node --no-warnings\n", "javascript"},
+		{"versioned interpreter", Default(), "bin/tool", "#!/usr/local/bin/python3.12\n", "python"},
+		{"no shebang", Default(), "LICENSE", "MIT License\n", ""},
+		{"unknown interpreter", Default(), "bin/x", "#!/usr/bin/awk -f\n", ""},
+		{"shebang ignored with extension", Default(), "run.txt", "#!/bin/sh\n", ""},
+		{"nil is built-in", nil, "main.go", "", "go"},
+		{"added extension", custom, "views/page.tpl", "", "gotemplate"},
+		{"overridden extension", custom, "web/App.vue", "", "vue3"},
+		{"removed extension", custom, "package.json", "", ""},
+		{"added filename", custom, "Tiltfile", "", "starlark"},
+		{"removed filename", custom, "Makefile", "all:\n", ""},
+		{"added interpreter", custom, "bin/task", "#!/usr/bin/env deno\n", "deno"},
+		{"removed interpreter", custom, "bin/old", "#!/usr/bin/perl\n", ""},
+		{"built-in kept", custom, "main.go", "", "go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.registry.Detect(tt.path, tt.content); got != tt.want {
+				t.Errorf("Detect(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_MaybeCode(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"main.go", true},
+		{"Dockerfile", true},
+		{"bin/deploy", true}, // Could be a script
+		{".env", false},
+		{"notes.txt", false},
+	}
+
+	for _, tt := range tests {
+		if got := Default().MaybeCode(tt.path); got != tt.want {
+			t.Errorf("MaybeCode(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	if r, err := New(Config{}); err != nil || r.Fingerprint() != "" {
+		t.Errorf("Expected empty config to give the built-in registry, got %q, %v", r.Fingerprint(), err)
+	}
+
+	a, _ := New(Config{Extensions: map[string]string{".tpl": "gotemplate"}})
+	b, _ := New(Config{Extensions: map[string]string{".tpl": "html"}})
+	if a.Fingerprint() == "" || a.Fingerprint() == b.Fingerprint() {
+		t.Errorf("Expected distinct fingerprints, got %q and %q", a.Fingerprint(), b.Fingerprint())
+	}
+
+	invalid := []Config{
+		{Extensions: map[string]string{"vue": "vue"}},
+		{Extensions: map[string]string{".tar.gz": "archive"}},
+		{Filenames: map[string]string{"build/Makefile": "makefile"}},
+		{Interpreters: map[string]string{"/usr/bin/deno": "typescript"}},
+	}
+	for _, cfg := range invalid {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("Expected New(%+v) to fail", cfg)
+		}
+	}
+}
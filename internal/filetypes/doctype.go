@@ -0,0 +1,80 @@
+package filetypes
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DocType tells documentation apart from code, and kinds of documentation
+// from each other, so retrieval can balance them
+type DocType string
+
+const (
+	DocCode      DocType = "code"      // Not documentation
+	DocReadme    DocType = "readme"    // README files
+	DocChangelog DocType = "changelog" // Changelogs and release notes
+	DocADR       DocType = "adr"       // Architecture decision records
+	DocDesign    DocType = "design"    // Architecture and design documents, RFCs
+	DocGuide     DocType = "guide"     // Any other documentation
+)
+
+// docLanguages are the languages of documentation files
+var docLanguages = map[string]bool{
+	"markdown":         true,
+	"restructuredtext": true,
+}
+
+// IsDocLanguage reports whether files in language are documentation
+func IsDocLanguage(language string) bool {
+	return docLanguages[language]
+}
+
+// docNames map upper-case file names, without extension, to their doc type
+var docNames = map[string]DocType{
+	"README":        DocReadme,
+	"CHANGELOG":     DocChangelog,
+	"CHANGES":       DocChangelog,
+	"HISTORY":       DocChangelog,
+	"NEWS":          DocChangelog,
+	"RELEASES":      DocChangelog,
+	"RELEASE_NOTES": DocChangelog,
+	"ARCHITECTURE":  DocDesign,
+	"DESIGN":        DocDesign,
+}
+
+// docDirectories map directory names to the doc type of documents below them
+var docDirectories = map[string]DocType{
+	"adr":              DocADR,
+	"adrs":             DocADR,
+	"decisions":        DocADR,
+	"decision-records": DocADR,
+	"rfc":              DocDesign,
+	"rfcs":             DocDesign,
+	"design":           DocDesign,
+}
+
+// GetDocType returns the doc type of a file in language, from its path
+func GetDocType(filePath, language string) DocType {
+	if !IsDocLanguage(language) {
+		return DocCode
+	}
+
+	filePath = filepath.ToSlash(filePath)
+	base := path.Base(filePath)
+	name := strings.ToUpper(strings.TrimSuffix(base, path.Ext(base)))
+	if docType, ok := docNames[name]; ok {
+		return docType
+	}
+	if strings.HasPrefix(name, "ADR-") || strings.HasPrefix(name, "ADR_") {
+		return DocADR
+	}
+
+	dirs := strings.Split(filePath, "/")
+	for i := len(dirs) - 2; i >= 0; i-- {
+		if docType, ok := docDirectories[strings.ToLower(dirs[i])]; ok {
+			return docType
+		}
+	}
+	return DocGuide
+}
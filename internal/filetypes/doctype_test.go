@@ -0,0 +1,30 @@
+package filetypes
+
+import "testing"
+
+func TestGetDocType(t *testing.T) {
+	tests := []struct {
+		path     string
+		language string
+		want     DocType
+	}{
+		{"internal/api/handler.go", "go", DocCode},
+		{"README.md", "markdown", DocReadme},
+		{"web/readme.md", "markdown", DocReadme},
+		{"CHANGELOG.md", "markdown", DocChangelog},
+		{"docs/RELEASE_NOTES.rst", "restructuredtext", DocChangelog},
+		{"ARCHITECTURE.md", "markdown", DocDesign},
+		{"docs/rfcs/0007-sharding.md", "markdown", DocDesign},
+		{"docs/adr/0001-use-qdrant.md", "markdown", DocADR},
+		{"docs/ADR-012-caching.md", "markdown", DocADR},
+		{"docs/adr/README.md", "markdown", DocReadme}, // File name wins
+		{"doc/INDEXING.md", "markdown", DocGuide},
+		{"docs/notes.txt", "", DocCode},
+	}
+
+	for _, tt := range tests {
+		if got := GetDocType(tt.path, tt.language); got != tt.want {
+			t.Errorf("GetDocType(%q, %q) = %q, want %q", tt.path, tt.language, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package filetypes
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Class is what a file holds, as far as search relevance goes: hand-written
+// source, or output of tools that rarely answers a question about the code
+type Class string
+
+const (
+	ClassSource    Class = "source"
+	ClassGenerated Class = "generated" // Code generator output, lockfiles, source maps
+	ClassVendored  Class = "vendored"  // Third-party code copied into the repository
+	ClassMinified  Class = "minified"  // Minified or single-line bundles and data
+	ClassBinary    Class = "binary"    // Not text
+)
+
+// generatedSuffixes are file name endings of generator output
+var generatedSuffixes = []string{
+	".pb.go", ".pb.gw.go", // protoc (Go)
+	"_pb2.py", "_pb2_grpc.py", "_pb2.pyi", // protoc (Python)
+	".pb.cc", ".pb.h", // protoc (C++)
+	"_pb.js", "_pb.d.ts", "_grpc_pb.js", "_pb.ts", // protoc (JS/TS)
+	".gen.go", "_generated.go", // go generate
+	".designer.cs", ".g.cs", // .NET designers and source generators
+	".g.dart", ".freezed.dart", // build_runner
+	".map", // Source maps
+}
+
+// generatedPrefixes are file name beginnings of generator output
+var generatedPrefixes = []string{
+	"zz_generated", // Kubernetes deepcopy, defaults, conversion
+}
+
+// lockfiles pin dependency versions; they are generated by package managers
+var lockfiles = map[string]bool{
+	"package-lock.json":   true,
+	"npm-shrinkwrap.json": true,
+	"yarn.lock":           true,
+	"pnpm-lock.yaml":      true,
+	"bun.lockb":           true,
+	"go.sum":              true,
+	"Cargo.lock":          true,
+	"poetry.lock":         true,
+	"Pipfile.lock":        true,
+	"uv.lock":             true,
+	"Gemfile.lock":        true,
+	"composer.lock":       true,
+	"mix.lock":            true,
+	"pubspec.lock":        true,
+	"packages.lock.json":  true,
+	"flake.lock":          true,
+}
+
+// minifiedSuffixes mark minified assets by name
+var minifiedSuffixes = []string{".min.js", ".min.mjs", ".min.css", "-min.js", ".bundle.js"}
+
+// vendorDirectories hold third-party code (vendor and node_modules are
+// skipped outright, see SkipDirectories)
+var vendorDirectories = map[string]bool{
+	"vendor":           true,
+	"node_modules":     true,
+	"third_party":      true,
+	"third-party":      true,
+	"thirdparty":       true,
+	"3rdparty":         true,
+	"external":         true,
+	"externals":        true,
+	"bower_components": true,
+	"jspm_packages":    true,
+	"Pods":             true,
+	"Carthage":         true,
+}
+
+// generatedMarker matches the header comments generators write, e.g. Go's
+// "// Code generated by protoc-gen-go. DO NOT EDIT."
+var generatedMarker = regexp.MustCompile(`(?i)(code generated .*do not edit|@generated\b|\bdo not edit\b|` +
+	`generated by the protocol buffer compiler|` +
+	`(file|code) (is|was|has been) (auto-?|automatically )?generated|auto-?generated (file|code))`)
+
+const (
+	// headerSize is how much of a file is searched for a generated marker,
+	// enough to get past a license header
+	headerSize = 4096
+
+	// binarySampleSize is how much of a file is checked for binary content
+	binarySampleSize = 8192
+
+	// minifiedLineLength is the average line length above which a file is
+	// considered minified; hand-written code stays well below it
+	minifiedLineLength = 300
+
+	// minifiedMinSize keeps short one-liners from counting as minified
+	minifiedMinSize = 1024
+)
+
+// Classify determines what a file holds from its path and content
+func Classify(path, content string) Class {
+	path = filepath.ToSlash(path)
+	dirs := strings.Split(path, "/")
+	base := dirs[len(dirs)-1]
+
+	if isBinary(content) {
+		return ClassBinary
+	}
+	if isGeneratedName(base) || generatedMarker.MatchString(header(content)) {
+		return ClassGenerated
+	}
+	for _, dir := range dirs[:len(dirs)-1] {
+		if vendorDirectories[dir] {
+			return ClassVendored
+		}
+	}
+	if hasSuffix(base, minifiedSuffixes) || isMinified(path, content) {
+		return ClassMinified
+	}
+	return ClassSource
+}
+
+// isGeneratedName reports whether a file name is that of generator output
+func isGeneratedName(base string) bool {
+	if lockfiles[base] || hasSuffix(base, generatedSuffixes) {
+		return true
+	}
+	for _, prefix := range generatedPrefixes {
+		if strings.HasPrefix(base, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// header returns the start of content searched for a generated marker
+func header(content string) string {
+	if len(content) > headerSize {
+		return content[:headerSize]
+	}
+	return content
+}
+
+// isBinary reports whether content looks like binary data: it has a NUL byte
+// or more than 10% of its sample is invalid UTF-8 or control characters
+func isBinary(content string) bool {
+	sample := content
+	if len(sample) > binarySampleSize {
+		sample = sample[:binarySampleSize]
+	}
+	if strings.IndexByte(sample, 0) >= 0 {
+		return true
+	}
+
+	var runes, odd int
+	for i, r := range sample {
+		runes++
+		switch {
+		case r == utf8.RuneError && len(sample)-i >= utf8.UTFMax:
+			odd++ // Invalid encoding, not a rune cut off by the sample
+		case r < 0x20 && r != '\n' && r != '\r' && r != '\t' && r != '\f':
+			odd++
+		}
+	}
+	return runes > 0 && odd*10 > runes
+}
+
+// isMinified reports whether content has the long lines of minified code or
+// single-line data. Prose is exempt: unwrapped paragraphs are long lines too.
+func isMinified(path, content string) bool {
+	if len(content) < minifiedMinSize {
+		return false
+	}
+	if IsDocLanguage(GetLanguage(path)) {
+		return false
+	}
+	lines := strings.Count(strings.TrimRight(content, "\n"), "\n") + 1
+	return len(content)/lines > minifiedLineLength
+}
+
+// hasSuffix reports whether s ends with any of suffixes
+func hasSuffix(s string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return true
+		}
+	}
+	return false
+}
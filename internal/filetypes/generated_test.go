@@ -0,0 +1,45 @@
+package filetypes
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	minified := "function a(){return 1}" + strings.Repeat(";var b=function(c){return c*2}", 60)
+	longProse := strings.Repeat("This paragraph was written on a single line, as markdown allows. ", 30)
+
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		want    Class
+	}{
+		{"plain go", "internal/api/handler.go", "package api\n\nfunc Handle() {}\n", ClassSource},
+		{"protobuf stub", "api/v1/user.pb.go", "package v1\n", ClassGenerated},
+		{"python protobuf", "gen/user_pb2.py", "import grpc\n", ClassGenerated},
+		{"go generate header", "internal/mocks/store.go", "// Code generated by mockery v2.20.0. DO NOT EDIT.\n\npackage mocks\n", ClassGenerated},
+		{"header after license", "pkg/x.go", strings.Repeat("// Licensed under the Apache License.\n", 20) + "// Code generated by stringer; DO NOT EDIT.\n", ClassGenerated},
+		{"at generated", "src/schema.ts", "/**\n * @generated\n */\nexport type A = {}\n", ClassGenerated},
+		{"lockfile", "web/package-lock.json", "{\n  \"lockfileVersion\": 3\n}\n", ClassGenerated},
+		{"source map", "dist/app.js.map", "{\"version\":3}", ClassGenerated},
+		{"kubernetes deepcopy", "apis/v1/zz_generated.deepcopy.go", "package v1\n", ClassGenerated},
+		{"mentions generation", "pkg/id.go", "package id\n\n// New returns a generated identifier\nfunc New() string { return \"\" }\n", ClassSource},
+		{"third party", "third_party/lib/lib.go", "package lib\n", ClassVendored},
+		{"min suffix", "static/jquery.min.js", "var a=1;", ClassMinified},
+		{"long lines", "static/app.js", minified, ClassMinified},
+		{"long prose", "docs/guide.md", longProse, ClassSource},
+		{"short one-liner", "config.json", "{\"a\":1}", ClassSource},
+		{"nul bytes", "assets/logo.go", "GIF89a\x00\x01\x02", ClassBinary},
+		{"control characters", "data/blob.json", strings.Repeat("\x01\x02\x03ab", 50), ClassBinary},
+		{"utf-8 text", "i18n/de.json", "{\"greeting\": \"Grüß Gott\"}\n", ClassSource},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.path, tt.content); got != tt.want {
+				t.Errorf("Classify(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -61,6 +61,23 @@ var Extensions = map[string]bool{
 	".bash": true,
 	".zsh":  true,
 
+	// Web frameworks
+	".vue":    true,
+	".svelte": true,
+
+	// Dart, Elixir, Lua
+	".dart": true,
+	".ex":   true,
+	".exs":  true,
+	".lua":  true,
+
+	// Infrastructure
+	".tf":         true,
+	".tfvars":     true,
+	".hcl":        true,
+	".dockerfile": true,
+	".mk":         true,
+
 	// Config/Data
 	".proto": true,
 	".sql":   true,
@@ -126,6 +143,23 @@ var Languages = map[string]string{
 	".bash": "bash",
 	".zsh":  "bash",
 
+	// Web frameworks
+	".vue":    "vue",
+	".svelte": "svelte",
+
+	// Dart, Elixir, Lua
+	".dart": "dart",
+	".ex":   "elixir",
+	".exs":  "elixir",
+	".lua":  "lua",
+
+	// Infrastructure
+	".tf":         "terraform",
+	".tfvars":     "terraform",
+	".hcl":        "hcl",
+	".dockerfile": "dockerfile",
+	".mk":         "makefile",
+
 	// Config/Data
 	".proto": "protobuf",
 	".sql":   "sql",
@@ -140,6 +174,39 @@ var Languages = map[string]string{
 	".rst": "restructuredtext",
 }
 
+// Filenames maps file names without a telling extension to their language
+var Filenames = map[string]string{
+	"Dockerfile":     "dockerfile",
+	"Containerfile":  "dockerfile",
+	"Makefile":       "makefile",
+	"makefile":       "makefile",
+	"GNUmakefile":    "makefile",
+	"CMakeLists.txt": "cmake",
+	"Jenkinsfile":    "groovy",
+	"Rakefile":       "ruby",
+	"Gemfile":        "ruby",
+	"Vagrantfile":    "ruby",
+}
+
+// Interpreters maps shebang interpreters to the language of extension-less
+// scripts that name them, e.g. "#!/usr/bin/env python3"
+var Interpreters = map[string]string{
+	"sh":     "bash",
+	"bash":   "bash",
+	"zsh":    "bash",
+	"ksh":    "bash",
+	"dash":   "bash",
+	"python": "python",
+	"node":   "javascript",
+	"nodejs": "javascript",
+	"deno":   "typescript",
+	"ruby":   "ruby",
+	"perl":   "perl",
+	"php":    "php",
+	"lua":    "lua",
+	"elixir": "elixir",
+}
+
 // SkipDirectories lists directories that should be skipped during file walks
 var SkipDirectories = map[string]bool{
 	// Version control
@@ -175,17 +242,16 @@ var SkipDirectories = map[string]bool{
 	"temp":   true,
 }
 
-// IsCodeFile returns true if the file should be indexed based on its extension
+// IsCodeFile returns true if the file should be indexed based on its name
+// in the built-in registry
 func IsCodeFile(path string) bool {
-	ext := strings.ToLower(filepath.Ext(path))
-	return Extensions[ext]
+	return defaultRegistry.IsCodeFile(path)
 }
 
-// GetLanguage returns the syntax highlighting language for a file path
-// Returns empty string if not a recognized code file
+// GetLanguage returns the syntax highlighting language for a file path in the
+// built-in registry. Returns empty string if not a recognized code file.
 func GetLanguage(path string) string {
-	ext := strings.ToLower(filepath.Ext(path))
-	return Languages[ext]
+	return defaultRegistry.Language(path)
 }
 
 // ShouldSkipDirectory returns true if a directory should be skipped during file walks
@@ -201,3 +267,39 @@ func ShouldSkipDirectory(name string) bool {
 	// Check skip list
 	return SkipDirectories[name]
 }
+
+// testDirectories hold test code regardless of file naming
+var testDirectories = map[string]bool{
+	"test":      true,
+	"tests":     true,
+	"__tests__": true,
+	"spec":      true,
+	"testdata":  true,
+}
+
+// IsTestFile reports whether a path looks like test code, by the naming
+// conventions of the common languages or by living in a test directory
+func IsTestFile(path string) bool {
+	path = filepath.ToSlash(path)
+	dirs := strings.Split(path, "/")
+	for _, dir := range dirs[:len(dirs)-1] {
+		if testDirectories[dir] {
+			return true
+		}
+	}
+
+	base := dirs[len(dirs)-1]
+	ext := filepath.Ext(base)
+	name := strings.TrimSuffix(base, ext)
+	switch {
+	case strings.HasSuffix(name, "_test"): // Go, Python
+		return true
+	case strings.HasSuffix(name, ".test"), strings.HasSuffix(name, ".spec"): // JS/TS
+		return true
+	case ext == ".py" && strings.HasPrefix(name, "test_"):
+		return true
+	case (ext == ".java" || ext == ".kt" || ext == ".scala") && (strings.HasSuffix(name, "Test") || strings.HasSuffix(name, "Tests")):
+		return true
+	}
+	return false
+}
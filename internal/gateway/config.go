@@ -3,31 +3,167 @@ package gateway
 import (
 	"fmt"
 	"os"
+	"path"
+	"slices"
+	"time"
 
+	"github.com/First008/mesh/internal/agent"
+	"github.com/First008/mesh/internal/filetypes"
+	"github.com/First008/mesh/internal/models"
+	"github.com/First008/mesh/internal/resilience"
+	"github.com/First008/mesh/internal/secrets"
+	"github.com/First008/mesh/internal/vectorstore"
 	"gopkg.in/yaml.v3"
 )
 
+const (
+	defaultGraphHops = 1 // Direct dependencies of search results
+	maxGraphHops     = 3
+)
+
 // Config represents the gateway configuration for multi-repo setup
 type Config struct {
-	Port              int          `yaml:"port"`
-	QdrantURL         string       `yaml:"qdrant_url"`
-	EmbeddingProvider string       `yaml:"embedding_provider"` // "ollama" or "openai"
-	EmbeddingModel    string       `yaml:"embedding_model"`
-	OllamaURL         string       `yaml:"ollama_url,omitempty"`
-	OpenAIKey         string       `yaml:"openai_key,omitempty"`
-	LLMProvider       string       `yaml:"llm_provider"` // "anthropic", "ollama", "openai"
-	LLMModel          string       `yaml:"llm_model"`
-	AnthropicKey      string       `yaml:"anthropic_key,omitempty"`
-	Repos             []RepoConfig `yaml:"repos"`
+	Port               int                   `yaml:"port"`
+	QdrantURL          string                `yaml:"qdrant_url"`
+	EmbeddingProvider  string                `yaml:"embedding_provider"` // "ollama" or "openai"
+	EmbeddingModel     string                `yaml:"embedding_model"`
+	OllamaURL          string                `yaml:"ollama_url,omitempty"`
+	OpenAIKey          string                `yaml:"openai_key,omitempty"`
+	LLMProvider        string                `yaml:"llm_provider"` // "anthropic", "ollama", "openai"
+	LLMModel           string                `yaml:"llm_model"`
+	LLMChain           []agent.LLMEntry      `yaml:"llm_chain,omitempty"` // Ordered fallback chain; replaces llm_provider/llm_model
+	AnthropicKey       string                `yaml:"anthropic_key,omitempty"`
+	Webhooks           WebhookConfig         `yaml:"webhooks,omitempty"`
+	AdminToken         string                `yaml:"admin_token,omitempty"`         // Bearer token for POST/DELETE /repos; empty disables them
+	Workspace          string                `yaml:"workspace,omitempty"`           // Clone directory for remote repos (default .mesh/workspace)
+	FetchInterval      time.Duration         `yaml:"fetch_interval,omitempty"`      // How often remote repos are fetched (default 5m)
+	StartupConcurrency int                   `yaml:"startup_concurrency,omitempty"` // Repos cloned and indexed at once at startup (default 2)
+	Resilience         resilience.Config     `yaml:"resilience,omitempty"`          // Retry, rate limit and circuit breaker policies
+	Models             []models.Capabilities `yaml:"models,omitempty"`              // Model capability overrides (context window, pricing, ...)
+	Repos              []RepoConfig          `yaml:"repos"`
+}
+
+// WebhookConfig holds the shared secrets used to validate incoming webhooks.
+// A provider with an empty secret accepts unsigned deliveries.
+type WebhookConfig struct {
+	GitHubSecret    string `yaml:"github_secret,omitempty"`    // HMAC key for X-Hub-Signature-256
+	GitLabToken     string `yaml:"gitlab_token,omitempty"`     // Compared against X-Gitlab-Token
+	GiteaSecret     string `yaml:"gitea_secret,omitempty"`     // HMAC key for X-Gitea-Signature / X-Forgejo-Signature
+	BitbucketSecret string `yaml:"bitbucket_secret,omitempty"` // HMAC key for X-Hub-Signature
 }
 
 // RepoConfig represents configuration for a single repository
 type RepoConfig struct {
-	Name            string   `yaml:"name"`
-	Path            string   `yaml:"path"`
-	FocusPaths      []string `yaml:"focus_paths,omitempty"`
-	Personality     string   `yaml:"personality,omitempty"`
-	ExcludePatterns []string `yaml:"exclude_patterns,omitempty"` // File patterns to exclude from search results
+	Name            string        `yaml:"name"`
+	Path            string        `yaml:"path"`
+	FocusPaths      []string      `yaml:"focus_paths,omitempty"`
+	Personality     string        `yaml:"personality,omitempty"`
+	ExcludePatterns []string      `yaml:"exclude_patterns,omitempty"` // File patterns to exclude from search results
+	Watch           bool          `yaml:"watch,omitempty"`            // Index uncommitted changes into a working-tree overlay
+	WatchDebounce   time.Duration `yaml:"watch_debounce,omitempty"`   // Quiet period before syncing edits (default 2s)
+	URL             string        `yaml:"url,omitempty"`              // Remote git URL, cloned into the workspace instead of using path
+	CredentialsEnv  string        `yaml:"credentials_env,omitempty"`  // Env var holding "token" or "user:token" for HTTPS remotes
+	CredentialsFile string        `yaml:"credentials_file,omitempty"` // File holding "token" or "user:token" for HTTPS remotes
+	SSHKeyFile      string        `yaml:"ssh_key_file,omitempty"`     // Private key for SSH remotes
+	Branches        BranchPolicy  `yaml:"branches,omitempty"`         // Which branches the scanner indexes and prunes
+	Secrets         secrets.Mode  `yaml:"secrets,omitempty"`          // Files with secrets: redact (default), skip, or off
+
+	// Generated, vendored, minified and binary files: downweight (default), skip, or off
+	Generated vectorstore.GeneratedMode `yaml:"generated,omitempty"`
+
+	// Extensions, file names and shebang interpreters indexed as code, on top
+	// of (or overriding) the built-in ones
+	FileTypes filetypes.Config `yaml:"file_types,omitempty"`
+
+	// Text embedded for each chunk: a Go template over the chunk and its
+	// file's path, package, enclosing symbol and imports (see
+	// vectorstore.EmbedContext), or "raw" for the content alone
+	EmbedTemplate string `yaml:"embed_template,omitempty"`
+
+	// Dependency edges followed from search results to the services, types
+	// and modules they use (default 1, 0 disables, at most maxGraphHops)
+	GraphHops *int `yaml:"graph_hops,omitempty"`
+
+	// Whether indexing asks the repo's LLM for a summary of each changed
+	// package, for the repository map of the cacheable context (default true)
+	Summaries *bool `yaml:"summaries,omitempty"`
+}
+
+// BranchPolicy controls which branches the scanner keeps indexed. Globs use
+// path.Match syntax, so "release/*" matches "release/1.2" but not "release/1/2".
+// The checked-out branch is never pruned.
+type BranchPolicy struct {
+	Include     []string      `yaml:"include,omitempty"`      // New branches to index automatically (default: none, only branches indexed before)
+	Exclude     []string      `yaml:"exclude,omitempty"`      // Branches never indexed; indexed ones are pruned
+	MaxBranches int           `yaml:"max_branches,omitempty"` // Keep only the most recently committed branches (0 = no limit)
+	InactiveTTL time.Duration `yaml:"inactive_ttl,omitempty"` // Prune branches without commits for this long (0 = never)
+}
+
+// IsRemote reports whether the repository is cloned from a remote URL
+func (r RepoConfig) IsRemote() bool {
+	return r.URL != ""
+}
+
+// SecretMode returns how files containing secrets are handled
+func (r RepoConfig) SecretMode() secrets.Mode {
+	mode, err := secrets.ParseMode(string(r.Secrets))
+	if err != nil {
+		return secrets.ModeRedact // Rejected by validate; never weaker than the default
+	}
+	return mode
+}
+
+// GeneratedMode returns how generated, vendored, minified and binary files
+// are handled
+func (r RepoConfig) GeneratedMode() vectorstore.GeneratedMode {
+	mode, err := vectorstore.ParseGeneratedMode(string(r.Generated))
+	if err != nil {
+		return vectorstore.GeneratedDownweight // Rejected by validate
+	}
+	return mode
+}
+
+// FileTypeRegistry returns which files of the repository are code
+func (r RepoConfig) FileTypeRegistry() *filetypes.Registry {
+	types, err := filetypes.New(r.FileTypes)
+	if err != nil {
+		return filetypes.Default() // Rejected by validate
+	}
+	return types
+}
+
+// EmbeddingTemplate returns the template rendering the text embedded for
+// each chunk
+func (r RepoConfig) EmbeddingTemplate() *vectorstore.EmbedTemplate {
+	t, err := vectorstore.ParseEmbedTemplate(r.EmbedTemplate)
+	if err != nil {
+		return nil // Rejected by validate; nil is the default
+	}
+	return t
+}
+
+// DependencyHops returns how many dependency edges search results are
+// expanded along
+func (r RepoConfig) DependencyHops() int {
+	if r.GraphHops == nil {
+		return defaultGraphHops
+	}
+	return *r.GraphHops
+}
+
+// PackageSummaries reports whether indexing summarizes packages with the LLM
+func (r RepoConfig) PackageSummaries() bool {
+	return r.Summaries == nil || *r.Summaries
+}
+
+// findRepo returns the configuration for a repository, or nil if unknown
+func (c *Config) findRepo(name string) *RepoConfig {
+	for i := range c.Repos {
+		if c.Repos[i].Name == name {
+			return &c.Repos[i]
+		}
+	}
+	return nil
 }
 
 // LoadConfig loads gateway configuration from a YAML file
@@ -49,6 +185,21 @@ func LoadConfig(path string) (*Config, error) {
 	if config.OpenAIKey == "" {
 		config.OpenAIKey = os.Getenv("OPENAI_API_KEY")
 	}
+	if config.AdminToken == "" {
+		config.AdminToken = os.Getenv("MESH_ADMIN_TOKEN")
+	}
+	if config.Webhooks.GitHubSecret == "" {
+		config.Webhooks.GitHubSecret = os.Getenv("MESH_GITHUB_WEBHOOK_SECRET")
+	}
+	if config.Webhooks.GitLabToken == "" {
+		config.Webhooks.GitLabToken = os.Getenv("MESH_GITLAB_WEBHOOK_TOKEN")
+	}
+	if config.Webhooks.GiteaSecret == "" {
+		config.Webhooks.GiteaSecret = os.Getenv("MESH_GITEA_WEBHOOK_SECRET")
+	}
+	if config.Webhooks.BitbucketSecret == "" {
+		config.Webhooks.BitbucketSecret = os.Getenv("MESH_BITBUCKET_WEBHOOK_SECRET")
+	}
 
 	// Validate config
 	if err := config.Validate(); err != nil {
@@ -72,8 +223,13 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("embedding_provider is required")
 	}
 
-	if c.LLMProvider == "" {
-		return fmt.Errorf("llm_provider is required")
+	if c.LLMProvider == "" && len(c.LLMChain) == 0 {
+		return fmt.Errorf("llm_provider or llm_chain is required")
+	}
+	for i, entry := range c.LLMChain {
+		if entry.Provider == "" {
+			return fmt.Errorf("llm_chain[%d]: provider is required", i)
+		}
 	}
 
 	if len(c.Repos) == 0 {
@@ -81,14 +237,66 @@ func (c *Config) Validate() error {
 	}
 
 	// Validate each repo
+	seen := make(map[string]bool, len(c.Repos))
 	for i, repo := range c.Repos {
-		if repo.Name == "" {
-			return fmt.Errorf("repo[%d]: name is required", i)
+		if err := repo.validate(); err != nil {
+			return fmt.Errorf("repo[%d]: %w", i, err)
+		}
+		if seen[repo.Name] {
+			return fmt.Errorf("repo[%d]: duplicate name %s", i, repo.Name)
 		}
-		if repo.Path == "" {
-			return fmt.Errorf("repo[%d]: path is required", i)
+		seen[repo.Name] = true
+	}
+
+	return nil
+}
+
+// validate checks a single repository configuration
+func (r RepoConfig) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if r.Path == "" && r.URL == "" {
+		return fmt.Errorf("path or url is required")
+	}
+	if r.URL != "" && r.Watch {
+		return fmt.Errorf("watch requires a local path, not a url")
+	}
+	if r.URL != "" {
+		if err := validateRemoteURL(r.URL); err != nil {
+			return err
 		}
 	}
+	if _, err := secrets.ParseMode(string(r.Secrets)); err != nil {
+		return err
+	}
+	if _, err := vectorstore.ParseGeneratedMode(string(r.Generated)); err != nil {
+		return err
+	}
+	if err := r.FileTypes.Validate(); err != nil {
+		return err
+	}
+	if _, err := vectorstore.ParseEmbedTemplate(r.EmbedTemplate); err != nil {
+		return err
+	}
+	if hops := r.DependencyHops(); hops < 0 || hops > maxGraphHops {
+		return fmt.Errorf("graph_hops must be between 0 and %d", maxGraphHops)
+	}
+	return r.Branches.validate()
+}
 
+// validate checks the branch globs and limits
+func (p BranchPolicy) validate() error {
+	for _, pattern := range append(slices.Clone(p.Include), p.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("branches: invalid pattern %q", pattern)
+		}
+	}
+	if p.MaxBranches < 0 {
+		return fmt.Errorf("branches: max_branches must not be negative")
+	}
+	if p.InactiveTTL < 0 {
+		return fmt.Errorf("branches: inactive_ttl must not be negative")
+	}
 	return nil
 }
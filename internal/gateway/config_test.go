@@ -4,6 +4,12 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/First008/mesh/internal/agent"
+	"github.com/First008/mesh/internal/filetypes"
+	"github.com/First008/mesh/internal/secrets"
+	"github.com/First008/mesh/internal/vectorstore"
 )
 
 func TestValidate_ValidConfig(t *testing.T) {
@@ -82,6 +88,28 @@ func TestValidate_MissingLLMProvider(t *testing.T) {
 	}
 }
 
+func TestValidate_LLMChain(t *testing.T) {
+	config := &Config{
+		Port:              8080,
+		QdrantURL:         "http://localhost:6333",
+		EmbeddingProvider: "ollama",
+		LLMChain: []agent.LLMEntry{
+			{Provider: "anthropic", Model: "claude-sonnet-4-5-20250929"},
+			{Provider: "ollama", Model: "llama3.3:70b"},
+		},
+		Repos: []RepoConfig{{Name: "repo1", Path: "/tmp/repo1"}},
+	}
+
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected llm_chain to replace llm_provider, got %v", err)
+	}
+
+	config.LLMChain = append(config.LLMChain, agent.LLMEntry{Model: "orphan"})
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for llm_chain entry without provider")
+	}
+}
+
 func TestValidate_NoRepos(t *testing.T) {
 	config := &Config{
 		Port:              8080,
@@ -162,6 +190,181 @@ func TestValidate_MultipleRepos(t *testing.T) {
 	}
 }
 
+func TestValidate_DuplicateRepoName(t *testing.T) {
+	config := &Config{
+		Port:              8080,
+		QdrantURL:         "http://localhost:6333",
+		EmbeddingProvider: "ollama",
+		LLMProvider:       "anthropic",
+		Repos: []RepoConfig{
+			{Name: "repo1", Path: "/tmp/repo1"},
+			{Name: "repo1", Path: "/tmp/other"},
+		},
+	}
+
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("Expected error for duplicate repo name")
+	}
+
+	if !contains(err.Error(), "duplicate") {
+		t.Errorf("Error should mention duplicate name, got: %v", err)
+	}
+}
+
+func TestValidate_BranchPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  BranchPolicy
+		wantErr bool
+	}{
+		{"globs", BranchPolicy{Include: []string{"release/*"}, Exclude: []string{"dependabot/*"}}, false},
+		{"limits", BranchPolicy{MaxBranches: 10, InactiveTTL: 720 * time.Hour}, false},
+		{"bad include", BranchPolicy{Include: []string{"release/["}}, true},
+		{"bad exclude", BranchPolicy{Exclude: []string{"[a-"}}, true},
+		{"negative max", BranchPolicy{MaxBranches: -1}, true},
+		{"negative ttl", BranchPolicy{InactiveTTL: -time.Hour}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := RepoConfig{Name: "repo1", Path: "/tmp/repo1", Branches: tt.policy}
+			if err := repo.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_Secrets(t *testing.T) {
+	tests := []struct {
+		mode     secrets.Mode
+		wantErr  bool
+		wantMode secrets.Mode
+	}{
+		{"", false, secrets.ModeRedact},
+		{"redact", false, secrets.ModeRedact},
+		{"skip", false, secrets.ModeSkip},
+		{"off", false, secrets.ModeOff},
+		{"mask", true, secrets.ModeRedact},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			repo := RepoConfig{Name: "repo1", Path: "/tmp/repo1", Secrets: tt.mode}
+			if err := repo.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := repo.SecretMode(); got != tt.wantMode {
+				t.Errorf("SecretMode() = %q, want %q", got, tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestValidate_Generated(t *testing.T) {
+	tests := []struct {
+		mode     vectorstore.GeneratedMode
+		wantErr  bool
+		wantMode vectorstore.GeneratedMode
+	}{
+		{"", false, vectorstore.GeneratedDownweight},
+		{"downweight", false, vectorstore.GeneratedDownweight},
+		{"skip", false, vectorstore.GeneratedSkip},
+		{"off", false, vectorstore.GeneratedOff},
+		{"hide", true, vectorstore.GeneratedDownweight},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			repo := RepoConfig{Name: "repo1", Path: "/tmp/repo1", Generated: tt.mode}
+			if err := repo.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := repo.GeneratedMode(); got != tt.wantMode {
+				t.Errorf("GeneratedMode() = %q, want %q", got, tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestValidate_FileTypes(t *testing.T) {
+	repo := RepoConfig{Name: "repo1", Path: "/tmp/repo1", FileTypes: filetypes.Config{
+		Extensions: map[string]string{".tpl": "gotemplate"},
+		Filenames:  map[string]string{"Tiltfile": "starlark"},
+	}}
+	if err := repo.validate(); err != nil {
+		t.Fatalf("validate() error = %v", err)
+	}
+	if got := repo.FileTypeRegistry().Language("Tiltfile"); got != "starlark" {
+		t.Errorf("Language(Tiltfile) = %q, want starlark", got)
+	}
+
+	repo.FileTypes.Extensions = map[string]string{"tpl": "gotemplate"}
+	if err := repo.validate(); err == nil {
+		t.Error("Expected an extension without a dot to be rejected")
+	}
+	if got := repo.FileTypeRegistry(); got != filetypes.Default() {
+		t.Error("Expected invalid file types to fall back to the built-in registry")
+	}
+}
+
+func TestValidate_EmbedTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantErr  bool
+	}{
+		{"default", "", false},
+		{"raw", "raw", false},
+		{"custom", "{{.Path}}\n{{.Content}}", false},
+		{"syntax error", "{{.Path", true},
+		{"unknown field", "{{.Module}}", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := RepoConfig{Name: "repo1", Path: "/tmp/repo1", EmbedTemplate: tt.template}
+			if err := repo.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	raw := RepoConfig{EmbedTemplate: "raw"}
+	if got := raw.EmbeddingTemplate().Fingerprint(); got != "" {
+		t.Errorf("Expected raw to have an empty fingerprint, got %q", got)
+	}
+}
+
+func TestValidate_GraphHops(t *testing.T) {
+	hops := func(n int) *int { return &n }
+	tests := []struct {
+		name     string
+		hops     *int
+		wantHops int
+		wantErr  bool
+	}{
+		{"default", nil, 1, false},
+		{"disabled", hops(0), 0, false},
+		{"max", hops(3), 3, false},
+		{"too many", hops(4), 4, true},
+		{"negative", hops(-1), -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := RepoConfig{Name: "repo1", Path: "/tmp/repo1", GraphHops: tt.hops}
+			if err := repo.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := repo.DependencyHops(); got != tt.wantHops {
+				t.Errorf("DependencyHops() = %d, want %d", got, tt.wantHops)
+			}
+		})
+	}
+}
+
 func TestValidate_RepoWithFocusPaths(t *testing.T) {
 	config := &Config{
 		Port:              8080,
@@ -354,3 +557,40 @@ func contains(s, substr string) bool {
 	}
 	return false
 }
+
+func TestLoadConfig_WatchSettings(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "gateway.yaml")
+
+	configYAML := `port: 8080
+qdrant_url: http://localhost:6333
+embedding_provider: ollama
+llm_provider: ollama
+repos:
+  - name: repo1
+    path: /tmp/repo1
+    watch: true
+    watch_debounce: 500ms
+  - name: repo2
+    path: /tmp/repo2
+`
+
+	if err := os.WriteFile(configPath, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if !config.Repos[0].Watch {
+		t.Error("Expected repo1 to have watch enabled")
+	}
+	if config.Repos[0].WatchDebounce != 500*time.Millisecond {
+		t.Errorf("Expected 500ms debounce, got %v", config.Repos[0].WatchDebounce)
+	}
+	if config.Repos[1].Watch {
+		t.Error("Expected repo2 to have watch disabled by default")
+	}
+}
@@ -0,0 +1,149 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog"
+)
+
+// defaultReloadDebounce is how long the config watcher waits for writes to settle
+const defaultReloadDebounce = time.Second
+
+// ConfigWatcher reloads the gateway's repositories when the config file changes.
+// It watches the file's directory, so editors that replace the file and mounted
+// ConfigMaps that swap symlinks are picked up too.
+type ConfigWatcher struct {
+	gateway  *Gateway
+	path     string
+	debounce time.Duration
+	lastHash [sha256.Size]byte
+	watcher  *fsnotify.Watcher
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	logger   zerolog.Logger
+}
+
+// NewConfigWatcher creates a watcher for the config file at path
+func NewConfigWatcher(gateway *Gateway, path string, logger zerolog.Logger) (*ConfigWatcher, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("create fsnotify watcher: %w", err)
+	}
+	if err := fsw.Add(filepath.Dir(path)); err != nil {
+		fsw.Close()
+		return nil, fmt.Errorf("watch %s: %w", filepath.Dir(path), err)
+	}
+
+	return &ConfigWatcher{
+		gateway:  gateway,
+		path:     path,
+		debounce: defaultReloadDebounce,
+		lastHash: sha256.Sum256(data),
+		watcher:  fsw,
+		stopChan: make(chan struct{}),
+		logger:   logger.With().Str("config", path).Logger(),
+	}, nil
+}
+
+// Start begins processing filesystem events
+func (cw *ConfigWatcher) Start(ctx context.Context) {
+	cw.wg.Add(1)
+	go cw.watchLoop(ctx)
+	cw.logger.Info().Msg("Config watcher started")
+}
+
+// Stop gracefully stops the watcher
+func (cw *ConfigWatcher) Stop() {
+	close(cw.stopChan)
+	cw.wg.Wait()
+	cw.watcher.Close()
+	cw.logger.Info().Msg("Config watcher stopped")
+}
+
+// watchLoop debounces directory events into reloads
+func (cw *ConfigWatcher) watchLoop(ctx context.Context) {
+	defer cw.wg.Done()
+
+	timer := time.NewTimer(cw.debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case event, ok := <-cw.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			timer.Reset(cw.debounce)
+		case err, ok := <-cw.watcher.Errors:
+			if !ok {
+				return
+			}
+			cw.logger.Warn().Err(err).Msg("Config watcher error")
+		case <-timer.C:
+			cw.reload()
+		case <-cw.stopChan:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reload applies the config file if its content changed. Invalid configs are
+// logged and leave the running repositories untouched.
+func (cw *ConfigWatcher) reload() {
+	data, err := os.ReadFile(cw.path)
+	if err != nil {
+		cw.logger.Warn().Err(err).Msg("Failed to read config file")
+		return
+	}
+
+	hash := sha256.Sum256(data)
+	if hash == cw.lastHash {
+		return
+	}
+	cw.lastHash = hash
+
+	config, err := LoadConfig(cw.path)
+	if err != nil {
+		cw.logger.Error().Err(err).Msg("Invalid config, keeping current repositories")
+		return
+	}
+
+	result, err := cw.gateway.Reload(config)
+	if err != nil {
+		cw.logger.Error().Err(err).Msg("Config reload partially failed")
+	}
+	cw.logger.Info().
+		Strs("added", result.Added).
+		Strs("updated", result.Updated).
+		Strs("removed", result.Removed).
+		Msg("Config reloaded")
+}
+
+// WatchConfig reloads repositories whenever the config file at path changes
+func (gw *Gateway) WatchConfig(ctx context.Context, path string) error {
+	watcher, err := NewConfigWatcher(gw, path, gw.logger)
+	if err != nil {
+		return err
+	}
+	gw.configWatcher = watcher
+	watcher.Start(ctx)
+	return nil
+}
@@ -0,0 +1,96 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/First008/mesh/internal/vectorstore"
+)
+
+// BranchAction describes what a branch event asks the gateway to do
+type BranchAction string
+
+const (
+	// BranchUpdated means new commits landed on the branch and it should be re-indexed
+	BranchUpdated BranchAction = "updated"
+
+	// BranchDeleted means the branch was removed and its index should be dropped
+	BranchDeleted BranchAction = "deleted"
+)
+
+// BranchEvent is the provider-independent form of a git hosting webhook
+// (GitHub, GitLab, Gitea/Forgejo, Bitbucket). Webhook receivers normalize
+// their native payloads into one or more BranchEvents.
+type BranchEvent struct {
+	Provider string       // "github", "gitlab", "gitea", "bitbucket"
+	Repo     string       // Repository name as sent by the provider (e.g. "backend-api")
+	FullName string       // Namespaced name (e.g. "team/backend-api"), optional
+	Branch   string       // Branch name without refs/heads/ prefix
+	Before   string       // Previous commit SHA, optional
+	After    string       // New commit SHA, optional
+	Action   BranchAction // What to do with the branch
+}
+
+// ResolveRepo maps the repository named in an event to a configured repository.
+// The short name is tried first, then the full name and its last path segment.
+func (gw *Gateway) ResolveRepo(event BranchEvent) (string, bool) {
+	candidates := []string{event.Repo, event.FullName}
+	if idx := strings.LastIndex(event.FullName, "/"); idx >= 0 {
+		candidates = append(candidates, event.FullName[idx+1:])
+	}
+
+	for _, name := range candidates {
+		if name == "" {
+			continue
+		}
+		if gw.findRepoConfig(name) != nil {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// HandleBranchEvent applies a normalized webhook event to the matching repository.
+// Updates are queued as re-index jobs and the job is returned; deletions are
+// applied immediately and return a nil job.
+func (gw *Gateway) HandleBranchEvent(ctx context.Context, event BranchEvent) (*JobStatus, error) {
+	repoName, ok := gw.ResolveRepo(event)
+	if !ok {
+		return nil, fmt.Errorf("repository not found: %s", event.Repo)
+	}
+
+	if err := vectorstore.ValidateBranchName(event.Branch); err != nil {
+		return nil, fmt.Errorf("event for %s: %w", repoName, err)
+	}
+
+	gw.logger.Info().
+		Str("provider", event.Provider).
+		Str("repo", repoName).
+		Str("branch", event.Branch).
+		Str("action", string(event.Action)).
+		Str("before", shortSHA(event.Before)).
+		Str("after", shortSHA(event.After)).
+		Msg("Handling branch event")
+
+	switch event.Action {
+	case BranchUpdated:
+		job, err := gw.EnqueueReindex(repoName, event.Branch)
+		if err != nil {
+			return nil, err
+		}
+		return &job, nil
+	case BranchDeleted:
+		return nil, gw.DeleteBranch(ctx, repoName, event.Branch)
+	default:
+		return nil, fmt.Errorf("unsupported branch action: %s", event.Action)
+	}
+}
+
+// shortSHA abbreviates a commit SHA for logging
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
@@ -12,8 +12,10 @@ import (
 	"time"
 
 	"github.com/First008/mesh/internal/agent"
+	contextbuilder "github.com/First008/mesh/internal/context"
 	"github.com/First008/mesh/internal/factory"
 	"github.com/First008/mesh/internal/llm"
+	"github.com/First008/mesh/internal/tokenizer"
 	"github.com/First008/mesh/internal/vectorstore"
 	"github.com/rs/zerolog"
 )
@@ -21,30 +23,72 @@ import (
 // Gateway orchestrates multiple repository agents
 // Each repo gets its own Agent instance (reusing existing code!)
 type Gateway struct {
-	agents  map[string]*agent.Agent // repo name -> agent
-	config  *Config
-	scanner *BranchScanner // Periodic branch scanner
-	mu      sync.RWMutex
-	logger  zerolog.Logger
+	agents        map[string]*agent.Agent             // repo name -> agent
+	watchers      map[string]*WorkingTreeWatcher      // repo name -> working tree watcher
+	trees         map[string]*vectorstore.WorkingTree // repo name -> working tree overlay
+	dynamic       map[string]bool                     // repos added through the API rather than the config file
+	states        map[string]*repoStatus              // repo name -> readiness
+	config        *Config                             // Repos is replaced, never modified in place, under mu
+	scanner       *BranchScanner                      // Periodic branch scanner
+	fetcher       *RemoteFetcher                      // Periodic fetch of remote repos
+	configWatcher *ConfigWatcher                      // Hot reload of the config file
+	jobs          *JobQueue                           // Asynchronous re-index jobs
+	watchCtx      context.Context                     // Context for watchers of repos added at runtime
+	initCtx       context.Context                     // Background clones and first indexes, including repos added at runtime
+	initCancel    context.CancelFunc                  // Stops background clones and first indexes
+	initWg        sync.WaitGroup                      // Background startup work
+	reconfigMu    sync.Mutex                          // Serializes adding, updating and removing repos
+	mu            sync.RWMutex
+	logger        zerolog.Logger
 }
 
-// New creates a new gateway with the given configuration
+// New creates a new gateway with the given configuration. Agents for local
+// repositories are created right away; remote clones and first indexes run in
+// the background, so the gateway can serve while they finish. Until a
+// repository's index is ready its agent answers with keyword search.
 func New(config *Config, logger zerolog.Logger) (*Gateway, error) {
 	gw := &Gateway{
-		agents: make(map[string]*agent.Agent),
-		config: config,
-		logger: logger,
+		agents:   make(map[string]*agent.Agent),
+		watchers: make(map[string]*WorkingTreeWatcher),
+		trees:    make(map[string]*vectorstore.WorkingTree),
+		dynamic:  make(map[string]bool),
+		states:   make(map[string]*repoStatus),
+		config:   config,
+		logger:   logger,
 	}
+	gw.jobs = NewJobQueue(gw.reindexBranch, logger)
+	gw.jobs.SetRepair(gw.repairBranch)
+	gw.jobs.OnFinish(gw.jobFinished)
 
 	// Initialize agents for each repo
-	for _, repoConfig := range config.Repos {
-		if err := gw.addRepo(repoConfig); err != nil {
+	var pending []string
+	for i := range config.Repos {
+		repoConfig := &config.Repos[i]
+		status := &repoStatus{state: RepoInitializing}
+		gw.states[repoConfig.Name] = status
+
+		if repoConfig.IsRemote() {
+			// The clone location is known before the clone exists
+			repoConfig.Path = config.workspacePath(repoConfig.Name)
+			pending = append(pending, repoConfig.Name)
+			continue
+		}
+
+		if err := gw.addRepo(*repoConfig, status); err != nil {
 			return nil, fmt.Errorf("failed to add repo %s: %w", repoConfig.Name, err)
 		}
+		if status.state == RepoIndexing {
+			pending = append(pending, repoConfig.Name)
+		}
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	gw.initCtx, gw.initCancel = ctx, cancel
+	gw.initRepos(ctx, pending)
+
 	logger.Info().
 		Int("repo_count", len(config.Repos)).
+		Int("pending", len(pending)).
 		Msg("Gateway initialized with repositories")
 
 	return gw, nil
@@ -56,35 +100,121 @@ func (gw *Gateway) StartScanner(ctx context.Context, interval time.Duration) {
 	gw.scanner.Start(ctx)
 }
 
-// addRepo creates an agent for a repository
-// Refactored into smaller methods for better maintainability
-func (gw *Gateway) addRepo(repoConfig RepoConfig) error {
-	repoLogger := gw.logger.With().Str("repo", repoConfig.Name).Logger()
+// StartFetcher starts periodic fetching of remote repositories, including
+// ones added at runtime
+func (gw *Gateway) StartFetcher(ctx context.Context) {
+	gw.fetcher = NewRemoteFetcher(gw, gw.config.FetchInterval, gw.logger)
+	gw.fetcher.Start(ctx)
+}
 
-	// Build agent config
-	agentConfig := gw.buildAgentConfig(repoConfig)
+// StartWatchers starts working-tree watchers for repositories with watch enabled.
+// Failures are logged per repository and leave that repository on committed code only.
+// Repositories added later get their watcher when they are added.
+func (gw *Gateway) StartWatchers(ctx context.Context) {
+	gw.mu.Lock()
+	gw.watchCtx = ctx
+	gw.mu.Unlock()
 
-	// Create agent
-	agt, err := agent.New(agentConfig, repoLogger)
-	if err != nil {
-		return fmt.Errorf("create agent: %w", err)
+	for _, repoConfig := range gw.repoConfigs() {
+		if !repoConfig.Watch {
+			continue
+		}
+		if err := gw.startWatcher(ctx, repoConfig); err != nil {
+			gw.logger.Warn().
+				Err(err).
+				Str("repo", repoConfig.Name).
+				Msg("Working tree watcher disabled")
+		}
+	}
+}
+
+// startWatcher creates a fresh overlay collection for the checked-out branch,
+// attaches it to the repository's agent and starts watching the working tree
+func (gw *Gateway) startWatcher(ctx context.Context, repoConfig RepoConfig) error {
+	if !gw.shouldIndex(repoConfig.Path) {
+		return fmt.Errorf("watch requires qdrant_url and a git repository")
+	}
+
+	gw.mu.RLock()
+	agt, exists := gw.agents[repoConfig.Name]
+	gw.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("repository not found: %s", repoConfig.Name)
 	}
 
-	// Detect branch
 	branch := gw.detectBranch(repoConfig.Path)
+	repoLogger := gw.logger.With().
+		Str("repo", repoConfig.Name).
+		Str("branch", branch).
+		Logger()
 
-	// Perform indexing if needed
-	if gw.shouldIndex(repoConfig.Path) {
-		if err := gw.performIndexing(repoConfig, branch, agt, repoLogger); err != nil {
-			repoLogger.Warn().Err(err).Msg("Indexing failed, continuing without vector search")
-		}
+	// The overlay only mirrors this process's view of the working tree
+	if err := vectorstore.DeleteWorkingTreeCollection(ctx, gw.config.QdrantURL, repoConfig.Name, branch); err != nil {
+		return fmt.Errorf("reset working tree collection: %w", err)
+	}
+
+	embeddingProvider, err := gw.newEmbeddingProvider(repoLogger)
+	if err != nil {
+		return fmt.Errorf("create embedding provider: %w", err)
+	}
+
+	store, err := vectorstore.NewWorkingTreeStore(
+		gw.config.QdrantURL,
+		embeddingProvider,
+		repoConfig.Name,
+		branch,
+		repoLogger,
+	)
+	if err != nil {
+		return fmt.Errorf("create working tree store: %w", err)
+	}
+
+	store.SetGeneratedMode(repoConfig.GeneratedMode())
+	tree := vectorstore.NewWorkingTree(store, repoConfig.Path, repoLogger)
+	tree.SetPatterns(repoConfig.ExcludePatterns, repoConfig.FocusPaths)
+	tree.SetSecretMode(repoConfig.SecretMode())
+	tree.SetGeneratedMode(repoConfig.GeneratedMode())
+	tree.SetFileTypes(repoConfig.FileTypeRegistry())
+	tree.SetEmbedTemplate(repoConfig.EmbeddingTemplate())
+	watcher, err := NewWorkingTreeWatcher(tree, repoConfig.Path, repoConfig.WatchDebounce, repoLogger)
+	if err != nil {
+		store.Close()
+		return err
 	}
 
-	// Register agent
-	gw.registerAgent(repoConfig.Name, agt)
+	agt.SetWorkingTree(tree)
+	watcher.Start(ctx)
+
+	gw.mu.Lock()
+	gw.watchers[repoConfig.Name] = watcher
+	gw.trees[repoConfig.Name] = tree
+	gw.mu.Unlock()
+
+	return nil
+}
+
+// addRepo creates and registers the agent for a repository, recording in
+// status whether it still needs its first index
+func (gw *Gateway) addRepo(repoConfig RepoConfig, status *repoStatus) error {
+	repoLogger := gw.logger.With().Str("repo", repoConfig.Name).Logger()
+
+	agt, branch, err := gw.newRepoAgent(repoConfig, repoLogger, false)
+	if err != nil {
+		return err
+	}
+
+	gw.mu.Lock()
+	gw.agents[repoConfig.Name] = agt
+	status.branch = branch
+	status.state = RepoReady
+	if gw.shouldIndex(repoConfig.Path) {
+		status.state = RepoIndexing
+	}
+	gw.mu.Unlock()
 
 	repoLogger.Info().
 		Str("branch", branch).
+		Str("state", string(status.state)).
 		Msg("Repository agent initialized")
 
 	return nil
@@ -93,20 +223,25 @@ func (gw *Gateway) addRepo(repoConfig RepoConfig) error {
 // buildAgentConfig constructs agent.Config from gateway and repo config
 func (gw *Gateway) buildAgentConfig(repoConfig RepoConfig) *agent.Config {
 	return &agent.Config{
-		RepoPath:          repoConfig.Path,
-		RepoName:          repoConfig.Name,
-		FocusPaths:        repoConfig.FocusPaths,
-		Personality:       repoConfig.Personality,
-		ExcludePatterns:   repoConfig.ExcludePatterns,
-		Port:              gw.config.Port,
-		AnthropicKey:      gw.config.AnthropicKey,
-		OpenAIKey:         gw.config.OpenAIKey,
-		QdrantURL:         gw.config.QdrantURL,
+		RepoPath:        repoConfig.Path,
+		RepoName:        repoConfig.Name,
+		FocusPaths:      repoConfig.FocusPaths,
+		Personality:     repoConfig.Personality,
+		ExcludePatterns: repoConfig.ExcludePatterns,
+		Secrets:         repoConfig.SecretMode(),
+		FileTypes:       repoConfig.FileTypes,
+		Port:            gw.config.Port,
+		AnthropicKey:    gw.config.AnthropicKey,
+		OpenAIKey:       gw.config.OpenAIKey,
+		// No QdrantURL: the gateway attaches the branch's collection once it is indexed
 		EmbeddingProvider: gw.config.EmbeddingProvider,
 		OllamaURL:         gw.config.OllamaURL,
 		OllamaModel:       gw.config.EmbeddingModel,
 		LLMProvider:       gw.config.LLMProvider,
 		LLMModel:          gw.config.LLMModel,
+		LLMChain:          gw.config.LLMChain,
+		Resilience:        gw.config.Resilience,
+		Models:            gw.config.Models,
 		CostLimits: agent.CostLimits{
 			DailyMaxUSD:       100.0,
 			PerQueryMaxTokens: 100000,
@@ -131,20 +266,12 @@ func (gw *Gateway) shouldIndex(repoPath string) bool {
 	return gw.config.QdrantURL != "" && vectorstore.IsGitRepo(repoPath)
 }
 
-// performIndexing creates vector store and indexes the repository
-func (gw *Gateway) performIndexing(repoConfig RepoConfig, branch string, agt *agent.Agent, logger zerolog.Logger) error {
+// attachVectorStore points the agent at the branch's vector collection
+func (gw *Gateway) attachVectorStore(repoConfig RepoConfig, branch string, agt *agent.Agent, logger zerolog.Logger) (*vectorstore.QdrantStore, error) {
 	// Create embedding provider
-	embeddingProvider, err := factory.NewEmbeddingProvider(
-		factory.EmbeddingConfig{
-			Provider:    gw.config.EmbeddingProvider,
-			OpenAIKey:   gw.config.OpenAIKey,
-			OllamaURL:   gw.config.OllamaURL,
-			OllamaModel: gw.config.EmbeddingModel,
-		},
-		logger,
-	)
+	embeddingProvider, err := gw.newEmbeddingProvider(logger)
 	if err != nil {
-		return fmt.Errorf("create embedding provider: %w", err)
+		return nil, fmt.Errorf("create embedding provider: %w", err)
 	}
 
 	// Create vector store
@@ -156,54 +283,67 @@ func (gw *Gateway) performIndexing(repoConfig RepoConfig, branch string, agt *ag
 		logger,
 	)
 	if err != nil {
-		return fmt.Errorf("create vector store: %w", err)
+		return nil, fmt.Errorf("create vector store: %w", err)
 	}
+	store.SetGeneratedMode(repoConfig.GeneratedMode())
+	store.SetDependencyGraph(repoConfig.Name, branch, repoConfig.DependencyHops())
 
-	// Update agent to use branch-aware vector store
+	// Update agent to use branch-aware vector store, and the branch's symbols
+	// and summary
 	agt.SetVectorStore(store)
+	agt.SetBranchIndex(branch)
 	logger.Info().Str("branch", branch).Msg("Updated agent to use branch-aware vector store")
+	return store, nil
+}
 
-	// Create indexer
-	indexer := vectorstore.NewIndexerWithBranch(
-		store,
-		repoConfig.Path,
-		repoConfig.Name,
-		branch,
-		logger,
-	)
-
-	// Perform indexing
-	logger.Info().Str("branch", branch).Msg("Starting incremental indexing")
-	if err := indexer.IndexIncremental(context.Background()); err != nil {
-		return fmt.Errorf("indexing failed: %w", err)
-	}
-
-	logger.Info().Str("branch", branch).Msg("Repository indexed successfully")
-	return nil
+// newEmbeddingProvider creates the configured embedding provider
+func (gw *Gateway) newEmbeddingProvider(logger zerolog.Logger) (vectorstore.EmbeddingProvider, error) {
+	return gw.config.newEmbeddingProvider(logger)
 }
 
-// registerAgent stores the agent in the gateway's agent map (thread-safe)
-func (gw *Gateway) registerAgent(repoName string, agt *agent.Agent) {
-	gw.mu.Lock()
-	gw.agents[repoName] = agt
-	gw.mu.Unlock()
+// newEmbeddingProvider creates the embedding provider described by the config
+func (c *Config) newEmbeddingProvider(logger zerolog.Logger) (vectorstore.EmbeddingProvider, error) {
+	return factory.NewEmbeddingProvider(
+		factory.EmbeddingConfig{
+			Provider:    c.EmbeddingProvider,
+			OpenAIKey:   c.OpenAIKey,
+			OllamaURL:   c.OllamaURL,
+			OllamaModel: c.EmbeddingModel,
+			Resilience:  c.Resilience.Embedding,
+		},
+		logger,
+	)
 }
 
 // Ask sends a question to a specific repository agent
 func (gw *Gateway) Ask(ctx context.Context, repoName, question string) (*llm.Response, error) {
+	return gw.AskWithOptions(ctx, repoName, question, contextbuilder.QueryOptions{})
+}
+
+// AskWithOptions sends a question with per-query context options
+func (gw *Gateway) AskWithOptions(ctx context.Context, repoName, question string, opts contextbuilder.QueryOptions) (*llm.Response, error) {
 	gw.mu.RLock()
 	agt, exists := gw.agents[repoName]
+	status := gw.states[repoName]
 	gw.mu.RUnlock()
 
 	if !exists {
+		if status != nil {
+			return nil, unavailableError(repoName, status)
+		}
 		return nil, fmt.Errorf("repository not found: %s", repoName)
 	}
 
-	return agt.Ask(ctx, question)
+	return agt.AskWithOptions(ctx, question, opts)
 }
 
 // AskAll sends a question to all repository agents and aggregates responses
 func (gw *Gateway) AskAll(ctx context.Context, question string) (map[string]*llm.Response, error) {
+	return gw.AskAllWithOptions(ctx, question, contextbuilder.QueryOptions{})
+}
+
+// AskAllWithOptions sends a question to all agents with per-query context options
+func (gw *Gateway) AskAllWithOptions(ctx context.Context, question string, opts contextbuilder.QueryOptions) (map[string]*llm.Response, error) {
 	gw.mu.RLock()
 	repos := make([]string, 0, len(gw.agents))
 	for name := range gw.agents {
@@ -221,7 +361,7 @@ func (gw *Gateway) AskAll(ctx context.Context, question string) (map[string]*llm
 		go func(name string) {
 			defer wg.Done()
 
-			resp, err := gw.Ask(ctx, name, question)
+			resp, err := gw.AskWithOptions(ctx, name, question, opts)
 			if err != nil {
 				errChan <- fmt.Errorf("%s: %w", name, err)
 				return
@@ -249,39 +389,25 @@ func (gw *Gateway) AskAll(ctx context.Context, question string) (map[string]*llm
 	return results, nil
 }
 
-// ListRepos returns the list of configured repositories
+// ListRepos returns the list of configured repositories, including ones
+// still initializing
 func (gw *Gateway) ListRepos() []string {
 	gw.mu.RLock()
 	defer gw.mu.RUnlock()
 
-	repos := make([]string, 0, len(gw.agents))
-	for name := range gw.agents {
-		repos = append(repos, name)
+	repos := make([]string, 0, len(gw.config.Repos))
+	for _, repo := range gw.config.Repos {
+		repos = append(repos, repo.Name)
 	}
 	return repos
 }
 
 // GetRepo returns information about a specific repository
 func (gw *Gateway) GetRepo(name string) (*RepoInfo, error) {
-	gw.mu.RLock()
-	_, exists := gw.agents[name]
-	gw.mu.RUnlock()
-
-	if !exists {
-		return nil, fmt.Errorf("repository not found: %s", name)
-	}
-
 	// Find repo config
-	var repoConfig *RepoConfig
-	for i := range gw.config.Repos {
-		if gw.config.Repos[i].Name == name {
-			repoConfig = &gw.config.Repos[i]
-			break
-		}
-	}
-
+	repoConfig := gw.findRepoConfig(name)
 	if repoConfig == nil {
-		return nil, fmt.Errorf("repository config not found: %s", name)
+		return nil, fmt.Errorf("repository not found: %s", name)
 	}
 
 	// Get branch info
@@ -292,50 +418,67 @@ func (gw *Gateway) GetRepo(name string) (*RepoInfo, error) {
 		}
 	}
 
-	return &RepoInfo{
+	info := &RepoInfo{
 		Name:   repoConfig.Name,
 		Path:   repoConfig.Path,
 		Branch: branch,
-	}, nil
-}
+	}
 
-// ReindexRepo triggers incremental re-indexing for a specific repository (current branch)
-func (gw *Gateway) ReindexRepo(ctx context.Context, repoName string) error {
-	// Find repo config
-	var repoConfig *RepoConfig
-	for i := range gw.config.Repos {
-		if gw.config.Repos[i].Name == repoName {
-			repoConfig = &gw.config.Repos[i]
-			break
-		}
+	gw.mu.RLock()
+	if status, ok := gw.states[name]; ok {
+		info.State = status.state
+		info.Error = status.err
+	}
+	if tree, ok := gw.trees[name]; ok {
+		info.Watching = true
+		info.WorkingTreeFiles = tree.ChangedFiles()
 	}
+	gw.mu.RUnlock()
 
+	return info, nil
+}
+
+// EnqueueReindex schedules an asynchronous incremental re-index of a repository
+// branch (the checked-out branch when branch is empty) and returns the job.
+// Requests for a branch that already has a queued job are coalesced into it.
+func (gw *Gateway) EnqueueReindex(repoName, branch string) (JobStatus, error) {
+	repoConfig := gw.findRepoConfig(repoName)
 	if repoConfig == nil {
-		return fmt.Errorf("repository config not found: %s", repoName)
+		return JobStatus{}, fmt.Errorf("repository config not found: %s", repoName)
 	}
 
-	// Detect current branch
-	branch := "main"
-	if vectorstore.IsGitRepo(repoConfig.Path) {
-		if b, err := vectorstore.GetCurrentBranch(repoConfig.Path); err == nil && b != "" {
-			branch = b
-		}
+	if branch == "" {
+		branch = gw.detectBranch(repoConfig.Path)
 	}
 
-	return gw.ReindexBranch(ctx, repoName, branch)
+	return gw.jobs.Submit(repoName, branch), nil
+}
+
+// GetJob returns the status of a re-index job
+func (gw *Gateway) GetJob(id string) (JobStatus, error) {
+	return gw.jobs.Get(id)
+}
+
+// ListJobs returns all known re-index jobs, newest first
+func (gw *Gateway) ListJobs() []JobStatus {
+	return gw.jobs.List()
 }
 
-// ReindexBranch triggers incremental re-indexing for a specific repository branch
+// CancelJob cancels a queued or running re-index job
+func (gw *Gateway) CancelJob(id string) (JobStatus, error) {
+	return gw.jobs.Cancel(id)
+}
+
+// ReindexBranch synchronously re-indexes a specific repository branch.
+// HTTP handlers and the scanner go through EnqueueReindex instead.
 func (gw *Gateway) ReindexBranch(ctx context.Context, repoName, branch string) error {
-	// Find repo config
-	var repoConfig *RepoConfig
-	for i := range gw.config.Repos {
-		if gw.config.Repos[i].Name == repoName {
-			repoConfig = &gw.config.Repos[i]
-			break
-		}
-	}
+	return gw.reindexBranch(ctx, repoName, branch, nil)
+}
 
+// reindexBranch performs incremental re-indexing, reporting file progress
+func (gw *Gateway) reindexBranch(ctx context.Context, repoName, branch string, progress func(done, total, errors int)) error {
+	// Find repo config
+	repoConfig := gw.findRepoConfig(repoName)
 	if repoConfig == nil {
 		return fmt.Errorf("repository config not found: %s", repoName)
 	}
@@ -345,16 +488,8 @@ func (gw *Gateway) ReindexBranch(ctx context.Context, repoName, branch string) e
 		Str("branch", branch).
 		Logger()
 
-	// Create embedding provider using factory (eliminates duplication)
-	embeddingProvider, err := factory.NewEmbeddingProvider(
-		factory.EmbeddingConfig{
-			Provider:    gw.config.EmbeddingProvider,
-			OpenAIKey:   gw.config.OpenAIKey,
-			OllamaURL:   gw.config.OllamaURL,
-			OllamaModel: gw.config.EmbeddingModel,
-		},
-		repoLogger,
-	)
+	// Create embedding provider
+	embeddingProvider, err := gw.newEmbeddingProvider(repoLogger)
 	if err != nil {
 		return fmt.Errorf("create embedding provider: %w", err)
 	}
@@ -379,22 +514,125 @@ func (gw *Gateway) ReindexBranch(ctx context.Context, repoName, branch string) e
 		branch,
 		repoLogger,
 	)
+	indexer.SetTokenizer(tokenizer.ForEmbeddingModel(gw.config.EmbeddingModel))
+	indexer.SetPatterns(repoConfig.ExcludePatterns, repoConfig.FocusPaths)
+	indexer.SetSecretMode(repoConfig.SecretMode())
+	indexer.SetGeneratedMode(repoConfig.GeneratedMode())
+	indexer.SetFileTypes(repoConfig.FileTypeRegistry())
+	indexer.SetEmbedTemplate(repoConfig.EmbeddingTemplate())
+	indexer.SetProgressFunc(progress)
+	if repoConfig.PackageSummaries() {
+		gw.mu.RLock()
+		agt := gw.agents[repoName]
+		gw.mu.RUnlock()
+		if agt != nil {
+			indexer.SetSummarizer(agt)
+		}
+	}
 
 	// Perform incremental indexing
 	repoLogger.Info().Msg("Triggering incremental re-index")
 	return indexer.IndexIncremental(ctx)
 }
 
+// DeleteBranch drops the vector collection and metadata for a repository branch.
+// The branch currently checked out in the repository is never deleted, since
+// its collection backs the repository's agent.
+func (gw *Gateway) DeleteBranch(ctx context.Context, repoName, branch string) error {
+	repoConfig := gw.findRepoConfig(repoName)
+	if repoConfig == nil {
+		return fmt.Errorf("repository config not found: %s", repoName)
+	}
+	if err := vectorstore.ValidateBranchName(branch); err != nil {
+		return err
+	}
+
+	repoLogger := gw.logger.With().
+		Str("repo", repoName).
+		Str("branch", branch).
+		Logger()
+
+	if branch == gw.detectBranch(repoConfig.Path) {
+		repoLogger.Warn().Msg("Refusing to delete index for checked-out branch")
+		return fmt.Errorf("branch %s is checked out in %s", branch, repoName)
+	}
+
+	// Stop pending re-indexes so they don't recreate the collection
+	gw.jobs.CancelBranch(repoName, branch)
+
+	if gw.config.QdrantURL != "" {
+		if err := vectorstore.DeleteBranchCollection(ctx, gw.config.QdrantURL, repoConfig.Name, branch); err != nil {
+			return fmt.Errorf("delete collection: %w", err)
+		}
+	}
+
+	if err := vectorstore.DeleteMetadata(repoConfig.Name, branch); err != nil {
+		return fmt.Errorf("delete metadata: %w", err)
+	}
+
+	repoLogger.Info().Msg("Branch index deleted")
+	return nil
+}
+
+// WebhookConfig returns the webhook validation secrets
+func (gw *Gateway) WebhookConfig() WebhookConfig {
+	return gw.config.Webhooks
+}
+
+// AdminToken returns the token guarding repository management, or "" when
+// it is disabled
+func (gw *Gateway) AdminToken() string {
+	return gw.config.AdminToken
+}
+
+// findRepoConfig returns the configuration for a repository, or nil if unknown
+func (gw *Gateway) findRepoConfig(name string) *RepoConfig {
+	gw.mu.RLock()
+	defer gw.mu.RUnlock()
+	return gw.config.findRepo(name)
+}
+
+// repoConfigs returns a snapshot of the configured repositories
+func (gw *Gateway) repoConfigs() []RepoConfig {
+	gw.mu.RLock()
+	defer gw.mu.RUnlock()
+	return gw.config.Repos
+}
+
 // Close closes all agents and releases resources
 func (gw *Gateway) Close() error {
-	gw.mu.Lock()
-	defer gw.mu.Unlock()
+	// Background workers look up repos under the lock, so stop them before taking it
+
+	// Stop reloading the config file
+	if gw.configWatcher != nil {
+		gw.configWatcher.Stop()
+	}
 
 	// Stop the scanner if running
 	if gw.scanner != nil {
 		gw.scanner.Stop()
 	}
 
+	// Stop fetching remote repos
+	if gw.fetcher != nil {
+		gw.fetcher.Stop()
+	}
+
+	// Abandon startup clones and indexes that have not finished
+	gw.initCancel()
+	gw.initWg.Wait()
+
+	// Cancel queued and running re-index jobs
+	gw.jobs.Close()
+
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	// Stop working tree watchers
+	for _, watcher := range gw.watchers {
+		watcher.Stop()
+	}
+
 	gw.logger.Info().Msg("Gateway closing")
 
 	return nil
@@ -402,7 +640,11 @@ func (gw *Gateway) Close() error {
 
 // RepoInfo contains information about a repository
 type RepoInfo struct {
-	Name   string `json:"name"`
-	Path   string `json:"path"`
-	Branch string `json:"branch"`
+	Name             string    `json:"name"`
+	Path             string    `json:"path"`
+	Branch           string    `json:"branch"`
+	State            RepoState `json:"state"`                        // initializing, indexing, ready or failed
+	Error            string    `json:"error,omitempty"`              // Why the repository failed
+	Watching         bool      `json:"watching"`                     // Working tree overlay active
+	WorkingTreeFiles []string  `json:"working_tree_files,omitempty"` // Files with uncommitted changes
 }
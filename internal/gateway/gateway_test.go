@@ -1,10 +1,14 @@
 package gateway
 
 import (
+	"context"
 	"io"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 
+	"github.com/First008/mesh/internal/vectorstore"
 	"github.com/rs/zerolog"
 )
 
@@ -238,3 +242,62 @@ func TestConfigValidation_MissingAnthropicKey(t *testing.T) {
 		t.Fatal("Expected error for missing Anthropic key")
 	}
 }
+
+func TestResolveRepo(t *testing.T) {
+	config := &Config{
+		Port:      8080,
+		QdrantURL: "http://localhost:6333",
+		Repos: []RepoConfig{
+			{Name: "backend-api", Path: "/repos/backend-api"},
+		},
+	}
+	gw := &Gateway{config: config, logger: testLogger()}
+
+	testCases := []struct {
+		name  string
+		event BranchEvent
+		want  string
+		found bool
+	}{
+		{"short name", BranchEvent{Repo: "backend-api"}, "backend-api", true},
+		{"full name slug", BranchEvent{Repo: "Backend API", FullName: "team/backend-api"}, "backend-api", true},
+		{"unknown", BranchEvent{Repo: "other", FullName: "team/other"}, "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, found := gw.ResolveRepo(tc.event)
+			if got != tc.want || found != tc.found {
+				t.Errorf("ResolveRepo() = (%q, %v), want (%q, %v)", got, found, tc.want, tc.found)
+			}
+		})
+	}
+}
+
+func TestDeleteBranch_RejectsInvalidNames(t *testing.T) {
+	gw, err := New(reloadTestConfig(t, "api"), testLogger())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+	other := vectorstore.GetMetadataPath("other", "main")
+	os.MkdirAll(filepath.Dir(other), 0755)
+	os.WriteFile(other, []byte("{}"), 0644)
+
+	for _, branch := range []string{"..", ".", "", "a..b"} {
+		if err := gw.DeleteBranch(context.Background(), "api", branch); err == nil {
+			t.Errorf("Expected DeleteBranch to reject %q", branch)
+		}
+		event := BranchEvent{Repo: "api", Branch: branch, Action: BranchDeleted}
+		if _, err := gw.HandleBranchEvent(context.Background(), event); err == nil {
+			t.Errorf("Expected HandleBranchEvent to reject %q", branch)
+		}
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("Expected other repos' metadata to survive: %v", err)
+	}
+}
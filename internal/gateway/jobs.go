@@ -0,0 +1,448 @@
+package gateway
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/First008/mesh/internal/vectorstore"
+	"github.com/rs/zerolog"
+)
+
+// JobState is the lifecycle state of a re-index job
+type JobState string
+
+const (
+	JobQueued    JobState = "queued"
+	JobRunning   JobState = "running"
+	JobSucceeded JobState = "succeeded"
+	JobFailed    JobState = "failed"
+	JobCanceled  JobState = "canceled"
+)
+
+// jobRetention is how long finished jobs remain visible via GET /jobs/:id
+const jobRetention = time.Hour
+
+// ErrJobNotFound is returned for unknown or expired job IDs
+var ErrJobNotFound = errors.New("job not found")
+
+// JobStatus is a point-in-time snapshot of a re-index job
+type JobStatus struct {
+	ID         string     `json:"id"`
+	Repo       string     `json:"repo"`
+	Branch     string     `json:"branch"`
+	State      JobState   `json:"state"`
+	FilesDone  int        `json:"files_done"`
+	FilesTotal int        `json:"files_total"`
+	Errors     int        `json:"errors"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	// Repair jobs verify the collection after re-indexing and fix what's off
+	Repair bool                      `json:"repair,omitempty"`
+	Report *vectorstore.VerifyReport `json:"report,omitempty"`
+}
+
+// Done reports whether the job reached a terminal state
+func (s JobStatus) Done() bool {
+	return s.State == JobSucceeded || s.State == JobFailed || s.State == JobCanceled
+}
+
+// job is the mutable record behind a JobStatus (guarded by JobQueue.mu)
+type job struct {
+	status JobStatus
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// ReindexFunc performs a re-index, reporting progress as files complete
+type ReindexFunc func(ctx context.Context, repo, branch string, progress func(done, total, errors int)) error
+
+// RepairFunc verifies a branch collection against its indexed commit and
+// repairs missing, stale and orphaned entries
+type RepairFunc func(ctx context.Context, repo, branch string) (*vectorstore.VerifyReport, error)
+
+// branchJobs tracks the running job and at most one queued follow-up for a repo+branch
+type branchJobs struct {
+	running *job
+	queued  *job
+}
+
+// JobQueue runs re-index jobs asynchronously with at most one in-flight job
+// per repo+branch. Triggers arriving while a job is queued coalesce into it;
+// triggers arriving while a job runs queue a single follow-up, so commits
+// pushed mid-run are still picked up.
+type JobQueue struct {
+	run      ReindexFunc
+	repair   RepairFunc      // Runs after run for repair jobs
+	onFinish func(JobStatus) // Called after a job that ran or was queued finishes
+	ctx      context.Context
+	stop     context.CancelFunc
+	jobs     map[string]*job        // job ID -> job
+	byKey    map[string]*branchJobs // repo+branch -> active jobs
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+	logger   zerolog.Logger
+	nowFunc  func() time.Time
+}
+
+// NewJobQueue creates a job queue that executes jobs with run
+func NewJobQueue(run ReindexFunc, logger zerolog.Logger) *JobQueue {
+	ctx, stop := context.WithCancel(context.Background())
+	return &JobQueue{
+		run:     run,
+		ctx:     ctx,
+		stop:    stop,
+		jobs:    make(map[string]*job),
+		byKey:   make(map[string]*branchJobs),
+		logger:  logger,
+		nowFunc: time.Now,
+	}
+}
+
+// OnFinish registers fn to be called, outside the queue's lock, with the final
+// status of every job that runs or is canceled while queued. Jobs canceled
+// while queued are reported from a new goroutine, so fn may take locks held by
+// callers of Cancel. Jobs dropped by Close are not reported.
+func (q *JobQueue) OnFinish(fn func(JobStatus)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.onFinish = fn
+}
+
+// notify reports a finished job to the OnFinish callback, if any
+func (q *JobQueue) notify(status JobStatus) {
+	q.mu.Lock()
+	fn := q.onFinish
+	q.mu.Unlock()
+	if fn != nil {
+		fn(status)
+	}
+}
+
+// SetRepair registers the function repair jobs run after re-indexing
+func (q *JobQueue) SetRepair(fn RepairFunc) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.repair = fn
+}
+
+// Submit enqueues a re-index of repo+branch, returning the job that will
+// cover it (an existing queued job when the request is coalesced)
+func (q *JobQueue) Submit(repo, branch string) JobStatus {
+	return q.submit(repo, branch, false)
+}
+
+// SubmitRepair enqueues a re-index of repo+branch followed by a repair of its
+// collection. It coalesces like Submit, turning a queued job into a repair,
+// so it never runs alongside other indexing of the branch.
+func (q *JobQueue) SubmitRepair(repo, branch string) JobStatus {
+	return q.submit(repo, branch, true)
+}
+
+// submit enqueues a re-index, optionally followed by a repair
+func (q *JobQueue) submit(repo, branch string, repair bool) JobStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.pruneLocked()
+
+	key := jobKey(repo, branch)
+	active := q.byKey[key]
+	if active == nil {
+		active = &branchJobs{}
+		q.byKey[key] = active
+	}
+
+	if active.queued != nil {
+		if repair {
+			active.queued.status.Repair = true
+		}
+		q.logger.Debug().
+			Str("job_id", active.queued.status.ID).
+			Str("repo", repo).
+			Str("branch", branch).
+			Msg("Coalesced re-index request into queued job")
+		return active.queued.status
+	}
+
+	j := &job{
+		status: JobStatus{
+			ID:        newJobID(),
+			Repo:      repo,
+			Branch:    branch,
+			State:     JobQueued,
+			CreatedAt: q.nowFunc(),
+			Repair:    repair,
+		},
+		done: make(chan struct{}),
+	}
+	q.jobs[j.status.ID] = j
+
+	if active.running == nil {
+		q.startLocked(key, active, j)
+	} else {
+		active.queued = j
+	}
+
+	q.logger.Info().
+		Str("job_id", j.status.ID).
+		Str("repo", repo).
+		Str("branch", branch).
+		Str("state", string(j.status.State)).
+		Msg("Re-index job submitted")
+
+	return j.status
+}
+
+// Get returns the current status of a job
+func (q *JobQueue) Get(id string) (JobStatus, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	j, ok := q.jobs[id]
+	if !ok {
+		return JobStatus{}, ErrJobNotFound
+	}
+	return j.status, nil
+}
+
+// List returns all known jobs, newest first
+func (q *JobQueue) List() []JobStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(q.jobs))
+	for _, j := range q.jobs {
+		statuses = append(statuses, j.status)
+	}
+	sort.Slice(statuses, func(i, k int) bool {
+		return statuses[i].CreatedAt.After(statuses[k].CreatedAt)
+	})
+	return statuses
+}
+
+// Cancel stops a queued or running job. Cancelling a finished job is a no-op.
+func (q *JobQueue) Cancel(id string) (JobStatus, error) {
+	q.mu.Lock()
+	j, ok := q.jobs[id]
+	if !ok {
+		q.mu.Unlock()
+		return JobStatus{}, ErrJobNotFound
+	}
+
+	canceled := false
+	switch j.status.State {
+	case JobQueued:
+		if active := q.byKey[jobKey(j.status.Repo, j.status.Branch)]; active != nil && active.queued == j {
+			active.queued = nil
+		}
+		q.finishLocked(j, JobCanceled, nil)
+		canceled = true
+	case JobRunning:
+		// The runner records the final state once the indexer returns
+		j.cancel()
+	}
+	status := j.status
+	q.mu.Unlock()
+
+	if canceled {
+		go q.notify(status)
+	}
+	return status, nil
+}
+
+// CancelBranch cancels every queued or running job for repo+branch
+func (q *JobQueue) CancelBranch(repo, branch string) {
+	q.mu.Lock()
+	active := q.byKey[jobKey(repo, branch)]
+	var ids []string
+	if active != nil {
+		if active.queued != nil {
+			ids = append(ids, active.queued.status.ID)
+		}
+		if active.running != nil {
+			ids = append(ids, active.running.status.ID)
+		}
+	}
+	q.mu.Unlock()
+
+	for _, id := range ids {
+		_, _ = q.Cancel(id)
+	}
+}
+
+// CancelRepo cancels every queued or running job for repo
+func (q *JobQueue) CancelRepo(repo string) {
+	q.mu.Lock()
+	var ids []string
+	for id, j := range q.jobs {
+		if j.status.Repo == repo && (j.status.State == JobQueued || j.status.State == JobRunning) {
+			ids = append(ids, id)
+		}
+	}
+	q.mu.Unlock()
+
+	for _, id := range ids {
+		_, _ = q.Cancel(id)
+	}
+}
+
+// Wait blocks until the job finishes or ctx is done
+func (q *JobQueue) Wait(ctx context.Context, id string) (JobStatus, error) {
+	q.mu.Lock()
+	j, ok := q.jobs[id]
+	q.mu.Unlock()
+	if !ok {
+		return JobStatus{}, ErrJobNotFound
+	}
+
+	select {
+	case <-j.done:
+		return q.Get(id)
+	case <-ctx.Done():
+		return JobStatus{}, ctx.Err()
+	}
+}
+
+// Close cancels all jobs and waits for running ones to return
+func (q *JobQueue) Close() {
+	q.stop()
+
+	q.mu.Lock()
+	for _, active := range q.byKey {
+		if active.queued != nil {
+			q.finishLocked(active.queued, JobCanceled, nil)
+			active.queued = nil
+		}
+	}
+	q.mu.Unlock()
+
+	q.wg.Wait()
+}
+
+// startLocked marks j running and executes it in the background
+func (q *JobQueue) startLocked(key string, active *branchJobs, j *job) {
+	ctx, cancel := context.WithCancel(q.ctx)
+	now := q.nowFunc()
+	j.cancel = cancel
+	j.status.State = JobRunning
+	j.status.StartedAt = &now
+	active.running = j
+
+	q.wg.Add(1)
+	go q.execute(ctx, key, j)
+}
+
+// execute runs a job and then promotes the queued follow-up, if any
+func (q *JobQueue) execute(ctx context.Context, key string, j *job) {
+	defer q.wg.Done()
+	defer j.cancel()
+
+	logger := q.logger.With().
+		Str("job_id", j.status.ID).
+		Str("repo", j.status.Repo).
+		Str("branch", j.status.Branch).
+		Logger()
+	logger.Info().Msg("Re-index job started")
+
+	progress := func(done, total, errors int) {
+		q.mu.Lock()
+		j.status.FilesDone = done
+		j.status.FilesTotal = total
+		j.status.Errors = errors
+		q.mu.Unlock()
+	}
+
+	err := q.run(ctx, j.status.Repo, j.status.Branch, progress)
+
+	// Only queued jobs are turned into repairs, so this one is settled
+	q.mu.Lock()
+	repair := q.repair
+	if !j.status.Repair {
+		repair = nil
+	} else if repair == nil && err == nil {
+		err = errors.New("repair is not supported")
+	}
+	q.mu.Unlock()
+
+	var report *vectorstore.VerifyReport
+	if repair != nil && err == nil {
+		report, err = repair(ctx, j.status.Repo, j.status.Branch)
+	}
+
+	// Deferred before the unlock so it runs after it
+	var final JobStatus
+	defer func() { q.notify(final) }()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	j.status.Report = report
+	switch {
+	case err == nil:
+		q.finishLocked(j, JobSucceeded, nil)
+		logger.Info().
+			Int("files", j.status.FilesTotal).
+			Int("errors", j.status.Errors).
+			Msg("Re-index job finished")
+	case ctx.Err() != nil:
+		q.finishLocked(j, JobCanceled, nil)
+		logger.Info().Msg("Re-index job canceled")
+	default:
+		q.finishLocked(j, JobFailed, err)
+		logger.Error().Err(err).Msg("Re-index job failed")
+	}
+	final = j.status
+
+	active := q.byKey[key]
+	active.running = nil
+	if next := active.queued; next != nil && q.ctx.Err() == nil {
+		active.queued = nil
+		q.startLocked(key, active, next)
+		return
+	}
+	delete(q.byKey, key)
+}
+
+// finishLocked records a terminal state for j
+func (q *JobQueue) finishLocked(j *job, state JobState, err error) {
+	now := q.nowFunc()
+	j.status.State = state
+	j.status.FinishedAt = &now
+	if err != nil {
+		j.status.Error = err.Error()
+	}
+	close(j.done)
+}
+
+// pruneLocked forgets finished jobs older than jobRetention
+func (q *JobQueue) pruneLocked() {
+	cutoff := q.nowFunc().Add(-jobRetention)
+	for id, j := range q.jobs {
+		if j.status.Done() && j.status.FinishedAt.Before(cutoff) {
+			delete(q.jobs, id)
+		}
+	}
+}
+
+// jobKey identifies the repo+branch a job indexes
+func jobKey(repo, branch string) string {
+	return repo + "\x00" + branch
+}
+
+// newJobID returns a random 16-hex-character job identifier
+func newJobID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
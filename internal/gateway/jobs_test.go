@@ -0,0 +1,222 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/First008/mesh/internal/vectorstore"
+)
+
+// blockingRun returns a ReindexFunc that reports progress and then blocks until
+// release is closed or the job is cancelled
+func blockingRun(release <-chan struct{}, runs *int32) ReindexFunc {
+	return func(ctx context.Context, repo, branch string, progress func(done, total, errors int)) error {
+		atomic.AddInt32(runs, 1)
+		progress(1, 4, 0)
+		select {
+		case <-release:
+			progress(4, 4, 0)
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func waitJob(t *testing.T, q *JobQueue, id string) JobStatus {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	status, err := q.Wait(ctx, id)
+	if err != nil {
+		t.Fatalf("Wait(%s) failed: %v", id, err)
+	}
+	return status
+}
+
+func TestJobQueue_RunsAndReportsProgress(t *testing.T) {
+	release := make(chan struct{})
+	var runs int32
+	q := NewJobQueue(blockingRun(release, &runs), testLogger())
+	defer q.Close()
+
+	job := q.Submit("api", "main")
+	if job.State != JobRunning {
+		t.Fatalf("Expected first job to start immediately, got %s", job.State)
+	}
+
+	// Wait for the first progress report
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, _ := q.Get(job.ID)
+		if status.FilesTotal == 4 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	close(release)
+	status := waitJob(t, q, job.ID)
+	if status.State != JobSucceeded {
+		t.Errorf("Expected succeeded, got %s", status.State)
+	}
+	if status.FilesDone != 4 || status.FilesTotal != 4 {
+		t.Errorf("Expected progress 4/4, got %d/%d", status.FilesDone, status.FilesTotal)
+	}
+	if status.StartedAt == nil || status.FinishedAt == nil {
+		t.Error("Expected started_at and finished_at to be set")
+	}
+}
+
+func TestJobQueue_CoalescesWhileRunning(t *testing.T) {
+	release := make(chan struct{})
+	var runs int32
+	q := NewJobQueue(blockingRun(release, &runs), testLogger())
+	defer q.Close()
+
+	first := q.Submit("api", "main")
+	second := q.Submit("api", "main")
+	third := q.Submit("api", "main")
+
+	if second.ID == first.ID {
+		t.Error("Submit during a run should queue a follow-up job")
+	}
+	if second.State != JobQueued {
+		t.Errorf("Expected follow-up to be queued, got %s", second.State)
+	}
+	if third.ID != second.ID {
+		t.Errorf("Expected third submit to coalesce into %s, got %s", second.ID, third.ID)
+	}
+
+	other := q.Submit("api", "develop")
+	if other.State != JobRunning {
+		t.Errorf("Other branch should run independently, got %s", other.State)
+	}
+
+	close(release)
+	waitJob(t, q, first.ID)
+	if status := waitJob(t, q, second.ID); status.State != JobSucceeded {
+		t.Errorf("Expected follow-up to succeed, got %s", status.State)
+	}
+	waitJob(t, q, other.ID)
+
+	if got := atomic.LoadInt32(&runs); got != 3 {
+		t.Errorf("Expected 3 runs (main, main follow-up, develop), got %d", got)
+	}
+}
+
+func TestJobQueue_RepairCoalescesWithReindex(t *testing.T) {
+	release := make(chan struct{})
+	var runs, repairs int32
+	q := NewJobQueue(blockingRun(release, &runs), testLogger())
+	q.SetRepair(func(ctx context.Context, repo, branch string) (*vectorstore.VerifyReport, error) {
+		atomic.AddInt32(&repairs, 1)
+		return &vectorstore.VerifyReport{Repo: repo, Branch: branch, Repaired: true}, nil
+	})
+	defer q.Close()
+
+	running := q.Submit("api", "main")
+	queued := q.Submit("api", "main")
+	repair := q.SubmitRepair("api", "main")
+	if repair.ID != queued.ID || !repair.Repair {
+		t.Errorf("Expected the repair to turn queued job %s into a repair, got %+v", queued.ID, repair)
+	}
+
+	close(release)
+	if status := waitJob(t, q, running.ID); status.Report != nil {
+		t.Errorf("Expected no report from a plain re-index, got %+v", status.Report)
+	}
+	status := waitJob(t, q, repair.ID)
+	if status.State != JobSucceeded || status.Report == nil || !status.Report.Repaired {
+		t.Errorf("Expected a succeeded repair with its report, got %+v", status)
+	}
+	if got := atomic.LoadInt32(&runs); got != 2 {
+		t.Errorf("Expected 2 runs, got %d", got)
+	}
+	if got := atomic.LoadInt32(&repairs); got != 1 {
+		t.Errorf("Expected 1 repair, got %d", got)
+	}
+}
+
+func TestJobQueue_Cancel(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	var runs int32
+	q := NewJobQueue(blockingRun(release, &runs), testLogger())
+	defer q.Close()
+
+	running := q.Submit("api", "main")
+	queued := q.Submit("api", "main")
+
+	status, err := q.Cancel(queued.ID)
+	if err != nil {
+		t.Fatalf("Cancel queued job failed: %v", err)
+	}
+	if status.State != JobCanceled {
+		t.Errorf("Expected queued job to be canceled, got %s", status.State)
+	}
+
+	if _, err := q.Cancel(running.ID); err != nil {
+		t.Fatalf("Cancel running job failed: %v", err)
+	}
+	if status := waitJob(t, q, running.ID); status.State != JobCanceled {
+		t.Errorf("Expected running job to be canceled, got %s", status.State)
+	}
+
+	if got := atomic.LoadInt32(&runs); got != 1 {
+		t.Errorf("Canceled follow-up should not run, got %d runs", got)
+	}
+
+	if _, err := q.Cancel("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound, got %v", err)
+	}
+}
+
+func TestJobQueue_Failure(t *testing.T) {
+	q := NewJobQueue(func(ctx context.Context, repo, branch string, progress func(done, total, errors int)) error {
+		return errors.New("qdrant unavailable")
+	}, testLogger())
+	defer q.Close()
+
+	job := q.Submit("api", "main")
+	status := waitJob(t, q, job.ID)
+	if status.State != JobFailed {
+		t.Errorf("Expected failed, got %s", status.State)
+	}
+	if status.Error != "qdrant unavailable" {
+		t.Errorf("Expected error message to be recorded, got %q", status.Error)
+	}
+
+	if jobs := q.List(); len(jobs) != 1 || jobs[0].ID != job.ID {
+		t.Errorf("Expected List to return the finished job, got %+v", jobs)
+	}
+}
+
+func TestJobQueue_OnFinish(t *testing.T) {
+	release := make(chan struct{})
+	q := NewJobQueue(func(ctx context.Context, repo, branch string, progress func(done, total, errors int)) error {
+		<-release
+		return nil
+	}, testLogger())
+	defer q.Close()
+
+	finished := make(chan JobStatus, 2)
+	q.OnFinish(func(status JobStatus) { finished <- status })
+
+	running := q.Submit("api", "main")
+	queued := q.Submit("api", "main")
+	if _, err := q.Cancel(queued.ID); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	if status := <-finished; status.ID != queued.ID || status.State != JobCanceled {
+		t.Errorf("Expected canceled queued job, got %+v", status)
+	}
+
+	close(release)
+	if status := <-finished; status.ID != running.ID || status.State != JobSucceeded {
+		t.Errorf("Expected succeeded job, got %+v", status)
+	}
+}
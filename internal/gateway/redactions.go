@@ -0,0 +1,35 @@
+package gateway
+
+import (
+	"fmt"
+
+	"github.com/First008/mesh/internal/secrets"
+	"github.com/First008/mesh/internal/vectorstore"
+)
+
+// Redactions returns the audit report of secrets found while indexing a
+// repository branch (the checked-out branch when branch is empty). A branch
+// without findings has a report with no files.
+func (gw *Gateway) Redactions(repoName, branch string) (*vectorstore.RedactionReport, error) {
+	info, err := gw.GetRepo(repoName)
+	if err != nil {
+		return nil, err
+	}
+	if branch == "" {
+		branch = info.Branch
+	}
+
+	report, err := vectorstore.LoadRedactionReport(repoName, branch)
+	if err != nil {
+		return nil, fmt.Errorf("load redaction report: %w", err)
+	}
+	if report == nil {
+		report = &vectorstore.RedactionReport{
+			RepoName: repoName,
+			Branch:   branch,
+			Mode:     gw.findRepoConfig(repoName).SecretMode(),
+			Files:    map[string][]secrets.Finding{},
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,369 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/First008/mesh/internal/agent"
+	"github.com/First008/mesh/internal/vectorstore"
+	"github.com/rs/zerolog"
+)
+
+// Errors returned when repositories are changed at runtime
+var (
+	ErrRepoExists   = errors.New("repository already exists")
+	ErrRepoNotFound = errors.New("repository not found")
+	ErrInvalidRepo  = errors.New("invalid repository config")
+)
+
+// ReloadResult lists the repositories changed by a config reload
+type ReloadResult struct {
+	Added   []string `json:"added"`
+	Updated []string `json:"updated"`
+	Removed []string `json:"removed"`
+}
+
+// AddRepo registers a repository at runtime. Its agent answers questions right
+// away; indexing runs as a background job, which is returned when one was queued.
+// Remote repositories start out initializing while they clone in the
+// background. Repositories added this way survive config file reloads.
+func (gw *Gateway) AddRepo(repoConfig RepoConfig) (*JobStatus, error) {
+	gw.reconfigMu.Lock()
+	defer gw.reconfigMu.Unlock()
+
+	return gw.addRepoLocked(repoConfig, true)
+}
+
+// RemoveRepo unregisters a repository at runtime. Questions already being
+// answered finish on the old agent before its stores are closed; indexed
+// collections are kept so adding the repository back does not re-embed it.
+func (gw *Gateway) RemoveRepo(name string) error {
+	gw.reconfigMu.Lock()
+	defer gw.reconfigMu.Unlock()
+
+	return gw.removeRepoLocked(name)
+}
+
+// Reload applies the repositories of a freshly loaded config: new ones are
+// added, missing ones removed and changed ones updated. Repositories added
+// through the API are kept unless the file now defines them. Other settings
+// need a restart.
+func (gw *Gateway) Reload(config *Config) (ReloadResult, error) {
+	gw.reconfigMu.Lock()
+	defer gw.reconfigMu.Unlock()
+
+	var result ReloadResult
+	var errs []error
+
+	if !sameGlobalSettings(gw.config, config) {
+		gw.logger.Warn().Msg("Config changes outside repos require a restart to take effect")
+	}
+
+	wanted := make(map[string]bool, len(config.Repos))
+	for _, repo := range config.Repos {
+		wanted[repo.Name] = true
+	}
+
+	for _, repo := range gw.repoConfigs() {
+		if wanted[repo.Name] || gw.isDynamic(repo.Name) {
+			continue
+		}
+		if err := gw.removeRepoLocked(repo.Name); err != nil {
+			errs = append(errs, fmt.Errorf("remove %s: %w", repo.Name, err))
+			continue
+		}
+		result.Removed = append(result.Removed, repo.Name)
+	}
+
+	for _, repo := range config.Repos {
+		current := gw.findRepoConfig(repo.Name)
+		switch {
+		case current == nil:
+			if _, err := gw.addRepoLocked(repo, false); err != nil {
+				errs = append(errs, fmt.Errorf("add %s: %w", repo.Name, err))
+				continue
+			}
+			result.Added = append(result.Added, repo.Name)
+		case repoChanged(*current, repo):
+			if err := gw.updateRepoLocked(*current, repo); err != nil {
+				errs = append(errs, fmt.Errorf("update %s: %w", repo.Name, err))
+				continue
+			}
+			result.Updated = append(result.Updated, repo.Name)
+		}
+
+		// The file now owns the repository
+		gw.mu.Lock()
+		delete(gw.dynamic, repo.Name)
+		gw.mu.Unlock()
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// addRepoLocked creates, registers and schedules indexing for a repository
+func (gw *Gateway) addRepoLocked(repoConfig RepoConfig, dynamic bool) (*JobStatus, error) {
+	if err := repoConfig.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRepo, err)
+	}
+	if gw.findRepoConfig(repoConfig.Name) != nil {
+		return nil, fmt.Errorf("%w: %s", ErrRepoExists, repoConfig.Name)
+	}
+
+	repoLogger := gw.logger.With().Str("repo", repoConfig.Name).Logger()
+
+	// Remote repositories are cloned in the background as at startup, so
+	// the caller and other reconfiguration don't wait for the clone
+	if repoConfig.IsRemote() {
+		repoConfig.Path = gw.config.workspacePath(repoConfig.Name)
+
+		gw.mu.Lock()
+		gw.states[repoConfig.Name] = &repoStatus{state: RepoInitializing}
+		gw.config.Repos = append(slices.Clip(gw.config.Repos), repoConfig)
+		if dynamic {
+			gw.dynamic[repoConfig.Name] = true
+		}
+		initCtx := gw.initCtx
+		gw.mu.Unlock()
+
+		gw.initRepos(initCtx, []string{repoConfig.Name})
+		repoLogger.Info().Bool("dynamic", dynamic).Msg("Repository added, cloning in the background")
+		return nil, nil
+	}
+	agt, branch, err := gw.newRepoAgent(repoConfig, repoLogger, false)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &repoStatus{state: RepoReady, branch: branch}
+	if gw.shouldIndex(repoConfig.Path) {
+		status.state = RepoIndexing
+	}
+
+	gw.mu.Lock()
+	gw.agents[repoConfig.Name] = agt
+	gw.states[repoConfig.Name] = status
+	// Clip forces a copy so snapshots held by readers never change
+	gw.config.Repos = append(slices.Clip(gw.config.Repos), repoConfig)
+	if dynamic {
+		gw.dynamic[repoConfig.Name] = true
+	}
+	watchCtx := gw.watchCtx
+	gw.mu.Unlock()
+
+	// The vector store is attached by jobFinished once this job succeeds
+	var job *JobStatus
+	if status.state == RepoIndexing {
+		submitted := gw.jobs.Submit(repoConfig.Name, branch)
+		job = &submitted
+	}
+
+	if repoConfig.Watch && watchCtx != nil {
+		if err := gw.startWatcher(watchCtx, repoConfig); err != nil {
+			repoLogger.Warn().Err(err).Msg("Working tree watcher disabled")
+		}
+	}
+
+	repoLogger.Info().
+		Str("branch", branch).
+		Bool("dynamic", dynamic).
+		Msg("Repository added")
+
+	return job, nil
+}
+
+// removeRepoLocked unregisters a repository and stops its background work
+func (gw *Gateway) removeRepoLocked(name string) error {
+	gw.mu.Lock()
+	if gw.config.findRepo(name) == nil {
+		gw.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrRepoNotFound, name)
+	}
+	watcher := gw.watchers[name]
+	agt := gw.agents[name]
+	delete(gw.agents, name)
+	delete(gw.watchers, name)
+	delete(gw.trees, name)
+	delete(gw.dynamic, name)
+	delete(gw.states, name)
+	gw.config.Repos = slices.DeleteFunc(slices.Clone(gw.config.Repos), func(repo RepoConfig) bool {
+		return repo.Name == name
+	})
+	gw.mu.Unlock()
+
+	gw.jobs.CancelRepo(name)
+	if watcher != nil {
+		watcher.Stop()
+	}
+	if agt != nil {
+		gw.closeAgent(name, agt)
+	}
+
+	gw.logger.Info().Str("repo", name).Msg("Repository removed")
+	return nil
+}
+
+// updateRepoLocked applies a changed repository config. Changes to where the
+// code comes from re-add the repository; other settings swap in a new agent
+// over the existing index and working tree.
+func (gw *Gateway) updateRepoLocked(current, updated RepoConfig) error {
+	if err := updated.validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRepo, err)
+	}
+
+	if !sameSource(current, updated) {
+		if err := gw.removeRepoLocked(current.Name); err != nil {
+			return err
+		}
+		_, err := gw.addRepoLocked(updated, false)
+		return err
+	}
+
+	// Remote repos keep their workspace clone
+	updated.Path = current.Path
+
+	gw.mu.RLock()
+	_, hasAgent := gw.agents[updated.Name]
+	gw.mu.RUnlock()
+
+	// Repos still being cloned get their agent from the new settings
+	if !hasAgent {
+		gw.mu.Lock()
+		gw.replaceRepoConfigLocked(updated)
+		gw.mu.Unlock()
+		gw.logger.Info().Str("repo", updated.Name).Msg("Repository settings updated")
+		return nil
+	}
+
+	repoLogger := gw.logger.With().Str("repo", updated.Name).Logger()
+	agt, _, err := gw.newRepoAgent(updated, repoLogger, gw.repoState(updated.Name) == RepoReady)
+	if err != nil {
+		return err
+	}
+
+	patternsChanged := !slices.Equal(current.ExcludePatterns, updated.ExcludePatterns) ||
+		!slices.Equal(current.FocusPaths, updated.FocusPaths) ||
+		current.SecretMode() != updated.SecretMode() ||
+		current.GeneratedMode() != updated.GeneratedMode() ||
+		current.FileTypeRegistry().Fingerprint() != updated.FileTypeRegistry().Fingerprint() ||
+		current.EmbeddingTemplate().Fingerprint() != updated.EmbeddingTemplate().Fingerprint()
+
+	gw.mu.Lock()
+	old := gw.agents[updated.Name]
+	if tree, ok := gw.trees[updated.Name]; ok {
+		old.ReleaseWorkingTree()
+		agt.SetWorkingTree(tree)
+		tree.SetPatterns(updated.ExcludePatterns, updated.FocusPaths)
+		tree.SetSecretMode(updated.SecretMode())
+		tree.SetGeneratedMode(updated.GeneratedMode())
+		tree.SetFileTypes(updated.FileTypeRegistry())
+		tree.SetEmbedTemplate(updated.EmbeddingTemplate())
+	}
+	gw.agents[updated.Name] = agt
+	gw.replaceRepoConfigLocked(updated)
+	gw.mu.Unlock()
+	gw.closeAgent(updated.Name, old)
+
+	// Re-index so newly excluded or skipped files are purged and included ones
+	// indexed, content is scrubbed under the new secret mode, and chunks are
+	// re-embedded with a new embed template
+	if patternsChanged && gw.shouldIndex(updated.Path) {
+		gw.reindexKnownBranches(updated.Name, repoLogger)
+	}
+
+	repoLogger.Info().Msg("Repository settings updated")
+	return nil
+}
+
+// closeAgent closes a removed or replaced agent in the background, once the
+// questions it is answering finish
+func (gw *Gateway) closeAgent(name string, agt *agent.Agent) {
+	go func() {
+		if err := agt.Close(); err != nil {
+			gw.logger.Warn().Err(err).Str("repo", name).Msg("Failed to close agent")
+		}
+	}()
+}
+
+// reindexKnownBranches queues a re-index of every indexed branch of a repository
+func (gw *Gateway) reindexKnownBranches(name string, logger zerolog.Logger) {
+	branches, err := vectorstore.GetKnownBranches(name)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to list indexed branches")
+		return
+	}
+	for _, branch := range branches {
+		gw.jobs.Submit(name, branch)
+	}
+}
+
+// replaceRepoConfigLocked swaps in the updated config of an existing
+// repository (gw.mu must be held)
+func (gw *Gateway) replaceRepoConfigLocked(updated RepoConfig) {
+	repos := slices.Clone(gw.config.Repos)
+	for i := range repos {
+		if repos[i].Name == updated.Name {
+			repos[i] = updated
+		}
+	}
+	gw.config.Repos = repos
+}
+
+// newRepoAgent creates an agent for a repository without indexing. With
+// attach, the agent searches the existing collection of its checked-out
+// branch; otherwise it uses keyword search until jobFinished attaches one.
+func (gw *Gateway) newRepoAgent(repoConfig RepoConfig, logger zerolog.Logger, attach bool) (*agent.Agent, string, error) {
+	agt, err := agent.New(gw.buildAgentConfig(repoConfig), logger)
+	if err != nil {
+		return nil, "", fmt.Errorf("create agent: %w", err)
+	}
+
+	branch := gw.detectBranch(repoConfig.Path)
+	if attach && gw.shouldIndex(repoConfig.Path) {
+		if _, err := gw.attachVectorStore(repoConfig, branch, agt, logger); err != nil {
+			logger.Warn().Err(err).Msg("Vector store unavailable, continuing without vector search")
+		}
+	}
+
+	return agt, branch, nil
+}
+
+// isDynamic reports whether a repository was added through the API
+func (gw *Gateway) isDynamic(name string) bool {
+	gw.mu.RLock()
+	defer gw.mu.RUnlock()
+	return gw.dynamic[name]
+}
+
+// repoChanged reports whether a reloaded repository differs from the running one
+func repoChanged(current, updated RepoConfig) bool {
+	if updated.IsRemote() {
+		// The running config points Path at the workspace clone
+		updated.Path = current.Path
+	}
+	return !reflect.DeepEqual(current, updated)
+}
+
+// sameSource reports whether two configs read code from the same place in the
+// same way, so the existing index, clone and watcher can be reused
+func sameSource(current, updated RepoConfig) bool {
+	if updated.IsRemote() {
+		updated.Path = current.Path
+	}
+	return current.Path == updated.Path &&
+		current.URL == updated.URL &&
+		current.CredentialsEnv == updated.CredentialsEnv &&
+		current.CredentialsFile == updated.CredentialsFile &&
+		current.SSHKeyFile == updated.SSHKeyFile &&
+		current.Watch == updated.Watch &&
+		current.WatchDebounce == updated.WatchDebounce
+}
+
+// sameGlobalSettings reports whether two configs match outside their repos
+func sameGlobalSettings(a, b *Config) bool {
+	x, y := *a, *b
+	x.Repos, y.Repos = nil, nil
+	return reflect.DeepEqual(x, y)
+}
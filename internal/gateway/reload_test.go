@@ -0,0 +1,268 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/First008/mesh/internal/vectorstore"
+)
+
+// reloadTestConfig returns a config whose repos are plain directories, so
+// agents are created without indexing or external services
+func reloadTestConfig(t *testing.T, names ...string) *Config {
+	t.Helper()
+	config := &Config{
+		Port:              8080,
+		EmbeddingProvider: "ollama",
+		LLMProvider:       "ollama",
+		LLMModel:          "llama3.3:70b",
+	}
+	for _, name := range names {
+		config.Repos = append(config.Repos, RepoConfig{Name: name, Path: t.TempDir()})
+	}
+	return config
+}
+
+func sortedRepos(gw *Gateway) []string {
+	repos := gw.ListRepos()
+	sort.Strings(repos)
+	return repos
+}
+
+func TestAddRemoveRepo(t *testing.T) {
+	gw, err := New(reloadTestConfig(t), testLogger())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	job, err := gw.AddRepo(RepoConfig{Name: "api", Path: t.TempDir(), Personality: "terse"})
+	if err != nil {
+		t.Fatalf("AddRepo failed: %v", err)
+	}
+	if job != nil {
+		t.Errorf("Expected no index job without qdrant, got %+v", job)
+	}
+	if repos := sortedRepos(gw); !slices.Equal(repos, []string{"api"}) {
+		t.Errorf("Expected [api], got %v", repos)
+	}
+	if _, err := gw.GetRepo("api"); err != nil {
+		t.Errorf("GetRepo after add failed: %v", err)
+	}
+
+	if _, err := gw.AddRepo(RepoConfig{Name: "api", Path: t.TempDir()}); !errors.Is(err, ErrRepoExists) {
+		t.Errorf("Expected ErrRepoExists, got %v", err)
+	}
+	if _, err := gw.AddRepo(RepoConfig{Name: "web"}); !errors.Is(err, ErrInvalidRepo) {
+		t.Errorf("Expected ErrInvalidRepo, got %v", err)
+	}
+
+	if err := gw.RemoveRepo("api"); err != nil {
+		t.Fatalf("RemoveRepo failed: %v", err)
+	}
+	if repos := gw.ListRepos(); len(repos) != 0 {
+		t.Errorf("Expected no repos after remove, got %v", repos)
+	}
+	if err := gw.RemoveRepo("api"); !errors.Is(err, ErrRepoNotFound) {
+		t.Errorf("Expected ErrRepoNotFound, got %v", err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	config := reloadTestConfig(t, "api", "web", "docs")
+	gw, err := New(config, testLogger())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if _, err := gw.AddRepo(RepoConfig{Name: "scratch", Path: t.TempDir()}); err != nil {
+		t.Fatalf("AddRepo failed: %v", err)
+	}
+
+	gw.mu.RLock()
+	oldAPI, oldDocs := gw.agents["api"], gw.agents["docs"]
+	gw.mu.RUnlock()
+
+	updated := reloadTestConfig(t, "worker")
+	api := *gw.findRepoConfig("api")
+	api.Personality = "Answer like a senior Go reviewer"
+	api.ExcludePatterns = []string{"*_test.go"}
+	updated.Repos = append(updated.Repos, api, *gw.findRepoConfig("docs"))
+
+	result, err := gw.Reload(updated)
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	if !slices.Equal(result.Added, []string{"worker"}) {
+		t.Errorf("Expected added [worker], got %v", result.Added)
+	}
+	if !slices.Equal(result.Updated, []string{"api"}) {
+		t.Errorf("Expected updated [api], got %v", result.Updated)
+	}
+	if !slices.Equal(result.Removed, []string{"web"}) {
+		t.Errorf("Expected removed [web], got %v", result.Removed)
+	}
+
+	// Repos added through the API are not owned by the file
+	if repos := sortedRepos(gw); !slices.Equal(repos, []string{"api", "docs", "scratch", "worker"}) {
+		t.Errorf("Unexpected repos after reload: %v", repos)
+	}
+
+	gw.mu.RLock()
+	newAPI, newDocs := gw.agents["api"], gw.agents["docs"]
+	gw.mu.RUnlock()
+	if newAPI == oldAPI {
+		t.Error("Expected changed repo to get a new agent")
+	}
+	if newDocs != oldDocs {
+		t.Error("Expected unchanged repo to keep its agent")
+	}
+	if got := gw.findRepoConfig("api").Personality; got != api.Personality {
+		t.Errorf("Expected updated personality, got %q", got)
+	}
+}
+
+// signalStore is a vector store that only supports Close, which closes closed
+type signalStore struct {
+	vectorstore.VectorStore
+	closed chan struct{}
+}
+
+func (s *signalStore) Close() error {
+	close(s.closed)
+	return nil
+}
+
+// closeSignal returns a store that closes the returned channel on Close
+func closeSignal() (*signalStore, chan struct{}) {
+	closed := make(chan struct{})
+	return &signalStore{closed: closed}, closed
+}
+
+func TestReload_ClosesReplacedAgents(t *testing.T) {
+	gw, err := New(reloadTestConfig(t, "api", "web", "docs"), testLogger())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	apiStore, apiClosed := closeSignal()
+	webStore, webClosed := closeSignal()
+	docsStore, docsClosed := closeSignal()
+	gw.mu.RLock()
+	gw.agents["api"].SetVectorStore(apiStore)
+	gw.agents["web"].SetVectorStore(webStore)
+	gw.agents["docs"].SetVectorStore(docsStore)
+	gw.mu.RUnlock()
+
+	updated := reloadTestConfig(t)
+	api := *gw.findRepoConfig("api")
+	api.Personality = "Answer like a senior Go reviewer"
+	updated.Repos = append(updated.Repos, api, *gw.findRepoConfig("docs"))
+	if _, err := gw.Reload(updated); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	for name, closed := range map[string]chan struct{}{"replaced api": apiClosed, "removed web": webClosed} {
+		select {
+		case <-closed:
+		case <-time.After(5 * time.Second):
+			t.Errorf("Expected the %s agent's store to be closed", name)
+		}
+	}
+	select {
+	case <-docsClosed:
+		t.Error("Expected the unchanged docs agent's store to stay open")
+	default:
+	}
+}
+
+// TestVerifyBranch_DuringReload runs verification while repos are added and
+// removed; the race detector flags unlocked reads of the config's repos
+func TestVerifyBranch_DuringReload(t *testing.T) {
+	gw, err := New(reloadTestConfig(t, "api"), testLogger())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			gw.AddRepo(RepoConfig{Name: "scratch", Path: t.TempDir()})
+			gw.RemoveRepo("scratch")
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		// No vector store is configured, so this fails after the lookup
+		if _, err := gw.VerifyBranch(context.Background(), "api", "main"); err == nil {
+			t.Error("Expected an error without a vector store")
+		}
+	}
+	<-done
+}
+
+func TestReload_ChangedPathReaddsRepo(t *testing.T) {
+	gw, err := New(reloadTestConfig(t, "api"), testLogger())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	updated := reloadTestConfig(t, "api")
+	result, err := gw.Reload(updated)
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if !slices.Equal(result.Updated, []string{"api"}) {
+		t.Errorf("Expected updated [api], got %v", result.Updated)
+	}
+	if got := gw.findRepoConfig("api").Path; got != updated.Repos[0].Path {
+		t.Errorf("Expected path %s, got %s", updated.Repos[0].Path, got)
+	}
+}
+
+func TestConfigWatcher_ReloadsOnChange(t *testing.T) {
+	dir := t.TempDir()
+	repoA, repoB := t.TempDir(), t.TempDir()
+	path := filepath.Join(dir, "repos.yaml")
+
+	write := func(repos string) {
+		t.Helper()
+		content := "port: 8080\nqdrant_url: http://localhost:6333\nembedding_provider: ollama\n" +
+			"llm_provider: ollama\nllm_model: llama3.3:70b\nrepos:\n" + repos
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("  - name: a\n    path: " + repoA + "\n")
+
+	// The running config has no qdrant_url, so repos added by the reload skip indexing
+	config := reloadTestConfig(t)
+	config.Repos = []RepoConfig{{Name: "a", Path: repoA}}
+	gw, err := New(config, testLogger())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	watcher, err := NewConfigWatcher(gw, path, testLogger())
+	if err != nil {
+		t.Fatalf("NewConfigWatcher failed: %v", err)
+	}
+	watcher.debounce = 10 * time.Millisecond
+	watcher.Start(context.Background())
+	defer watcher.Stop()
+
+	write("  - name: a\n    path: " + repoA + "\n  - name: b\n    path: " + repoB + "\n")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for gw.findRepoConfig("b") == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for config reload")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
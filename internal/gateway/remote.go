@@ -0,0 +1,278 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/First008/mesh/internal/vectorstore"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// defaultWorkspace is where remote repositories are cloned
+	defaultWorkspace = ".mesh/workspace"
+
+	// defaultFetchInterval is how often remote repositories are fetched
+	defaultFetchInterval = 5 * time.Minute
+
+	// cloneTimeout bounds the initial clone of a remote repository
+	cloneTimeout = 30 * time.Minute
+
+	// fetchTimeout bounds a single fetch of a remote repository
+	fetchTimeout = 5 * time.Minute
+
+	// defaultTokenUser is the HTTP username sent with bare tokens
+	// (accepted by GitHub, GitLab, Gitea and Bitbucket app passwords alike)
+	defaultTokenUser = "x-access-token"
+)
+
+// workspacePath returns the managed clone location for a remote repository
+func (c *Config) workspacePath(repoName string) string {
+	workspace := c.Workspace
+	if workspace == "" {
+		workspace = defaultWorkspace
+	}
+	return filepath.Join(workspace, repoName+".git")
+}
+
+// gitAuth carries credentials for a remote without persisting them in git config
+type gitAuth struct {
+	header     string // HTTP Authorization header value
+	sshCommand string // GIT_SSH_COMMAND override
+}
+
+// loadGitAuth resolves credentials for a remote repository from env/file
+func loadGitAuth(repo RepoConfig) (gitAuth, error) {
+	var auth gitAuth
+
+	var credentials string
+	switch {
+	case repo.CredentialsEnv != "":
+		credentials = os.Getenv(repo.CredentialsEnv)
+		if credentials == "" {
+			return auth, fmt.Errorf("credentials env %s is empty", repo.CredentialsEnv)
+		}
+	case repo.CredentialsFile != "":
+		data, err := os.ReadFile(repo.CredentialsFile)
+		if err != nil {
+			return auth, fmt.Errorf("read credentials file: %w", err)
+		}
+		credentials = strings.TrimSpace(string(data))
+	}
+
+	if credentials != "" {
+		if !strings.Contains(credentials, ":") {
+			credentials = defaultTokenUser + ":" + credentials
+		}
+		auth.header = "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+	}
+
+	if repo.SSHKeyFile != "" {
+		// git runs GIT_SSH_COMMAND through the shell
+		auth.sshCommand = "ssh -i " + shellQuote(repo.SSHKeyFile) + " -o IdentitiesOnly=yes -o StrictHostKeyChecking=accept-new"
+	}
+
+	return auth, nil
+}
+
+// shellQuote quotes s as a single POSIX shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// scpURL matches scp-style remotes such as git@github.com:team/api.git
+var scpURL = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*@[A-Za-z0-9][A-Za-z0-9.-]*:[^\s]+$`)
+
+// validateRemoteURL accepts https://, ssh:// and scp-style remotes only.
+// Local paths and file:// would read the server's disk, transports such as
+// ext:: run commands, and a leading "-" would reach git or ssh as an option.
+func validateRemoteURL(rawURL string) error {
+	if scpURL.MatchString(rawURL) {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err == nil && (u.Scheme == "https" || u.Scheme == "ssh") && u.Hostname() != "" &&
+		!strings.HasPrefix(u.Hostname(), "-") && !strings.HasPrefix(u.User.Username(), "-") {
+		return nil
+	}
+	return fmt.Errorf("url must be https://, ssh:// or user@host:path, got %q", rawURL)
+}
+
+// gitCommand builds a git command with credentials applied per invocation.
+// The auth header goes in GIT_CONFIG_* variables rather than a -c argument,
+// since arguments are visible to every local user through ps and /proc.
+func gitCommand(ctx context.Context, auth gitAuth, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if auth.header != "" {
+		cmd.Env = append(cmd.Env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0="+auth.header,
+		)
+	}
+	if auth.sshCommand != "" {
+		cmd.Env = append(cmd.Env, "GIT_SSH_COMMAND="+auth.sshCommand)
+	}
+	return cmd
+}
+
+// runGit runs a git command with credentials applied per invocation
+func runGit(ctx context.Context, auth gitAuth, args ...string) error {
+	cmd := gitCommand(ctx, auth, args...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// cloneRemote makes sure dest holds a bare clone of the repository whose local
+// branches track the remote's branches one-to-one. Existing clones are reused.
+func cloneRemote(ctx context.Context, repo RepoConfig, dest string) error {
+	if err := validateRemoteURL(repo.URL); err != nil {
+		return err
+	}
+	auth, err := loadGitAuth(repo)
+	if err != nil {
+		return err
+	}
+
+	if !vectorstore.IsGitRepo(dest) {
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return fmt.Errorf("create workspace: %w", err)
+		}
+		if err := runGit(ctx, auth, "clone", "--bare", "--", repo.URL, dest); err != nil {
+			return err
+		}
+	} else if err := runGit(ctx, auth, "-C", dest, "remote", "set-url", "--", "origin", repo.URL); err != nil {
+		return err
+	}
+
+	// Bare clones don't configure a fetch refspec; map remote branches onto
+	// local ones so branch listing and rev-parse see fetched commits
+	return runGit(ctx, auth, "-C", dest, "config", "remote.origin.fetch", "+refs/heads/*:refs/heads/*")
+}
+
+// fetchRemote fetches all branches, pruning ones deleted upstream
+func fetchRemote(ctx context.Context, repo RepoConfig, dir string) error {
+	auth, err := loadGitAuth(repo)
+	if err != nil {
+		return err
+	}
+	return runGit(ctx, auth, "-C", dir, "fetch", "--prune", "origin")
+}
+
+// prepareRemote clones a remote repository into the workspace and points its
+// Path at the clone. Local repositories are left untouched.
+func (gw *Gateway) prepareRemote(ctx context.Context, repo *RepoConfig) error {
+	if !repo.IsRemote() {
+		return nil
+	}
+
+	dest := gw.config.workspacePath(repo.Name)
+	gw.logger.Info().
+		Str("repo", repo.Name).
+		Str("path", dest).
+		Msg("Preparing remote repository clone")
+
+	ctx, cancel := context.WithTimeout(ctx, cloneTimeout)
+	err := cloneRemote(ctx, *repo, dest)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("clone %s: %w", repo.Name, err)
+	}
+
+	repo.Path = dest
+	return nil
+}
+
+// RemoteFetcher periodically fetches remote repositories so the branch
+// scanner sees new commits without anyone pulling manually
+type RemoteFetcher struct {
+	gateway  *Gateway
+	interval time.Duration
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	logger   zerolog.Logger
+}
+
+// NewRemoteFetcher creates a fetcher for the gateway's remote repositories
+func NewRemoteFetcher(gateway *Gateway, interval time.Duration, logger zerolog.Logger) *RemoteFetcher {
+	if interval <= 0 {
+		interval = defaultFetchInterval
+	}
+
+	return &RemoteFetcher{
+		gateway:  gateway,
+		interval: interval,
+		stopChan: make(chan struct{}),
+		logger:   logger,
+	}
+}
+
+// Start begins the periodic fetch loop
+func (rf *RemoteFetcher) Start(ctx context.Context) {
+	rf.wg.Add(1)
+	go rf.fetchLoop(ctx)
+	rf.logger.Info().
+		Dur("interval", rf.interval).
+		Msg("Remote fetcher started")
+}
+
+// Stop gracefully stops the fetcher
+func (rf *RemoteFetcher) Stop() {
+	close(rf.stopChan)
+	rf.wg.Wait()
+	rf.logger.Info().Msg("Remote fetcher stopped")
+}
+
+// fetchLoop is the main fetch loop
+func (rf *RemoteFetcher) fetchLoop(ctx context.Context) {
+	defer rf.wg.Done()
+
+	ticker := time.NewTicker(rf.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			rf.fetchAll(ctx)
+		case <-rf.stopChan:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// fetchAll fetches every remote repository
+func (rf *RemoteFetcher) fetchAll(ctx context.Context) {
+	for _, repo := range rf.gateway.repoConfigs() {
+		// Repos still being cloned are fetched once the clone finishes
+		if !repo.IsRemote() || rf.gateway.repoState(repo.Name) == RepoInitializing {
+			continue
+		}
+
+		fetchCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
+		err := fetchRemote(fetchCtx, repo, repo.Path)
+		cancel()
+		if err != nil {
+			rf.logger.Warn().Err(err).Str("repo", repo.Name).Msg("Failed to fetch remote repository")
+			continue
+		}
+		rf.logger.Debug().Str("repo", repo.Name).Msg("Fetched remote repository")
+	}
+}
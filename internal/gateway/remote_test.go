@@ -0,0 +1,271 @@
+package gateway
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/First008/mesh/internal/vectorstore"
+)
+
+// initRemote creates a working repo and a bare "remote" cloned from it
+func initRemote(t *testing.T) (work, remote string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	work = filepath.Join(tmpDir, "work")
+	remote = filepath.Join(tmpDir, "remote.git")
+
+	if exec.Command("git", "init", "-b", "main", work).Run() != nil {
+		t.Skip("Skipping test: git not available")
+	}
+	gitIn(t, work, "config", "user.email", "test@example.com")
+	gitIn(t, work, "config", "user.name", "Test User")
+	os.WriteFile(filepath.Join(work, "main.go"), []byte("package main\n"), 0644)
+	gitIn(t, work, "add", ".")
+	gitIn(t, work, "commit", "-m", "initial")
+	gitIn(t, work, "branch", "feature")
+
+	if err := exec.Command("git", "clone", "--bare", work, remote).Run(); err != nil {
+		t.Skipf("Skipping test: bare clone failed: %v", err)
+	}
+	gitIn(t, work, "remote", "add", "origin", remote)
+	return work, remote
+}
+
+// serveRemote serves a bare repository over HTTPS with git http-backend and
+// returns its URL, since managed clones only accept network remotes
+func serveRemote(t *testing.T, remote string) string {
+	t.Helper()
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("Skipping test: git not available")
+	}
+	server := httptest.NewTLSServer(&cgi.Handler{
+		Path: gitPath,
+		Args: []string{"http-backend"},
+		Env:  []string{"GIT_PROJECT_ROOT=" + filepath.Dir(remote), "GIT_HTTP_EXPORT_ALL=1"},
+	})
+	t.Cleanup(server.Close)
+	t.Setenv("GIT_SSL_NO_VERIFY", "true") // Self-signed test certificate
+	return server.URL + "/" + filepath.Base(remote)
+}
+
+func gitIn(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v: %s", args, err, out)
+	}
+}
+
+func TestCloneAndFetchRemote(t *testing.T) {
+	work, remote := initRemote(t)
+	dest := filepath.Join(t.TempDir(), "workspace", "api.git")
+	repo := RepoConfig{Name: "api", URL: serveRemote(t, remote)}
+	ctx := context.Background()
+
+	if err := cloneRemote(ctx, repo, dest); err != nil {
+		t.Fatalf("cloneRemote failed: %v", err)
+	}
+	if !vectorstore.IsBareRepo(dest) {
+		t.Error("Expected managed clone to be bare")
+	}
+
+	branches, err := vectorstore.GetAllBranches(dest)
+	if err != nil {
+		t.Fatalf("GetAllBranches failed: %v", err)
+	}
+	if len(branches) != 2 {
+		t.Errorf("Expected main and feature branches, got %v", branches)
+	}
+
+	// Push a new commit and delete a branch upstream
+	os.WriteFile(filepath.Join(work, "new.go"), []byte("package main\n"), 0644)
+	gitIn(t, work, "add", ".")
+	gitIn(t, work, "commit", "-m", "second")
+	gitIn(t, work, "push", "origin", "main")
+	gitIn(t, work, "push", "origin", "--delete", "feature")
+
+	if err := fetchRemote(ctx, repo, dest); err != nil {
+		t.Fatalf("fetchRemote failed: %v", err)
+	}
+
+	want, _ := vectorstore.GetBranchCommit(work, "main")
+	got, err := vectorstore.GetBranchCommit(dest, "main")
+	if err != nil || got != want {
+		t.Errorf("Expected fetched main at %s, got %s (%v)", want, got, err)
+	}
+	if _, err := vectorstore.GetBranchCommit(dest, "feature"); err == nil {
+		t.Error("Expected feature branch to be pruned after upstream deletion")
+	}
+
+	// Re-running clone on an existing workspace reuses it
+	if err := cloneRemote(ctx, repo, dest); err != nil {
+		t.Errorf("cloneRemote on existing clone failed: %v", err)
+	}
+}
+
+func TestLoadGitAuth(t *testing.T) {
+	t.Setenv("MESH_TEST_GIT_TOKEN", "tok123")
+	credFile := filepath.Join(t.TempDir(), "creds")
+	os.WriteFile(credFile, []byte("bot:secret\n"), 0600)
+
+	testCases := []struct {
+		name       string
+		repo       RepoConfig
+		wantHeader string
+		wantErr    bool
+	}{
+		{
+			name:       "token from env",
+			repo:       RepoConfig{CredentialsEnv: "MESH_TEST_GIT_TOKEN"},
+			wantHeader: "x-access-token:tok123",
+		},
+		{
+			name:       "user and token from file",
+			repo:       RepoConfig{CredentialsFile: credFile},
+			wantHeader: "bot:secret",
+		},
+		{
+			name:    "empty env",
+			repo:    RepoConfig{CredentialsEnv: "MESH_TEST_GIT_TOKEN_UNSET"},
+			wantErr: true,
+		},
+		{
+			name: "no credentials",
+			repo: RepoConfig{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			auth, err := loadGitAuth(tc.repo)
+			if tc.wantErr {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadGitAuth failed: %v", err)
+			}
+
+			if tc.wantHeader == "" {
+				if auth.header != "" {
+					t.Errorf("Expected no auth header, got %q", auth.header)
+				}
+				return
+			}
+			encoded := strings.TrimPrefix(auth.header, "Authorization: Basic ")
+			decoded, _ := base64.StdEncoding.DecodeString(encoded)
+			if string(decoded) != tc.wantHeader {
+				t.Errorf("Expected credentials %q, got %q", tc.wantHeader, decoded)
+			}
+		})
+	}
+}
+
+func TestGitCommand_CredentialsNotInArgs(t *testing.T) {
+	t.Setenv("MESH_TEST_GIT_TOKEN", "tok123")
+	auth, err := loadGitAuth(RepoConfig{CredentialsEnv: "MESH_TEST_GIT_TOKEN"})
+	if err != nil {
+		t.Fatalf("loadGitAuth failed: %v", err)
+	}
+
+	cmd := gitCommand(context.Background(), auth, "fetch", "--prune", "origin")
+	encoded := strings.TrimPrefix(auth.header, "Authorization: Basic ")
+	for _, arg := range cmd.Args {
+		if strings.Contains(arg, encoded) || strings.Contains(arg, "tok123") || strings.Contains(arg, "extraHeader") {
+			t.Errorf("Expected credentials kept out of the command line, got argument %q", arg)
+		}
+	}
+	if !slices.Contains(cmd.Env, "GIT_CONFIG_VALUE_0="+auth.header) || !slices.Contains(cmd.Env, "GIT_CONFIG_KEY_0=http.extraHeader") {
+		t.Error("Expected the auth header in the command's GIT_CONFIG_* environment")
+	}
+}
+
+func TestValidateRemoteURL(t *testing.T) {
+	tests := []struct {
+		url   string
+		valid bool
+	}{
+		{"https://github.com/team/api.git", true},
+		{"ssh://git@github.com/team/api.git", true},
+		{"ssh://git@github.com:2222/team/api.git", true},
+		{"git@github.com:team/api.git", true},
+		{"--upload-pack=touch /tmp/x", false},
+		{"-oProxyCommand=touch /tmp/x", false},
+		{"ssh://-oProxyCommand=touch/team/api.git", false},
+		{"git@-oProxyCommand=x:team/api.git", false},
+		{"http://github.com/team/api.git", false},
+		{"file:///etc", false},
+		{"/srv/repos/api.git", false},
+		{"ext::sh -c touch% /tmp/x", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if err := validateRemoteURL(tt.url); (err == nil) != tt.valid {
+				t.Errorf("validateRemoteURL(%q) = %v, want valid=%v", tt.url, err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestLoadGitAuth_SSHKeyIsQuoted(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "pwned")
+	for _, keyFile := range []string{
+		"/keys/deploy key",
+		"$(touch " + marker + ")",
+		"`touch " + marker + "`",
+		"/keys/it's; touch " + marker,
+	} {
+		auth, err := loadGitAuth(RepoConfig{SSHKeyFile: keyFile})
+		if err != nil {
+			t.Fatalf("loadGitAuth failed: %v", err)
+		}
+
+		// The shell must see the path as the single word after -i
+		script := "set -- " + strings.TrimPrefix(auth.sshCommand, "ssh ") + `; printf %s "$2"`
+		out, err := exec.Command("sh", "-c", script).Output()
+		if err != nil {
+			t.Fatalf("sh failed for %q: %v", keyFile, err)
+		}
+		if string(out) != keyFile {
+			t.Errorf("Expected key path %q, shell saw %q", keyFile, out)
+		}
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("Expected the key path not to be executed by the shell")
+	}
+}
+
+func TestValidate_RemoteRepo(t *testing.T) {
+	config := &Config{
+		Port:              8080,
+		QdrantURL:         "http://localhost:6333",
+		EmbeddingProvider: "ollama",
+		LLMProvider:       "ollama",
+		Repos:             []RepoConfig{{Name: "api", URL: "https://example.com/team/api.git"}},
+	}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Remote repo without path should be valid, got: %v", err)
+	}
+
+	config.Repos[0].Watch = true
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for watch on remote repo")
+	}
+
+	config.Repos[0] = RepoConfig{Name: "api", URL: "--upload-pack=touch /tmp/x"}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for a url parsed as an option")
+	}
+}
@@ -2,6 +2,9 @@ package gateway
 
 import (
 	"context"
+	"path"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
@@ -9,8 +12,9 @@ import (
 	"github.com/rs/zerolog"
 )
 
-// BranchScanner periodically scans all repositories for branch changes
-// and triggers incremental re-indexing when commits change
+// BranchScanner periodically scans all repositories for branch changes,
+// triggers incremental re-indexing when commits change, and indexes new and
+// prunes stale branches according to each repository's BranchPolicy
 type BranchScanner struct {
 	gateway      *Gateway
 	scanInterval time.Duration
@@ -76,44 +80,142 @@ func (bs *BranchScanner) scanAllRepos(ctx context.Context) {
 	bs.logger.Debug().Msg("Starting periodic branch scan")
 
 	// Get all configured repos
-	for _, repoConfig := range bs.gateway.config.Repos {
+	for _, repoConfig := range bs.gateway.repoConfigs() {
 		bs.scanRepo(ctx, repoConfig)
 	}
 }
 
-// scanRepo scans a single repository for known indexed branches only
+// scanRepo applies the repository's branch policy: branches it selects are
+// re-indexed when their commit changed (new branches get their first index),
+// and indexed branches it no longer selects, including deleted ones, are pruned
 func (bs *BranchScanner) scanRepo(ctx context.Context, repoConfig RepoConfig) {
 	repoLogger := bs.logger.With().Str("repo", repoConfig.Name).Logger()
 
-	// Check if it's a git repo
-	if !vectorstore.IsGitRepo(repoConfig.Path) {
-		repoLogger.Debug().Msg("Not a git repository, skipping")
+	if !bs.gateway.shouldIndex(repoConfig.Path) {
+		repoLogger.Debug().Msg("Indexing disabled or not a git repository, skipping")
 		return
 	}
 
-	// Get known branches from metadata directory
-	// Only scan branches that have been indexed before
+	// Branches indexed before, read from the metadata directory
 	knownBranches, err := vectorstore.GetKnownBranches(repoConfig.Name)
 	if err != nil {
 		repoLogger.Debug().Err(err).Msg("Failed to get known branches")
 		return
 	}
 
-	if len(knownBranches) == 0 {
-		repoLogger.Debug().Msg("No known branches to scan yet")
+	// Never prune on a failed listing
+	heads, err := vectorstore.GetBranchCommitTimes(repoConfig.Path)
+	if err != nil {
+		repoLogger.Warn().Err(err).Msg("Failed to list branches")
 		return
 	}
 
-	repoLogger.Debug().
-		Int("known_branches", len(knownBranches)).
-		Msg("Scanning known branches for changes")
+	current := bs.gateway.detectBranch(repoConfig.Path)
+	selected := repoConfig.Branches.selectBranches(heads, knownBranches, current, time.Now())
 
-	// Check each known branch for changes
 	for _, branch := range knownBranches {
+		if !slices.Contains(selected, branch) {
+			bs.pruneBranch(ctx, repoConfig, branch, heads)
+		}
+	}
+
+	if len(selected) == 0 {
+		repoLogger.Debug().Msg("No branches to scan yet")
+		return
+	}
+
+	repoLogger.Debug().
+		Int("branches", len(selected)).
+		Msg("Scanning branches for changes")
+
+	// Check each branch for changes
+	for _, branch := range selected {
 		bs.checkBranchForChanges(ctx, repoConfig, branch)
 	}
 }
 
+// pruneBranch deletes the collection and metadata of an indexed branch
+func (bs *BranchScanner) pruneBranch(ctx context.Context, repoConfig RepoConfig, branch string, heads map[string]time.Time) {
+	reason := "branch policy"
+	if _, exists := heads[branch]; !exists {
+		reason = "deleted"
+	}
+
+	repoLogger := bs.logger.With().
+		Str("repo", repoConfig.Name).
+		Str("branch", branch).
+		Str("reason", reason).
+		Logger()
+
+	if err := bs.gateway.DeleteBranch(ctx, repoConfig.Name, branch); err != nil {
+		repoLogger.Warn().Err(err).Msg("Failed to prune branch index")
+		return
+	}
+	repoLogger.Info().Msg("Pruned branch index")
+}
+
+// selectBranches returns the branches to keep indexed, newest commit first:
+// known branches that still exist plus new ones matching Include, without
+// excluded, inactive or surplus branches. A known checked-out branch is
+// always kept.
+func (p BranchPolicy) selectBranches(heads map[string]time.Time, known []string, current string, now time.Time) []string {
+	candidates := make(map[string]bool)
+	for _, branch := range known {
+		if _, exists := heads[branch]; exists {
+			candidates[branch] = true
+		}
+	}
+	for branch := range heads {
+		if matchAny(p.Include, branch) {
+			candidates[branch] = true
+		}
+	}
+
+	var selected []string
+	keepCurrent := false
+	for branch := range candidates {
+		if branch == current {
+			keepCurrent = true
+			continue
+		}
+		if matchAny(p.Exclude, branch) {
+			continue
+		}
+		if p.InactiveTTL > 0 && now.Sub(heads[branch]) > p.InactiveTTL {
+			continue
+		}
+		selected = append(selected, branch)
+	}
+
+	sort.Slice(selected, func(i, j int) bool {
+		ti, tj := heads[selected[i]], heads[selected[j]]
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return selected[i] < selected[j]
+	})
+
+	limit := p.MaxBranches
+	if keepCurrent {
+		// The checked-out branch takes one of the slots
+		selected = append([]string{current}, selected...)
+	}
+	if limit > 0 && len(selected) > limit {
+		selected = selected[:limit]
+	}
+	return selected
+}
+
+// matchAny reports whether branch matches one of the globs
+func matchAny(patterns []string, branch string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, branch); ok {
+			return true
+		}
+	}
+	return false
+}
+
 // checkBranchForChanges checks if a branch needs re-indexing
 func (bs *BranchScanner) checkBranchForChanges(ctx context.Context, repoConfig RepoConfig, branch string) {
 	repoLogger := bs.logger.With().
@@ -145,10 +247,11 @@ func (bs *BranchScanner) checkBranchForChanges(ctx context.Context, repoConfig R
 		Str("new_commit", currentCommit[:8]).
 		Msg("Branch has changes, triggering re-index")
 
-	// Trigger re-indexing via the gateway's ReindexBranch method
-	if err := bs.gateway.ReindexBranch(ctx, repoConfig.Name, branch); err != nil {
+	// Queue re-indexing; repeated scans while the job is pending coalesce into it
+	job, err := bs.gateway.EnqueueReindex(repoConfig.Name, branch)
+	if err != nil {
 		repoLogger.Error().Err(err).Msg("Failed to trigger re-index")
 	} else {
-		repoLogger.Info().Msg("Re-index triggered successfully")
+		repoLogger.Info().Str("job_id", job.ID).Msg("Re-index triggered successfully")
 	}
 }
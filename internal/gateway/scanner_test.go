@@ -0,0 +1,70 @@
+package gateway
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBranchPolicy_SelectBranches(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	heads := map[string]time.Time{
+		"main":          now.Add(-30 * 24 * time.Hour),
+		"develop":       now.Add(-time.Hour),
+		"release/1.0":   now.Add(-90 * 24 * time.Hour),
+		"release/2.0":   now.Add(-2 * time.Hour),
+		"feature/login": now.Add(-3 * time.Hour),
+		"dependabot/x":  now.Add(-4 * time.Hour),
+	}
+
+	tests := []struct {
+		name   string
+		policy BranchPolicy
+		known  []string
+		want   []string
+	}{
+		{
+			name:  "no policy keeps existing known branches",
+			known: []string{"main", "develop", "gone"},
+			want:  []string{"main", "develop"},
+		},
+		{
+			name:   "include discovers new branches",
+			policy: BranchPolicy{Include: []string{"release/*"}},
+			known:  []string{"main"},
+			want:   []string{"main", "release/2.0", "release/1.0"},
+		},
+		{
+			name:   "exclude prunes known branches but not the checked-out one",
+			policy: BranchPolicy{Include: []string{"*", "*/*"}, Exclude: []string{"dependabot/*", "main"}},
+			known:  []string{"main", "dependabot/x"},
+			want:   []string{"main", "develop", "release/2.0", "feature/login", "release/1.0"},
+		},
+		{
+			name:   "inactive branches are dropped",
+			policy: BranchPolicy{Include: []string{"release/*"}, InactiveTTL: 7 * 24 * time.Hour},
+			known:  []string{"main"},
+			want:   []string{"main", "release/2.0"},
+		},
+		{
+			name:   "max branches keeps the newest plus the checked-out branch",
+			policy: BranchPolicy{Include: []string{"*", "*/*"}, MaxBranches: 3},
+			known:  []string{"main"},
+			want:   []string{"main", "develop", "release/2.0"},
+		},
+		{
+			name:   "unknown checked-out branch is only added when included",
+			policy: BranchPolicy{Include: []string{"develop"}},
+			want:   []string{"develop"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.selectBranches(heads, tt.known, "main", now)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("selectBranches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// RepoState is the readiness of a repository's agent
+type RepoState string
+
+const (
+	RepoInitializing RepoState = "initializing" // Remote clone in progress, no agent yet
+	RepoIndexing     RepoState = "indexing"     // Agent answers with keyword search until the first index completes
+	RepoReady        RepoState = "ready"        // Semantic search available (or not needed)
+	RepoFailed       RepoState = "failed"       // Clone or first index failed; see Error
+)
+
+// ErrRepoUnavailable is returned for questions to a repository that has no agent yet
+var ErrRepoUnavailable = errors.New("repository unavailable")
+
+// defaultStartupConcurrency bounds how many repos clone and index at startup
+const defaultStartupConcurrency = 2
+
+// repoStatus tracks a repository's readiness (guarded by Gateway.mu). Entries
+// are replaced when a repository is re-added, so background work holding an
+// old entry can tell it is stale.
+type repoStatus struct {
+	state  RepoState
+	branch string // Branch whose index backs the agent
+	err    string
+}
+
+// startupConcurrency returns the configured startup concurrency or the default
+func (c *Config) startupConcurrency() int {
+	if c.StartupConcurrency > 0 {
+		return c.StartupConcurrency
+	}
+	return defaultStartupConcurrency
+}
+
+// initRepos clones and indexes repositories in the background, at most
+// startup_concurrency at a time
+func (gw *Gateway) initRepos(ctx context.Context, names []string) {
+	sem := make(chan struct{}, gw.config.startupConcurrency())
+	for _, name := range names {
+		gw.initWg.Add(1)
+		go func() {
+			defer gw.initWg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+			gw.initRepo(ctx, name)
+		}()
+	}
+}
+
+// initRepo finishes starting a repository: remote repositories are cloned and
+// get their agent, then the first index runs as a job. The job outcome is
+// applied by jobFinished; waiting here only holds the concurrency slot.
+func (gw *Gateway) initRepo(ctx context.Context, name string) {
+	gw.mu.RLock()
+	status := gw.states[name]
+	initializing := status != nil && status.state == RepoInitializing
+	gw.mu.RUnlock()
+	if status == nil {
+		return
+	}
+
+	if initializing {
+		if err := gw.cloneAndAdd(ctx, name, status); err != nil {
+			if ctx.Err() == nil {
+				gw.logger.Error().Err(err).Str("repo", name).Msg("Repository failed to initialize")
+				gw.setRepoState(name, status, RepoFailed, err)
+			}
+			return
+		}
+	}
+
+	gw.mu.RLock()
+	indexing, branch := gw.states[name] == status && status.state == RepoIndexing, status.branch
+	gw.mu.RUnlock()
+	if !indexing {
+		return
+	}
+
+	job := gw.jobs.Submit(name, branch)
+	_, _ = gw.jobs.Wait(ctx, job.ID)
+}
+
+// cloneAndAdd clones a remote repository and registers its agent
+func (gw *Gateway) cloneAndAdd(ctx context.Context, name string, status *repoStatus) error {
+	repoConfig := gw.findRepoConfig(name)
+	if repoConfig == nil {
+		return nil
+	}
+	repo := *repoConfig
+	if err := gw.prepareRemote(ctx, &repo); err != nil {
+		return err
+	}
+
+	gw.reconfigMu.Lock()
+	defer gw.reconfigMu.Unlock()
+
+	// The repository may have been removed or changed while cloning
+	gw.mu.RLock()
+	current := gw.states[name] == status
+	gw.mu.RUnlock()
+	repoConfig = gw.findRepoConfig(name)
+	if !current || repoConfig == nil {
+		return nil
+	}
+	return gw.addRepo(*repoConfig, status)
+}
+
+// jobFinished applies the outcome of an index job for a repository's
+// checked-out branch: the first success attaches the vector store, a failure
+// before that marks the repository failed. Either way the agent keeps
+// answering, with keyword search until an index exists.
+func (gw *Gateway) jobFinished(job JobStatus) {
+	gw.reconfigMu.Lock()
+	defer gw.reconfigMu.Unlock()
+
+	gw.mu.RLock()
+	status := gw.states[job.Repo]
+	agt := gw.agents[job.Repo]
+	pending := status != nil && agt != nil && status.state != RepoReady && status.branch == job.Branch
+	gw.mu.RUnlock()
+	if !pending {
+		return
+	}
+
+	repoConfig := gw.findRepoConfig(job.Repo)
+	if repoConfig == nil {
+		return
+	}
+	repoLogger := gw.logger.With().Str("repo", job.Repo).Logger()
+
+	switch job.State {
+	case JobSucceeded:
+		if _, err := gw.attachVectorStore(*repoConfig, job.Branch, agt, repoLogger); err != nil {
+			repoLogger.Error().Err(err).Msg("Indexed repository could not attach its vector store")
+			gw.setRepoState(job.Repo, status, RepoFailed, err)
+			return
+		}
+		gw.setRepoState(job.Repo, status, RepoReady, nil)
+		repoLogger.Info().Str("branch", job.Branch).Msg("Repository ready")
+	case JobFailed:
+		gw.setRepoState(job.Repo, status, RepoFailed, errors.New(job.Error))
+	case JobCanceled:
+		gw.setRepoState(job.Repo, status, RepoFailed, errors.New("index job canceled"))
+	}
+}
+
+// setRepoState updates status if it is still the repository's current entry
+func (gw *Gateway) setRepoState(name string, status *repoStatus, state RepoState, err error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	if gw.states[name] != status {
+		return
+	}
+	status.state = state
+	status.err = ""
+	if err != nil {
+		status.err = err.Error()
+	}
+}
+
+// repoState returns the readiness of a repository, or "" if unknown
+func (gw *Gateway) repoState(name string) RepoState {
+	gw.mu.RLock()
+	defer gw.mu.RUnlock()
+
+	if status, ok := gw.states[name]; ok {
+		return status.state
+	}
+	return ""
+}
+
+// Ready reports whether every repository finished starting, successfully or
+// not, along with the state of each one
+func (gw *Gateway) Ready() (bool, map[string]RepoState) {
+	gw.mu.RLock()
+	defer gw.mu.RUnlock()
+
+	ready := true
+	states := make(map[string]RepoState, len(gw.states))
+	for name, status := range gw.states {
+		states[name] = status.state
+		if status.state == RepoInitializing || status.state == RepoIndexing {
+			ready = false
+		}
+	}
+	return ready, states
+}
+
+// unavailableError explains why a configured repository has no agent yet
+func unavailableError(name string, status *repoStatus) error {
+	if status.state == RepoFailed {
+		return fmt.Errorf("%w: %s failed to initialize: %s", ErrRepoUnavailable, name, status.err)
+	}
+	return fmt.Errorf("%w: %s is still initializing", ErrRepoUnavailable, name)
+}
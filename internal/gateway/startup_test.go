@@ -0,0 +1,206 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	contextbuilder "github.com/First008/mesh/internal/context"
+)
+
+// waitRepoState polls until the repository reaches want
+func waitRepoState(t *testing.T, gw *Gateway, name string, want RepoState) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for gw.repoState(name) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s to be %s, got %s", name, want, gw.repoState(name))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestNew_ClonesRemoteInBackground(t *testing.T) {
+	_, remote := initRemote(t)
+	config := reloadTestConfig(t)
+	config.Workspace = filepath.Join(t.TempDir(), "workspace")
+	config.Repos = []RepoConfig{{Name: "api", URL: serveRemote(t, remote)}}
+
+	gw, err := New(config, testLogger())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer gw.Close()
+
+	// Listed while cloning
+	if repos := gw.ListRepos(); len(repos) != 1 || repos[0] != "api" {
+		t.Errorf("Expected [api], got %v", repos)
+	}
+
+	waitRepoState(t, gw, "api", RepoReady)
+
+	info, err := gw.GetRepo("api")
+	if err != nil {
+		t.Fatalf("GetRepo failed: %v", err)
+	}
+	if info.Path != config.workspacePath("api") {
+		t.Errorf("Expected workspace path, got %s", info.Path)
+	}
+	if ready, _ := gw.Ready(); !ready {
+		t.Error("Expected gateway to be ready")
+	}
+}
+
+func TestNew_FailedCloneMarksRepoFailed(t *testing.T) {
+	config := reloadTestConfig(t, "docs")
+	config.Workspace = filepath.Join(t.TempDir(), "workspace")
+	config.Repos = append(config.Repos, RepoConfig{Name: "api", URL: filepath.Join(t.TempDir(), "missing.git")})
+
+	gw, err := New(config, testLogger())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer gw.Close()
+
+	waitRepoState(t, gw, "api", RepoFailed)
+
+	info, err := gw.GetRepo("api")
+	if err != nil {
+		t.Fatalf("GetRepo failed: %v", err)
+	}
+	if info.Error == "" {
+		t.Error("Expected failure reason")
+	}
+
+	_, err = gw.AskWithOptions(context.Background(), "api", "How does auth work?", contextbuilder.QueryOptions{})
+	if !errors.Is(err, ErrRepoUnavailable) {
+		t.Errorf("Expected ErrRepoUnavailable, got %v", err)
+	}
+
+	// A failed repo does not hold up readiness of the others
+	ready, states := gw.Ready()
+	if !ready {
+		t.Error("Expected gateway to be ready once startup settled")
+	}
+	if states["docs"] != RepoReady || states["api"] != RepoFailed {
+		t.Errorf("Unexpected states: %v", states)
+	}
+}
+
+func TestAddRepo_ClonesRemoteInBackground(t *testing.T) {
+	_, remote := initRemote(t)
+	config := reloadTestConfig(t)
+	config.Workspace = filepath.Join(t.TempDir(), "workspace")
+
+	gw, err := New(config, testLogger())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer gw.Close()
+
+	job, err := gw.AddRepo(RepoConfig{Name: "api", URL: serveRemote(t, remote)})
+	if err != nil {
+		t.Fatalf("AddRepo failed: %v", err)
+	}
+	if job != nil {
+		t.Errorf("Expected no job before the clone finished, got %+v", job)
+	}
+	if repos := gw.ListRepos(); len(repos) != 1 || repos[0] != "api" {
+		t.Errorf("Expected [api], got %v", repos)
+	}
+
+	waitRepoState(t, gw, "api", RepoReady)
+
+	info, err := gw.GetRepo("api")
+	if err != nil {
+		t.Fatalf("GetRepo failed: %v", err)
+	}
+	if info.Path != config.workspacePath("api") {
+		t.Errorf("Expected workspace path, got %s", info.Path)
+	}
+}
+
+func TestClose_CancelsRuntimeClone(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("Skipping test: git not available")
+	}
+
+	// A remote that never answers, so the clone only ends when cancelled
+	release := make(chan struct{})
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	t.Setenv("GIT_SSL_NO_VERIFY", "true")
+
+	config := reloadTestConfig(t)
+	config.Workspace = filepath.Join(t.TempDir(), "workspace")
+	gw, err := New(config, testLogger())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	start := time.Now()
+	if _, err := gw.AddRepo(RepoConfig{Name: "api", URL: server.URL + "/api.git"}); err != nil {
+		t.Fatalf("AddRepo failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("AddRepo waited for the clone (%s)", elapsed)
+	}
+	if state := gw.repoState("api"); state != RepoInitializing {
+		t.Errorf("Expected %s, got %s", RepoInitializing, state)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		gw.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Close did not cancel the pending clone")
+	}
+}
+
+func TestJobFinished(t *testing.T) {
+	gw, err := New(reloadTestConfig(t, "api"), testLogger())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer gw.Close()
+
+	gw.mu.Lock()
+	status := gw.states["api"]
+	status.state = RepoIndexing
+	status.branch = "main"
+	gw.mu.Unlock()
+
+	if ready, _ := gw.Ready(); ready {
+		t.Error("Expected gateway not ready while indexing")
+	}
+
+	// Jobs for other branches don't affect readiness
+	gw.jobFinished(JobStatus{Repo: "api", Branch: "feature", State: JobFailed, Error: "boom"})
+	if state := gw.repoState("api"); state != RepoIndexing {
+		t.Errorf("Expected indexing, got %s", state)
+	}
+
+	gw.jobFinished(JobStatus{Repo: "api", Branch: "main", State: JobFailed, Error: "qdrant unavailable"})
+	info, err := gw.GetRepo("api")
+	if err != nil {
+		t.Fatalf("GetRepo failed: %v", err)
+	}
+	if info.State != RepoFailed || info.Error != "qdrant unavailable" {
+		t.Errorf("Expected failed with reason, got %s %q", info.State, info.Error)
+	}
+}
@@ -0,0 +1,60 @@
+package gateway
+
+import (
+	"fmt"
+
+	"github.com/First008/mesh/internal/symbols"
+	"github.com/First008/mesh/internal/vectorstore"
+)
+
+// maxSymbolReferences caps the references returned for a name, so a common
+// one doesn't flood an MCP client's context
+const maxSymbolReferences = 200
+
+// SymbolLookup is where a name is defined and used in a repository branch
+type SymbolLookup struct {
+	Repo            string               `json:"repo"`
+	Branch          string               `json:"branch"`
+	Name            string               `json:"name"`
+	Indexed         bool                 `json:"indexed"` // False until the branch's first indexing run
+	Definitions     []symbols.Definition `json:"definitions"`
+	References      []symbols.Reference  `json:"references"`
+	TotalReferences int                  `json:"total_references"` // Before the maxSymbolReferences cap
+}
+
+// Symbols looks up the definitions and references of name in a repository
+// branch (the checked-out branch when branch is empty)
+func (gw *Gateway) Symbols(repoName, branch, name string) (*SymbolLookup, error) {
+	info, err := gw.GetRepo(repoName)
+	if err != nil {
+		return nil, err
+	}
+	if branch == "" {
+		branch = info.Branch
+	}
+
+	table, err := vectorstore.LoadSymbolTable(repoName, branch)
+	if err != nil {
+		return nil, fmt.Errorf("load symbol index: %w", err)
+	}
+
+	lookup := &SymbolLookup{
+		Repo:        repoName,
+		Branch:      branch,
+		Name:        name,
+		Indexed:     table != nil,
+		Definitions: table.Definitions(name),
+		References:  table.References(name),
+	}
+	lookup.TotalReferences = len(lookup.References)
+	if len(lookup.References) > maxSymbolReferences {
+		lookup.References = lookup.References[:maxSymbolReferences]
+	}
+	if lookup.Definitions == nil {
+		lookup.Definitions = []symbols.Definition{}
+	}
+	if lookup.References == nil {
+		lookup.References = []symbols.Reference{}
+	}
+	return lookup, nil
+}
@@ -0,0 +1,109 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/First008/mesh/internal/tokenizer"
+	"github.com/First008/mesh/internal/vectorstore"
+	"github.com/rs/zerolog"
+)
+
+// VerifyBranch checks a repository branch's collection against the git tree
+// at its indexed commit (the checked-out branch when branch is empty).
+// Repairs go through the job queue instead (see RepairBranch).
+func (gw *Gateway) VerifyBranch(ctx context.Context, repoName, branch string) (*vectorstore.VerifyReport, error) {
+	return gw.verifyBranch(ctx, repoName, branch, false)
+}
+
+// RepairBranch schedules a re-index of a repository branch (the checked-out
+// branch when branch is empty) followed by a repair of its collection, and
+// returns the job. It coalesces with other indexing of the branch like
+// EnqueueReindex; the job's report lists what was fixed.
+func (gw *Gateway) RepairBranch(repoName, branch string) (JobStatus, error) {
+	repoConfig := gw.findRepoConfig(repoName)
+	if repoConfig == nil {
+		return JobStatus{}, fmt.Errorf("repository config not found: %s", repoName)
+	}
+
+	if branch == "" {
+		branch = gw.detectBranch(repoConfig.Path)
+	}
+
+	return gw.jobs.SubmitRepair(repoName, branch), nil
+}
+
+// repairBranch verifies and repairs a branch collection for a repair job
+func (gw *Gateway) repairBranch(ctx context.Context, repoName, branch string) (*vectorstore.VerifyReport, error) {
+	return gw.verifyBranch(ctx, repoName, branch, true)
+}
+
+// verifyBranch verifies a branch collection from a snapshot of the repository
+// config taken under gw.mu, since reloads replace the config's repos
+func (gw *Gateway) verifyBranch(ctx context.Context, repoName, branch string, repair bool) (*vectorstore.VerifyReport, error) {
+	repoConfig := gw.findRepoConfig(repoName)
+	if repoConfig == nil {
+		return nil, fmt.Errorf("repository config not found: %s", repoName)
+	}
+	return verifyRepoBranch(ctx, gw.config, *repoConfig, branch, repair, gw.logger)
+}
+
+// VerifyBranch verifies a branch collection using only the configuration, so
+// it can run without a gateway (mesh-verify). Remote repositories are read from
+// their existing workspace clone.
+func VerifyBranch(ctx context.Context, config *Config, repoName, branch string, repair bool, logger zerolog.Logger) (*vectorstore.VerifyReport, error) {
+	repoConfig := config.findRepo(repoName)
+	if repoConfig == nil {
+		return nil, fmt.Errorf("repository config not found: %s", repoName)
+	}
+	return verifyRepoBranch(ctx, config, *repoConfig, branch, repair, logger)
+}
+
+// verifyRepoBranch verifies a branch collection of repoConfig, reading only
+// the settings outside repos from config
+func verifyRepoBranch(ctx context.Context, config *Config, repoConfig RepoConfig, branch string, repair bool, logger zerolog.Logger) (*vectorstore.VerifyReport, error) {
+	if config.QdrantURL == "" {
+		return nil, fmt.Errorf("no vector store configured")
+	}
+
+	repoPath := repoConfig.Path
+	if repoPath == "" && repoConfig.IsRemote() {
+		repoPath = config.workspacePath(repoConfig.Name)
+	}
+	if !vectorstore.IsGitRepo(repoPath) {
+		return nil, fmt.Errorf("%s is not a git repository", repoPath)
+	}
+
+	if branch == "" {
+		current, err := vectorstore.GetCurrentBranch(repoPath)
+		if err != nil || current == "" {
+			return nil, fmt.Errorf("detect current branch of %s", repoConfig.Name)
+		}
+		branch = current
+	}
+
+	repoLogger := logger.With().
+		Str("repo", repoConfig.Name).
+		Str("branch", branch).
+		Logger()
+
+	embeddingProvider, err := config.newEmbeddingProvider(repoLogger)
+	if err != nil {
+		return nil, fmt.Errorf("create embedding provider: %w", err)
+	}
+
+	store, err := vectorstore.NewQdrantStoreWithBranch(config.QdrantURL, embeddingProvider, repoConfig.Name, branch, repoLogger)
+	if err != nil {
+		return nil, fmt.Errorf("create vector store: %w", err)
+	}
+	defer store.Close()
+
+	indexer := vectorstore.NewIndexerWithBranch(store, repoPath, repoConfig.Name, branch, repoLogger)
+	indexer.SetTokenizer(tokenizer.ForEmbeddingModel(config.EmbeddingModel))
+	indexer.SetPatterns(repoConfig.ExcludePatterns, repoConfig.FocusPaths)
+	indexer.SetSecretMode(repoConfig.SecretMode())
+	indexer.SetGeneratedMode(repoConfig.GeneratedMode())
+	indexer.SetFileTypes(repoConfig.FileTypeRegistry())
+	indexer.SetEmbedTemplate(repoConfig.EmbeddingTemplate())
+	return indexer.Verify(ctx, repair)
+}
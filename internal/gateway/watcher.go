@@ -0,0 +1,180 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/First008/mesh/internal/filetypes"
+	"github.com/First008/mesh/internal/vectorstore"
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog"
+)
+
+// defaultWatchDebounce is how long the watcher waits for edits to settle
+const defaultWatchDebounce = 2 * time.Second
+
+// WorkingTreeWatcher watches a repository with fsnotify and, once edits
+// settle, syncs uncommitted changes into the repository's working-tree overlay
+type WorkingTreeWatcher struct {
+	tree     *vectorstore.WorkingTree
+	repoPath string
+	debounce time.Duration
+	watcher  *fsnotify.Watcher
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	logger   zerolog.Logger
+}
+
+// NewWorkingTreeWatcher creates a watcher for repoPath feeding tree
+func NewWorkingTreeWatcher(tree *vectorstore.WorkingTree, repoPath string, debounce time.Duration, logger zerolog.Logger) (*WorkingTreeWatcher, error) {
+	if debounce <= 0 {
+		debounce = defaultWatchDebounce
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("create fsnotify watcher: %w", err)
+	}
+
+	w := &WorkingTreeWatcher{
+		tree:     tree,
+		repoPath: repoPath,
+		debounce: debounce,
+		watcher:  fsw,
+		stopChan: make(chan struct{}),
+		logger:   logger,
+	}
+
+	if err := w.addTree(repoPath); err != nil {
+		fsw.Close()
+		return nil, err
+	}
+
+	// .git itself (not recursively) so commits, checkouts and stash
+	// changes to HEAD and the index trigger a resync
+	if err := fsw.Add(filepath.Join(repoPath, ".git")); err != nil {
+		w.logger.Debug().Err(err).Msg("Not watching .git directory")
+	}
+
+	return w, nil
+}
+
+// Start performs an initial sync and begins processing filesystem events
+func (w *WorkingTreeWatcher) Start(ctx context.Context) {
+	w.wg.Add(1)
+	go w.watchLoop(ctx)
+	w.logger.Info().
+		Dur("debounce", w.debounce).
+		Msg("Working tree watcher started")
+}
+
+// Stop gracefully stops the watcher
+func (w *WorkingTreeWatcher) Stop() {
+	close(w.stopChan)
+	w.wg.Wait()
+	w.watcher.Close()
+	w.logger.Info().Msg("Working tree watcher stopped")
+}
+
+// watchLoop debounces filesystem events into overlay syncs
+func (w *WorkingTreeWatcher) watchLoop(ctx context.Context) {
+	defer w.wg.Done()
+
+	// Pick up changes made while the gateway was down
+	w.sync(ctx)
+
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if !w.relevant(event) {
+				continue
+			}
+			// New directories are not covered by existing watches
+			if event.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := w.addTree(event.Name); err != nil {
+						w.logger.Warn().Err(err).Str("path", event.Name).Msg("Failed to watch new directory")
+					}
+				}
+			}
+			timer.Reset(w.debounce)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.logger.Warn().Err(err).Msg("Working tree watcher error")
+		case <-timer.C:
+			w.sync(ctx)
+		case <-w.stopChan:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sync reconciles the overlay and logs what changed
+func (w *WorkingTreeWatcher) sync(ctx context.Context) {
+	stats, err := w.tree.Sync(ctx)
+	if err != nil {
+		w.logger.Error().Err(err).Msg("Working tree sync failed")
+		return
+	}
+
+	if stats.Indexed+stats.Deleted+stats.Removed+stats.Errors == 0 {
+		return
+	}
+	w.logger.Info().
+		Int("indexed", stats.Indexed).
+		Int("deleted", stats.Deleted).
+		Int("removed", stats.Removed).
+		Int("errors", stats.Errors).
+		Int("changed_files", len(w.tree.ChangedFiles())).
+		Msg("Working tree overlay updated")
+}
+
+// relevant filters out churn inside .git other than HEAD and index updates
+func (w *WorkingTreeWatcher) relevant(event fsnotify.Event) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+
+	rel, err := filepath.Rel(w.repoPath, event.Name)
+	if err != nil {
+		return false
+	}
+	if rel == ".git" || strings.HasPrefix(rel, ".git"+string(filepath.Separator)) {
+		name := filepath.Base(rel)
+		return name == "HEAD" || name == "index"
+	}
+	return true
+}
+
+// addTree watches root and all subdirectories that indexing doesn't skip
+func (w *WorkingTreeWatcher) addTree(root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != root && filetypes.ShouldSkipDirectory(d.Name()) {
+			return filepath.SkipDir
+		}
+		if err := w.watcher.Add(path); err != nil {
+			return fmt.Errorf("watch %s: %w", path, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,36 @@
+package gateway
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestWorkingTreeWatcher_Relevant(t *testing.T) {
+	repo := "/repos/api"
+	w := &WorkingTreeWatcher{repoPath: repo}
+
+	testCases := []struct {
+		name     string
+		event    fsnotify.Event
+		expected bool
+	}{
+		{"source write", fsnotify.Event{Name: filepath.Join(repo, "main.go"), Op: fsnotify.Write}, true},
+		{"new directory", fsnotify.Event{Name: filepath.Join(repo, "pkg"), Op: fsnotify.Create}, true},
+		{"chmod only", fsnotify.Event{Name: filepath.Join(repo, "main.go"), Op: fsnotify.Chmod}, false},
+		{"git index", fsnotify.Event{Name: filepath.Join(repo, ".git", "index"), Op: fsnotify.Write}, true},
+		{"git HEAD", fsnotify.Event{Name: filepath.Join(repo, ".git", "HEAD"), Op: fsnotify.Write}, true},
+		{"git lock file", fsnotify.Event{Name: filepath.Join(repo, ".git", "index.lock"), Op: fsnotify.Create}, false},
+		{"git objects", fsnotify.Event{Name: filepath.Join(repo, ".git", "objects"), Op: fsnotify.Write}, false},
+		{"gitignore", fsnotify.Event{Name: filepath.Join(repo, ".gitignore"), Op: fsnotify.Write}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := w.relevant(tc.event); got != tc.expected {
+				t.Errorf("relevant(%s %s) = %v, expected %v", tc.event.Op, tc.event.Name, got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+// Package ignore matches repository paths against gitignore-style patterns.
+//
+// A Matcher collects rules from several sources (nested .gitignore files,
+// .meshignore, configured patterns), each applying below the directory it was
+// defined in. Later sources and deeper directories take precedence, and within
+// a source the last matching rule wins, as in git.
+package ignore
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// rule is a single compiled pattern
+type rule struct {
+	re      *regexp.Regexp
+	negate  bool // "!pattern" re-includes
+	dirOnly bool // "pattern/" only matches directories
+}
+
+// ruleSet holds the rules of one source, relative to base
+type ruleSet struct {
+	base  string // Directory the rules apply below ("" for the root)
+	order int    // Precedence: higher wins over lower
+	rules []rule
+}
+
+// Matcher reports whether paths are matched by its rules
+type Matcher struct {
+	sets  []ruleSet
+	added int
+}
+
+// New creates an empty matcher that matches nothing
+func New() *Matcher {
+	return &Matcher{}
+}
+
+// Empty reports whether the matcher has no rules
+func (m *Matcher) Empty() bool {
+	return len(m.sets) == 0
+}
+
+// AddFile adds the rules of a gitignore-format file located in dir
+// (slash-separated, relative to the repository root)
+func (m *Matcher) AddFile(dir string, content []byte) {
+	var patterns []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		patterns = append(patterns, scanner.Text())
+	}
+	m.AddPatterns(dir, patterns)
+}
+
+// AddPatterns adds gitignore-syntax patterns applying below dir. Rules added
+// later take precedence over earlier ones in the same or a shallower directory.
+func (m *Matcher) AddPatterns(dir string, patterns []string) {
+	set := ruleSet{base: strings.Trim(path.Clean("/"+dir), "/"), order: m.added}
+	for _, pattern := range patterns {
+		if r, ok := compile(pattern); ok {
+			set.rules = append(set.rules, r)
+		}
+	}
+	m.added++
+	if len(set.rules) == 0 {
+		return
+	}
+
+	m.sets = append(m.sets, set)
+	// Deeper directories override shallower ones, then insertion order
+	sort.SliceStable(m.sets, func(i, j int) bool {
+		di, dj := depth(m.sets[i].base), depth(m.sets[j].base)
+		if di != dj {
+			return di < dj
+		}
+		return m.sets[i].order < m.sets[j].order
+	})
+}
+
+// Match reports whether the file at relPath is matched, either directly or
+// because one of its parent directories is. As in git, a file inside a
+// matched directory cannot be re-included.
+func (m *Matcher) Match(relPath string) bool {
+	return m.matchPath(relPath, false)
+}
+
+// MatchDir reports whether the directory at relPath is matched, so a walk can
+// skip it entirely
+func (m *Matcher) MatchDir(relPath string) bool {
+	return m.matchPath(relPath, true)
+}
+
+// matchPath checks every parent directory of relPath, then relPath itself
+func (m *Matcher) matchPath(relPath string, isDir bool) bool {
+	if len(m.sets) == 0 {
+		return false
+	}
+
+	relPath = strings.Trim(path.Clean("/"+relPath), "/")
+	if relPath == "" {
+		return false
+	}
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		if m.match(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.match(relPath, isDir)
+}
+
+// match applies every rule to a single path; the last match decides
+func (m *Matcher) match(relPath string, isDir bool) bool {
+	matched := false
+	for _, set := range m.sets {
+		rel := relPath
+		if set.base != "" {
+			if !strings.HasPrefix(relPath, set.base+"/") {
+				continue
+			}
+			rel = relPath[len(set.base)+1:]
+		}
+		for _, r := range set.rules {
+			if r.dirOnly && !isDir {
+				continue
+			}
+			if r.re.MatchString(rel) {
+				matched = !r.negate
+			}
+		}
+	}
+	return matched
+}
+
+// compile converts one gitignore line into a rule
+func compile(pattern string) (rule, bool) {
+	var r rule
+
+	pattern = strings.TrimRight(pattern, " \t\r")
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return r, false
+	}
+	if strings.HasPrefix(pattern, "!") {
+		r.negate = true
+		pattern = pattern[1:]
+	} else if strings.HasPrefix(pattern, `\`) {
+		// "\#" and "\!" escape a leading special character
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		r.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+	if pattern == "" {
+		return r, false
+	}
+
+	// A slash anywhere but the end anchors the pattern to its directory
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !anchored {
+		expr.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "/**") && i+3 == len(pattern):
+			expr.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				expr.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			expr.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return r, false
+	}
+	r.re = re
+	return r, true
+}
+
+// depth counts the directories in a slash-separated path
+func depth(dir string) int {
+	if dir == "" {
+		return 0
+	}
+	return strings.Count(dir, "/") + 1
+}
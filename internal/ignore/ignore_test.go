@@ -0,0 +1,110 @@
+package ignore
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		want     bool
+	}{
+		{"basename anywhere", []string{"*.log"}, "a/b/debug.log", true},
+		{"no match", []string{"*.log"}, "main.go", false},
+		{"comment and blank", []string{"# *.go", ""}, "main.go", false},
+		{"escaped hash", []string{`\#notes.go`}, "#notes.go", true},
+		{"anchored", []string{"/build"}, "build/out.go", true},
+		{"anchored not nested", []string{"/build"}, "cmd/build/out.go", false},
+		{"middle slash anchors", []string{"docs/api"}, "x/docs/api/a.md", false},
+		{"directory only", []string{"vendor/"}, "pkg/vendor/lib.go", true},
+		{"directory only skips files", []string{"vendor/"}, "vendor", false},
+		{"leading double star", []string{"**/testdata"}, "a/b/testdata/x.json", true},
+		{"trailing double star", []string{"docs/**"}, "docs/a/b.md", true},
+		{"middle double star", []string{"a/**/z.go"}, "a/b/c/z.go", true},
+		{"middle double star zero dirs", []string{"a/**/z.go"}, "a/z.go", true},
+		{"star stays in segment", []string{"internal/*.go"}, "internal/sub/x.go", false},
+		{"question mark", []string{"file?.go"}, "file1.go", true},
+		{"character class", []string{"*.[ch]"}, "lib/x.h", true},
+		{"negated class", []string{"*.[!ch]"}, "lib/x.h", false},
+		{"negation", []string{"*.go", "!keep.go"}, "keep.go", false},
+		{"last match wins", []string{"!keep.go", "*.go"}, "keep.go", true},
+		{"parent exclusion wins", []string{"gen/", "!gen/keep.go"}, "gen/keep.go", true},
+		{"exclude pattern style", []string{"**/*_test.go"}, "pkg/a_test.go", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+			m.AddPatterns("", tt.patterns)
+			if got := m.Match(tt.path); got != tt.want {
+				t.Errorf("Match(%q) with %v = %v, want %v", tt.path, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch_NestedFiles(t *testing.T) {
+	m := New()
+	m.AddFile("sub", []byte("*.gen.go\n!keep.gen.go\n/local.go\n"))
+	m.AddFile("", []byte("*.tmp\nkeep.gen.go\n"))
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"sub/x.gen.go", true},
+		{"x.gen.go", false},                  // Nested rules only apply below their directory
+		{"sub/keep.gen.go", false},           // Deeper file overrides the root
+		{"keep.gen.go", true},                // Root rule applies outside sub
+		{"sub/local.go", true},               // Anchored to sub
+		{"sub/deeper/local.go", false},       // Not anchored elsewhere
+		{"sub/deeper/cache.tmp", true},       // Root rules apply everywhere
+		{"subdir/x.gen.go", false},           // Prefix of a sibling is not below sub
+		{"./sub/../sub/other.gen.go", true},  // Paths are cleaned
+		{"sub/nested/dir/file.gen.go", true}, // Unanchored nested rules apply at any depth
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := m.Match(tt.path); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchDir(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []string{"node_modules/", "/dist", "*.go"})
+
+	tests := []struct {
+		dir  string
+		want bool
+	}{
+		{"web/node_modules", true},
+		{"dist", true},
+		{"web/dist", false},
+		{"web/node_modules/pkg", true},
+		{"", false},
+		{"cmd", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			if got := m.MatchDir(tt.dir); got != tt.want {
+				t.Errorf("MatchDir(%q) = %v, want %v", tt.dir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []string{"# only a comment", ""})
+	if !m.Empty() {
+		t.Error("Expected matcher without rules to be empty")
+	}
+	if m.Match("anything.go") {
+		t.Error("Empty matcher should match nothing")
+	}
+}
@@ -5,20 +5,38 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/First008/mesh/internal/tokenizer"
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/shared/constant"
 	"github.com/rs/zerolog"
 )
 
 // AnthropicProvider implements the LLMProvider interface for Anthropic's Claude API
 type AnthropicProvider struct {
-	client anthropic.Client
-	model  string
-	logger zerolog.Logger
+	client    anthropic.Client
+	model     string
+	maxTokens int64 // Output token limit per request
+	logger    zerolog.Logger
 }
 
+const (
+	// DefaultAnthropicMaxTokens is the output limit used when the model's is unknown
+	DefaultAnthropicMaxTokens = 8192
+
+	// anthropicNonStreamingMaxTokens keeps requests under the SDK's 10 minute
+	// non-streaming estimate (128K tokens per hour); larger values are rejected
+	anthropicNonStreamingMaxTokens = 21000
+)
+
 // NewAnthropicProvider creates a new Anthropic provider
 func NewAnthropicProvider(apiKey, model string, logger zerolog.Logger) (*AnthropicProvider, error) {
+	return NewAnthropicProviderWithMaxTokens(apiKey, model, DefaultAnthropicMaxTokens, logger)
+}
+
+// NewAnthropicProviderWithMaxTokens creates a new Anthropic provider with an
+// output token limit, typically the model's max output from the model registry
+func NewAnthropicProviderWithMaxTokens(apiKey, model string, maxTokens int, logger zerolog.Logger) (*AnthropicProvider, error) {
 	if apiKey == "" {
 		return nil, fmt.Errorf("anthropic API key is required")
 	}
@@ -29,12 +47,24 @@ func NewAnthropicProvider(apiKey, model string, logger zerolog.Logger) (*Anthrop
 
 	client := anthropic.NewClient(
 		option.WithAPIKey(apiKey),
+		option.WithMaxRetries(0), // Retries are handled by the resilience guard
 	)
 
+	if maxTokens <= 0 {
+		maxTokens = DefaultAnthropicMaxTokens
+	}
+	if maxTokens > anthropicNonStreamingMaxTokens {
+		maxTokens = anthropicNonStreamingMaxTokens
+	}
+	if limit, ok := constant.ModelNonStreamingTokens[model]; ok && maxTokens > limit {
+		maxTokens = limit
+	}
+
 	return &AnthropicProvider{
-		client: client,
-		model:  model,
-		logger: logger,
+		client:    client,
+		model:     model,
+		maxTokens: int64(maxTokens),
+		logger:    logger,
 	}, nil
 }
 
@@ -43,7 +73,7 @@ func (ap *AnthropicProvider) Ask(ctx context.Context, systemPrompt, userPrompt s
 	// Build the request
 	params := anthropic.MessageNewParams{
 		Model:     anthropic.Model(ap.model),
-		MaxTokens: 8192, // Claude Sonnet 4.5 supports up to 8192 output tokens
+		MaxTokens: ap.maxTokens,
 		Messages: []anthropic.MessageParam{
 			anthropic.NewUserMessage(anthropic.NewTextBlock(userPrompt)),
 		},
@@ -119,7 +149,7 @@ func (ap *AnthropicProvider) AskWithCache(ctx context.Context, systemPrompt, cac
 	// Build request with cache control
 	params := anthropic.MessageNewParams{
 		Model:     anthropic.Model(ap.model),
-		MaxTokens: 8192, // Claude Sonnet 4.5 supports up to 8192 output tokens
+		MaxTokens: ap.maxTokens,
 		Messages: []anthropic.MessageParam{
 			{
 				Role:    anthropic.MessageParamRoleUser,
@@ -181,12 +211,10 @@ func (ap *AnthropicProvider) AskWithCache(ctx context.Context, systemPrompt, cac
 	return response, nil
 }
 
-// CountTokens estimates the number of tokens in a text
-// This is a rough estimate: ~4 characters per token for English text
+// CountTokens counts the tokens in a text for budget management
+// Claude's tokenizer is not public; cl100k_base BPE tracks it closely
 func (ap *AnthropicProvider) CountTokens(text string) (int, error) {
-	// Rough approximation: 1 token ≈ 4 characters for English
-	// This is not exact but good enough for budget management
-	return len(text) / 4, nil
+	return tokenizer.ForModel(ap.model).Count(text), nil
 }
 
 // GetModel returns the model identifier
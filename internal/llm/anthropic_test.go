@@ -203,3 +203,29 @@ func TestGetModel(t *testing.T) {
 // For unit tests, these would require mocking the HTTP client,
 // which is beyond the scope of basic unit testing.
 // The Agent tests will test these methods with mocked providers.
+
+func TestNewAnthropicProviderWithMaxTokens(t *testing.T) {
+	tests := []struct {
+		name      string
+		model     string
+		maxTokens int
+		expected  int64
+	}{
+		{"registry value", "claude-3-5-haiku-20241022", 8192, 8192},
+		{"unset uses default", "claude-sonnet-4-5-20250929", 0, DefaultAnthropicMaxTokens},
+		{"capped for non-streaming", "claude-sonnet-4-5-20250929", 64000, anthropicNonStreamingMaxTokens},
+		{"model non-streaming limit", "claude-opus-4-1-20250805", 32000, 8192},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewAnthropicProviderWithMaxTokens("test-key-123", tt.model, tt.maxTokens, testLogger())
+			if err != nil {
+				t.Fatalf("NewAnthropicProviderWithMaxTokens failed: %v", err)
+			}
+			if provider.maxTokens != tt.expected {
+				t.Errorf("Expected max tokens %d, got %d", tt.expected, provider.maxTokens)
+			}
+		})
+	}
+}
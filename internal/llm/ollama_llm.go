@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/First008/mesh/internal/tokenizer"
 	"github.com/rs/zerolog"
 )
 
@@ -147,11 +148,10 @@ func (op *OllamaLLMProvider) AskWithCache(ctx context.Context, systemPrompt, cac
 	return op.Ask(ctx, systemPrompt, combinedPrompt.String())
 }
 
-// CountTokens estimates the number of tokens in a text
-// This is a rough estimate: ~4 characters per token for English text
+// CountTokens counts the tokens in a text for budget management
+// Local model vocabularies are not bundled; cl100k_base BPE is a close proxy
 func (op *OllamaLLMProvider) CountTokens(text string) (int, error) {
-	// Rough approximation: 1 token ≈ 4 characters for English
-	return len(text) / 4, nil
+	return tokenizer.ForModel(op.model).Count(text), nil
 }
 
 // GetModel returns the model identifier
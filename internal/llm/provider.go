@@ -49,4 +49,8 @@ type Response struct {
 
 	// Model is the specific model that generated this response
 	Model string
+
+	// Provider is the provider type that answered (e.g. "anthropic"), set by
+	// the agent when walking its fallback chain
+	Provider string
 }
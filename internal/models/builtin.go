@@ -0,0 +1,51 @@
+package models
+
+// builtinModels are the models MESH knows out of the box (pricing as of December 2025)
+var builtinModels = []Capabilities{
+	// Anthropic
+	{
+		Name: "claude-opus-4-5", Provider: "anthropic", Aliases: []string{"claude-opus-4.5"},
+		ContextWindow: 200000, MaxOutputTokens: 64000, SupportsCaching: true,
+		InputPricePerMToken: 5.00, OutputPricePerMToken: 25.00,
+	},
+	{
+		Name: "claude-sonnet-4-5", Provider: "anthropic", Aliases: []string{"claude-sonnet-4.5"},
+		ContextWindow: 200000, MaxOutputTokens: 64000, SupportsCaching: true,
+		InputPricePerMToken: 3.00, OutputPricePerMToken: 15.00,
+	},
+	{
+		Name: "claude-haiku-4-5", Provider: "anthropic", Aliases: []string{"claude-haiku-4.5"},
+		ContextWindow: 200000, MaxOutputTokens: 64000, SupportsCaching: true,
+		InputPricePerMToken: 1.00, OutputPricePerMToken: 5.00,
+	},
+	{
+		Name: "claude-3-5-haiku", Provider: "anthropic", Aliases: []string{"claude-haiku-3.5"},
+		ContextWindow: 200000, MaxOutputTokens: 8192, SupportsCaching: true,
+		InputPricePerMToken: 0.80, OutputPricePerMToken: 4.00,
+	},
+
+	// OpenAI
+	{
+		Name: "gpt-4o", Provider: "openai",
+		ContextWindow: 128000, MaxOutputTokens: 16384,
+		InputPricePerMToken: 2.50, OutputPricePerMToken: 10.00,
+	},
+	{
+		Name: "gpt-4o-mini", Provider: "openai",
+		ContextWindow: 128000, MaxOutputTokens: 16384,
+		InputPricePerMToken: 0.15, OutputPricePerMToken: 0.60,
+	},
+}
+
+// providerDefault returns conservative capabilities for models missing from the registry
+func providerDefault(provider string) Capabilities {
+	switch provider {
+	case "ollama":
+		// Local models run with a limited num_ctx; keep prompts small for speed
+		return Capabilities{Provider: provider, ContextWindow: 32768, MaxOutputTokens: 4096}
+	case "anthropic":
+		return Capabilities{Provider: provider, ContextWindow: 200000, MaxOutputTokens: 8192, SupportsCaching: true}
+	default:
+		return Capabilities{Provider: provider, ContextWindow: 128000, MaxOutputTokens: 4096}
+	}
+}
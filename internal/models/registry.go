@@ -0,0 +1,159 @@
+// Package models describes what each LLM can do.
+//
+// The registry maps model identifiers to their context window, output limit,
+// prompt caching support and pricing. Built-in entries cover the models MESH
+// ships defaults for; YAML overrides add new models or correct existing ones
+// without code changes. Context and search budgets are derived from these
+// capabilities instead of matching on model names.
+package models
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Capabilities describes one model
+type Capabilities struct {
+	Name                 string   `yaml:"name"`                            // Model ID, also matched as a prefix of dated IDs
+	Provider             string   `yaml:"provider"`                        // "anthropic", "ollama", "openai"
+	Aliases              []string `yaml:"aliases,omitempty"`               // Alternative IDs (e.g. "claude-sonnet-4.5")
+	ContextWindow        int      `yaml:"context_window"`                  // Total tokens the model accepts
+	MaxOutputTokens      int      `yaml:"max_output_tokens"`               // Largest response the model can produce
+	SupportsCaching      bool     `yaml:"supports_caching,omitempty"`      // Prompt caching available
+	InputPricePerMToken  float64  `yaml:"input_price_per_mtok,omitempty"`  // USD per million input tokens
+	OutputPricePerMToken float64  `yaml:"output_price_per_mtok,omitempty"` // USD per million output tokens
+
+	cachingSet bool // supports_caching was present in the YAML entry
+}
+
+// UnmarshalYAML decodes an entry, remembering whether supports_caching was
+// set so an override can turn caching off
+func (c *Capabilities) UnmarshalYAML(node *yaml.Node) error {
+	type plain Capabilities
+	if err := node.Decode((*plain)(c)); err != nil {
+		return err
+	}
+	var fields struct {
+		SupportsCaching *bool `yaml:"supports_caching"`
+	}
+	if err := node.Decode(&fields); err != nil {
+		return err
+	}
+	c.cachingSet = fields.SupportsCaching != nil
+	return nil
+}
+
+// InputTokens returns the tokens left for the prompt after reserving room for the response
+func (c Capabilities) InputTokens() int {
+	if c.MaxOutputTokens >= c.ContextWindow {
+		return c.ContextWindow / 2
+	}
+	return c.ContextWindow - c.MaxOutputTokens
+}
+
+// Priced reports whether pricing is known for the model
+func (c Capabilities) Priced() bool {
+	return c.InputPricePerMToken > 0 || c.OutputPricePerMToken > 0
+}
+
+// Registry resolves model identifiers to capabilities
+type Registry struct {
+	models []Capabilities
+}
+
+// NewRegistry returns the built-in models with overrides applied. An override
+// replaces the built-in entry of the same name; zero fields keep built-in values,
+// except supports_caching, which applies whenever it is set.
+func NewRegistry(overrides []Capabilities) *Registry {
+	r := &Registry{models: append([]Capabilities(nil), builtinModels...)}
+	for _, override := range overrides {
+		r.add(override)
+	}
+	return r
+}
+
+// add merges an entry into the registry
+func (r *Registry) add(entry Capabilities) {
+	name := normalize(entry.Name)
+	for i := range r.models {
+		if normalize(r.models[i].Name) == name {
+			r.models[i] = merge(r.models[i], entry)
+			return
+		}
+	}
+	r.models = append(r.models, entry)
+}
+
+// merge overlays the non-zero fields of override onto base
+func merge(base, override Capabilities) Capabilities {
+	if override.Provider != "" {
+		base.Provider = override.Provider
+	}
+	if len(override.Aliases) > 0 {
+		base.Aliases = override.Aliases
+	}
+	if override.ContextWindow > 0 {
+		base.ContextWindow = override.ContextWindow
+	}
+	if override.MaxOutputTokens > 0 {
+		base.MaxOutputTokens = override.MaxOutputTokens
+	}
+	if override.SupportsCaching || override.cachingSet {
+		base.SupportsCaching = override.SupportsCaching
+	}
+	if override.InputPricePerMToken > 0 {
+		base.InputPricePerMToken = override.InputPricePerMToken
+	}
+	if override.OutputPricePerMToken > 0 {
+		base.OutputPricePerMToken = override.OutputPricePerMToken
+	}
+	return base
+}
+
+// Lookup returns the capabilities of a model, falling back to the
+// provider's default for unknown models
+func (r *Registry) Lookup(provider, model string) Capabilities {
+	if caps, ok := r.Find(model); ok {
+		return caps
+	}
+	caps := providerDefault(strings.ToLower(provider))
+	caps.Name = model
+	return caps
+}
+
+// Find resolves a model ID. Exact names and aliases win, then the longest
+// entry that prefixes the ID, so dated releases inherit their family.
+func (r *Registry) Find(model string) (Capabilities, bool) {
+	id := normalize(model)
+
+	var best Capabilities
+	bestLen := 0
+	for _, entry := range r.models {
+		for _, name := range append([]string{entry.Name}, entry.Aliases...) {
+			name = normalize(name)
+			if name == id {
+				return entry, true
+			}
+			if len(name) > bestLen && hasFamilyPrefix(id, name) {
+				best = entry
+				bestLen = len(name)
+			}
+		}
+	}
+	return best, bestLen > 0
+}
+
+// hasFamilyPrefix reports whether id starts with name at a separator boundary
+func hasFamilyPrefix(id, name string) bool {
+	if !strings.HasPrefix(id, name) {
+		return false
+	}
+	rest := id[len(name):]
+	return rest == "" || rest[0] == '-' || rest[0] == ':' || rest[0] == '@'
+}
+
+// normalize lowercases an ID and treats "4.5" and "4-5" alike
+func normalize(id string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(id)), ".", "-")
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestRegistry_Find(t *testing.T) {
+	registry := NewRegistry(nil)
+
+	tests := []struct {
+		model string
+		want  string
+		found bool
+	}{
+		{"claude-sonnet-4-5", "claude-sonnet-4-5", true},
+		{"claude-sonnet-4-5-20250929", "claude-sonnet-4-5", true},
+		{"claude-sonnet-4.5", "claude-sonnet-4-5", true},
+		{"Claude-Haiku-4-5-20251001", "claude-haiku-4-5", true},
+		{"claude-3-5-haiku-latest", "claude-3-5-haiku", true},
+		{"gpt-4o-mini-2024-07-18", "gpt-4o-mini", true},
+		{"gpt-4o-2024-08-06", "gpt-4o", true},
+		{"claude-sonnet-4-50", "", false},
+		{"llama3.3:70b", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			caps, ok := registry.Find(tt.model)
+			if ok != tt.found {
+				t.Fatalf("Expected found=%v, got %v", tt.found, ok)
+			}
+			if caps.Name != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, caps.Name)
+			}
+		})
+	}
+}
+
+func TestRegistry_Lookup_ProviderDefault(t *testing.T) {
+	registry := NewRegistry(nil)
+
+	caps := registry.Lookup("ollama", "llama3.3:70b")
+	if caps.ContextWindow != 32768 || caps.SupportsCaching {
+		t.Errorf("Expected conservative ollama default, got %+v", caps)
+	}
+	if caps.Name != "llama3.3:70b" {
+		t.Errorf("Expected name to be kept, got %q", caps.Name)
+	}
+	if caps.Priced() {
+		t.Error("Expected unknown model to have no pricing")
+	}
+
+	if caps := registry.Lookup("anthropic", "claude-future-9"); !caps.SupportsCaching {
+		t.Errorf("Expected anthropic default to support caching, got %+v", caps)
+	}
+}
+
+func TestRegistry_Overrides(t *testing.T) {
+	registry := NewRegistry([]Capabilities{
+		{Name: "claude-sonnet-4.5", InputPricePerMToken: 2.50},
+		{Name: "qwen2.5-coder", Provider: "ollama", ContextWindow: 131072, MaxOutputTokens: 8192},
+	})
+
+	sonnet := registry.Lookup("anthropic", "claude-sonnet-4-5-20250929")
+	if sonnet.InputPricePerMToken != 2.50 {
+		t.Errorf("Expected overridden input price 2.50, got %v", sonnet.InputPricePerMToken)
+	}
+	if sonnet.OutputPricePerMToken != 15.00 || sonnet.ContextWindow != 200000 {
+		t.Errorf("Expected unset fields to keep built-in values, got %+v", sonnet)
+	}
+
+	qwen := registry.Lookup("ollama", "qwen2.5-coder:32b")
+	if qwen.ContextWindow != 131072 {
+		t.Errorf("Expected added model context window 131072, got %d", qwen.ContextWindow)
+	}
+	if qwen.InputTokens() != 131072-8192 {
+		t.Errorf("Expected input tokens %d, got %d", 131072-8192, qwen.InputTokens())
+	}
+
+	// Overrides must not leak into other registries
+	if NewRegistry(nil).Lookup("anthropic", "claude-sonnet-4-5").InputPricePerMToken != 3.00 {
+		t.Error("Expected built-in pricing to be unchanged")
+	}
+}
+
+func TestRegistry_Overrides_SupportsCaching(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want bool
+	}{
+		{"unset keeps built-in", "- name: claude-sonnet-4-5\n  input_price_per_mtok: 2.50\n", true},
+		{"false turns caching off", "- name: claude-sonnet-4-5\n  supports_caching: false\n", false},
+		{"true keeps caching on", "- name: claude-sonnet-4-5\n  supports_caching: true\n", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var overrides []Capabilities
+			if err := yaml.Unmarshal([]byte(tt.yaml), &overrides); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			caps := NewRegistry(overrides).Lookup("anthropic", "claude-sonnet-4-5")
+			if caps.SupportsCaching != tt.want {
+				t.Errorf("Expected SupportsCaching=%v, got %v", tt.want, caps.SupportsCaching)
+			}
+			if caps.ContextWindow != 200000 {
+				t.Errorf("Expected built-in context window to be kept, got %d", caps.ContextWindow)
+			}
+		})
+	}
+}
+
+func TestCapabilities_InputTokens(t *testing.T) {
+	tests := []struct {
+		caps Capabilities
+		want int
+	}{
+		{Capabilities{ContextWindow: 200000, MaxOutputTokens: 64000}, 136000},
+		{Capabilities{ContextWindow: 8192, MaxOutputTokens: 8192}, 4096},
+	}
+
+	for _, tt := range tests {
+		if got := tt.caps.InputTokens(); got != tt.want {
+			t.Errorf("Expected %d input tokens for %+v, got %d", tt.want, tt.caps, got)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package resilience
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// ErrCircuitOpen is returned while a backend's circuit breaker rejects calls
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerState is the state of a circuit breaker
+type BreakerState string
+
+const (
+	StateClosed   BreakerState = "closed"    // Calls flow normally
+	StateOpen     BreakerState = "open"      // Calls fail fast until the cooldown ends
+	StateHalfOpen BreakerState = "half_open" // One trial call decides whether to close
+)
+
+// Status is a snapshot of a breaker, as reported on /health
+type Status struct {
+	Name                string       `json:"name"`
+	State               BreakerState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	LastError           string       `json:"last_error,omitempty"`
+	OpenedAt            *time.Time   `json:"opened_at,omitempty"`
+}
+
+// Breaker opens after a run of consecutive failures and lets a single trial
+// call through once the cooldown has passed
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+	logger    zerolog.Logger
+	now       func() time.Time
+
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int
+	lastError string
+	openedAt  time.Time
+	probing   bool // A half-open trial call is in flight
+}
+
+// NewBreaker creates a closed breaker
+func NewBreaker(threshold int, cooldown time.Duration, logger zerolog.Logger) *Breaker {
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		logger:    logger,
+		now:       time.Now,
+		state:     StateClosed,
+	}
+}
+
+// Allow returns ErrCircuitOpen if the call must not be made. Every allowed
+// call must be followed by exactly one of Success, Failure or Release.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = StateHalfOpen
+		b.probing = true
+		b.logger.Info().Msg("Circuit breaker half-open, sending trial call")
+		return nil
+	case StateHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	}
+	return nil
+}
+
+// Success records a call that reached the backend
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != StateClosed {
+		b.logger.Info().Msg("Circuit breaker closed")
+	}
+	b.state = StateClosed
+	b.failures = 0
+	b.lastError = ""
+	b.probing = false
+}
+
+// Failure records a transient failure, opening the breaker at the threshold
+func (b *Breaker) Failure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.lastError = err.Error()
+	b.probing = false
+
+	if b.state == StateHalfOpen || (b.state == StateClosed && b.failures >= b.threshold) {
+		b.state = StateOpen
+		b.openedAt = b.now()
+		b.logger.Warn().
+			Err(err).
+			Int("consecutive_failures", b.failures).
+			Dur("cooldown", b.cooldown).
+			Msg("Circuit breaker opened")
+	}
+}
+
+// Release ends an allowed call whose outcome says nothing about the backend
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+// State returns the current state, moving an expired open breaker to half-open
+func (b *Breaker) State() BreakerState {
+	return b.Status().State
+}
+
+// Status returns a snapshot of the breaker
+func (b *Breaker) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.state
+	if state == StateOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		state = StateHalfOpen
+	}
+
+	status := Status{
+		State:               state,
+		ConsecutiveFailures: b.failures,
+		LastError:           b.lastError,
+	}
+	if b.state != StateClosed {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
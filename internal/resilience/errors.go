@@ -0,0 +1,69 @@
+package resilience
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/ollama/ollama/api"
+	"github.com/openai/openai-go"
+)
+
+// Classify reports whether err is worth retrying and how long the backend
+// asked callers to wait. API errors are retryable for 408, 409, 425, 429 and
+// 5xx (including Anthropic's 529 overloaded); other API errors are permanent.
+// Errors without a status (network failures, timeouts) are retryable.
+func Classify(err error) (retryable bool, retryAfter time.Duration) {
+	var anthropicErr *anthropic.Error
+	if errors.As(err, &anthropicErr) {
+		return retryableStatus(anthropicErr.StatusCode), parseRetryAfter(anthropicErr.Response)
+	}
+
+	var openaiErr *openai.Error
+	if errors.As(err, &openaiErr) {
+		return retryableStatus(openaiErr.StatusCode), parseRetryAfter(openaiErr.Response)
+	}
+
+	var ollamaErr api.StatusError
+	if errors.As(err, &ollamaErr) {
+		return retryableStatus(ollamaErr.StatusCode), 0
+	}
+
+	return true, 0
+}
+
+// retryableStatus reports whether an HTTP status indicates a transient failure
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooEarly, http.StatusTooManyRequests:
+		return true
+	}
+	return code >= 500
+}
+
+// parseRetryAfter reads retry-after-ms or Retry-After (seconds or HTTP date)
+func parseRetryAfter(resp *http.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+
+	if ms, err := strconv.ParseFloat(resp.Header.Get("Retry-After-Ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
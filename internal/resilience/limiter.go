@@ -0,0 +1,116 @@
+package resilience
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter enforces per-minute request and token budgets using token buckets
+// that refill continuously. A nil bucket is unlimited.
+type Limiter struct {
+	requests *bucket
+	tokens   *bucket
+}
+
+// NewLimiter creates a limiter; limits of 0 or less are unlimited
+func NewLimiter(requestsPerMinute, tokensPerMinute int) *Limiter {
+	return &Limiter{
+		requests: newBucket(requestsPerMinute),
+		tokens:   newBucket(tokensPerMinute),
+	}
+}
+
+// Wait blocks until one request and the given number of tokens fit the
+// budgets, or ctx is done
+func (l *Limiter) Wait(ctx context.Context, tokens int) error {
+	wait := l.requests.reserve(1)
+	if w := l.tokens.reserve(float64(tokens)); w > wait {
+		wait = w
+	}
+	if wait <= 0 {
+		return nil
+	}
+	if err := sleepContext(ctx, wait); err != nil {
+		l.requests.refund(1)
+		l.tokens.refund(float64(tokens))
+		return err
+	}
+	return nil
+}
+
+// Charge deducts tokens consumed beyond the estimate passed to Wait,
+// delaying later calls instead of this one
+func (l *Limiter) Charge(tokens int) {
+	l.tokens.reserve(float64(tokens))
+}
+
+// bucket is a token bucket holding at most one minute of budget
+type bucket struct {
+	mu       sync.Mutex
+	capacity float64
+	rate     float64 // Units per second
+	level    float64 // May go negative while reservations are pending
+	last     time.Time
+	now      func() time.Time
+}
+
+func newBucket(perMinute int) *bucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &bucket{
+		capacity: float64(perMinute),
+		rate:     float64(perMinute) / 60,
+		level:    float64(perMinute),
+		last:     time.Now(),
+		now:      time.Now,
+	}
+}
+
+// reserve takes n units and returns how long to wait until they are covered.
+// Requests larger than the bucket are clamped so they can eventually proceed.
+func (b *bucket) reserve(n float64) time.Duration {
+	if b == nil || n <= 0 {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	if n > b.capacity {
+		n = b.capacity
+	}
+	b.level -= n
+	if b.level >= 0 {
+		return 0
+	}
+	return time.Duration(-b.level / b.rate * float64(time.Second))
+}
+
+// refund returns units from a reservation that was abandoned
+func (b *bucket) refund(n float64) {
+	if b == nil || n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if n > b.capacity {
+		n = b.capacity
+	}
+	b.level += n
+	if b.level > b.capacity {
+		b.level = b.capacity
+	}
+}
+
+// refill adds the budget accrued since the last call
+func (b *bucket) refill() {
+	now := b.now()
+	b.level += now.Sub(b.last).Seconds() * b.rate
+	if b.level > b.capacity {
+		b.level = b.capacity
+	}
+	b.last = now
+}
@@ -0,0 +1,83 @@
+package resilience
+
+import (
+	"context"
+
+	"github.com/First008/mesh/internal/llm"
+	"github.com/First008/mesh/internal/tokenizer"
+	"github.com/First008/mesh/internal/vectorstore"
+)
+
+// llmProvider guards every request made by an LLM provider
+type llmProvider struct {
+	llm.LLMProvider
+	guard *Guard
+}
+
+// WrapLLM returns an LLM provider whose requests run through guard
+func WrapLLM(provider llm.LLMProvider, guard *Guard) llm.LLMProvider {
+	return &llmProvider{LLMProvider: provider, guard: guard}
+}
+
+// Ask sends a question through the guard
+func (p *llmProvider) Ask(ctx context.Context, systemPrompt, userPrompt string) (*llm.Response, error) {
+	return p.call(ctx, func(ctx context.Context) (*llm.Response, error) {
+		return p.LLMProvider.Ask(ctx, systemPrompt, userPrompt)
+	}, systemPrompt, userPrompt)
+}
+
+// AskWithCache sends a question with cacheable context through the guard
+func (p *llmProvider) AskWithCache(ctx context.Context, systemPrompt, cacheableContext, regularContext, question string) (*llm.Response, error) {
+	return p.call(ctx, func(ctx context.Context) (*llm.Response, error) {
+		return p.LLMProvider.AskWithCache(ctx, systemPrompt, cacheableContext, regularContext, question)
+	}, systemPrompt, cacheableContext, regularContext, question)
+}
+
+// call runs ask under the guard, estimating its input tokens from texts
+func (p *llmProvider) call(ctx context.Context, ask func(ctx context.Context) (*llm.Response, error), texts ...string) (*llm.Response, error) {
+	estimate := 0
+	for _, text := range texts {
+		if n, err := p.LLMProvider.CountTokens(text); err == nil {
+			estimate += n
+		}
+	}
+
+	var resp *llm.Response
+	err := p.guard.Do(ctx, estimate, func(ctx context.Context) (int, error) {
+		var err error
+		resp, err = ask(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return resp.InputTokens + resp.OutputTokens, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// embeddingProvider guards every request made by an embedding provider
+type embeddingProvider struct {
+	vectorstore.EmbeddingProvider
+	guard *Guard
+}
+
+// WrapEmbedding returns an embedding provider whose requests run through guard
+func WrapEmbedding(provider vectorstore.EmbeddingProvider, guard *Guard) vectorstore.EmbeddingProvider {
+	return &embeddingProvider{EmbeddingProvider: provider, guard: guard}
+}
+
+// CreateEmbedding creates an embedding through the guard
+func (p *embeddingProvider) CreateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	var embedding []float32
+	err := p.guard.Do(ctx, tokenizer.ForModel(p.GetModelName()).Count(text), func(ctx context.Context) (int, error) {
+		var err error
+		embedding, err = p.EmbeddingProvider.CreateEmbedding(ctx, text)
+		return 0, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return embedding, nil
+}
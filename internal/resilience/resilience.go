@@ -0,0 +1,246 @@
+// Package resilience protects calls to embedding and LLM providers.
+//
+// A Guard combines retries with exponential backoff (honoring Retry-After),
+// request and token rate limiting, and a circuit breaker. Guards are shared
+// per backend so every repository and branch talking to the same model sees
+// the same limits and breaker state, which is reported on /health.
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Default policy values
+const (
+	DefaultMaxRetries       = 3
+	DefaultBaseDelay        = 500 * time.Millisecond
+	DefaultMaxDelay         = 30 * time.Second
+	DefaultFailureThreshold = 5
+	DefaultCooldown         = 30 * time.Second
+)
+
+// Policy configures retries, rate limits and the circuit breaker for a backend.
+// Zero values select the defaults; rate limits of 0 are unlimited.
+type Policy struct {
+	MaxRetries        int           `yaml:"max_retries,omitempty"`         // Retries after the first attempt (default 3, -1 disables)
+	BaseDelay         time.Duration `yaml:"base_delay,omitempty"`          // First backoff delay, doubled per retry (default 500ms)
+	MaxDelay          time.Duration `yaml:"max_delay,omitempty"`           // Cap for computed backoff (default 30s)
+	RequestsPerMinute int           `yaml:"requests_per_minute,omitempty"` // Request rate limit
+	TokensPerMinute   int           `yaml:"tokens_per_minute,omitempty"`   // Estimated token rate limit
+	FailureThreshold  int           `yaml:"failure_threshold,omitempty"`   // Consecutive failures that open the breaker (default 5)
+	Cooldown          time.Duration `yaml:"cooldown,omitempty"`            // Time the breaker stays open before a trial call (default 30s)
+}
+
+// Config holds the policies for the embedding and LLM backends
+type Config struct {
+	Embedding Policy `yaml:"embedding,omitempty"`
+	LLM       Policy `yaml:"llm,omitempty"`
+}
+
+// withDefaults fills unset fields with their defaults
+func (p Policy) withDefaults() Policy {
+	if p.MaxRetries == 0 {
+		p.MaxRetries = DefaultMaxRetries
+	} else if p.MaxRetries < 0 {
+		p.MaxRetries = 0
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = DefaultBaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = DefaultMaxDelay
+	}
+	if p.FailureThreshold <= 0 {
+		p.FailureThreshold = DefaultFailureThreshold
+	}
+	if p.Cooldown <= 0 {
+		p.Cooldown = DefaultCooldown
+	}
+	return p
+}
+
+// Guard runs calls to one backend under a retry policy, rate limiter and circuit breaker
+type Guard struct {
+	name    string
+	policy  Policy
+	breaker *Breaker
+	limiter *Limiter
+	logger  zerolog.Logger
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+// NewGuard creates a guard for the named backend
+func NewGuard(name string, policy Policy, logger zerolog.Logger) *Guard {
+	policy = policy.withDefaults()
+	logger = logger.With().Str("backend", name).Logger()
+	return &Guard{
+		name:    name,
+		policy:  policy,
+		breaker: NewBreaker(policy.FailureThreshold, policy.Cooldown, logger),
+		limiter: NewLimiter(policy.RequestsPerMinute, policy.TokensPerMinute),
+		logger:  logger,
+		sleep:   sleepContext,
+	}
+}
+
+// Name returns the backend name
+func (g *Guard) Name() string {
+	return g.name
+}
+
+// Status returns the breaker status for the backend
+func (g *Guard) Status() Status {
+	status := g.breaker.Status()
+	status.Name = g.name
+	return status
+}
+
+// Do runs fn, retrying transient failures. estimate is the number of tokens
+// the call is expected to consume; fn returns the tokens actually used (0 if
+// unknown) so the limiter can account for underestimates.
+func (g *Guard) Do(ctx context.Context, estimate int, fn func(ctx context.Context) (int, error)) error {
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		if err := g.breaker.Allow(); err != nil {
+			if lastErr != nil {
+				return fmt.Errorf("%s: %w (last error: %v)", g.name, err, lastErr)
+			}
+			return fmt.Errorf("%s: %w", g.name, err)
+		}
+
+		if err := g.limiter.Wait(ctx, estimate); err != nil {
+			g.breaker.Release()
+			return err
+		}
+
+		used, err := fn(ctx)
+		if err == nil {
+			g.breaker.Success()
+			if used > estimate {
+				g.limiter.Charge(used - estimate)
+			}
+			return nil
+		}
+
+		// Cancellation by the caller says nothing about the backend
+		if ctx.Err() != nil {
+			g.breaker.Release()
+			return err
+		}
+
+		retryable, retryAfter := Classify(err)
+		if !retryable {
+			// The backend answered; the request itself was rejected
+			g.breaker.Success()
+			return err
+		}
+		g.breaker.Failure(err)
+		lastErr = err
+
+		if attempt >= g.policy.MaxRetries {
+			return err
+		}
+
+		delay := g.backoff(attempt)
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+
+		g.logger.Warn().
+			Err(err).
+			Int("attempt", attempt+1).
+			Dur("delay", delay).
+			Msg("Provider call failed, retrying")
+
+		if err := g.sleep(ctx, delay); err != nil {
+			return lastErr
+		}
+	}
+}
+
+// backoff returns the jittered exponential delay before retry attempt+1
+func (g *Guard) backoff(attempt int) time.Duration {
+	delay := g.policy.BaseDelay << attempt
+	if delay <= 0 || delay > g.policy.MaxDelay {
+		delay = g.policy.MaxDelay
+	}
+	// Equal jitter keeps at least half the delay while spreading retries
+	half := delay / 2
+	return half + time.Duration(rand.Int64N(int64(half)+1))
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]*Guard)
+)
+
+// Shared returns the process-wide guard for a backend, creating it with the
+// given policy on first use. Later policies for the same name are ignored.
+func Shared(name string, policy Policy, logger zerolog.Logger) *Guard {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if g, ok := registry[name]; ok {
+		return g
+	}
+	g := NewGuard(name, policy, logger)
+	registry[name] = g
+	return g
+}
+
+// Statuses returns the breaker status of every shared guard, sorted by name
+func Statuses() []Status {
+	registryMu.Lock()
+	guards := make([]*Guard, 0, len(registry))
+	for _, g := range registry {
+		guards = append(guards, g)
+	}
+	registryMu.Unlock()
+
+	statuses := make([]Status, 0, len(guards))
+	for _, g := range guards {
+		statuses = append(statuses, g.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// Degraded reports whether any shared guard has an open or half-open breaker
+func Degraded() bool {
+	for _, status := range Statuses() {
+		if status.State != StateClosed {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCircuitOpen reports whether err was caused by an open circuit breaker
+func IsCircuitOpen(err error) bool {
+	return errors.Is(err, ErrCircuitOpen)
+}
@@ -0,0 +1,203 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/ollama/ollama/api"
+	"github.com/rs/zerolog"
+)
+
+// anthropicError builds an SDK error with the given status and headers
+func anthropicError(status int, header http.Header) error {
+	req, _ := http.NewRequest(http.MethodPost, "https://api.anthropic.com/v1/messages", nil)
+	if header == nil {
+		header = http.Header{}
+	}
+	return fmt.Errorf("anthropic API error: %w", &anthropic.Error{
+		StatusCode: status,
+		Request:    req,
+		Response:   &http.Response{StatusCode: status, Header: header},
+	})
+}
+
+// testGuard returns a guard that records sleeps instead of waiting
+func testGuard(policy Policy) (*Guard, *[]time.Duration) {
+	g := NewGuard("test", policy, zerolog.Nop())
+	var slept []time.Duration
+	g.sleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	return g, &slept
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		retryable  bool
+		retryAfter time.Duration
+	}{
+		{"overloaded", anthropicError(529, nil), true, 0},
+		{"rate limited seconds", anthropicError(429, http.Header{"Retry-After": {"7"}}), true, 7 * time.Second},
+		{"rate limited ms", anthropicError(429, http.Header{"Retry-After-Ms": {"1500"}}), true, 1500 * time.Millisecond},
+		{"bad request", anthropicError(400, nil), false, 0},
+		{"ollama server error", fmt.Errorf("ollama embedding error: %w", api.StatusError{StatusCode: 503}), true, 0},
+		{"ollama not found", api.StatusError{StatusCode: 404}, false, 0},
+		{"network", errors.New("dial tcp: connection refused"), true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retryable, retryAfter := Classify(tt.err)
+			if retryable != tt.retryable {
+				t.Errorf("Expected retryable=%v, got %v", tt.retryable, retryable)
+			}
+			if retryAfter != tt.retryAfter {
+				t.Errorf("Expected retry after %v, got %v", tt.retryAfter, retryAfter)
+			}
+		})
+	}
+}
+
+func TestGuard_RetriesTransientErrors(t *testing.T) {
+	g, slept := testGuard(Policy{MaxRetries: 3, BaseDelay: 10 * time.Millisecond})
+
+	calls := 0
+	err := g.Do(context.Background(), 0, func(ctx context.Context) (int, error) {
+		calls++
+		if calls < 3 {
+			return 0, anthropicError(529, nil)
+		}
+		return 0, nil
+	})
+	if err != nil {
+		t.Fatalf("Expected success after retries, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+	if len(*slept) != 2 {
+		t.Errorf("Expected 2 backoff sleeps, got %v", *slept)
+	}
+	if g.Status().State != StateClosed {
+		t.Errorf("Expected breaker closed after success, got %s", g.Status().State)
+	}
+}
+
+func TestGuard_HonorsRetryAfter(t *testing.T) {
+	g, slept := testGuard(Policy{MaxRetries: 1, BaseDelay: 10 * time.Millisecond})
+
+	calls := 0
+	g.Do(context.Background(), 0, func(ctx context.Context) (int, error) {
+		calls++
+		if calls == 1 {
+			return 0, anthropicError(429, http.Header{"Retry-After": {"5"}})
+		}
+		return 0, nil
+	})
+
+	if len(*slept) != 1 || (*slept)[0] != 5*time.Second {
+		t.Errorf("Expected a single 5s sleep, got %v", *slept)
+	}
+}
+
+func TestGuard_DoesNotRetryPermanentErrors(t *testing.T) {
+	g, _ := testGuard(Policy{MaxRetries: 3})
+
+	calls := 0
+	err := g.Do(context.Background(), 0, func(ctx context.Context) (int, error) {
+		calls++
+		return 0, anthropicError(400, nil)
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("Expected one failed call, got %d calls (%v)", calls, err)
+	}
+	if g.Status().ConsecutiveFailures != 0 {
+		t.Errorf("Expected client errors not to count against the breaker")
+	}
+}
+
+func TestGuard_OpensBreaker(t *testing.T) {
+	g, _ := testGuard(Policy{MaxRetries: -1, FailureThreshold: 2, Cooldown: time.Minute})
+	now := time.Now()
+	g.breaker.now = func() time.Time { return now }
+
+	failing := func(ctx context.Context) (int, error) {
+		return 0, errors.New("connection refused")
+	}
+	g.Do(context.Background(), 0, failing)
+	g.Do(context.Background(), 0, failing)
+
+	if g.Status().State != StateOpen {
+		t.Fatalf("Expected breaker open after threshold, got %s", g.Status().State)
+	}
+
+	calls := 0
+	err := g.Do(context.Background(), 0, func(ctx context.Context) (int, error) {
+		calls++
+		return 0, nil
+	})
+	if !IsCircuitOpen(err) || calls != 0 {
+		t.Errorf("Expected fast failure while open, got %d calls (%v)", calls, err)
+	}
+
+	// After the cooldown a trial call closes the breaker again
+	now = now.Add(time.Minute)
+	if err := g.Do(context.Background(), 0, func(ctx context.Context) (int, error) {
+		return 0, nil
+	}); err != nil {
+		t.Fatalf("Expected trial call to succeed, got %v", err)
+	}
+	if g.Status().State != StateClosed {
+		t.Errorf("Expected breaker closed after trial call, got %s", g.Status().State)
+	}
+}
+
+func TestGuard_CancelledContextStopsRetries(t *testing.T) {
+	g, _ := testGuard(Policy{MaxRetries: 3})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := 0
+	g.Do(ctx, 0, func(ctx context.Context) (int, error) {
+		calls++
+		cancel()
+		return 0, ctx.Err()
+	})
+	if calls != 1 {
+		t.Errorf("Expected no retries after cancellation, got %d calls", calls)
+	}
+	if g.Status().ConsecutiveFailures != 0 {
+		t.Errorf("Expected cancellation not to count against the breaker")
+	}
+}
+
+func TestBucket_Reserve(t *testing.T) {
+	b := newBucket(60) // One unit per second
+	now := time.Now()
+	b.now = func() time.Time { return now }
+	b.last = now
+
+	if wait := b.reserve(60); wait != 0 {
+		t.Errorf("Expected full bucket to cover 60 units, got wait %v", wait)
+	}
+	if wait := b.reserve(2); wait != 2*time.Second {
+		t.Errorf("Expected 2s wait on empty bucket, got %v", wait)
+	}
+
+	// Refill covers the debt and a new unit after three seconds
+	now = now.Add(3 * time.Second)
+	if wait := b.reserve(1); wait != 0 {
+		t.Errorf("Expected refilled bucket to cover 1 unit, got wait %v", wait)
+	}
+
+	if newBucket(0) != nil {
+		t.Error("Expected zero limit to be unlimited")
+	}
+}
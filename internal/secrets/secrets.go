@@ -0,0 +1,165 @@
+// Package secrets detects and redacts credentials in file content.
+//
+// Content is scanned before it is embedded into the vector store or sent to an
+// LLM provider. Rules combine regular expressions for well-known key formats
+// (cloud provider keys, tokens, PEM private keys, connection strings) with an
+// entropy check for generic "password = ..." style assignments.
+package secrets
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Mode is what happens to content containing secrets
+type Mode string
+
+const (
+	ModeRedact Mode = "redact" // Replace each secret with a [REDACTED:rule] marker
+	ModeSkip   Mode = "skip"   // Leave files with secrets out entirely
+	ModeOff    Mode = "off"    // Don't scan
+)
+
+// ParseMode validates a configured mode; "" means redact
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case "":
+		return ModeRedact, nil
+	case ModeRedact, ModeSkip, ModeOff:
+		return Mode(s), nil
+	}
+	return "", fmt.Errorf("unknown secrets mode %q (want redact, skip or off)", s)
+}
+
+// Finding is a detected secret. The secret itself is never recorded.
+type Finding struct {
+	Rule string `json:"rule"`
+	Line int    `json:"line"`
+}
+
+// rule detects one kind of secret
+type rule struct {
+	name       string
+	re         *regexp.Regexp
+	group      int     // Submatch holding the secret (0 = whole match)
+	minEntropy float64 // Minimum Shannon entropy (bits per char) of the secret, if set
+	needDigit  bool    // Secret must contain a digit
+}
+
+// rules are checked in order; a span claimed by an earlier rule is not
+// reported again by a later one
+var rules = []rule{
+	{name: "private-key", re: regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY(?: BLOCK)?-----.*?-----END [A-Z ]*PRIVATE KEY(?: BLOCK)?-----`)},
+	{name: "aws-access-key", re: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{name: "aws-secret-key", re: regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})`), group: 1},
+	{name: "github-token", re: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`)},
+	{name: "gitlab-token", re: regexp.MustCompile(`\bglpat-[A-Za-z0-9_\-]{20,}`)},
+	{name: "slack-token", re: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9\-]{10,}`)},
+	{name: "anthropic-key", re: regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_\-]{20,}`)},
+	{name: "openai-key", re: regexp.MustCompile(`\bsk-(?:proj-)?[A-Za-z0-9_\-]{20,}`)},
+	{name: "stripe-key", re: regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{20,}`)},
+	{name: "google-api-key", re: regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}`)},
+	{name: "jwt", re: regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]{10,}\.eyJ[A-Za-z0-9_\-]{10,}\.[A-Za-z0-9_\-]{10,}`)},
+	{name: "connection-string", re: regexp.MustCompile(`\b[a-zA-Z][a-zA-Z0-9+.\-]*://[^\s:/@"'<>]+:([^\s@/"'<>$%{]{3,})@`), group: 1},
+	{
+		name:       "generic-secret",
+		re:         regexp.MustCompile(`(?i)(?:password|passwd|secret|api[_\-]?key|apikey|token|credential)[A-Za-z0-9_\-]*["']?\s*[:=]\s*["']?([A-Za-z0-9+/=_\-.]{12,})`),
+		group:      1,
+		minEntropy: 3.5,
+		needDigit:  true,
+	},
+}
+
+// span is the location of a secret in the scanned content
+type span struct {
+	start, end int
+	rule       string
+}
+
+// Scan returns the secrets found in content, in order of appearance
+func Scan(content string) []Finding {
+	_, findings := scan(content)
+	return findings
+}
+
+// Redact replaces each secret in content with a [REDACTED:rule] marker and
+// returns the redacted content with what was found
+func Redact(content string) (string, []Finding) {
+	spans, findings := scan(content)
+	if len(spans) == 0 {
+		return content, nil
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, s := range spans {
+		sb.WriteString(content[last:s.start])
+		sb.WriteString("[REDACTED:" + s.rule + "]")
+		last = s.end
+	}
+	sb.WriteString(content[last:])
+	return sb.String(), findings
+}
+
+// scan applies every rule and returns non-overlapping spans sorted by position
+func scan(content string) ([]span, []Finding) {
+	var spans []span
+	for _, r := range rules {
+		for _, m := range r.re.FindAllStringSubmatchIndex(content, -1) {
+			start, end := m[2*r.group], m[2*r.group+1]
+			if start < 0 || !r.accepts(content[start:end]) || overlaps(spans, start, end) {
+				continue
+			}
+			spans = append(spans, span{start: start, end: end, rule: r.name})
+		}
+	}
+	if len(spans) == 0 {
+		return nil, nil
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	findings := make([]Finding, len(spans))
+	for i, s := range spans {
+		findings[i] = Finding{Rule: s.rule, Line: strings.Count(content[:s.start], "\n") + 1}
+	}
+	return spans, findings
+}
+
+// accepts applies the rule's checks on the matched secret
+func (r rule) accepts(secret string) bool {
+	if r.needDigit && !strings.ContainsAny(secret, "0123456789") {
+		return false
+	}
+	return r.minEntropy == 0 || entropy(secret) >= r.minEntropy
+}
+
+// overlaps reports whether [start, end) intersects a span already claimed
+func overlaps(spans []span, start, end int) bool {
+	for _, s := range spans {
+		if start < s.end && s.start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// entropy returns the Shannon entropy of s in bits per character
+func entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	for _, c := range s {
+		counts[c]++
+	}
+	n := float64(len([]rune(s)))
+	var h float64
+	for _, count := range counts {
+		p := float64(count) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
@@ -47,6 +47,9 @@ func (s *GatewayServer) setupRoutes() {
 	// Health check
 	s.engine.GET("/health", s.handleHealth)
 
+	// Readiness: 503 until every repository has cloned and indexed
+	s.engine.GET("/ready", s.handleReady)
+
 	// Gateway info
 	s.engine.GET("/info", s.handleGatewayInfo)
 
@@ -56,17 +59,39 @@ func (s *GatewayServer) setupRoutes() {
 	// Get specific repository info
 	s.engine.GET("/repos/:repo", s.handleGetRepo)
 
+	// Register and unregister repositories at runtime (admin token required)
+	s.engine.POST("/repos", s.requireAdmin, s.handleAddRepo)
+	s.engine.DELETE("/repos/:repo", s.requireAdmin, s.handleRemoveRepo)
+
 	// Ask a specific repository
 	s.engine.POST("/ask/:repo", s.handleAskRepo)
 
 	// Ask all repositories
 	s.engine.POST("/ask-all", s.handleAskAll)
 
-	// Trigger re-indexing for a specific repository
+	// Queue re-indexing for a specific repository (returns a job ID)
 	s.engine.POST("/repos/:repo/reindex", s.handleReindexRepo)
 
-	// GitHub webhook for automatic re-indexing
+	// Check a branch collection against git, optionally repairing it
+	s.engine.GET("/repos/:repo/verify", s.handleVerifyRepo)
+	s.engine.POST("/repos/:repo/repair", s.handleRepairRepo)
+
+	// Audit report of secrets redacted (or skipped) while indexing a branch
+	s.engine.GET("/repos/:repo/redactions", s.handleRedactions)
+
+	// Where an identifier is defined and referenced in a branch
+	s.engine.GET("/repos/:repo/symbols", s.handleSymbols)
+
+	// Re-index job status and cancellation
+	s.engine.GET("/jobs", s.handleListJobs)
+	s.engine.GET("/jobs/:id", s.handleGetJob)
+	s.engine.DELETE("/jobs/:id", s.handleCancelJob)
+
+	// Git hosting webhooks for automatic re-indexing
 	s.engine.POST("/webhooks/github", s.handleGitHubWebhook)
+	s.engine.POST("/webhooks/gitlab", s.handleGitLabWebhook)
+	s.engine.POST("/webhooks/gitea", s.handleGiteaWebhook)
+	s.engine.POST("/webhooks/bitbucket", s.handleBitbucketWebhook)
 }
 
 // Start starts the HTTP server
@@ -1,16 +1,44 @@
 package server
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/First008/mesh/internal/filetypes"
+	"github.com/First008/mesh/internal/gateway"
+	"github.com/First008/mesh/internal/resilience"
+	"github.com/First008/mesh/internal/secrets"
+	"github.com/First008/mesh/internal/vectorstore"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 // handleHealth returns the health status of the gateway
 func (s *GatewayServer) handleHealth(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
-		"status": "healthy",
-		"mode":   "gateway",
+		"status":    healthStatus(),
+		"mode":      "gateway",
+		"providers": resilience.Statuses(),
+	})
+}
+
+// handleReady reports whether every repository finished starting. Repos that
+// are still indexing already answer with keyword search; this is for
+// deployments that want to wait for semantic search.
+func (s *GatewayServer) handleReady(c *gin.Context) {
+	ready, repos := s.gateway.Ready()
+
+	status := http.StatusOK
+	if !ready {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, gin.H{
+		"ready": ready,
+		"repos": repos,
 	})
 }
 
@@ -61,6 +89,185 @@ func (s *GatewayServer) handleGetRepo(c *gin.Context) {
 	c.JSON(http.StatusOK, info)
 }
 
+// AddRepoRequest is the request body for registering a repository at runtime.
+// Credentials and key files are not accepted: a caller could otherwise send a
+// server secret to a URL of its choosing. Private remotes go in the config file.
+type AddRepoRequest struct {
+	Name            string   `json:"name" binding:"required"`
+	Path            string   `json:"path,omitempty"`
+	URL             string   `json:"url,omitempty"`
+	FocusPaths      []string `json:"focus_paths,omitempty"`
+	Personality     string   `json:"personality,omitempty"`
+	ExcludePatterns []string `json:"exclude_patterns,omitempty"`
+	Watch           bool     `json:"watch,omitempty"`
+	Secrets         string   `json:"secrets,omitempty"`   // redact (default), skip, or off
+	Generated       string   `json:"generated,omitempty"` // downweight (default), skip, or off
+
+	FileTypes filetypes.Config `json:"file_types,omitempty"` // Extensions, file names and shebangs indexed as code
+
+	EmbedTemplate string `json:"embed_template,omitempty"` // Text embedded per chunk; "raw" for content alone
+	GraphHops     *int   `json:"graph_hops,omitempty"`     // Dependency edges followed from search results (default 1)
+	Summaries     *bool  `json:"summaries,omitempty"`      // LLM-written package summaries (default true)
+
+	WatchDebounce string              `json:"watch_debounce,omitempty"` // Quiet period before syncing edits, e.g. "2s"
+	Branches      BranchPolicyRequest `json:"branches,omitempty"`       // Which branches the scanner indexes and prunes
+}
+
+// BranchPolicyRequest is a gateway.BranchPolicy with its TTL written as a
+// duration string, as in the config file ("720h")
+type BranchPolicyRequest struct {
+	Include     []string `json:"include,omitempty"`
+	Exclude     []string `json:"exclude,omitempty"`
+	MaxBranches int      `json:"max_branches,omitempty"`
+	InactiveTTL string   `json:"inactive_ttl,omitempty"`
+}
+
+// repoConfig converts the request into a gateway repository config
+func (r AddRepoRequest) repoConfig() (gateway.RepoConfig, error) {
+	watchDebounce, err := parseDuration("watch_debounce", r.WatchDebounce)
+	if err != nil {
+		return gateway.RepoConfig{}, err
+	}
+	inactiveTTL, err := parseDuration("branches.inactive_ttl", r.Branches.InactiveTTL)
+	if err != nil {
+		return gateway.RepoConfig{}, err
+	}
+
+	return gateway.RepoConfig{
+		Name:            r.Name,
+		Path:            r.Path,
+		URL:             r.URL,
+		FocusPaths:      r.FocusPaths,
+		Personality:     r.Personality,
+		ExcludePatterns: r.ExcludePatterns,
+		Watch:           r.Watch,
+		WatchDebounce:   watchDebounce,
+		Branches: gateway.BranchPolicy{
+			Include:     r.Branches.Include,
+			Exclude:     r.Branches.Exclude,
+			MaxBranches: r.Branches.MaxBranches,
+			InactiveTTL: inactiveTTL,
+		},
+		Secrets:       secrets.Mode(r.Secrets),
+		Generated:     vectorstore.GeneratedMode(r.Generated),
+		FileTypes:     r.FileTypes,
+		EmbedTemplate: r.EmbedTemplate,
+		GraphHops:     r.GraphHops,
+		Summaries:     r.Summaries,
+	}, nil
+}
+
+// parseDuration parses an optional duration field ("" = 0)
+func parseDuration(field, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", field, err)
+	}
+	return d, nil
+}
+
+// handleAddRepo registers a repository; indexing continues in the background
+func (s *GatewayServer) handleAddRepo(c *gin.Context) {
+	// Unknown fields are refused so credentials_env and friends fail loudly
+	var req AddRepoRequest
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request: " + err.Error(),
+		})
+		return
+	}
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request",
+		})
+		return
+	}
+
+	repoConfig, err := req.repoConfig()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	job, err := s.gateway.AddRepo(repoConfig)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, gateway.ErrRepoExists):
+			status = http.StatusConflict
+		case errors.Is(err, gateway.ErrInvalidRepo):
+			status = http.StatusBadRequest
+		}
+		s.logger.Error().Err(err).Str("repo", req.Name).Msg("Failed to add repository")
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	info, err := s.gateway.GetRepo(req.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"repo": info,
+		"job":  job,
+	})
+}
+
+// requireAdmin lets repository management through only with the configured
+// admin token as a bearer token; without one the routes are disabled
+func (s *GatewayServer) requireAdmin(c *gin.Context) {
+	token := s.gateway.AdminToken()
+	if token == "" {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": "repository management is disabled; set admin_token to enable it",
+		})
+		return
+	}
+
+	bearer, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || !validToken(token, bearer) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "invalid admin token",
+		})
+		return
+	}
+	c.Next()
+}
+
+// handleRemoveRepo unregisters a repository, keeping its indexed collections
+func (s *GatewayServer) handleRemoveRepo(c *gin.Context) {
+	repoName := c.Param("repo")
+
+	if err := s.gateway.RemoveRepo(repoName); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, gateway.ErrRepoNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "removed",
+		"repo":   repoName,
+	})
+}
+
 // handleAskRepo handles questions to a specific repository
 func (s *GatewayServer) handleAskRepo(c *gin.Context) {
 	repoName := c.Param("repo")
@@ -79,10 +286,14 @@ func (s *GatewayServer) handleAskRepo(c *gin.Context) {
 		Msg("Processing question for repository")
 
 	// Ask the gateway
-	response, err := s.gateway.Ask(c.Request.Context(), repoName, req.Question)
+	response, err := s.gateway.AskWithOptions(c.Request.Context(), repoName, req.Question, req.queryOptions())
 	if err != nil {
 		s.logger.Error().Err(err).Str("repo", repoName).Msg("Failed to process question")
-		c.JSON(http.StatusInternalServerError, gin.H{
+		status := http.StatusInternalServerError
+		if errors.Is(err, gateway.ErrRepoUnavailable) {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{
 			"error": err.Error(),
 		})
 		return
@@ -97,7 +308,8 @@ func (s *GatewayServer) handleAskRepo(c *gin.Context) {
 			"output_tokens": response.OutputTokens,
 			"cached_tokens": response.CachedTokens,
 		},
-		"model": response.Model,
+		"model":    response.Model,
+		"provider": response.Provider,
 	})
 }
 
@@ -116,7 +328,7 @@ func (s *GatewayServer) handleAskAll(c *gin.Context) {
 		Msg("Processing question for all repositories")
 
 	// Ask all repositories
-	responses, err := s.gateway.AskAll(c.Request.Context(), req.Question)
+	responses, err := s.gateway.AskAllWithOptions(c.Request.Context(), req.Question, req.queryOptions())
 	if err != nil {
 		s.logger.Warn().Err(err).Msg("Some repositories failed to respond")
 		// Continue even if some repos failed
@@ -136,7 +348,8 @@ func (s *GatewayServer) handleAskAll(c *gin.Context) {
 				"output_tokens": response.OutputTokens,
 				"cached_tokens": response.CachedTokens,
 			},
-			"model": response.Model,
+			"model":    response.Model,
+			"provider": response.Provider,
 		}
 		totalInputTokens += response.InputTokens
 		totalOutputTokens += response.OutputTokens
@@ -154,25 +367,194 @@ func (s *GatewayServer) handleAskAll(c *gin.Context) {
 	})
 }
 
-// handleReindexRepo triggers re-indexing for a specific repository
+// handleReindexRepo queues re-indexing for a repository branch.
+// Defaults to the checked-out branch; override with ?branch=<name>.
 func (s *GatewayServer) handleReindexRepo(c *gin.Context) {
 	repoName := c.Param("repo")
+	branch := c.Query("branch")
+
+	job, err := s.gateway.EnqueueReindex(repoName, branch)
+	if err != nil {
+		s.logger.Error().Err(err).Str("repo", repoName).Msg("Failed to queue re-index")
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	s.logger.Info().
+		Str("repo", repoName).
+		Str("branch", job.Branch).
+		Str("job_id", job.ID).
+		Msg("Re-index queued")
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status": "accepted",
+		"repo":   repoName,
+		"branch": job.Branch,
+		"job_id": job.ID,
+		"job":    job,
+	})
+}
+
+// handleVerifyRepo compares a branch collection against the git tree at its
+// indexed commit. Defaults to the checked-out branch; override with ?branch=<name>.
+func (s *GatewayServer) handleVerifyRepo(c *gin.Context) {
+	repoName := c.Param("repo")
+
+	if _, err := s.gateway.GetRepo(repoName); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	report, err := s.gateway.VerifyBranch(c.Request.Context(), repoName, c.Query("branch"))
+	if err != nil {
+		s.logger.Error().Err(err).Str("repo", repoName).Msg("Verification failed")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"consistent": report.Consistent(),
+		"report":     report,
+	})
+}
+
+// handleRepairRepo queues a re-index of a branch followed by a repair of
+// missing, stale and orphaned entries; the job's status carries the report.
+// Defaults to the checked-out branch; override with ?branch=<name>.
+func (s *GatewayServer) handleRepairRepo(c *gin.Context) {
+	repoName := c.Param("repo")
+
+	if _, err := s.gateway.GetRepo(repoName); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	job, err := s.gateway.RepairBranch(repoName, c.Query("branch"))
+	if err != nil {
+		s.logger.Error().Err(err).Str("repo", repoName).Msg("Failed to queue repair")
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
 
 	s.logger.Info().
 		Str("repo", repoName).
-		Msg("Triggering re-indexing")
+		Str("branch", job.Branch).
+		Str("job_id", job.ID).
+		Msg("Repair queued")
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status": "accepted",
+		"repo":   repoName,
+		"branch": job.Branch,
+		"job_id": job.ID,
+		"job":    job,
+	})
+}
+
+// handleRedactions returns the files of a branch that had secrets scrubbed
+// while indexing, with the rules and lines that matched
+func (s *GatewayServer) handleRedactions(c *gin.Context) {
+	repoName := c.Param("repo")
+
+	if _, err := s.gateway.GetRepo(repoName); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
 
-	if err := s.gateway.ReindexRepo(c.Request.Context(), repoName); err != nil {
-		s.logger.Error().Err(err).Str("repo", repoName).Msg("Failed to re-index repository")
+	report, err := s.gateway.Redactions(repoName, c.Query("branch"))
+	if err != nil {
+		s.logger.Error().Err(err).Str("repo", repoName).Msg("Failed to load redaction report")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// handleSymbols returns the definitions and references of the identifier in
+// the name query parameter
+func (s *GatewayServer) handleSymbols(c *gin.Context) {
+	repoName := c.Param("repo")
+
+	if _, err := s.gateway.GetRepo(repoName); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	name := strings.TrimSpace(c.Query("name"))
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "name is required",
+		})
+		return
+	}
+
+	lookup, err := s.gateway.Symbols(repoName, c.Query("branch"), name)
+	if err != nil {
+		s.logger.Error().Err(err).Str("repo", repoName).Msg("Failed to look up symbol")
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
 
+	c.JSON(http.StatusOK, lookup)
+}
+
+// handleListJobs returns all known re-index jobs
+func (s *GatewayServer) handleListJobs(c *gin.Context) {
+	jobs := s.gateway.ListJobs()
+
 	c.JSON(http.StatusOK, gin.H{
-		"status":  "success",
-		"repo":    repoName,
-		"message": "Repository re-indexed successfully",
+		"jobs":  jobs,
+		"count": len(jobs),
 	})
 }
+
+// handleGetJob returns the status and progress of a re-index job
+func (s *GatewayServer) handleGetJob(c *gin.Context) {
+	job, err := s.gateway.GetJob(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// handleCancelJob cancels a queued or running re-index job
+func (s *GatewayServer) handleCancelJob(c *gin.Context) {
+	job, err := s.gateway.CancelJob(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	s.logger.Info().
+		Str("job_id", job.ID).
+		Str("repo", job.Repo).
+		Str("branch", job.Branch).
+		Msg("Re-index job cancellation requested")
+
+	c.JSON(http.StatusOK, job)
+}
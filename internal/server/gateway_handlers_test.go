@@ -0,0 +1,133 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/First008/mesh/internal/gateway"
+	"github.com/rs/zerolog"
+)
+
+// newTestGatewayServer returns a server over a gateway without repos or
+// external services
+func newTestGatewayServer(t *testing.T, adminToken string) *GatewayServer {
+	t.Helper()
+	gw, err := gateway.New(&gateway.Config{
+		Port:              8080,
+		EmbeddingProvider: "ollama",
+		LLMProvider:       "ollama",
+		LLMModel:          "llama3.3:70b",
+		AdminToken:        adminToken,
+	}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("gateway.New failed: %v", err)
+	}
+	t.Cleanup(func() { gw.Close() })
+	return NewGateway(gw, 8080, zerolog.Nop())
+}
+
+func TestRepoManagement_RequiresAdminToken(t *testing.T) {
+	repoPath := t.TempDir()
+	addBody := `{"name": "api", "path": "` + repoPath + `"}`
+
+	tests := []struct {
+		name       string
+		adminToken string
+		method     string
+		path       string
+		auth       string
+		body       string
+		wantStatus int
+	}{
+		{"disabled without a token", "", http.MethodPost, "/repos", "Bearer ", addBody, http.StatusForbidden},
+		{"delete disabled without a token", "", http.MethodDelete, "/repos/api", "", "", http.StatusForbidden},
+		{"missing token", "s3cret", http.MethodPost, "/repos", "", addBody, http.StatusUnauthorized},
+		{"wrong token", "s3cret", http.MethodPost, "/repos", "Bearer wrong", addBody, http.StatusUnauthorized},
+		{"credentials refused", "s3cret", http.MethodPost, "/repos", "Bearer s3cret",
+			`{"name": "api", "url": "https://example.com/api.git", "credentials_env": "ANTHROPIC_API_KEY"}`, http.StatusBadRequest},
+		{"ssh key refused", "s3cret", http.MethodPost, "/repos", "Bearer s3cret",
+			`{"name": "api", "url": "git@example.com:api.git", "ssh_key_file": "/etc/ssh/ssh_host_rsa_key"}`, http.StatusBadRequest},
+		{"invalid duration", "s3cret", http.MethodPost, "/repos", "Bearer s3cret",
+			`{"name": "api", "path": "` + repoPath + `", "watch_debounce": "soon"}`, http.StatusBadRequest},
+		{"valid token", "s3cret", http.MethodPost, "/repos", "Bearer s3cret", addBody, http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestGatewayServer(t, tt.adminToken)
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			server.engine.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAddRepoRequest_RepoConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    gateway.RepoConfig
+		wantErr bool
+	}{
+		{
+			name: "watch debounce and branch policy",
+			body: `{"name": "api", "path": "/repos/api", "watch": true, "watch_debounce": "5s",
+				"branches": {"include": ["release/*"], "exclude": ["tmp/*"], "max_branches": 3, "inactive_ttl": "720h"}}`,
+			want: gateway.RepoConfig{
+				Name:          "api",
+				Path:          "/repos/api",
+				Watch:         true,
+				WatchDebounce: 5 * time.Second,
+				Branches: gateway.BranchPolicy{
+					Include:     []string{"release/*"},
+					Exclude:     []string{"tmp/*"},
+					MaxBranches: 3,
+					InactiveTTL: 720 * time.Hour,
+				},
+			},
+		},
+		{
+			name: "durations default to zero",
+			body: `{"name": "api", "path": "/repos/api"}`,
+			want: gateway.RepoConfig{Name: "api", Path: "/repos/api"},
+		},
+		{
+			name:    "invalid watch debounce",
+			body:    `{"name": "api", "path": "/repos/api", "watch_debounce": "soon"}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid inactive ttl",
+			body:    `{"name": "api", "path": "/repos/api", "branches": {"inactive_ttl": "30d"}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req AddRepoRequest
+			if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			got, err := req.repoConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("repoConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("repoConfig() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
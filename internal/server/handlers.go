@@ -3,18 +3,40 @@ package server
 import (
 	"net/http"
 
+	contextbuilder "github.com/First008/mesh/internal/context"
+	"github.com/First008/mesh/internal/resilience"
+	"github.com/First008/mesh/internal/vectorstore"
 	"github.com/gin-gonic/gin"
 )
 
 // AskRequest is the request body for the /ask endpoint
 type AskRequest struct {
-	Question string `json:"question" binding:"required"`
+	Question     string   `json:"question" binding:"required"`
+	WorkingTree  bool     `json:"working_tree,omitempty"`  // Include uncommitted changes (requires watch mode)
+	Paths        []string `json:"paths,omitempty"`         // Only search files under these paths (focus_paths syntax)
+	Languages    []string `json:"languages,omitempty"`     // Only search files in these languages ("go", "typescript", ...)
+	ExcludeTests bool     `json:"exclude_tests,omitempty"` // Leave test files out of the search
+	DocTypes     []string `json:"doc_types,omitempty"`     // Only search these kinds of file ("code", "readme", "adr", ...)
+}
+
+// queryOptions converts request flags into context options
+func (r AskRequest) queryOptions() contextbuilder.QueryOptions {
+	return contextbuilder.QueryOptions{
+		WorkingTree: r.WorkingTree,
+		Filter: vectorstore.SearchFilter{
+			Paths:        r.Paths,
+			Languages:    r.Languages,
+			ExcludeTests: r.ExcludeTests,
+			DocTypes:     r.DocTypes,
+		},
+	}
 }
 
 // AskResponse is the response body for the /ask endpoint
 type AskResponse struct {
 	Answer       string  `json:"answer"`
 	Model        string  `json:"model"`
+	Provider     string  `json:"provider,omitempty"` // Provider in the fallback chain that answered
 	InputTokens  int     `json:"input_tokens"`
 	OutputTokens int     `json:"output_tokens"`
 	CachedTokens int     `json:"cached_tokens"`
@@ -38,7 +60,7 @@ func (s *Server) handleAsk(c *gin.Context) {
 	}
 
 	// Ask the agent
-	response, err := s.agent.Ask(c.Request.Context(), req.Question)
+	response, err := s.agent.AskWithOptions(c.Request.Context(), req.Question, req.queryOptions())
 	if err != nil {
 		s.logger.Error().Err(err).Msg("Agent.Ask failed")
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
@@ -51,6 +73,7 @@ func (s *Server) handleAsk(c *gin.Context) {
 	c.JSON(http.StatusOK, AskResponse{
 		Answer:       response.Content,
 		Model:        response.Model,
+		Provider:     response.Provider,
 		InputTokens:  response.InputTokens,
 		OutputTokens: response.OutputTokens,
 		CachedTokens: response.CachedTokens,
@@ -59,20 +82,30 @@ func (s *Server) handleAsk(c *gin.Context) {
 
 // HealthResponse is the response body for /health
 type HealthResponse struct {
-	Status string `json:"status"`
-	Repo   string `json:"repo"`
-	Model  string `json:"model"`
+	Status    string              `json:"status"`
+	Repo      string              `json:"repo"`
+	Model     string              `json:"model"`
+	Providers []resilience.Status `json:"providers"`
 }
 
 // handleHealth handles GET /health requests
 func (s *Server) handleHealth(c *gin.Context) {
 	c.JSON(http.StatusOK, HealthResponse{
-		Status: "healthy",
-		Repo:   s.agent.GetRepoName(),
-		Model:  s.agent.GetModel(),
+		Status:    healthStatus(),
+		Repo:      s.agent.GetRepoName(),
+		Model:     s.agent.GetModel(),
+		Providers: resilience.Statuses(),
 	})
 }
 
+// healthStatus is "degraded" while any provider circuit breaker is not closed
+func healthStatus() string {
+	if resilience.Degraded() {
+		return "degraded"
+	}
+	return "healthy"
+}
+
 // InfoResponse is the response body for /info
 type InfoResponse struct {
 	RepoName string `json:"repo_name"`
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/First008/mesh/internal/gateway"
+	"github.com/gin-gonic/gin"
+)
+
+// bitbucketRef is a branch or tag reference in a Bitbucket Cloud push change
+type bitbucketRef struct {
+	Type   string `json:"type"` // "branch" or "tag"
+	Name   string `json:"name"`
+	Target struct {
+		Hash string `json:"hash"`
+	} `json:"target"`
+}
+
+// bitbucketRepository identifies a Bitbucket Cloud repository
+type bitbucketRepository struct {
+	Name     string `json:"name"`
+	FullName string `json:"full_name"` // workspace/repo-slug
+}
+
+// BitbucketWebhookEvent represents the fields MESH uses from Bitbucket Cloud
+// repo:push and pullrequest:* webhooks (simplified)
+type BitbucketWebhookEvent struct {
+	Repository bitbucketRepository `json:"repository"`
+	Push       struct {
+		Changes []struct {
+			Old *bitbucketRef `json:"old"` // nil when the branch was created
+			New *bitbucketRef `json:"new"` // nil when the branch was deleted
+		} `json:"changes"`
+	} `json:"push"`
+	PullRequest struct {
+		Source struct {
+			Branch struct {
+				Name string `json:"name"`
+			} `json:"branch"`
+			Repository bitbucketRepository `json:"repository"`
+		} `json:"source"`
+		Destination struct {
+			Branch struct {
+				Name string `json:"name"`
+			} `json:"branch"`
+			Repository bitbucketRepository `json:"repository"`
+		} `json:"destination"`
+	} `json:"pullrequest"`
+}
+
+// handleBitbucketWebhook handles Bitbucket Cloud webhooks
+func (s *GatewayServer) handleBitbucketWebhook(c *gin.Context) {
+	secret := s.gateway.WebhookConfig().BitbucketSecret
+	s.handleWebhook(c, "bitbucket", verifyBitbucketSignature(secret), parseBitbucketWebhook)
+}
+
+// verifyBitbucketSignature checks X-Hub-Signature ("sha256=<hex hmac>")
+func verifyBitbucketSignature(secret string) webhookVerifier {
+	return func(header http.Header, body []byte) error {
+		if secret == "" {
+			return nil
+		}
+		signature, ok := strings.CutPrefix(header.Get("X-Hub-Signature"), "sha256=")
+		if !ok || !validHMACSHA256(secret, body, signature) {
+			return errInvalidSignature
+		}
+		return nil
+	}
+}
+
+// parseBitbucketWebhook normalizes Bitbucket Cloud push and pull request events.
+// A single push may carry several branch changes, each becoming one event.
+func parseBitbucketWebhook(header http.Header, body []byte) ([]gateway.BranchEvent, error) {
+	var event BitbucketWebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+
+	base := gateway.BranchEvent{
+		Provider: "bitbucket",
+		Repo:     bitbucketSlug(event.Repository),
+		FullName: event.Repository.FullName,
+	}
+
+	switch header.Get("X-Event-Key") {
+	case "repo:push":
+		var events []gateway.BranchEvent
+		for _, change := range event.Push.Changes {
+			ev := base
+			switch {
+			case change.New != nil && change.New.Type == "branch":
+				ev.Branch = change.New.Name
+				ev.After = change.New.Target.Hash
+				ev.Action = gateway.BranchUpdated
+				if change.Old != nil {
+					ev.Before = change.Old.Target.Hash
+				}
+			case change.New == nil && change.Old != nil && change.Old.Type == "branch":
+				ev.Branch = change.Old.Name
+				ev.Before = change.Old.Target.Hash
+				ev.Action = gateway.BranchDeleted
+			default:
+				continue // Tag changes
+			}
+			events = append(events, ev)
+		}
+		return events, nil
+
+	case "pullrequest:created", "pullrequest:updated":
+		pr := event.PullRequest
+		// Branches of forks do not exist in our clone
+		if pr.Source.Repository.FullName != pr.Destination.Repository.FullName {
+			return nil, nil
+		}
+		base.Branch = pr.Source.Branch.Name
+		base.Action = gateway.BranchUpdated
+		return []gateway.BranchEvent{base}, nil
+
+	case "pullrequest:fulfilled":
+		base.Branch = event.PullRequest.Destination.Branch.Name
+		base.Action = gateway.BranchUpdated
+		return []gateway.BranchEvent{base}, nil
+
+	default:
+		return nil, nil
+	}
+}
+
+// bitbucketSlug returns the repository slug, which Bitbucket only exposes via full_name
+func bitbucketSlug(repo bitbucketRepository) string {
+	if idx := strings.LastIndex(repo.FullName, "/"); idx >= 0 {
+		return repo.FullName[idx+1:]
+	}
+	return repo.Name
+}
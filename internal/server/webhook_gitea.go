@@ -0,0 +1,122 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/First008/mesh/internal/gateway"
+	"github.com/gin-gonic/gin"
+)
+
+// GiteaWebhookEvent represents the fields MESH uses from Gitea/Forgejo
+// push, delete and pull request webhooks (simplified)
+type GiteaWebhookEvent struct {
+	Ref        string `json:"ref"`      // refs/heads/main for push, bare name for delete
+	RefType    string `json:"ref_type"` // "branch" or "tag" for delete events
+	Before     string `json:"before"`
+	After      string `json:"after"`
+	Action     string `json:"action"` // Pull request action: "opened", "synchronized", "closed", ...
+	Repository struct {
+		Name     string `json:"name"`
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	PullRequest struct {
+		Merged bool `json:"merged"`
+		Head   struct {
+			Ref    string `json:"ref"`
+			RepoID int    `json:"repo_id"`
+		} `json:"head"`
+		Base struct {
+			Ref    string `json:"ref"`
+			RepoID int    `json:"repo_id"`
+		} `json:"base"`
+	} `json:"pull_request"`
+}
+
+// handleGiteaWebhook handles Gitea and Forgejo webhooks
+func (s *GatewayServer) handleGiteaWebhook(c *gin.Context) {
+	secret := s.gateway.WebhookConfig().GiteaSecret
+	s.handleWebhook(c, "gitea", verifyGiteaSignature(secret), parseGiteaWebhook)
+}
+
+// verifyGiteaSignature checks the hex HMAC-SHA256 signature sent by
+// Gitea (X-Gitea-Signature) or Forgejo (X-Forgejo-Signature)
+func verifyGiteaSignature(secret string) webhookVerifier {
+	return func(header http.Header, body []byte) error {
+		if secret == "" {
+			return nil
+		}
+		signature := header.Get("X-Gitea-Signature")
+		if signature == "" {
+			signature = header.Get("X-Forgejo-Signature")
+		}
+		if !validHMACSHA256(secret, body, signature) {
+			return errInvalidSignature
+		}
+		return nil
+	}
+}
+
+// giteaEventType returns the event name from the Gitea or Forgejo header
+func giteaEventType(header http.Header) string {
+	if eventType := header.Get("X-Gitea-Event"); eventType != "" {
+		return eventType
+	}
+	return header.Get("X-Forgejo-Event")
+}
+
+// parseGiteaWebhook normalizes Gitea/Forgejo push, delete and pull request events
+func parseGiteaWebhook(header http.Header, body []byte) ([]gateway.BranchEvent, error) {
+	var event GiteaWebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+
+	base := gateway.BranchEvent{
+		Provider: "gitea",
+		Repo:     event.Repository.Name,
+		FullName: event.Repository.FullName,
+	}
+
+	switch giteaEventType(header) {
+	case "push":
+		branch, ok := branchFromRef(event.Ref)
+		if !ok {
+			return nil, nil
+		}
+		base.Branch = branch
+		base.Before = event.Before
+		base.After = event.After
+		base.Action = pushAction(false, event.After)
+		return []gateway.BranchEvent{base}, nil
+
+	case "delete":
+		if event.RefType != "branch" {
+			return nil, nil
+		}
+		base.Branch = event.Ref
+		base.Action = gateway.BranchDeleted
+		return []gateway.BranchEvent{base}, nil
+
+	case "pull_request":
+		pr := event.PullRequest
+		switch {
+		case event.Action == "closed" && pr.Merged:
+			base.Branch = pr.Base.Ref
+		case event.Action == "opened" || event.Action == "reopened" || event.Action == "synchronized":
+			// Branches of forks do not exist in our clone
+			if pr.Head.RepoID != pr.Base.RepoID {
+				return nil, nil
+			}
+			base.Branch = pr.Head.Ref
+		default:
+			return nil, nil
+		}
+		base.Action = gateway.BranchUpdated
+		return []gateway.BranchEvent{base}, nil
+
+	default:
+		return nil, nil
+	}
+}
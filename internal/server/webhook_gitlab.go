@@ -0,0 +1,108 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/First008/mesh/internal/gateway"
+	"github.com/gin-gonic/gin"
+)
+
+// GitLabWebhookEvent represents the fields MESH uses from GitLab push,
+// tag push and merge request hooks (simplified)
+type GitLabWebhookEvent struct {
+	ObjectKind string `json:"object_kind"` // "push", "tag_push", "merge_request"
+	Ref        string `json:"ref"`
+	Before     string `json:"before"`
+	After      string `json:"after"`
+	Project    struct {
+		ID                int    `json:"id"`
+		Name              string `json:"name"`
+		Path              string `json:"path"`
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		Action          string `json:"action"` // "open", "update", "reopen", "merge", "close"
+		SourceBranch    string `json:"source_branch"`
+		TargetBranch    string `json:"target_branch"`
+		SourceProjectID int    `json:"source_project_id"`
+		TargetProjectID int    `json:"target_project_id"`
+		LastCommit      struct {
+			ID string `json:"id"`
+		} `json:"last_commit"`
+	} `json:"object_attributes"`
+}
+
+// handleGitLabWebhook handles GitLab push, tag push and merge request hooks
+func (s *GatewayServer) handleGitLabWebhook(c *gin.Context) {
+	token := s.gateway.WebhookConfig().GitLabToken
+	s.handleWebhook(c, "gitlab", verifyGitLabToken(token), parseGitLabWebhook)
+}
+
+// verifyGitLabToken checks the X-Gitlab-Token secret token header
+func verifyGitLabToken(token string) webhookVerifier {
+	return func(header http.Header, body []byte) error {
+		if token == "" {
+			return nil
+		}
+		if !validToken(token, header.Get("X-Gitlab-Token")) {
+			return errInvalidSignature
+		}
+		return nil
+	}
+}
+
+// parseGitLabWebhook normalizes GitLab hooks.
+// Tag pushes are acknowledged but ignored (only branches are indexed).
+// Merge requests re-index the source branch while open and the target branch once merged.
+func parseGitLabWebhook(header http.Header, body []byte) ([]gateway.BranchEvent, error) {
+	var event GitLabWebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+
+	base := gateway.BranchEvent{
+		Provider: "gitlab",
+		Repo:     event.Project.Path,
+		FullName: event.Project.PathWithNamespace,
+	}
+	if base.Repo == "" {
+		base.Repo = event.Project.Name
+	}
+
+	switch event.ObjectKind {
+	case "push":
+		branch, ok := branchFromRef(event.Ref)
+		if !ok {
+			return nil, nil
+		}
+		base.Branch = branch
+		base.Before = event.Before
+		base.After = event.After
+		base.Action = pushAction(false, event.After)
+		return []gateway.BranchEvent{base}, nil
+
+	case "merge_request":
+		attrs := event.ObjectAttributes
+		switch attrs.Action {
+		case "open", "update", "reopen":
+			// Branches of forks do not exist in our clone
+			if attrs.SourceProjectID != attrs.TargetProjectID {
+				return nil, nil
+			}
+			base.Branch = attrs.SourceBranch
+			base.After = attrs.LastCommit.ID
+		case "merge":
+			base.Branch = attrs.TargetBranch
+		default:
+			return nil, nil
+		}
+		base.Action = gateway.BranchUpdated
+		return []gateway.BranchEvent{base}, nil
+
+	default:
+		// "tag_push" and other hooks carry no branch changes
+		return nil, nil
+	}
+}
@@ -1,16 +1,44 @@
 package server
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
 
+	"github.com/First008/mesh/internal/gateway"
+	"github.com/First008/mesh/internal/vectorstore"
 	"github.com/gin-gonic/gin"
 )
 
+// maxWebhookBodyBytes caps webhook payloads (GitHub's own limit is 25MB)
+const maxWebhookBodyBytes = 25 << 20
+
+// zeroSHA is sent as the before/after commit when a branch is created/deleted
+const zeroSHA = "0000000000000000000000000000000000000000"
+
+// errInvalidSignature is returned when a webhook fails token/signature validation
+var errInvalidSignature = errors.New("invalid webhook signature")
+
+// webhookVerifier validates a delivery against the provider's native scheme
+type webhookVerifier func(header http.Header, body []byte) error
+
+// webhookParser normalizes a delivery into branch events.
+// An empty result means the event is valid but irrelevant (ping, tag, closed PR...).
+type webhookParser func(header http.Header, body []byte) ([]gateway.BranchEvent, error)
+
 // GitHubPushEvent represents a GitHub push webhook payload (simplified)
 type GitHubPushEvent struct {
-	Ref        string `json:"ref"`    // refs/heads/main
-	Before     string `json:"before"` // Previous commit SHA
-	After      string `json:"after"`  // New commit SHA
+	Ref        string `json:"ref"`     // refs/heads/main
+	Before     string `json:"before"`  // Previous commit SHA
+	After      string `json:"after"`   // New commit SHA
+	Deleted    bool   `json:"deleted"` // True when the push deleted the ref
 	Repository struct {
 		Name     string `json:"name"`
 		FullName string `json:"full_name"` // owner/repo
@@ -19,59 +47,180 @@ type GitHubPushEvent struct {
 
 // handleGitHubWebhook handles GitHub push webhooks for automatic re-indexing
 func (s *GatewayServer) handleGitHubWebhook(c *gin.Context) {
+	secret := s.gateway.WebhookConfig().GitHubSecret
+	s.handleWebhook(c, "github", verifyGitHubSignature(secret), parseGitHubWebhook)
+}
+
+// verifyGitHubSignature checks X-Hub-Signature-256 ("sha256=<hex hmac>")
+func verifyGitHubSignature(secret string) webhookVerifier {
+	return func(header http.Header, body []byte) error {
+		if secret == "" {
+			return nil
+		}
+		signature, ok := strings.CutPrefix(header.Get("X-Hub-Signature-256"), "sha256=")
+		if !ok || !validHMACSHA256(secret, body, signature) {
+			return errInvalidSignature
+		}
+		return nil
+	}
+}
+
+// parseGitHubWebhook normalizes GitHub push events
+func parseGitHubWebhook(header http.Header, body []byte) ([]gateway.BranchEvent, error) {
+	// Deliveries without the header are treated as push (backward compatible)
+	if eventType := header.Get("X-GitHub-Event"); eventType != "" && eventType != "push" {
+		return nil, nil
+	}
+
 	var event GitHubPushEvent
-	if err := c.ShouldBindJSON(&event); err != nil {
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+
+	branch, ok := branchFromRef(event.Ref)
+	if !ok {
+		return nil, nil // Tag push
+	}
+
+	return []gateway.BranchEvent{{
+		Provider: "github",
+		Repo:     event.Repository.Name,
+		FullName: event.Repository.FullName,
+		Branch:   branch,
+		Before:   event.Before,
+		After:    event.After,
+		Action:   pushAction(event.Deleted, event.After),
+	}}, nil
+}
+
+// handleWebhook validates, normalizes and dispatches a webhook delivery
+func (s *GatewayServer) handleWebhook(c *gin.Context, provider string, verify webhookVerifier, parse webhookParser) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBodyBytes))
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "invalid webhook payload",
+			"error": "failed to read webhook payload",
 		})
 		return
 	}
 
-	// Extract branch from ref (refs/heads/main -> main)
-	branch := ""
-	if len(event.Ref) > 11 && event.Ref[:11] == "refs/heads/" {
-		branch = event.Ref[11:]
-	} else {
+	if err := verify(c.Request.Header, body); err != nil {
+		s.logger.Warn().
+			Err(err).
+			Str("provider", provider).
+			Str("client_ip", c.ClientIP()).
+			Msg("Rejected webhook")
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	events, err := parse(c.Request.Header, body)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "not a branch push event",
+			"error": err.Error(),
 		})
 		return
 	}
 
-	s.logger.Info().
-		Str("repo", event.Repository.Name).
-		Str("branch", branch).
-		Str("before", event.Before[:8]).
-		Str("after", event.After[:8]).
-		Msg("Received GitHub webhook")
-
-	// Find matching repo in gateway by repository name
-	// GitHub sends "repo-name", we need to match it to our configured repos
-	repoName := event.Repository.Name
-
-	// Trigger re-indexing for this branch
-	if err := s.gateway.ReindexBranch(c.Request.Context(), repoName, branch); err != nil {
-		s.logger.Error().
-			Err(err).
-			Str("repo", repoName).
-			Str("branch", branch).
-			Msg("Failed to trigger webhook re-index")
+	for _, event := range events {
+		if err := vectorstore.ValidateBranchName(event.Branch); err != nil {
+			s.logger.Warn().
+				Err(err).
+				Str("provider", provider).
+				Str("client_ip", c.ClientIP()).
+				Msg("Rejected webhook")
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "failed to trigger re-index",
+	if len(events) == 0 {
+		s.logger.Debug().Str("provider", provider).Msg("Ignoring webhook without branch changes")
+		c.JSON(http.StatusOK, gin.H{
+			"status":   "ignored",
+			"provider": provider,
 		})
 		return
 	}
 
 	s.logger.Info().
-		Str("repo", repoName).
-		Str("branch", branch).
-		Msg("Webhook re-index triggered successfully")
-
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "success",
-		"repo":    repoName,
-		"branch":  branch,
-		"message": "Re-index triggered",
+		Str("provider", provider).
+		Int("events", len(events)).
+		Msg("Received webhook")
+
+	results := make([]gin.H, 0, len(events))
+	failed, queued := 0, 0
+	for _, event := range events {
+		result := gin.H{
+			"repo":   event.Repo,
+			"branch": event.Branch,
+			"action": event.Action,
+		}
+
+		job, err := s.gateway.HandleBranchEvent(c.Request.Context(), event)
+		if err != nil {
+			s.logger.Error().
+				Err(err).
+				Str("provider", provider).
+				Str("repo", event.Repo).
+				Str("branch", event.Branch).
+				Msg("Failed to handle webhook event")
+			result["error"] = err.Error()
+			failed++
+		} else if job != nil {
+			result["job_id"] = job.ID
+			queued++
+		}
+		results = append(results, result)
+	}
+
+	status := http.StatusOK
+	statusText := "success"
+	if queued > 0 {
+		status = http.StatusAccepted
+		statusText = "accepted"
+	}
+	if failed > 0 {
+		status = http.StatusInternalServerError
+		statusText = "error"
+	}
+
+	c.JSON(status, gin.H{
+		"status":   statusText,
+		"provider": provider,
+		"events":   results,
 	})
 }
+
+// branchFromRef extracts the branch from a full ref (refs/heads/main -> main)
+func branchFromRef(ref string) (string, bool) {
+	branch, ok := strings.CutPrefix(ref, "refs/heads/")
+	return branch, ok && branch != ""
+}
+
+// pushAction maps a push to an update or, for deleted refs, a deletion
+func pushAction(deleted bool, after string) gateway.BranchAction {
+	if deleted || after == zeroSHA {
+		return gateway.BranchDeleted
+	}
+	return gateway.BranchUpdated
+}
+
+// validHMACSHA256 reports whether signatureHex is the HMAC-SHA256 of body
+func validHMACSHA256(secret string, body []byte, signatureHex string) bool {
+	signature, err := hex.DecodeString(strings.TrimSpace(signatureHex))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(signature, mac.Sum(nil))
+}
+
+// validToken compares a shared token in constant time
+func validToken(expected, actual string) bool {
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(actual)) == 1
+}
@@ -0,0 +1,261 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/First008/mesh/internal/gateway"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func headers(kv ...string) http.Header {
+	h := http.Header{}
+	for i := 0; i+1 < len(kv); i += 2 {
+		h.Set(kv[i], kv[i+1])
+	}
+	return h
+}
+
+func TestVerifyGitHubSignature(t *testing.T) {
+	body := `{"ref":"refs/heads/main"}`
+	verify := verifyGitHubSignature("s3cret")
+
+	if err := verify(headers("X-Hub-Signature-256", "sha256="+sign("s3cret", body)), []byte(body)); err != nil {
+		t.Errorf("Valid signature rejected: %v", err)
+	}
+	if err := verify(headers("X-Hub-Signature-256", "sha256="+sign("wrong", body)), []byte(body)); err == nil {
+		t.Error("Signature with wrong secret should be rejected")
+	}
+	if err := verify(headers(), []byte(body)); err == nil {
+		t.Error("Missing signature should be rejected when secret is configured")
+	}
+	if err := verifyGitHubSignature("")(headers(), []byte(body)); err != nil {
+		t.Errorf("Unsigned delivery should be accepted without secret: %v", err)
+	}
+}
+
+func TestVerifyGitLabToken(t *testing.T) {
+	verify := verifyGitLabToken("token-123")
+
+	if err := verify(headers("X-Gitlab-Token", "token-123"), nil); err != nil {
+		t.Errorf("Valid token rejected: %v", err)
+	}
+	if err := verify(headers("X-Gitlab-Token", "token-124"), nil); err == nil {
+		t.Error("Wrong token should be rejected")
+	}
+}
+
+func TestVerifyGiteaSignature(t *testing.T) {
+	body := `{"ref":"refs/heads/main"}`
+	verify := verifyGiteaSignature("s3cret")
+
+	if err := verify(headers("X-Gitea-Signature", sign("s3cret", body)), []byte(body)); err != nil {
+		t.Errorf("Valid Gitea signature rejected: %v", err)
+	}
+	if err := verify(headers("X-Forgejo-Signature", sign("s3cret", body)), []byte(body)); err != nil {
+		t.Errorf("Valid Forgejo signature rejected: %v", err)
+	}
+	if err := verify(headers("X-Gitea-Signature", sign("s3cret", body+" ")), []byte(body)); err == nil {
+		t.Error("Signature over different body should be rejected")
+	}
+}
+
+func TestVerifyBitbucketSignature(t *testing.T) {
+	body := `{"push":{}}`
+	verify := verifyBitbucketSignature("s3cret")
+
+	if err := verify(headers("X-Hub-Signature", "sha256="+sign("s3cret", body)), []byte(body)); err != nil {
+		t.Errorf("Valid signature rejected: %v", err)
+	}
+	if err := verify(headers("X-Hub-Signature", sign("s3cret", body)), []byte(body)); err == nil {
+		t.Error("Signature without sha256= prefix should be rejected")
+	}
+}
+
+func TestParseGitHubWebhook(t *testing.T) {
+	testCases := []struct {
+		name       string
+		eventType  string
+		body       string
+		wantEvents int
+		wantAction gateway.BranchAction
+	}{
+		{
+			name:       "branch push",
+			eventType:  "push",
+			body:       `{"ref":"refs/heads/feature/x","before":"aaa","after":"bbb","repository":{"name":"api","full_name":"org/api"}}`,
+			wantEvents: 1,
+			wantAction: gateway.BranchUpdated,
+		},
+		{
+			name:       "branch delete",
+			eventType:  "push",
+			body:       `{"ref":"refs/heads/old","before":"aaa","after":"` + zeroSHA + `","deleted":true,"repository":{"name":"api"}}`,
+			wantEvents: 1,
+			wantAction: gateway.BranchDeleted,
+		},
+		{
+			name:       "tag push",
+			eventType:  "push",
+			body:       `{"ref":"refs/tags/v1.0.0","repository":{"name":"api"}}`,
+			wantEvents: 0,
+		},
+		{
+			name:       "ping",
+			eventType:  "ping",
+			body:       `{"zen":"Keep it logically awesome."}`,
+			wantEvents: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			events, err := parseGitHubWebhook(headers("X-GitHub-Event", tc.eventType), []byte(tc.body))
+			if err != nil {
+				t.Fatalf("parseGitHubWebhook failed: %v", err)
+			}
+			if len(events) != tc.wantEvents {
+				t.Fatalf("Expected %d events, got %d", tc.wantEvents, len(events))
+			}
+			if tc.wantEvents > 0 && events[0].Action != tc.wantAction {
+				t.Errorf("Expected action %s, got %s", tc.wantAction, events[0].Action)
+			}
+		})
+	}
+}
+
+func TestParseGitLabWebhook(t *testing.T) {
+	push := `{"object_kind":"push","ref":"refs/heads/main","before":"aaa","after":"bbb",
+		"project":{"id":1,"name":"API","path":"api","path_with_namespace":"team/api"}}`
+	events, err := parseGitLabWebhook(headers("X-Gitlab-Event", "Push Hook"), []byte(push))
+	if err != nil {
+		t.Fatalf("parseGitLabWebhook failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Branch != "main" || events[0].Repo != "api" || events[0].FullName != "team/api" {
+		t.Errorf("Unexpected push events: %+v", events)
+	}
+
+	deleted := `{"object_kind":"push","ref":"refs/heads/gone","after":"` + zeroSHA + `","project":{"path":"api"}}`
+	events, _ = parseGitLabWebhook(headers(), []byte(deleted))
+	if len(events) != 1 || events[0].Action != gateway.BranchDeleted {
+		t.Errorf("Expected branch deletion, got %+v", events)
+	}
+
+	tag := `{"object_kind":"tag_push","ref":"refs/tags/v1","project":{"path":"api"}}`
+	events, _ = parseGitLabWebhook(headers(), []byte(tag))
+	if len(events) != 0 {
+		t.Errorf("Tag push should be ignored, got %+v", events)
+	}
+
+	mrOpen := `{"object_kind":"merge_request","project":{"path":"api"},
+		"object_attributes":{"action":"open","source_branch":"feat","target_branch":"main","source_project_id":1,"target_project_id":1}}`
+	events, _ = parseGitLabWebhook(headers(), []byte(mrOpen))
+	if len(events) != 1 || events[0].Branch != "feat" {
+		t.Errorf("Open MR should re-index source branch, got %+v", events)
+	}
+
+	mrMerged := `{"object_kind":"merge_request","project":{"path":"api"},
+		"object_attributes":{"action":"merge","source_branch":"feat","target_branch":"main","source_project_id":1,"target_project_id":1}}`
+	events, _ = parseGitLabWebhook(headers(), []byte(mrMerged))
+	if len(events) != 1 || events[0].Branch != "main" {
+		t.Errorf("Merged MR should re-index target branch, got %+v", events)
+	}
+
+	mrFork := `{"object_kind":"merge_request","project":{"path":"api"},
+		"object_attributes":{"action":"update","source_branch":"feat","target_branch":"main","source_project_id":2,"target_project_id":1}}`
+	events, _ = parseGitLabWebhook(headers(), []byte(mrFork))
+	if len(events) != 0 {
+		t.Errorf("MR from fork should be ignored, got %+v", events)
+	}
+}
+
+func TestParseGiteaWebhook(t *testing.T) {
+	push := `{"ref":"refs/heads/dev","before":"aaa","after":"bbb","repository":{"name":"api","full_name":"team/api"}}`
+	events, err := parseGiteaWebhook(headers("X-Gitea-Event", "push"), []byte(push))
+	if err != nil {
+		t.Fatalf("parseGiteaWebhook failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Branch != "dev" || events[0].Action != gateway.BranchUpdated {
+		t.Errorf("Unexpected push events: %+v", events)
+	}
+
+	del := `{"ref":"dev","ref_type":"branch","repository":{"name":"api"}}`
+	events, _ = parseGiteaWebhook(headers("X-Forgejo-Event", "delete"), []byte(del))
+	if len(events) != 1 || events[0].Branch != "dev" || events[0].Action != gateway.BranchDeleted {
+		t.Errorf("Expected Forgejo branch deletion, got %+v", events)
+	}
+
+	tagDel := `{"ref":"v1","ref_type":"tag","repository":{"name":"api"}}`
+	events, _ = parseGiteaWebhook(headers("X-Gitea-Event", "delete"), []byte(tagDel))
+	if len(events) != 0 {
+		t.Errorf("Tag deletion should be ignored, got %+v", events)
+	}
+
+	merged := `{"action":"closed","repository":{"name":"api"},
+		"pull_request":{"merged":true,"head":{"ref":"feat","repo_id":1},"base":{"ref":"main","repo_id":1}}}`
+	events, _ = parseGiteaWebhook(headers("X-Gitea-Event", "pull_request"), []byte(merged))
+	if len(events) != 1 || events[0].Branch != "main" {
+		t.Errorf("Merged PR should re-index base branch, got %+v", events)
+	}
+}
+
+func TestParseBitbucketWebhook(t *testing.T) {
+	push := `{"repository":{"name":"API","full_name":"team/api"},"push":{"changes":[
+		{"old":{"type":"branch","name":"main","target":{"hash":"aaa"}},"new":{"type":"branch","name":"main","target":{"hash":"bbb"}}},
+		{"old":{"type":"branch","name":"stale","target":{"hash":"ccc"}},"new":null},
+		{"old":null,"new":{"type":"tag","name":"v1","target":{"hash":"ddd"}}}
+	]}}`
+	events, err := parseBitbucketWebhook(headers("X-Event-Key", "repo:push"), []byte(push))
+	if err != nil {
+		t.Fatalf("parseBitbucketWebhook failed: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 branch events, got %d: %+v", len(events), events)
+	}
+	if events[0].Repo != "api" || events[0].Branch != "main" || events[0].Action != gateway.BranchUpdated {
+		t.Errorf("Unexpected first event: %+v", events[0])
+	}
+	if events[1].Branch != "stale" || events[1].Action != gateway.BranchDeleted {
+		t.Errorf("Unexpected second event: %+v", events[1])
+	}
+
+	fulfilled := `{"repository":{"full_name":"team/api"},"pullrequest":{
+		"source":{"branch":{"name":"feat"},"repository":{"full_name":"team/api"}},
+		"destination":{"branch":{"name":"main"},"repository":{"full_name":"team/api"}}}}`
+	events, _ = parseBitbucketWebhook(headers("X-Event-Key", "pullrequest:fulfilled"), []byte(fulfilled))
+	if len(events) != 1 || events[0].Branch != "main" {
+		t.Errorf("Fulfilled PR should re-index destination branch, got %+v", events)
+	}
+}
+
+func TestHandleWebhook_RejectsInvalidBranch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// No gateway: invalid deliveries must be rejected before reaching it
+	s := &GatewayServer{logger: zerolog.New(io.Discard)}
+
+	for _, ref := range []string{"refs/heads/..", "refs/heads/.", "refs/heads/a/../b"} {
+		body := `{"ref":"` + ref + `","after":"` + zeroSHA + `","deleted":true,"repository":{"name":"api"}}`
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/webhooks/github", strings.NewReader(body))
+		c.Request.Header.Set("X-GitHub-Event", "push")
+
+		s.handleWebhook(c, "github", verifyGitHubSignature(""), parseGitHubWebhook)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d: %s", ref, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package symbols
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+// extractGo extracts a Go file's symbols from its syntax tree. Returns nil if
+// the file doesn't parse, for the line patterns to take over.
+func extractGo(path, content string) *File {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+
+	lines := strings.Split(content, "\n")
+	f := &File{}
+	pkg := file.Name.Name
+	for _, imp := range file.Imports {
+		if importPath, err := strconv.Unquote(imp.Path.Value); err == nil {
+			f.Imports = append(f.Imports, importPath)
+		}
+	}
+	define := func(name *ast.Ident, kind Kind, container string, node ast.Node) {
+		if name == nil || name.Name == "_" {
+			return
+		}
+		line := fset.Position(name.Pos()).Line
+		f.Definitions = append(f.Definitions, Definition{
+			Name:      name.Name,
+			Kind:      kind,
+			Path:      path,
+			Line:      fset.Position(node.Pos()).Line,
+			EndLine:   fset.Position(node.End()).Line,
+			Container: container,
+			Package:   pkg,
+			Signature: strings.TrimSpace(lines[line-1]),
+		})
+	}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				define(d.Name, KindMethod, receiverType(d.Recv.List[0].Type), d)
+			} else {
+				define(d.Name, KindFunction, "", d)
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					kind := KindType
+					switch t := s.Type.(type) {
+					case *ast.StructType:
+						kind = KindStruct
+					case *ast.InterfaceType:
+						kind = KindInterface
+						for _, m := range t.Methods.List {
+							if _, ok := m.Type.(*ast.FuncType); ok && len(m.Names) > 0 {
+								define(m.Names[0], KindMethod, s.Name.Name, m)
+							}
+						}
+					}
+					define(s.Name, kind, "", s)
+				case *ast.ValueSpec:
+					kind := KindVar
+					if d.Tok == token.CONST {
+						kind = KindConst
+					}
+					for _, name := range s.Names {
+						define(name, kind, "", s)
+					}
+				}
+			}
+		}
+	}
+
+	// References: every identifier other than declared names, locals,
+	// imported package names and Go's predeclared identifiers. Fields and
+	// methods (x.name, Type{name: ...}) are references even when a local
+	// shares their name.
+	skip := goImportNames(file)
+	locals := goLocals(file)
+	for _, def := range f.Definitions {
+		delete(locals, def.Name) // A name the file also defines stays
+	}
+	declared := make(map[*ast.Ident]bool)
+	reference := func(id *ast.Ident) {
+		declared[id] = true
+		if !skip[id.Name] && id.Name != "_" {
+			f.addReference(id.Name, fset.Position(id.Pos()).Line)
+		}
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.File:
+			declared[n.Name] = true
+		case *ast.FuncDecl:
+			declared[n.Name] = true
+		case *ast.TypeSpec:
+			declared[n.Name] = true
+		case *ast.ValueSpec:
+			for _, name := range n.Names {
+				declared[name] = true
+			}
+		case *ast.Field:
+			for _, name := range n.Names {
+				declared[name] = true
+			}
+		case *ast.SelectorExpr:
+			reference(n.Sel)
+		case *ast.CompositeLit:
+			for _, elt := range n.Elts {
+				if kv, ok := elt.(*ast.KeyValueExpr); ok {
+					if key, ok := kv.Key.(*ast.Ident); ok {
+						reference(key)
+					}
+				}
+			}
+		case *ast.ImportSpec:
+			return false
+		case *ast.Ident:
+			if declared[n] || locals[n.Name] || types.Universe.Lookup(n.Name) != nil {
+				return true
+			}
+			reference(n)
+		}
+		return true
+	})
+	return f
+}
+
+// receiverType returns the type name of a method receiver: "Server" for
+// "*Server" and "List[T]"
+func receiverType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverType(t.X)
+	case *ast.IndexExpr:
+		return receiverType(t.X)
+	case *ast.IndexListExpr:
+		return receiverType(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// goImportNames returns the names a file refers to its imports by
+func goImportNames(file *ast.File) map[string]bool {
+	names := make(map[string]bool)
+	for _, imp := range file.Imports {
+		if imp.Name != nil {
+			names[imp.Name.Name] = true
+			continue
+		}
+		importPath, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := importPath[strings.LastIndex(importPath, "/")+1:]
+		// "gopkg.in/yaml.v3" and "github.com/x/y/v2" are used as yaml and y
+		if i := strings.Index(name, "."); i > 0 {
+			name = name[:i]
+		}
+		if len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+			parent := strings.TrimSuffix(importPath, "/"+name)
+			name = parent[strings.LastIndex(parent, "/")+1:]
+		}
+		names[strings.TrimPrefix(name, "go-")] = true
+	}
+	return names
+}
+
+// goLocals returns the names declared as locals of a file's functions:
+// parameters, results, receivers and := or range variables. "err", "ctx"
+// and "i" would otherwise be the most referenced names in any Go repository.
+func goLocals(file *ast.File) map[string]bool {
+	locals := make(map[string]bool)
+	addFields := func(list *ast.FieldList) {
+		if list == nil {
+			return
+		}
+		for _, field := range list.List {
+			for _, name := range field.Names {
+				locals[name.Name] = true
+			}
+		}
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncType:
+			addFields(n.Params)
+			addFields(n.Results)
+		case *ast.FuncDecl:
+			addFields(n.Recv)
+		case *ast.AssignStmt:
+			if n.Tok == token.DEFINE {
+				for _, lhs := range n.Lhs {
+					if id, ok := lhs.(*ast.Ident); ok {
+						locals[id.Name] = true
+					}
+				}
+			}
+		case *ast.RangeStmt:
+			for _, expr := range []ast.Expr{n.Key, n.Value} {
+				if id, ok := expr.(*ast.Ident); ok {
+					locals[id.Name] = true
+				}
+			}
+		}
+		return true
+	})
+	return locals
+}
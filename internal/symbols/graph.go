@@ -0,0 +1,329 @@
+package symbols
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	maxDependencies     = 20 // Dependencies kept per file, heaviest first
+	maxScopeDefinitions = 3  // Names defined in more files in scope are too ambiguous to link
+)
+
+// extensionFamilies group extensions whose files can use each other's names
+var extensionFamilies = map[string]string{
+	".tsx": ".ts", ".js": ".ts", ".jsx": ".ts", ".mjs": ".ts", ".cjs": ".ts",
+	".kt": ".java", ".scala": ".java",
+	".h": ".c", ".hh": ".c", ".hpp": ".c", ".cc": ".c", ".cpp": ".c", ".cxx": ".c",
+}
+
+// packageFiles are the stems of files that stand for their directory when it
+// is imported ("pkg/__init__.py" for "import pkg")
+var packageFiles = map[string]bool{"__init__": true, "index": true, "mod": true}
+
+// Dependency is a file another file uses
+type Dependency struct {
+	Path   string `json:"path"`
+	Weight int    `json:"weight"` // References to names it defines, plus one if imported
+}
+
+// Graph maps each file to the files it depends on, heaviest first
+type Graph map[string][]Dependency
+
+// Related is a file reachable from a set of roots in a Graph
+type Related struct {
+	Path string
+	Hops int // Edges from the nearest root
+	From string
+}
+
+// BuildGraph links each file to the files defining the names it references
+// and the modules it imports. Names only resolve within a file's scope: its
+// own directory (Go packages, Java packages, neighbouring modules) and what it
+// imports, resolved by path suffix so no build manifest is needed.
+func BuildGraph(files map[string]*File) Graph {
+	paths := make([]string, 0, len(files))
+	for p, f := range files {
+		if f != nil {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	resolver := newModuleResolver(paths)
+	definedIn := make(map[string][]string)
+	for _, p := range paths {
+		seen := make(map[string]bool)
+		for _, def := range files[p].Definitions {
+			if !seen[def.Name] {
+				seen[def.Name] = true
+				definedIn[def.Name] = append(definedIn[def.Name], p)
+			}
+		}
+	}
+
+	graph := make(Graph)
+	for _, p := range paths {
+		f := files[p]
+		scopeDirs := map[string]bool{path.Dir(p): true}
+		imported := make(map[string]bool)
+		weights := make(map[string]int)
+		for _, imp := range f.Imports {
+			if strings.HasSuffix(p, ".go") {
+				if dir := resolver.goPackage(imp); dir != "" {
+					scopeDirs[dir] = true
+				}
+				continue
+			}
+			targets, dir := resolver.module(p, imp)
+			for _, target := range targets {
+				if target != p {
+					imported[target] = true
+					weights[target]++
+				}
+			}
+			if dir != "" {
+				scopeDirs[dir] = true
+			}
+		}
+
+		for name, lines := range f.References {
+			var targets []string
+			for _, q := range definedIn[name] {
+				if q == p || family(q) != family(p) {
+					continue
+				}
+				if imported[q] || scopeDirs[path.Dir(q)] {
+					targets = append(targets, q)
+				}
+			}
+			if len(targets) > maxScopeDefinitions {
+				continue
+			}
+			for _, q := range targets {
+				weights[q] += len(lines)
+			}
+		}
+
+		if deps := sortDependencies(weights); len(deps) > 0 {
+			graph[p] = deps
+		}
+	}
+	return graph
+}
+
+// sortDependencies orders dependencies heaviest first and keeps
+// maxDependencies of them
+func sortDependencies(weights map[string]int) []Dependency {
+	deps := make([]Dependency, 0, len(weights))
+	for p, weight := range weights {
+		if weight > 0 {
+			deps = append(deps, Dependency{Path: p, Weight: weight})
+		}
+	}
+	sort.Slice(deps, func(i, j int) bool {
+		if deps[i].Weight != deps[j].Weight {
+			return deps[i].Weight > deps[j].Weight
+		}
+		return deps[i].Path < deps[j].Path
+	})
+	if len(deps) > maxDependencies {
+		deps = deps[:maxDependencies]
+	}
+	return deps
+}
+
+// Related returns the files reachable from roots within hops edges, nearest
+// first and, at each distance, in root order and heaviest first. The roots
+// themselves are left out.
+func (g Graph) Related(roots []string, hops int) []Related {
+	seen := make(map[string]bool, len(roots))
+	for _, root := range roots {
+		seen[root] = true
+	}
+
+	var related []Related
+	frontier := roots
+	for hop := 1; hop <= hops && len(frontier) > 0; hop++ {
+		var next []string
+		for _, from := range frontier {
+			for _, dep := range g[from] {
+				if seen[dep.Path] {
+					continue
+				}
+				seen[dep.Path] = true
+				related = append(related, Related{Path: dep.Path, Hops: hop, From: from})
+				next = append(next, dep.Path)
+			}
+		}
+		frontier = next
+	}
+	return related
+}
+
+// family returns the extension of a path, folding those that share names
+func family(p string) string {
+	ext := path.Ext(p)
+	if shared, ok := extensionFamilies[ext]; ok {
+		return shared
+	}
+	return ext
+}
+
+// moduleResolver finds the files and directories of a repository that import
+// statements refer to
+type moduleResolver struct {
+	stems map[string][]string // Path without extension (or package directory) -> files
+	dirs  map[string]bool
+	bases map[string][]string // Last element -> stems and directories ending in it
+}
+
+func newModuleResolver(paths []string) *moduleResolver {
+	r := &moduleResolver{
+		stems: make(map[string][]string),
+		dirs:  make(map[string]bool),
+		bases: make(map[string][]string),
+	}
+	addKey := func(key string) {
+		base := path.Base(key)
+		for _, existing := range r.bases[base] {
+			if existing == key {
+				return
+			}
+		}
+		r.bases[base] = append(r.bases[base], key)
+	}
+
+	for _, p := range paths {
+		stem := strings.TrimSuffix(p, path.Ext(p))
+		r.stems[stem] = append(r.stems[stem], p)
+		addKey(stem)
+		dir := path.Dir(p)
+		if packageFiles[path.Base(stem)] && dir != "." {
+			r.stems[dir] = append(r.stems[dir], p)
+		}
+		for ; dir != "." && !r.dirs[dir]; dir = path.Dir(dir) {
+			r.dirs[dir] = true
+			addKey(dir)
+		}
+	}
+	return r
+}
+
+// goPackage returns the directory of a Go import path, the longest one it
+// ends with, or "" for packages outside the repository
+func (r *moduleResolver) goPackage(importPath string) string {
+	best := ""
+	for _, key := range r.bases[path.Base(importPath)] {
+		if r.dirs[key] && (importPath == key || strings.HasSuffix(importPath, "/"+key)) && len(key) > len(best) {
+			best = key
+		}
+	}
+	return best
+}
+
+// module returns the files an import in the file at from refers to, or the
+// directory when it names a package of several files (Java wildcards)
+func (r *moduleResolver) module(from, module string) ([]string, string) {
+	target, relative := normalizeModule(path.Dir(from), module)
+	// Imports may name a definition in the module ("crate::db::Pool",
+	// "com.acme.Util.method"), so its parent is tried too
+	for _, candidate := range []string{target, path.Dir(target)} {
+		if candidate == "" || candidate == "." {
+			break
+		}
+		if files, dir := r.resolve(candidate, relative); len(files) > 0 || dir != "" {
+			return files, dir
+		}
+	}
+	return nil, ""
+}
+
+// resolve finds the files or directory a normalized module path names
+func (r *moduleResolver) resolve(target string, relative bool) ([]string, string) {
+	if files := r.lookup(target); len(files) > 0 {
+		return files, ""
+	}
+	if r.dirs[target] && relative {
+		return nil, target
+	}
+	if relative {
+		return nil, ""
+	}
+
+	// Absolute modules are matched against path suffixes, since source roots
+	// ("src/", "src/main/java/") aren't part of the module name
+	for _, key := range r.bases[path.Base(target)] {
+		if key == target || strings.HasSuffix(key, "/"+target) {
+			if files := r.lookup(key); len(files) > 0 {
+				return files, ""
+			}
+			if r.dirs[key] {
+				return nil, key
+			}
+		}
+	}
+	return nil, ""
+}
+
+// lookup returns the files a module path names, with or without extension
+func (r *moduleResolver) lookup(target string) []string {
+	if files, ok := r.stems[target]; ok {
+		return files
+	}
+	if files, ok := r.stems[strings.TrimSuffix(target, path.Ext(target))]; ok {
+		for _, f := range files {
+			if f == target {
+				return []string{f}
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeModule turns an import into a slash-separated path, joined to
+// the importing directory when the import is relative
+func normalizeModule(dir, module string) (string, bool) {
+	switch {
+	case strings.Contains(module, "/"):
+		// Paths: "./api", "../lib/db", "pkg/util.h"
+		if strings.HasPrefix(module, ".") {
+			return path.Join(dir, module), true
+		}
+		return strings.Trim(module, "/"), false
+
+	case strings.HasPrefix(module, "."):
+		// Python relative imports: ".models", "..lib.db"
+		rest := strings.TrimLeft(module, ".")
+		for i := 1; i < len(module)-len(rest); i++ {
+			dir = path.Dir(dir)
+		}
+		return path.Join(dir, strings.ReplaceAll(rest, ".", "/")), true
+
+	case strings.Contains(module, "::"):
+		// Rust paths: "crate::db::Pool", "super::util"
+		parts := strings.Split(module, "::")
+		relative := false
+		switch parts[0] {
+		case "crate":
+			parts = parts[1:]
+		case "self":
+			parts, relative = parts[1:], true
+		case "super":
+			parts, relative, dir = parts[1:], true, path.Dir(dir)
+		}
+		target := strings.Join(parts, "/")
+		if relative {
+			target = path.Join(dir, target)
+		}
+		return target, relative
+
+	case strings.Contains(module, "\\"):
+		// PHP namespaces: "App\Models\User"
+		return strings.Trim(strings.ReplaceAll(module, "\\", "/"), "/"), false
+	}
+
+	// Dotted modules and classes: "app.models", "com.acme.User", "com.acme."
+	return strings.Trim(strings.ReplaceAll(module, ".", "/"), "/"), false
+}
@@ -0,0 +1,92 @@
+package symbols
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtract_Imports(t *testing.T) {
+	tests := []struct {
+		language string
+		content  string
+		want     []string
+	}{
+		{"go", "package api\n\nimport (\n\t\"fmt\"\n\tdb \"example.com/app/internal/db\"\n)\n", []string{"fmt", "example.com/app/internal/db"}},
+		{"python", "import os.path\nfrom .models import User\nfrom app.db import (\n    connect,\n)\n", []string{"os.path", ".models", "app.db"}},
+		{"typescript", "import { api } from './api'\nimport './styles.css'\nimport {\n  a,\n} from \"../lib/util\"\nconst fs = require('fs')\n", []string{"./api", "./styles.css", "../lib/util", "fs"}},
+		{"java", "package com.acme;\n\nimport com.acme.db.Store;\nimport static com.acme.Util.check;\n", []string{"com.acme.db.Store", "com.acme.Util.check"}},
+		{"rust", "use crate::db::Pool;\nmod handlers;\n", []string{"crate::db::Pool", "handlers"}},
+		{"c", "#include <stdio.h>\n#include \"util/strings.h\"\n", []string{"util/strings.h"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			var got []string
+			if f := Extract("file", tt.language, tt.content); f != nil {
+				got = f.Imports
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Imports = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildGraph(t *testing.T) {
+	sources := map[string]struct{ language, content string }{
+		// Go: same-package names and imported packages
+		"internal/api/handler.go": {"go", "package api\n\nimport \"example.com/app/internal/users\"\n\nfunc Handle(s *users.Service) { s.Create(); s.Create(); render() }\n"},
+		"internal/api/render.go":  {"go", "package api\n\nfunc render() {}\n"},
+		"internal/users/users.go": {"go", "package users\n\ntype Service struct{}\n\nfunc (s *Service) Create() {}\n"},
+		"internal/other/users.go": {"go", "package other\n\ntype Service struct{}\n"},
+		// Python: relative and absolute module imports
+		"src/app/views.py":  {"python", "from .models import User\nfrom app.db import connect\n\ndef index():\n    return User(connect())\n"},
+		"src/app/models.py": {"python", "class User:\n    pass\n"},
+		"src/app/db.py":     {"python", "def connect():\n    pass\n"},
+		// TypeScript: relative paths, with index files standing for directories
+		"web/page.tsx":       {"typescript", "import { fetchUsers } from './api'\n\nexport const Page = () => fetchUsers()\n"},
+		"web/api/index.ts":   {"typescript", "export async function fetchUsers() {}\n"},
+		"web/util/format.ts": {"typescript", "export function fetchUsers() {}\n"},
+	}
+	files := make(map[string]*File)
+	for path, src := range sources {
+		files[path] = Extract(path, src.language, src.content)
+	}
+
+	graph := BuildGraph(files)
+	want := Graph{
+		"internal/api/handler.go": {{"internal/users/users.go", 2}, {"internal/api/render.go", 1}},
+		"src/app/views.py":        {{"src/app/db.py", 2}, {"src/app/models.py", 2}},
+		"web/page.tsx":            {{"web/api/index.ts", 2}},
+	}
+	if !reflect.DeepEqual(graph, want) {
+		t.Errorf("BuildGraph = %v\nwant %v", graph, want)
+	}
+
+	related := graph.Related([]string{"internal/api/handler.go", "internal/api/render.go"}, 2)
+	if !reflect.DeepEqual(related, []Related{{"internal/users/users.go", 1, "internal/api/handler.go"}}) {
+		t.Errorf("Related = %v", related)
+	}
+}
+
+func TestGraphRelated(t *testing.T) {
+	graph := Graph{
+		"a": {{"b", 5}, {"c", 1}},
+		"b": {{"d", 2}, {"a", 1}},
+		"d": {{"e", 1}},
+	}
+
+	tests := []struct {
+		hops int
+		want []Related
+	}{
+		{0, nil},
+		{1, []Related{{"b", 1, "a"}, {"c", 1, "a"}}},
+		{2, []Related{{"b", 1, "a"}, {"c", 1, "a"}, {"d", 2, "b"}}},
+	}
+	for _, tt := range tests {
+		if got := graph.Related([]string{"a"}, tt.hops); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Related(a, %d) = %v, want %v", tt.hops, got, tt.want)
+		}
+	}
+}
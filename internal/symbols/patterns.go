@@ -0,0 +1,232 @@
+package symbols
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// keywordDefinition matches declarations introduced by a keyword:
+	// "def name", "pub fn name", "export default class Name", ...
+	keywordDefinition = regexp.MustCompile(`^\s*(?:(?:export|default|declare|pub(?:\([\w:]+\))?|public|private|protected|internal|static|abstract|final|async|open|data|sealed|inline|unsafe|partial)\s+)*` +
+		`(def|class|fn|fun|func|function|struct|enum|trait|impl|interface|object|module|type|record)\s+(?:self\.)?([A-Za-z_$][\w$]*)`)
+
+	// assignedFunction matches JavaScript functions assigned to a name:
+	// "const name = (...) =>", "export const name = async function"
+	assignedFunction = regexp.MustCompile(`^\s*(?:export\s+)?(?:const|let|var)\s+([A-Za-z_$][\w$]*)\s*(?::[^=]+)?=\s*(?:async\s+)?(?:function\b|\([^)]*\)\s*(?::[^=]+)?=>|[A-Za-z_$][\w$]*\s*=>)`)
+
+	// typedMethod matches C-family method declarations, which have no
+	// keyword: "public static List<User> findAll(", "void run() {"
+	typedMethod = regexp.MustCompile(`^\s*(?:(?:public|private|protected|internal|static|final|abstract|synchronized|override|virtual|async|extern|sealed|native)\s+)*` +
+		`([A-Za-z_][\w.<>\[\], ?]*?[\w>\]?])\s+([A-Za-z_]\w*)\s*\([^;]*$`)
+
+	// implFor matches the type of a Rust "impl Trait for Type" block
+	implFor = regexp.MustCompile(`\bfor\s+([A-Za-z_]\w*)`)
+
+	// callSite matches a name followed by "(": a call, or a declaration
+	callSite = regexp.MustCompile(`([A-Za-z_$][\w$]*)\s*\(`)
+
+	// typeName matches capitalized identifiers, which are types and classes
+	// in most languages
+	typeName = regexp.MustCompile(`\b([A-Z][\w$]*)`)
+)
+
+// importPatterns find what a file imports, per language; the first submatch
+// is the module
+var importPatterns = map[string][]*regexp.Regexp{
+	"python": {
+		regexp.MustCompile(`^import\s+([\w.]+)`),
+		regexp.MustCompile(`^from\s+([\w.]+)\s+import\b`),
+	},
+	"typescript": {
+		regexp.MustCompile(`^\s*import\s.*\bfrom\s+['"]([^'"]+)['"]`),
+		regexp.MustCompile(`^\s*import\s+['"]([^'"]+)['"]`),
+		regexp.MustCompile(`^\s*export\s.*\bfrom\s+['"]([^'"]+)['"]`),
+		regexp.MustCompile(`^\}\s*from\s+['"]([^'"]+)['"]`), // End of a multi-line import
+		regexp.MustCompile(`\brequire\(\s*['"]([^'"]+)['"]\s*\)`),
+	},
+	"java":  {regexp.MustCompile(`^import\s+(?:static\s+)?([\w.]+)`)},
+	"rust":  {regexp.MustCompile(`^\s*(?:pub\s+)?(?:use|mod)\s+([\w:]+)`)},
+	"ruby":  {regexp.MustCompile(`^\s*require(?:_relative)?\s*\(?\s*['"]([^'"]+)['"]`)},
+	"cpp":   {regexp.MustCompile(`^\s*#\s*include\s+"([^"]+)"`)},
+	"php":   {regexp.MustCompile(`^\s*use\s+([\w\\]+)`)},
+	"swift": {regexp.MustCompile(`^\s*import\s+(\w+)`)},
+}
+
+// importLanguages map languages to the language whose import patterns they share
+var importLanguages = map[string]string{
+	"javascript": "typescript",
+	"kotlin":     "java",
+	"scala":      "java",
+	"c":          "cpp",
+}
+
+// keywordKinds map declaration keywords to the kind they declare; impl
+// blocks declare nothing but are the container of their functions
+var keywordKinds = map[string]Kind{
+	"def":       KindFunction,
+	"fn":        KindFunction,
+	"fun":       KindFunction,
+	"func":      KindFunction,
+	"function":  KindFunction,
+	"class":     KindClass,
+	"object":    KindClass,
+	"record":    KindClass,
+	"struct":    KindStruct,
+	"enum":      KindEnum,
+	"trait":     KindTrait,
+	"interface": KindInterface,
+	"module":    KindModule,
+	"type":      KindType,
+}
+
+// typedMethodLanguages are the languages whose methods are declared with a
+// return type rather than a keyword
+var typedMethodLanguages = map[string]bool{
+	"java":   true,
+	"csharp": true,
+	"c":      true,
+	"cpp":    true,
+}
+
+// notSymbols are keywords and common builtins that look like calls or types
+var notSymbols = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "catch": true, "return": true,
+	"function": true, "def": true, "fn": true, "fun": true, "func": true, "class": true,
+	"new": true, "else": true, "typeof": true, "sizeof": true, "await": true, "yield": true, "throw": true,
+	"print": true, "super": true, "this": true, "self": true, "elif": true, "match": true,
+	"with": true, "assert": true, "and": true, "or": true, "not": true, "in": true, "is": true,
+	"lambda": true, "require": true, "import": true, "case": true, "using": true,
+	"True": true, "False": true, "None": true, "String": true, "Object": true, "Self": true,
+}
+
+// patternLanguages are the languages extractPatterns knows
+var patternLanguages = map[string]bool{
+	"go": true, "python": true, "javascript": true, "typescript": true, "java": true,
+	"kotlin": true, "scala": true, "rust": true, "ruby": true, "csharp": true,
+	"c": true, "cpp": true, "php": true, "swift": true,
+}
+
+// container is a class-like declaration and the indentation of its line
+type container struct {
+	name   string
+	indent int
+}
+
+// extractPatterns extracts symbols line by line, ctags-style. Definitions
+// nested deeper than a class, impl or interface line belong to it.
+func extractPatterns(path, language, content string) *File {
+	if !patternLanguages[language] {
+		return nil
+	}
+
+	imports := importPatterns[language]
+	if shared, ok := importLanguages[language]; ok {
+		imports = importPatterns[shared]
+	}
+
+	f := &File{}
+	var stack []container
+	for i, line := range strings.Split(content, "\n") {
+		if m := matchImport(imports, line); m != "" {
+			f.Imports = append(f.Imports, m)
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || isComment(trimmed) {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		for len(stack) > 0 && indent <= stack[len(stack)-1].indent {
+			stack = stack[:len(stack)-1]
+		}
+		enclosing := ""
+		if len(stack) > 0 {
+			enclosing = stack[len(stack)-1].name
+		}
+
+		name, kind := "", Kind("")
+		if m := keywordDefinition.FindStringSubmatch(line); m != nil {
+			name, kind = m[2], keywordKinds[m[1]]
+			if m[1] == "impl" {
+				if impl := implFor.FindStringSubmatch(line); impl != nil {
+					name = impl[1]
+				}
+				stack = append(stack, container{name: name, indent: indent})
+			} else if kind == KindClass || kind == KindStruct || kind == KindInterface ||
+				kind == KindTrait || kind == KindModule || kind == KindEnum {
+				stack = append(stack, container{name: name, indent: indent})
+			}
+		} else if m := assignedFunction.FindStringSubmatch(line); m != nil {
+			name, kind = m[1], KindFunction
+		} else if m := typedMethod.FindStringSubmatch(line); m != nil && typedMethodLanguages[language] &&
+			!notSymbols[m[1]] && !notSymbols[m[2]] {
+			name, kind = m[2], KindFunction
+		}
+
+		if kind != "" {
+			def := Definition{Name: name, Kind: kind, Path: path, Line: i + 1, Signature: trimmed}
+			if kind == KindFunction && enclosing != "" {
+				def.Kind, def.Container = KindMethod, enclosing
+			}
+			f.Definitions = append(f.Definitions, def)
+		}
+
+		code := stripStrings(line)
+		for _, m := range callSite.FindAllStringSubmatch(code, -1) {
+			if _, keyword := keywordKinds[m[1]]; m[1] != name && !keyword && !notSymbols[m[1]] {
+				f.addReference(m[1], i+1)
+			}
+		}
+		for _, m := range typeName.FindAllStringSubmatch(code, -1) {
+			if m[1] != name && !notSymbols[m[1]] {
+				f.addReference(m[1], i+1)
+			}
+		}
+	}
+	return f
+}
+
+// matchImport returns the module a line imports, or ""
+func matchImport(patterns []*regexp.Regexp, line string) string {
+	for _, re := range patterns {
+		if m := re.FindStringSubmatch(line); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// isComment reports whether a trimmed line is a comment
+func isComment(trimmed string) bool {
+	for _, prefix := range []string{"//", "/*", "*", "#", "--"} {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripStrings blanks out quoted strings, whose capitalized words are prose
+// rather than types
+func stripStrings(line string) string {
+	var b strings.Builder
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
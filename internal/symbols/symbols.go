@@ -0,0 +1,204 @@
+// Package symbols extracts the definitions and references of identifiers
+// from source files, for go-to-definition and find-references.
+//
+// Go files are parsed with go/ast. Other languages use ctags-style line
+// patterns: declarations are found by keyword ("def", "class", "fn", ...) and
+// references are call sites and capitalized (type-like) identifiers. Neither
+// resolves types, so a reference is a name, not a binding.
+package symbols
+
+import (
+	"sort"
+	"strings"
+)
+
+// Kind is what a definition declares
+type Kind string
+
+const (
+	KindFunction  Kind = "function"
+	KindMethod    Kind = "method"
+	KindType      Kind = "type"
+	KindStruct    Kind = "struct"
+	KindInterface Kind = "interface"
+	KindClass     Kind = "class"
+	KindEnum      Kind = "enum"
+	KindTrait     Kind = "trait"
+	KindModule    Kind = "module"
+	KindConst     Kind = "const"
+	KindVar       Kind = "var"
+)
+
+// Definition is where an identifier is declared
+type Definition struct {
+	Name      string `json:"name"`
+	Kind      Kind   `json:"kind"`
+	Path      string `json:"path"`
+	Line      int    `json:"line"`                // 1-based
+	EndLine   int    `json:"end_line,omitempty"`  // Last line of the declaration (0 = unknown)
+	Container string `json:"container,omitempty"` // Receiver type or enclosing class
+	Package   string `json:"package,omitempty"`   // Package the file declares (Go)
+	Signature string `json:"signature"`           // The declaration's first line, trimmed
+}
+
+// Reference is a line an identifier is used on
+type Reference struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+}
+
+// File is the symbols of one file
+type File struct {
+	Definitions []Definition     `json:"definitions,omitempty"`
+	References  map[string][]int `json:"references,omitempty"` // Identifier -> lines it is used on
+	Imports     []string         `json:"imports,omitempty"`    // Packages and modules imported, as written
+}
+
+// Extract returns the symbols of a file in language, or nil for languages
+// without symbols (documentation, data formats) and files without any
+func Extract(path, language, content string) *File {
+	var f *File
+	if language == "go" {
+		f = extractGo(path, content)
+	}
+	if f == nil {
+		f = extractPatterns(path, language, content)
+	}
+	if f == nil || (len(f.Definitions) == 0 && len(f.References) == 0 && len(f.Imports) == 0) {
+		return nil
+	}
+	return f
+}
+
+// addReference records a use of name on line, once per line
+func (f *File) addReference(name string, line int) {
+	if f.References == nil {
+		f.References = make(map[string][]int)
+	}
+	lines := f.References[name]
+	if len(lines) > 0 && lines[len(lines)-1] == line {
+		return
+	}
+	f.References[name] = append(lines, line)
+}
+
+// Table looks up definitions and references across the files of a branch
+type Table struct {
+	definitions map[string][]Definition
+	references  map[string][]Reference
+	folded      map[string][]string // Lower-cased name -> names, for case-insensitive lookup
+}
+
+// NewTable indexes the symbols of a branch's files, keyed by path
+func NewTable(files map[string]*File) *Table {
+	t := &Table{
+		definitions: make(map[string][]Definition),
+		references:  make(map[string][]Reference),
+		folded:      make(map[string][]string),
+	}
+	addName := func(name string) {
+		if _, ok := t.definitions[name]; ok {
+			return
+		}
+		if _, ok := t.references[name]; ok {
+			return
+		}
+		key := strings.ToLower(name)
+		t.folded[key] = append(t.folded[key], name)
+	}
+
+	for path, f := range files {
+		if f == nil {
+			continue
+		}
+		for _, def := range f.Definitions {
+			addName(def.Name)
+			def.Path = path
+			t.definitions[def.Name] = append(t.definitions[def.Name], def)
+		}
+		for name, lines := range f.References {
+			addName(name)
+			for _, line := range lines {
+				t.references[name] = append(t.references[name], Reference{Path: path, Line: line})
+			}
+		}
+	}
+
+	for _, defs := range t.definitions {
+		sort.Slice(defs, func(i, j int) bool {
+			if defs[i].Path != defs[j].Path {
+				return defs[i].Path < defs[j].Path
+			}
+			return defs[i].Line < defs[j].Line
+		})
+	}
+	for _, refs := range t.references {
+		sort.Slice(refs, func(i, j int) bool {
+			if refs[i].Path != refs[j].Path {
+				return refs[i].Path < refs[j].Path
+			}
+			return refs[i].Line < refs[j].Line
+		})
+	}
+	return t
+}
+
+// Definitions returns where name is defined. A qualified name ("Server.Start",
+// "api.NewServer") matches definitions in that container or package. Names
+// are matched exactly, falling back to ignoring case if nothing matches.
+func (t *Table) Definitions(name string) []Definition {
+	if t == nil {
+		return nil
+	}
+	qualifier, name := splitQualified(name)
+	var defs []Definition
+	for _, n := range t.names(name, func(n string) bool { return len(t.definitions[n]) > 0 }) {
+		for _, def := range t.definitions[n] {
+			if qualifier == "" || def.Container == qualifier || def.Package == qualifier {
+				defs = append(defs, def)
+			}
+		}
+	}
+	return defs
+}
+
+// References returns the lines name is used on. Qualifiers are ignored:
+// references aren't resolved to a type or package.
+func (t *Table) References(name string) []Reference {
+	if t == nil {
+		return nil
+	}
+	_, name = splitQualified(name)
+	var refs []Reference
+	for _, n := range t.names(name, func(n string) bool { return len(t.references[n]) > 0 }) {
+		refs = append(refs, t.references[n]...)
+	}
+	return refs
+}
+
+// names returns name if it is known, else the names equal to it ignoring case
+func (t *Table) names(name string, known func(string) bool) []string {
+	if known(name) {
+		return []string{name}
+	}
+	var names []string
+	for _, n := range t.folded[strings.ToLower(name)] {
+		if known(n) {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// splitQualified splits "Server.Start" into its qualifier and name; Rust and
+// C++ style "Server::start" work too. Only the last qualifier is kept:
+// "api.Server.Start" is qualified by "Server".
+func splitQualified(name string) (string, string) {
+	parts := strings.Split(strings.ReplaceAll(strings.TrimSpace(name), "::", "."), ".")
+	name = strings.TrimSuffix(parts[len(parts)-1], "()")
+	if len(parts) == 1 {
+		return "", name
+	}
+	return parts[len(parts)-2], name
+}
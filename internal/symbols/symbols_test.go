@@ -0,0 +1,188 @@
+package symbols
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+const goSource = `package api
+
+import (
+	"context"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Server serves the API
+type Server struct {
+	store Store
+}
+
+// Store persists users
+type Store interface {
+	Get(ctx context.Context, id string) (*User, error)
+}
+
+const maxUsers = 10
+
+// NewServer creates a server
+func NewServer(store Store) *Server {
+	return &Server{store: store}
+}
+
+func (s *Server) Start(ctx context.Context) error {
+	u, err := s.store.Get(ctx, "1")
+	if err != nil {
+		return err
+	}
+	_ = yaml.Marshal
+	return validate(u, maxUsers)
+}
+`
+
+func TestExtract_Go(t *testing.T) {
+	f := Extract("api/server.go", "go", goSource)
+	if f == nil {
+		t.Fatal("Expected symbols")
+	}
+
+	type def struct {
+		name      string
+		kind      Kind
+		line      int
+		endLine   int
+		container string
+	}
+	var got []def
+	for _, d := range f.Definitions {
+		if d.Package != "api" || d.Path != "api/server.go" {
+			t.Errorf("Unexpected package or path in %+v", d)
+		}
+		got = append(got, def{d.Name, d.Kind, d.Line, d.EndLine, d.Container})
+	}
+	want := []def{
+		{"Server", KindStruct, 9, 11, ""},
+		{"Get", KindMethod, 15, 15, "Store"},
+		{"Store", KindInterface, 14, 16, ""},
+		{"maxUsers", KindConst, 18, 18, ""},
+		{"NewServer", KindFunction, 21, 23, ""},
+		{"Start", KindMethod, 25, 32, "Server"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Definitions = %+v\nwant %+v", got, want)
+	}
+	if sig := f.Definitions[4].Signature; sig != "func NewServer(store Store) *Server {" {
+		t.Errorf("Signature = %q", sig)
+	}
+
+	wantRefs := map[string][]int{
+		"Store":    {10, 21},
+		"User":     {15},
+		"Server":   {21, 22, 25},
+		"store":    {22, 26}, // Field, though NewServer has a store parameter
+		"Get":      {26},
+		"Marshal":  {30},
+		"validate": {31},
+		"maxUsers": {31},
+		"Context":  {15, 25},
+	}
+	for name, lines := range wantRefs {
+		if !reflect.DeepEqual(f.References[name], lines) {
+			t.Errorf("References[%s] = %v, want %v", name, f.References[name], lines)
+		}
+	}
+	// Locals, imports and predeclared identifiers aren't references
+	for _, name := range []string{"ctx", "err", "u", "s", "id", "context", "yaml", "nil", "error", "string", "api"} {
+		if lines, ok := f.References[name]; ok {
+			t.Errorf("Expected no references to %s, got %v", name, lines)
+		}
+	}
+}
+
+func TestExtract_Patterns(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		content  string
+		want     []string // name/kind/container@line
+	}{
+		{"python", "python", "class Repo(Base):\n    def name(self):\n        return helper()\n\ndef helper():\n    pass\n",
+			[]string{"Repo/class/@1", "name/method/Repo@2", "helper/function/@5"}},
+		{"typescript", "typescript", "export interface User {\n  id: string\n}\nexport const load = async (id: string) => {\n}\nexport default class Api {\n  get() {}\n}\n",
+			[]string{"User/interface/@1", "load/function/@4", "Api/class/@6"}},
+		{"rust", "rust", "pub struct Pool {}\n\nimpl Drop for Pool {\n    fn drop(&mut self) {}\n}\n",
+			[]string{"Pool/struct/@1", "drop/method/Pool@4"}},
+		{"java", "java", "public class UserService {\n    public List<User> findAll(int limit) {\n        return repo.query(limit);\n    }\n}\n",
+			[]string{"UserService/class/@1", "findAll/method/UserService@2"}},
+		{"ruby", "ruby", "module Billing\n  def self.charge(amount)\n  end\nend\n",
+			[]string{"Billing/module/@1", "charge/method/Billing@2"}},
+		{"markdown", "markdown", "# def heading\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			if f := Extract("file", tt.language, tt.content); f != nil {
+				for _, d := range f.Definitions {
+					got = append(got, d.Name+"/"+string(d.Kind)+"/"+d.Container+"@"+strconv.Itoa(d.Line))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Definitions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	f := Extract("app.py", "python", "# Helper() in a comment\nx = helper(Config(), \"Not A Type\")\n")
+	if !reflect.DeepEqual(f.References, map[string][]int{"helper": {2}, "Config": {2}}) {
+		t.Errorf("References = %v", f.References)
+	}
+}
+
+func TestExtract_GoFallsBackOnSyntaxErrors(t *testing.T) {
+	f := Extract("broken.go", "go", "package x\n\nfunc Broken( {\n}\n\ntype Thing struct {\n")
+	if f == nil || len(f.Definitions) != 2 || f.Definitions[1].Name != "Thing" || f.Definitions[1].Line != 6 {
+		t.Errorf("Expected the line patterns to find Broken and Thing, got %+v", f)
+	}
+}
+
+func TestTable(t *testing.T) {
+	table := NewTable(map[string]*File{
+		"api/server.go": Extract("api/server.go", "go", goSource),
+		"web/app.py":    Extract("web/app.py", "python", "def server():\n    pass\n\nclass App:\n    def start(self):\n        pass\n"),
+		"README.md":     nil,
+	})
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"Server", []string{"api/server.go:9"}},
+		{"server", []string{"web/app.py:1"}}, // Exact matches come first
+		{"Start", []string{"api/server.go:25"}},
+		{"start", []string{"web/app.py:5"}},
+		{"App.start", []string{"web/app.py:5"}},
+		{"Server.Start", []string{"api/server.go:25"}},
+		{"api.NewServer", []string{"api/server.go:21"}},
+		{"other.NewServer", nil},
+		{"STORE", []string{"api/server.go:14"}},
+		{"Missing", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, d := range table.Definitions(tt.name) {
+			got = append(got, d.Path+":"+strconv.Itoa(d.Line))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Definitions(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	refs := table.References("Server.store")
+	if !reflect.DeepEqual(refs, []Reference{{"api/server.go", 22}, {"api/server.go", 26}}) {
+		t.Errorf("References = %v", refs)
+	}
+	if (*Table)(nil).Definitions("Server") != nil {
+		t.Error("Expected a nil table to find nothing")
+	}
+}
@@ -112,6 +112,9 @@ type MockVectorStore struct {
 	// DeleteFileFunc is called when DeleteFile() is invoked
 	DeleteFileFunc func(ctx context.Context, filePath string) error
 
+	// ListIndexedChunksFunc is called when ListIndexedChunks() is invoked
+	ListIndexedChunksFunc func(ctx context.Context) (map[string]string, error)
+
 	// DeleteCollectionFunc is called when DeleteCollection() is invoked
 	DeleteCollectionFunc func(ctx context.Context) error
 
@@ -202,6 +205,21 @@ func (m *MockVectorStore) DeleteFile(ctx context.Context, filePath string) error
 	return nil
 }
 
+// ListIndexedChunks implements vectorstore.VectorStore.ListIndexedChunks
+func (m *MockVectorStore) ListIndexedChunks(ctx context.Context) (map[string]string, error) {
+	m.CallCount++
+
+	if m.ListIndexedChunksFunc != nil {
+		return m.ListIndexedChunksFunc(ctx)
+	}
+
+	hashes := make(map[string]string, len(m.IndexedFiles))
+	for path := range m.IndexedFiles {
+		hashes[path] = "mock-hash-" + path
+	}
+	return hashes, nil
+}
+
 // DeleteCollection implements vectorstore.VectorStore.DeleteCollection
 func (m *MockVectorStore) DeleteCollection(ctx context.Context) error {
 	m.CallCount++
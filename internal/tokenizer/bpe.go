@@ -0,0 +1,67 @@
+package tokenizer
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+func init() {
+	// Read vocabularies from the embedded assets instead of downloading them
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+// bpe counts tokens with a byte-pair encoder
+type bpe struct {
+	name string
+	enc  *tiktoken.Tiktoken
+}
+
+// newBPE loads the named encoding
+func newBPE(encoding string) (*bpe, error) {
+	enc, err := tiktoken.GetEncoding(encoding)
+	if err != nil {
+		return nil, err
+	}
+	return &bpe{name: encoding, enc: enc}, nil
+}
+
+// maxSegment bounds the bytes encoded at once. Merging is quadratic in the
+// length of a word, so minified or base64 content must be split up.
+const maxSegment = 2048
+
+// Count encodes text, treating special tokens such as <|endoftext|> as plain text
+func (b *bpe) Count(text string) int {
+	count := 0
+	for text != "" {
+		segment := text
+		if len(segment) > maxSegment {
+			segment = text[:splitPoint(text, maxSegment)]
+		}
+		count += len(b.enc.EncodeOrdinary(segment))
+		text = text[len(segment):]
+	}
+	return count
+}
+
+// splitPoint returns where to cut text at or before limit: after the last
+// whitespace if there is one, otherwise at a rune boundary
+func splitPoint(text string, limit int) int {
+	if i := strings.LastIndexAny(text[:limit], " \t\n"); i > 0 {
+		return i + 1
+	}
+	for limit > 0 && !utf8.RuneStart(text[limit]) {
+		limit--
+	}
+	if limit == 0 {
+		return len(text)
+	}
+	return limit
+}
+
+// Name returns the encoding name
+func (b *bpe) Name() string {
+	return b.name
+}
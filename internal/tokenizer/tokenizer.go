@@ -0,0 +1,127 @@
+// Package tokenizer counts tokens for budgeting prompts and embedding chunks.
+//
+// Counts come from byte-pair encoders whose vocabularies are bundled with the
+// binary (cl100k_base and o200k_base), so no network access is needed. Models
+// whose own vocabulary is not bundled (Claude, bge-m3, nomic-embed-text, local
+// Ollama models) are counted with cl100k_base, which tracks them far more
+// closely than a fixed characters-per-token ratio. Their counts are an
+// approximation, not the model's own tokenization: embedding inputs for such
+// models are counted with EmbeddingMargin on top, since exceeding an
+// embedder's window fails the request.
+package tokenizer
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+)
+
+// Encoding names
+const (
+	CL100K = "cl100k_base" // GPT-4, text-embedding-3, default for other models
+	O200K  = "o200k_base"  // GPT-4o, GPT-4.1, o-series
+)
+
+// EmbeddingMargin scales cl100k_base counts for embedding models whose
+// vocabulary is not bundled. The XLM-RoBERTa (bge-m3) and BERT
+// (nomic-embed-text) vocabularies have few code-specific merges and split
+// source code into more tokens than cl100k_base does.
+const EmbeddingMargin = 1.5
+
+// Tokenizer counts the tokens a model sees for a text
+type Tokenizer interface {
+	// Count returns the number of tokens in text
+	Count(text string) int
+
+	// Name returns the encoding name (e.g. "cl100k_base")
+	Name() string
+}
+
+// Estimate is the fallback tokenizer: ~4 characters per token
+type Estimate struct{}
+
+// Count estimates tokens, rounding up
+func (Estimate) Count(text string) int {
+	return int(math.Ceil(float64(len(text)) / 4.0))
+}
+
+// Name returns "estimate"
+func (Estimate) Name() string {
+	return "estimate"
+}
+
+var (
+	encodersMu sync.Mutex
+	encoders   = make(map[string]Tokenizer)
+)
+
+// Get returns the tokenizer for an encoding, loading its vocabulary on first
+// use. Unknown encodings or load failures fall back to Estimate.
+func Get(encoding string) Tokenizer {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	if tok, ok := encoders[encoding]; ok {
+		return tok
+	}
+	tok, err := newBPE(encoding)
+	if err != nil {
+		encoders[encoding] = Estimate{}
+		return Estimate{}
+	}
+	encoders[encoding] = tok
+	return tok
+}
+
+// Default returns the cl100k_base tokenizer
+func Default() Tokenizer {
+	return Get(CL100K)
+}
+
+// ForModel returns the tokenizer for an LLM or embedding model ID
+func ForModel(model string) Tokenizer {
+	return Get(encodingForModel(model))
+}
+
+// ForEmbeddingModel returns the tokenizer for sizing an embedding model's
+// input: exact for OpenAI embedding models, cl100k_base with EmbeddingMargin
+// for the rest
+func ForEmbeddingModel(model string) Tokenizer {
+	if strings.HasPrefix(strings.ToLower(model), "text-embedding-") {
+		return ForModel(model)
+	}
+	return WithMargin(Default(), EmbeddingMargin)
+}
+
+// margin scales another tokenizer's counts up
+type margin struct {
+	tok    Tokenizer
+	factor float64
+}
+
+// WithMargin returns a tokenizer counting factor times tok's count, rounded up
+func WithMargin(tok Tokenizer, factor float64) Tokenizer {
+	return margin{tok: tok, factor: factor}
+}
+
+// Count returns the scaled count
+func (m margin) Count(text string) int {
+	return int(math.Ceil(float64(m.tok.Count(text)) * m.factor))
+}
+
+// Name returns the encoding name with the factor (e.g. "cl100k_base*1.5")
+func (m margin) Name() string {
+	return fmt.Sprintf("%s*%g", m.tok.Name(), m.factor)
+}
+
+// encodingForModel picks the encoding for a model ID
+func encodingForModel(model string) string {
+	id := strings.ToLower(model)
+	for _, prefix := range []string{"gpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "o1", "o3", "o4"} {
+		if id == prefix || strings.HasPrefix(id, prefix+"-") {
+			return O200K
+		}
+	}
+	return CL100K
+}
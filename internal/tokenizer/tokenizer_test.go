@@ -0,0 +1,115 @@
+package tokenizer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		text     string
+		want     int
+	}{
+		{"empty", CL100K, "", 0},
+		{"words", CL100K, "hello world", 2},
+		{"code", CL100K, "func main() {\n\tfmt.Println(\"Hello\")\n}", 10},
+		{"non-ASCII", CL100K, "日本語のテキストをトークン化する", 15},
+		{"special token as text", CL100K, "<|endoftext|>", 7},
+		{"non-ASCII o200k", O200K, "日本語のテキストをトークン化する", 12},
+		{"long word", CL100K, strings.Repeat("x", 100000), 12500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok := Get(tt.encoding)
+			if tok.Name() != tt.encoding {
+				t.Fatalf("Expected %s tokenizer, got %s", tt.encoding, tok.Name())
+			}
+			if got := tok.Count(tt.text); got != tt.want {
+				t.Errorf("Count(%.20q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitPoint(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  int
+	}{
+		{"after whitespace", "abc def ghi", 9, 8},
+		{"no whitespace", "abcdefghij", 4, 4},
+		{"rune boundary", "ab日本", 3, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitPoint(tt.text, tt.limit); got != tt.want {
+				t.Errorf("splitPoint(%q, %d) = %d, want %d", tt.text, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForModel(t *testing.T) {
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"gpt-4o", O200K},
+		{"gpt-4o-mini-2024-07-18", O200K},
+		{"gpt-4-turbo", CL100K},
+		{"text-embedding-3-small", CL100K},
+		{"claude-sonnet-4-5-20250929", CL100K},
+		{"bge-m3", CL100K},
+		{"o1-preview", O200K},
+		{"ollama-custom", CL100K},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			if got := ForModel(tt.model).Name(); got != tt.want {
+				t.Errorf("ForModel(%q) = %s, want %s", tt.model, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGet_UnknownEncodingFallsBack(t *testing.T) {
+	tok := Get("no-such-encoding")
+	if tok.Name() != "estimate" {
+		t.Fatalf("Expected estimate fallback, got %s", tok.Name())
+	}
+	if got := tok.Count("12345678"); got != 2 {
+		t.Errorf("Expected 2 estimated tokens, got %d", got)
+	}
+}
+
+func TestForEmbeddingModel(t *testing.T) {
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"text-embedding-3-small", CL100K},
+		{"text-embedding-ada-002", CL100K},
+		{"bge-m3", "cl100k_base*1.5"},
+		{"nomic-embed-text", "cl100k_base*1.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			if got := ForEmbeddingModel(tt.model).Name(); got != tt.want {
+				t.Errorf("ForEmbeddingModel(%q) = %s, want %s", tt.model, got, tt.want)
+			}
+		})
+	}
+
+	// "func main() {...}" is 10 cl100k_base tokens; 15 with the margin
+	if got := ForEmbeddingModel("bge-m3").Count("func main() {\n\tfmt.Println(\"Hello\")\n}"); got != 15 {
+		t.Errorf("Expected margin count 15, got %d", got)
+	}
+}
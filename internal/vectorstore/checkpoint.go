@@ -0,0 +1,215 @@
+package vectorstore
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Checkpoints let an interrupted indexing run (crash, restart, cancellation)
+// resume where it stopped instead of re-embedding every file
+
+const (
+	// checkpointBatch is how many completed files trigger a checkpoint write
+	checkpointBatch = 50
+
+	// checkpointInterval bounds the time between checkpoint writes
+	checkpointInterval = 5 * time.Second
+)
+
+// IndexCheckpoint records the files completed by an in-flight indexing run.
+// It only applies to a run with the same FromCommit and ToCommit.
+type IndexCheckpoint struct {
+	RepoName   string    `json:"repo_name"`
+	Branch     string    `json:"branch"`
+	FromCommit string    `json:"from_commit,omitempty"` // Empty for a full index
+	ToCommit   string    `json:"to_commit"`
+	Completed  []string  `json:"completed"`
+	StartedAt  time.Time `json:"started_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// GetCheckpointPath returns path to the checkpoint file for repo+branch
+// Example: .mesh/my-repo/main/checkpoint.json
+func GetCheckpointPath(repoName, branch string) string {
+	return filepath.Join(filepath.Dir(GetMetadataPath(repoName, branch)), "checkpoint.json")
+}
+
+// LoadCheckpoint loads the checkpoint for a repo+branch
+// Returns nil if no run was interrupted
+func LoadCheckpoint(repoName, branch string) (*IndexCheckpoint, error) {
+	data, err := os.ReadFile(GetCheckpointPath(repoName, branch))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var cp IndexCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+// SaveCheckpoint atomically writes the checkpoint for a repo+branch
+func SaveCheckpoint(cp *IndexCheckpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(GetCheckpointPath(cp.RepoName, cp.Branch), data)
+}
+
+// DeleteCheckpoint removes the checkpoint for a repo+branch
+// Missing checkpoints are not an error
+func DeleteCheckpoint(repoName, branch string) error {
+	err := os.Remove(GetCheckpointPath(repoName, branch))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// writeFileAtomic writes data to a temp file and renames it into place, so a
+// crash mid-write never leaves a truncated file behind
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// runCheckpoint tracks completed files during a run and persists them
+// periodically (thread-safe)
+type runCheckpoint struct {
+	cp        IndexCheckpoint
+	completed map[string]bool
+	pending   int
+	lastSave  time.Time
+	mu        sync.Mutex
+}
+
+// startCheckpoint resumes the checkpoint left by an interrupted run over the
+// same commits, or starts a fresh one
+func (idx *Indexer) startCheckpoint(fromCommit, toCommit string) *runCheckpoint {
+	rc := &runCheckpoint{
+		cp: IndexCheckpoint{
+			RepoName:   idx.repoName,
+			Branch:     idx.branch,
+			FromCommit: fromCommit,
+			ToCommit:   toCommit,
+			StartedAt:  time.Now(),
+		},
+		completed: make(map[string]bool),
+	}
+
+	prev, err := LoadCheckpoint(idx.repoName, idx.branch)
+	if err != nil {
+		idx.logger.Warn().Err(err).Msg("Ignoring unreadable checkpoint")
+	}
+	if prev != nil && prev.FromCommit == fromCommit && prev.ToCommit == toCommit {
+		rc.cp = *prev
+		for _, path := range prev.Completed {
+			rc.completed[path] = true
+		}
+		idx.logger.Info().
+			Int("completed", len(prev.Completed)).
+			Time("started_at", prev.StartedAt).
+			Msg("Resuming interrupted indexing run from checkpoint")
+	}
+
+	idx.checkpoint = rc
+	rc.save(idx)
+	return rc
+}
+
+// suspendCheckpoint persists the checkpoint of a run that did not finish.
+// Completed files are skipped on resume, so their redactions, symbols,
+// dependency graph and summary hashes are saved with it.
+func (idx *Indexer) suspendCheckpoint() {
+	if idx.checkpoint == nil {
+		return
+	}
+	idx.checkpoint.save(idx)
+	idx.saveRedactions()
+	idx.saveSymbols()
+	idx.suspendSummary()
+	idx.checkpoint = nil
+}
+
+// resumed reports whether the run picks up files completed by an interrupted one
+func (rc *runCheckpoint) resumed() bool {
+	if rc == nil {
+		return false
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.completed) > 0
+}
+
+// isDone reports whether a file was completed by this run or the one it resumes
+func (rc *runCheckpoint) isDone(relPath string) bool {
+	if rc == nil {
+		return false
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.completed[relPath]
+}
+
+// markDone records a completed file, writing the checkpoint every
+// checkpointBatch files or checkpointInterval
+func (rc *runCheckpoint) markDone(idx *Indexer, relPath string) {
+	if rc == nil {
+		return
+	}
+	rc.mu.Lock()
+	rc.completed[relPath] = true
+	rc.cp.Completed = append(rc.cp.Completed, relPath)
+	rc.pending++
+	due := rc.pending >= checkpointBatch || time.Since(rc.lastSave) >= checkpointInterval
+	rc.mu.Unlock()
+
+	if due {
+		rc.save(idx)
+	}
+}
+
+// save writes the checkpoint; failures only cost resumability, so they are logged
+func (rc *runCheckpoint) save(idx *Indexer) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.cp.UpdatedAt = time.Now()
+	if err := SaveCheckpoint(&rc.cp); err != nil {
+		idx.logger.Warn().Err(err).Msg("Failed to write indexing checkpoint")
+		return
+	}
+	rc.pending = 0
+	rc.lastSave = rc.cp.UpdatedAt
+}
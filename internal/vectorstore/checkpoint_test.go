@@ -0,0 +1,271 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// failingStore is a mockStore whose IndexFile fails for selected base paths
+type failingStore struct {
+	*mockStore
+	fail map[string]bool
+}
+
+func (f *failingStore) IndexFile(ctx context.Context, filePath, content string) error {
+	if f.fail[extractBasePath(filePath)] {
+		return fmt.Errorf("embedding failed")
+	}
+	return f.mockStore.IndexFile(ctx, filePath, content)
+}
+
+func TestCheckpoint_SaveLoadDelete(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	if cp, err := LoadCheckpoint("repo", "feature/x"); err != nil || cp != nil {
+		t.Fatalf("Expected no checkpoint, got %v (%v)", cp, err)
+	}
+
+	cp := &IndexCheckpoint{
+		RepoName:  "repo",
+		Branch:    "feature/x",
+		ToCommit:  "abc",
+		Completed: []string{"a.go", "b.go"},
+	}
+	if err := SaveCheckpoint(cp); err != nil {
+		t.Fatalf("SaveCheckpoint failed: %v", err)
+	}
+
+	loaded, err := LoadCheckpoint("repo", "feature/x")
+	if err != nil || loaded == nil {
+		t.Fatalf("LoadCheckpoint failed: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Completed, cp.Completed) || loaded.ToCommit != "abc" {
+		t.Errorf("Checkpoint roundtrip mismatch: %+v", loaded)
+	}
+
+	if err := DeleteCheckpoint("repo", "feature/x"); err != nil {
+		t.Fatalf("DeleteCheckpoint failed: %v", err)
+	}
+	if err := DeleteCheckpoint("repo", "feature/x"); err != nil {
+		t.Errorf("Deleting a missing checkpoint should not fail: %v", err)
+	}
+}
+
+func TestIndexIncremental_RecordsAndRetriesFailedFiles(t *testing.T) {
+	src, _ := initBareRepo(t)
+
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	store := &failingStore{mockStore: newMockStore(), fail: map[string]bool{"b.go": true}}
+	indexer := NewIndexerWithBranch(store, src, "repo", "feature", testLogger())
+	if err := indexer.IndexIncremental(context.Background()); err != nil {
+		t.Fatalf("IndexIncremental failed: %v", err)
+	}
+
+	meta, _ := LoadMetadata("repo", "feature")
+	if meta == nil || !reflect.DeepEqual(meta.FailedFiles, []string{"b.go"}) {
+		t.Fatalf("Expected b.go recorded as failed, got %+v", meta)
+	}
+
+	if f := meta.FailedAttempts["b.go"]; f.Attempts != 1 || f.LastAttempt.IsZero() {
+		t.Errorf("Expected b.go's first attempt recorded, got %+v", f)
+	}
+
+	// The retry waits out its backoff
+	needs, _, err := NeedsReindexing(src, "repo", "feature")
+	if err != nil || needs {
+		t.Errorf("Expected no re-indexing during backoff, got %v (%v)", needs, err)
+	}
+	meta.FailedAttempts["b.go"] = FailedFile{Attempts: 1, LastAttempt: time.Now().Add(-failedRetryDelay)}
+	SaveMetadata(meta)
+	needs, _, err = NeedsReindexing(src, "repo", "feature")
+	if err != nil || !needs {
+		t.Errorf("Expected failed files to require re-indexing after backoff, got %v (%v)", needs, err)
+	}
+
+	// The provider recovers; only the failed file is retried
+	delete(store.fail, "b.go")
+	indexCnt := store.indexCnt
+	if err := indexer.IndexIncremental(context.Background()); err != nil {
+		t.Fatalf("Retry IndexIncremental failed: %v", err)
+	}
+
+	if store.indexCnt-indexCnt != 1 {
+		t.Errorf("Expected only b.go to be retried, got %d index calls", store.indexCnt-indexCnt)
+	}
+	meta, _ = LoadMetadata("repo", "feature")
+	if meta == nil || len(meta.FailedFiles) != 0 {
+		t.Errorf("Expected no failed files after retry, got %+v", meta)
+	}
+}
+
+func TestIndexIncremental_ResumesFromCheckpoint(t *testing.T) {
+	src, _ := initBareRepo(t)
+
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	commit, _ := GetBranchCommit(src, "feature")
+
+	testCases := []struct {
+		name       string
+		checkpoint IndexCheckpoint
+		wantIndex  int
+	}{
+		{
+			name:       "matching checkpoint skips completed files",
+			checkpoint: IndexCheckpoint{ToCommit: commit, Completed: []string{"a.go"}},
+			wantIndex:  1,
+		},
+		{
+			name:       "checkpoint for another commit is ignored",
+			checkpoint: IndexCheckpoint{ToCommit: "0123456789abcdef", Completed: []string{"a.go"}},
+			wantIndex:  2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			DeleteMetadata("repo", "feature")
+			tc.checkpoint.RepoName = "repo"
+			tc.checkpoint.Branch = "feature"
+			if err := SaveCheckpoint(&tc.checkpoint); err != nil {
+				t.Fatalf("SaveCheckpoint failed: %v", err)
+			}
+
+			store := newMockStore()
+			indexer := NewIndexerWithBranch(store, src, "repo", "feature", testLogger())
+			if err := indexer.IndexIncremental(context.Background()); err != nil {
+				t.Fatalf("IndexIncremental failed: %v", err)
+			}
+
+			if store.indexCnt != tc.wantIndex {
+				t.Errorf("Expected %d index calls, got %d", tc.wantIndex, store.indexCnt)
+			}
+			if cp, _ := LoadCheckpoint("repo", "feature"); cp != nil {
+				t.Errorf("Expected checkpoint to be removed after a completed run, got %+v", cp)
+			}
+		})
+	}
+}
+
+func TestIndexIncremental_CancelledRunKeepsCheckpoint(t *testing.T) {
+	src, _ := initBareRepo(t)
+
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	indexer := NewIndexerWithBranch(newMockStore(), src, "repo", "feature", testLogger())
+	if err := indexer.IndexIncremental(ctx); err == nil {
+		t.Fatal("Expected cancelled run to fail")
+	}
+
+	if meta, _ := LoadMetadata("repo", "feature"); meta != nil {
+		t.Errorf("Cancelled run must not record metadata, got %+v", meta)
+	}
+	cp, err := LoadCheckpoint("repo", "feature")
+	if err != nil || cp == nil {
+		t.Fatalf("Expected checkpoint to survive a cancelled run, got %v (%v)", cp, err)
+	}
+	commit, _ := GetBranchCommit(src, "feature")
+	if cp.ToCommit != commit || cp.FromCommit != "" {
+		t.Errorf("Unexpected checkpoint commits: %+v", cp)
+	}
+}
+
+// cancellingStore is a mockStore that cancels the run once a.go is indexed,
+// failing b.go
+type cancellingStore struct {
+	*mockStore
+	cancel context.CancelFunc
+	aDone  chan struct{}
+}
+
+func (c *cancellingStore) IndexFile(ctx context.Context, filePath, content string) error {
+	switch extractBasePath(filePath) {
+	case "a.go":
+		defer close(c.aDone)
+	case "b.go":
+		<-c.aDone
+		c.cancel()
+		return ctx.Err()
+	}
+	return c.mockStore.IndexFile(ctx, filePath, content)
+}
+
+func TestIndexIncremental_ResumeKeepsCompletedFilesIndexes(t *testing.T) {
+	src, _ := initBareRepo(t)
+	gitCmd(t, src, "checkout", "-q", "feature")
+	os.WriteFile(filepath.Join(src, "a.go"), []byte("package a\n\nfunc A() {}\n"), 0644)
+	os.WriteFile(filepath.Join(src, "b.go"), []byte("package b\n\nfunc B() {}\n"), 0644)
+	gitCmd(t, src, "add", "a.go", "b.go")
+	gitCmd(t, src, "commit", "-m", "add definitions")
+
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := &cancellingStore{mockStore: newMockStore(), cancel: cancel, aDone: make(chan struct{})}
+	indexer := NewIndexerWithBranch(store, src, "repo", "feature", testLogger())
+	if err := indexer.IndexIncremental(ctx); err == nil {
+		t.Fatal("Expected cancelled run to fail")
+	}
+
+	cp, _ := LoadCheckpoint("repo", "feature")
+	if cp == nil || !reflect.DeepEqual(cp.Completed, []string{"a.go"}) {
+		t.Fatalf("Expected a.go checkpointed, got %+v", cp)
+	}
+	if graph, err := LoadDependencyGraph("repo", "feature"); err != nil || graph == nil {
+		t.Errorf("Expected the dependency graph saved with the checkpoint (%v)", err)
+	}
+
+	// The resumed run skips a.go, but its symbols, graph node and summary
+	// hash must survive
+	resumeStore := newMockStore()
+	indexer = NewIndexerWithBranch(resumeStore, src, "repo", "feature", testLogger())
+	if err := indexer.IndexIncremental(context.Background()); err != nil {
+		t.Fatalf("Resumed IndexIncremental failed: %v", err)
+	}
+	if resumeStore.indexCnt != 1 {
+		t.Errorf("Expected only b.go to be indexed on resume, got %d index calls", resumeStore.indexCnt)
+	}
+
+	index, err := LoadSymbolIndex("repo", "feature")
+	if err != nil || index == nil {
+		t.Fatalf("LoadSymbolIndex failed: %v", err)
+	}
+	for _, file := range []string{"a.go", "b.go"} {
+		if index.Files[file] == nil {
+			t.Errorf("Expected symbols for %s, got %v", file, index.Files)
+		}
+	}
+
+	summary, err := LoadRepoSummary("repo", "feature")
+	if err != nil || summary == nil {
+		t.Fatalf("LoadRepoSummary failed: %v", err)
+	}
+	if summary.Files["a.go"] == "" || summary.Files["b.go"] == "" {
+		t.Errorf("Expected summary hashes for a.go and b.go, got %v", summary.Files)
+	}
+}
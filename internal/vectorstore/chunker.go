@@ -3,16 +3,20 @@ package vectorstore
 import (
 	"crypto/sha256"
 	"fmt"
-	"math"
 	"strings"
+	"unicode/utf8"
+
+	"github.com/First008/mesh/internal/tokenizer"
 )
 
-// Token budget configuration for bge-m3 model (8192 token context)
+// Token budget configuration for bge-m3 model (8192 token context). Chunks
+// are counted with the embedding model's tokenizer, which for bge-m3 already
+// includes tokenizer.EmbeddingMargin, and a chunk never counts over
+// MaxTokensPerChunk, leaving more than 2x headroom below the window.
 const (
-	MaxTokensPerChunk     = 3500 // Safe chunk size to avoid context limit
-	OverlapTokens         = 250  // Overlap between chunks for context continuity
-	MaxTokensWholeFile    = 3200 // Embed whole file if under this limit
-	CharsPerTokenEstimate = 4.0  // Conservative estimate: 1 token ≈ 4 chars
+	MaxTokensPerChunk  = 3500 // Safe chunk size to avoid context limit
+	OverlapTokens      = 250  // Overlap between chunks for context continuity
+	MaxTokensWholeFile = 3200 // Embed whole file if under this limit
 )
 
 // CodeChunk represents a chunk of code with metadata
@@ -22,30 +26,37 @@ type CodeChunk struct {
 	StartLine  int
 	EndLine    int
 	Header     string // Context header for better retrieval
+	Symbol     string // Declaration or heading path named in the header ("" if none)
 	ChunkID    string // Stable identifier: hash(path + startLine + endLine)
 }
 
-// estimateTokens provides conservative token count estimate
-// Uses ~4 chars per token as a safe approximation for code/text
+// estimateTokens counts tokens with the default BPE tokenizer
 func estimateTokens(text string) int {
-	return int(math.Ceil(float64(len(text)) / CharsPerTokenEstimate))
+	return tokenizer.Default().Count(text)
 }
 
-// charsForTokens converts token count to approximate character count
-func charsForTokens(tokens int) int {
-	return int(float64(tokens) * CharsPerTokenEstimate)
+// ChunkFile splits a file into semantically meaningful chunks, counting
+// tokens with the default tokenizer
+func ChunkFile(filePath, content, language string) []CodeChunk {
+	return ChunkFileWithTokenizer(filePath, content, language, tokenizer.Default())
 }
 
-// ChunkFile splits a file into semantically meaningful chunks
+// ChunkFileWithTokenizer splits a file into semantically meaningful chunks
 // Uses token-budget-based chunking to ensure no chunk exceeds model limits
-func ChunkFile(filePath, content, language string) []CodeChunk {
-	// Convert token budgets to character counts (conservative approximation)
-	maxChunkChars := charsForTokens(MaxTokensPerChunk)
-	overlapChars := charsForTokens(OverlapTokens)
+func ChunkFileWithTokenizer(filePath, content, language string, tok tokenizer.Tokenizer) []CodeChunk {
 	minChunkChars := 500 // Skip tiny chunks
 
+	// Count per line once; chunkers sum these instead of re-encoding text.
+	// The sum slightly overcounts (no merges across newlines), which is safe.
+	lines := strings.Split(content, "\n")
+	lineTokens := make([]int, len(lines))
+	fileTokens := 0
+	for i, line := range lines {
+		lineTokens[i] = tok.Count(line) + 1 // +1 for the newline
+		fileTokens += lineTokens[i]
+	}
+
 	// Check if file fits within safe token budget for whole-file embedding
-	fileTokens := estimateTokens(content)
 	if fileTokens <= MaxTokensWholeFile {
 		chunk := CodeChunk{
 			Content:    content,
@@ -63,13 +74,22 @@ func ChunkFile(filePath, content, language string) []CodeChunk {
 
 	switch language {
 	case "go":
-		chunks = chunkGoCode(filePath, content, maxChunkChars, overlapChars)
+		chunks = chunkByDeclarations(filePath, "go", lines, lineTokens, isGoDeclaration)
 	case "typescript", "javascript":
-		chunks = chunkTSCode(filePath, content, maxChunkChars, overlapChars)
+		chunks = chunkByDeclarations(filePath, "typescript", lines, lineTokens, isTSDeclaration)
+	case "markdown", "restructuredtext":
+		// Sections are packed whole, so even short chunks carry a heading
+		// and are kept
+		chunks = enforceTokenLimit(chunkByHeadings(filePath, language, lines, lineTokens), tok)
+		for i := range chunks {
+			chunks[i].ChunkID = generateChunkID(filePath, chunks[i].StartLine, chunks[i].EndLine)
+		}
+		return chunks
 	default:
 		// Fallback: simple line-based chunking
-		chunks = chunkByLines(filePath, content, language, maxChunkChars, overlapChars)
+		chunks = chunkByLines(filePath, language, lines, lineTokens)
 	}
+	chunks = enforceTokenLimit(chunks, tok)
 
 	// Filter out chunks that are too small and generate chunk IDs
 	filtered := make([]CodeChunk, 0, len(chunks))
@@ -83,30 +103,44 @@ func ChunkFile(filePath, content, language string) []CodeChunk {
 	return filtered
 }
 
-// chunkGoCode splits Go code by top-level declarations
-func chunkGoCode(filePath, content string, maxSize, overlap int) []CodeChunk {
-	lines := strings.Split(content, "\n")
+// isGoDeclaration detects top-level Go declarations
+func isGoDeclaration(trimmed string) bool {
+	return strings.HasPrefix(trimmed, "func ") ||
+		strings.HasPrefix(trimmed, "type ") ||
+		strings.HasPrefix(trimmed, "const ") ||
+		strings.HasPrefix(trimmed, "var ") ||
+		strings.HasPrefix(trimmed, "package ") ||
+		strings.HasPrefix(trimmed, "import ")
+}
+
+// isTSDeclaration detects TypeScript/JavaScript exports, classes and functions
+func isTSDeclaration(trimmed string) bool {
+	return strings.HasPrefix(trimmed, "export ") ||
+		strings.HasPrefix(trimmed, "class ") ||
+		strings.HasPrefix(trimmed, "function ") ||
+		strings.HasPrefix(trimmed, "const ") ||
+		strings.HasPrefix(trimmed, "interface ") ||
+		strings.HasPrefix(trimmed, "type ")
+}
+
+// chunkByDeclarations splits code at top-level declarations, keeping each
+// chunk within MaxTokensPerChunk
+func chunkByDeclarations(filePath, language string, lines []string, lineTokens []int, isDeclaration func(string) bool) []CodeChunk {
 	var chunks []CodeChunk
 	var currentChunk strings.Builder
+	currentTokens := 0
 	currentLine := 1
 	chunkStartLine := 1
 	chunkIndex := 0
 
 	for i, line := range lines {
-		// Detect top-level declarations
-		trimmed := strings.TrimSpace(line)
-		isTopLevel := strings.HasPrefix(trimmed, "func ") ||
-			strings.HasPrefix(trimmed, "type ") ||
-			strings.HasPrefix(trimmed, "const ") ||
-			strings.HasPrefix(trimmed, "var ") ||
-			strings.HasPrefix(trimmed, "package ") ||
-			strings.HasPrefix(trimmed, "import ")
+		isTopLevel := isDeclaration(strings.TrimSpace(line))
 
-		// Check if adding this line would exceed maxSize
-		willExceed := currentChunk.Len()+len(line)+1 > maxSize
+		// Check if adding this line would exceed the budget
+		willExceed := currentTokens+lineTokens[i] > MaxTokensPerChunk
 
-		// Hard limit: force split if chunk reaches maxSize (strict enforcement)
-		hardLimit := currentChunk.Len() >= maxSize
+		// Hard limit: force split if chunk reaches the budget (strict enforcement)
+		hardLimit := currentTokens >= MaxTokensPerChunk
 
 		// Prefer split at: (1) good boundary when approaching limit, or (2) hard limit
 		shouldSplit := (willExceed && isTopLevel) || hardLimit
@@ -114,18 +148,21 @@ func chunkGoCode(filePath, content string, maxSize, overlap int) []CodeChunk {
 		if shouldSplit && currentChunk.Len() > 0 {
 			// Save current chunk
 			chunkContent := currentChunk.String()
+			symbol := extractSymbol(chunkContent)
 			chunks = append(chunks, CodeChunk{
 				Content:    chunkContent,
 				ChunkIndex: chunkIndex,
 				StartLine:  chunkStartLine,
 				EndLine:    currentLine - 1,
-				Header:     buildHeader(filePath, "go", extractSymbol(chunkContent)),
+				Header:     buildHeader(filePath, language, symbol),
+				Symbol:     symbol,
 			})
 
 			// Start new chunk with overlap
 			currentChunk.Reset()
-			overlapLines := getOverlapLines(lines, i, overlap)
+			overlapLines, overlapTokens := getOverlapLines(lines, lineTokens, i, OverlapTokens)
 			currentChunk.WriteString(overlapLines)
+			currentTokens = overlapTokens
 			chunkStartLine = i - strings.Count(overlapLines, "\n")
 			if chunkStartLine < 1 {
 				chunkStartLine = 1
@@ -136,84 +173,21 @@ func chunkGoCode(filePath, content string, maxSize, overlap int) []CodeChunk {
 		// Add current line to chunk
 		currentChunk.WriteString(line)
 		currentChunk.WriteString("\n")
+		currentTokens += lineTokens[i]
 		currentLine++
 	}
 
 	// Add final chunk
 	if currentChunk.Len() > 0 {
 		chunkContent := currentChunk.String()
+		symbol := extractSymbol(chunkContent)
 		chunks = append(chunks, CodeChunk{
 			Content:    chunkContent,
 			ChunkIndex: chunkIndex,
 			StartLine:  chunkStartLine,
 			EndLine:    currentLine - 1,
-			Header:     buildHeader(filePath, "go", extractSymbol(chunkContent)),
-		})
-	}
-
-	return chunks
-}
-
-// chunkTSCode splits TypeScript/JavaScript code by exports and classes
-func chunkTSCode(filePath, content string, maxSize, overlap int) []CodeChunk {
-	lines := strings.Split(content, "\n")
-	var chunks []CodeChunk
-	var currentChunk strings.Builder
-	currentLine := 1
-	chunkStartLine := 1
-	chunkIndex := 0
-
-	for i, line := range lines {
-		// Check if adding this line would exceed maxSize
-		willExceed := currentChunk.Len()+len(line)+1 > maxSize
-
-		trimmed := strings.TrimSpace(line)
-		isTopLevel := strings.HasPrefix(trimmed, "export ") ||
-			strings.HasPrefix(trimmed, "class ") ||
-			strings.HasPrefix(trimmed, "function ") ||
-			strings.HasPrefix(trimmed, "const ") ||
-			strings.HasPrefix(trimmed, "interface ") ||
-			strings.HasPrefix(trimmed, "type ")
-
-		// Hard limit: force split if chunk reaches maxSize (strict enforcement)
-		hardLimit := currentChunk.Len() >= maxSize
-
-		// Prefer split at: (1) good boundary when approaching limit, or (2) hard limit
-		shouldSplit := (willExceed && isTopLevel) || hardLimit
-
-		if shouldSplit && currentChunk.Len() > 0 {
-			chunkContent := currentChunk.String()
-			chunks = append(chunks, CodeChunk{
-				Content:    chunkContent,
-				ChunkIndex: chunkIndex,
-				StartLine:  chunkStartLine,
-				EndLine:    currentLine - 1,
-				Header:     buildHeader(filePath, "typescript", extractSymbol(chunkContent)),
-			})
-
-			currentChunk.Reset()
-			overlapLines := getOverlapLines(lines, i, overlap)
-			currentChunk.WriteString(overlapLines)
-			chunkStartLine = i - strings.Count(overlapLines, "\n")
-			if chunkStartLine < 1 {
-				chunkStartLine = 1
-			}
-			chunkIndex++
-		}
-
-		currentChunk.WriteString(line)
-		currentChunk.WriteString("\n")
-		currentLine++
-	}
-
-	if currentChunk.Len() > 0 {
-		chunkContent := currentChunk.String()
-		chunks = append(chunks, CodeChunk{
-			Content:    chunkContent,
-			ChunkIndex: chunkIndex,
-			StartLine:  chunkStartLine,
-			EndLine:    currentLine - 1,
-			Header:     buildHeader(filePath, "typescript", extractSymbol(chunkContent)),
+			Header:     buildHeader(filePath, language, symbol),
+			Symbol:     symbol,
 		})
 	}
 
@@ -221,52 +195,42 @@ func chunkTSCode(filePath, content string, maxSize, overlap int) []CodeChunk {
 }
 
 // chunkByLines simple line-based chunking for unsupported languages
-func chunkByLines(filePath, content, language string, maxSize, overlap int) []CodeChunk {
-	lines := strings.Split(content, "\n")
+func chunkByLines(filePath, language string, lines []string, lineTokens []int) []CodeChunk {
 	var chunks []CodeChunk
 	chunkIndex := 0
 
 	for i := 0; i < len(lines); {
 		var chunk strings.Builder
+		chunkTokens := 0
 		startLine := i + 1
 
-		// Take lines until maxSize, with hard limit enforcement
+		// Take lines until the budget, with hard limit enforcement
 		for i < len(lines) {
 			line := lines[i]
 
-			// If single line exceeds maxSize, split it by character boundaries
-			if len(line) > maxSize && chunk.Len() == 0 {
-				// Split oversized line into character chunks
-				for j := 0; j < len(line); j += maxSize {
-					end := j + maxSize
-					if end > len(line) {
-						end = len(line)
-					}
-					chunks = append(chunks, CodeChunk{
-						Content:    line[j:end],
-						ChunkIndex: chunkIndex,
-						StartLine:  startLine,
-						EndLine:    startLine,
-						Header:     buildHeader(filePath, language, ""),
-					})
-					chunkIndex++
-				}
+			// A single line over the budget becomes its own chunk, which
+			// enforceTokenLimit splits
+			if lineTokens[i] > MaxTokensPerChunk && chunk.Len() == 0 {
+				chunks = append(chunks, CodeChunk{
+					Content:    line,
+					ChunkIndex: chunkIndex,
+					StartLine:  startLine,
+					EndLine:    startLine,
+					Header:     buildHeader(filePath, language, ""),
+				})
+				chunkIndex++
 				i++
 				break
 			}
 
 			// Check if adding this line exceeds limit
-			if chunk.Len() > 0 && chunk.Len()+len(line)+1 > maxSize {
-				break
-			}
-
-			// Hard limit: force split if reaching maxSize
-			if chunk.Len() >= maxSize {
+			if chunk.Len() > 0 && chunkTokens+lineTokens[i] > MaxTokensPerChunk {
 				break
 			}
 
 			chunk.WriteString(line)
 			chunk.WriteString("\n")
+			chunkTokens += lineTokens[i]
 			i++
 		}
 
@@ -282,12 +246,10 @@ func chunkByLines(filePath, content, language string, maxSize, overlap int) []Co
 				Header:     buildHeader(filePath, language, ""),
 			})
 
-			// Backtrack for overlap
-			overlapLines := overlap / 50 // Rough: 50 chars per line
-			if overlapLines > 0 && i < len(lines) {
-				i -= overlapLines
-				if i < startLine {
-					i = startLine
+			// Backtrack for overlap, always advancing at least one line
+			if i < len(lines) {
+				for overlap := 0; i > startLine && overlap+lineTokens[i-1] <= OverlapTokens; i-- {
+					overlap += lineTokens[i-1]
 				}
 			}
 
@@ -298,6 +260,57 @@ func chunkByLines(filePath, content, language string, maxSize, overlap int) []Co
 	return chunks
 }
 
+// enforceTokenLimit splits chunks that count over MaxTokensPerChunk when
+// encoded whole. Chunkers budget with summed per-line counts, which a long
+// line or merges across lines can push past the limit.
+func enforceTokenLimit(chunks []CodeChunk, tok tokenizer.Tokenizer) []CodeChunk {
+	limited := make([]CodeChunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		if tok.Count(chunk.Content) <= MaxTokensPerChunk {
+			chunk.ChunkIndex = len(limited)
+			limited = append(limited, chunk)
+			continue
+		}
+		for _, piece := range splitByTokens(chunk.Content, tok, MaxTokensPerChunk) {
+			part := chunk
+			part.Content = piece
+			part.ChunkIndex = len(limited)
+			limited = append(limited, part)
+		}
+	}
+	return limited
+}
+
+// splitByTokens cuts text into pieces of at most limit tokens, at a line
+// break when one falls in the second half of a piece, otherwise at a rune
+// boundary
+func splitByTokens(text string, tok tokenizer.Tokenizer, limit int) []string {
+	var pieces []string
+	for text != "" {
+		// Start from a prefix most text has more tokens than the limit in,
+		// and shrink it in proportion to its count until it fits
+		cut := min(len(text), 4*limit)
+		for tokens := tok.Count(text[:cut]); tokens > limit; tokens = tok.Count(text[:cut]) {
+			cut = cut * limit / tokens
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+			if cut == 0 {
+				_, cut = utf8.DecodeRuneInString(text) // A single rune over the limit
+				break
+			}
+		}
+		if cut < len(text) {
+			if i := strings.LastIndexByte(text[:cut], '\n'); i >= cut/2 {
+				cut = i + 1
+			}
+		}
+		pieces = append(pieces, text[:cut])
+		text = text[cut:]
+	}
+	return pieces
+}
+
 // buildHeader creates a context header for better retrieval
 // Format: "path/to/file.go :: Go :: func FunctionName"
 func buildHeader(filePath, language, symbol string) string {
@@ -369,28 +382,22 @@ func extractSymbol(code string) string {
 	return ""
 }
 
-// getOverlapLines returns the last N characters worth of lines in CORRECT order
-func getOverlapLines(lines []string, fromIndex, numChars int) string {
+// getOverlapLines returns the lines preceding fromIndex, in order, that fit
+// within numTokens, along with their token count
+func getOverlapLines(lines []string, lineTokens []int, fromIndex, numTokens int) (string, int) {
 	if fromIndex <= 0 {
-		return ""
-	}
-
-	// Collect lines backwards, then reverse to correct order
-	var collected []string
-	charsCollected := 0
-
-	for i := fromIndex - 1; i >= 0 && charsCollected < numChars; i-- {
-		line := lines[i]
-		collected = append(collected, line)
-		charsCollected += len(line) + 1 // +1 for newline
+		return "", 0
 	}
 
-	// Reverse to get correct order
-	for i, j := 0, len(collected)-1; i < j; i, j = i+1, j-1 {
-		collected[i], collected[j] = collected[j], collected[i]
+	// Walk backwards to find the first line of the overlap
+	first := fromIndex
+	tokens := 0
+	for first > 0 && tokens < numTokens {
+		first--
+		tokens += lineTokens[first]
 	}
 
-	return strings.Join(collected, "\n") + "\n"
+	return strings.Join(lines[first:fromIndex], "\n") + "\n", tokens
 }
 
 // countLines counts the number of newlines in text
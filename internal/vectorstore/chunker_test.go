@@ -4,50 +4,50 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"unicode/utf8"
+
+	"github.com/First008/mesh/internal/tokenizer"
 )
 
-// TestTokenEstimation verifies token estimation is conservative
+// TestTokenEstimation verifies tokens are counted with BPE rather than a character ratio
 func TestTokenEstimation(t *testing.T) {
 	tests := []struct {
-		name     string
-		text     string
-		minChars int
+		name string
+		text string
+		want int
 	}{
-		{
-			name:     "empty string",
-			text:     "",
-			minChars: 0,
-		},
-		{
-			name:     "short text",
-			text:     "hello world",
-			minChars: 2,
-		},
-		{
-			name:     "code snippet",
-			text:     "func main() {\n\tfmt.Println(\"Hello\")\n}",
-			minChars: 8,
-		},
+		{"empty string", "", 0},
+		{"short text", "hello world", 2},
+		{"code snippet", "func main() {\n\tfmt.Println(\"Hello\")\n}", 10},
+		{"non-ASCII", "日本語のテキストをトークン化する", 15},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tokens := estimateTokens(tt.text)
-			expectedChars := charsForTokens(tokens)
-
-			// Verify round-trip: chars -> tokens -> chars >= original
-			if expectedChars < len(tt.text) {
-				t.Errorf("Token estimation too aggressive: %d tokens for %d chars (expected >= %d chars)",
-					tokens, len(tt.text), expectedChars)
-			}
-
-			if tokens < tt.minChars {
-				t.Errorf("estimateTokens(%q) = %d, want >= %d", tt.text, tokens, tt.minChars)
+			if tokens := estimateTokens(tt.text); tokens != tt.want {
+				t.Errorf("estimateTokens(%q) = %d, want %d", tt.text, tokens, tt.want)
 			}
 		})
 	}
 }
 
+// TestChunkFile_DenseText verifies chunks stay within budget when text has
+// far more tokens per character than the old 4 chars/token estimate assumed
+func TestChunkFile_DenseText(t *testing.T) {
+	// ~1 token per 3 bytes: 12KB passed as whole-file under the old estimate
+	content := strings.Repeat("日本語のテキスト\n", 500)
+
+	chunks := ChunkFile("notes.txt", content, "text")
+	if len(chunks) < 2 {
+		t.Fatalf("Expected dense text to be chunked, got %d chunk(s)", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if tokens := estimateTokens(chunk.Content); tokens > MaxTokensPerChunk {
+			t.Errorf("Chunk %d exceeds token limit: %d tokens (max %d)", i, tokens, MaxTokensPerChunk)
+		}
+	}
+}
+
 // TestChunkFile_SmallFile verifies small files are not chunked
 func TestChunkFile_SmallFile(t *testing.T) {
 	content := strings.Repeat("// Small file\n", 100) // ~1400 chars, ~350 tokens
@@ -242,6 +242,51 @@ func TestChunkFile_NoChunkExceedsTokenLimit(t *testing.T) {
 	t.Logf("Large file split into %d chunks, all within limits", len(chunks))
 }
 
+// TestChunkFile_EmbeddingWindow verifies chunks sized for bge-m3, whose
+// vocabulary is not bundled, stay within its 8192-token window with the
+// tokenizer's safety margin applied, whatever the content
+func TestChunkFile_EmbeddingWindow(t *testing.T) {
+	const window = 8192
+	tok := tokenizer.ForEmbeddingModel("bge-m3")
+
+	longGoLine := "var table = []string{" + strings.Repeat(`"abcdef", `, 6000) + "}"
+	tests := []struct {
+		name     string
+		path     string
+		language string
+		content  string
+	}{
+		{"dense text", "notes.txt", "text", strings.Repeat("日本語のテキスト\n", 2000)},
+		{"minified line", "app.min.js", "text", strings.Repeat("a=b[c]+d(e);", 10000)},
+		{"long line in declarations", "table.go", "go", "package table\n\nfunc f() {}\n\n" + longGoLine + "\n\nfunc g() {}\n"},
+		{"long section", "guide.md", "markdown", "# Guide\n\n" + strings.Repeat("Une phrase assez longue, répétée à l'infini. ", 2000)},
+		{"symbols", "data.txt", "text", strings.Repeat("⟨∀x∈ℝ: ∃ε>0⟩ ", 6000)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := ChunkFileWithTokenizer(tt.path, tt.content, tt.language, tok)
+			if len(chunks) < 2 {
+				t.Fatalf("Expected content to be chunked, got %d chunk(s)", len(chunks))
+			}
+			for i, chunk := range chunks {
+				if tokens := tok.Count(chunk.Content); tokens > MaxTokensPerChunk {
+					t.Errorf("Chunk %d exceeds token limit: %d tokens (max %d)", i, tokens, MaxTokensPerChunk)
+				}
+				if tokens := tok.Count(chunk.Header + "\n" + chunk.Content); tokens > window {
+					t.Errorf("Chunk %d exceeds the embedding window: %d tokens (max %d)", i, tokens, window)
+				}
+				if !utf8.ValidString(chunk.Content) {
+					t.Errorf("Chunk %d splits a rune", i)
+				}
+				if i > 0 && chunk.ChunkIndex <= chunks[i-1].ChunkIndex {
+					t.Errorf("Expected increasing chunk indexes, got %d after %d", chunk.ChunkIndex, chunks[i-1].ChunkIndex)
+				}
+			}
+		})
+	}
+}
+
 // TestChunkID_Stability verifies chunk IDs are stable and unique
 func TestChunkID_Stability(t *testing.T) {
 	content := strings.Repeat("func Test() {}\n", 500)
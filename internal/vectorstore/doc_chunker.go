@@ -0,0 +1,307 @@
+package vectorstore
+
+import (
+	"regexp"
+	"strings"
+)
+
+// docSection is a heading and the lines up to the next heading
+type docSection struct {
+	path  []string // Heading titles from the top level down to this section's
+	start int      // Index of the heading line (0 for text before the first heading)
+	end   int      // Index after the last line
+}
+
+var (
+	// atxHeading matches "## Title" markdown headings
+	atxHeading = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?[ \t#]*$`)
+
+	// setextUnderline matches the "===" (level 1) or "---" (level 2) line
+	// under a markdown heading
+	setextUnderline = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+
+	// fenceOpen matches the start of a markdown fenced code block
+	fenceOpen = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+)
+
+// chunkByHeadings splits documentation at its headings, packing whole
+// sections into chunks within MaxTokensPerChunk. Sections that don't fit are
+// split between paragraphs, never inside a fenced code block unless the block
+// alone exceeds the budget. Each chunk's header carries its heading path.
+func chunkByHeadings(filePath, language string, lines []string, lineTokens []int) []CodeChunk {
+	var chunks []CodeChunk
+	add := func(start, end int, path []string) {
+		heading := strings.Join(path, " > ")
+		var content strings.Builder
+		for _, line := range lines[start:end] {
+			content.WriteString(line)
+			content.WriteString("\n")
+		}
+		chunks = append(chunks, CodeChunk{
+			Content:    content.String(),
+			ChunkIndex: len(chunks),
+			StartLine:  start + 1,
+			EndLine:    end,
+			Header:     buildHeader(filePath, language, heading),
+			Symbol:     heading,
+		})
+	}
+
+	// Whole sections are packed until the next one would exceed the budget
+	packStart, packEnd, packTokens := 0, 0, 0
+	var packPath []string
+	flush := func() {
+		if packEnd > packStart {
+			add(packStart, packEnd, packPath)
+		}
+		packStart, packTokens = packEnd, 0
+	}
+
+	for _, section := range splitSections(lines, language) {
+		tokens := sumTokens(lineTokens, section.start, section.end)
+		if packTokens > 0 && packTokens+tokens > MaxTokensPerChunk {
+			flush()
+		}
+		if tokens <= MaxTokensPerChunk {
+			if packTokens == 0 {
+				packStart, packPath = section.start, section.path
+			}
+			packEnd = section.end
+			packTokens += tokens
+			continue
+		}
+
+		// Oversized section: pack its blocks instead
+		blockStart, blockTokens := section.start, 0
+		for _, block := range docBlocks(lines, language, section.start, section.end) {
+			tokens := sumTokens(lineTokens, block[0], block[1])
+			if blockTokens > 0 && blockTokens+tokens > MaxTokensPerChunk {
+				add(blockStart, block[0], section.path)
+				blockStart, blockTokens = block[0], 0
+			}
+			if tokens <= MaxTokensPerChunk {
+				blockTokens += tokens
+				continue
+			}
+
+			// A single block over the budget is split by lines
+			for _, piece := range chunkByLines(filePath, language, lines[block[0]:block[1]], lineTokens[block[0]:block[1]]) {
+				piece.ChunkIndex = len(chunks)
+				piece.StartLine += block[0]
+				piece.EndLine += block[0]
+				piece.Symbol = strings.Join(section.path, " > ")
+				piece.Header = buildHeader(filePath, language, piece.Symbol)
+				chunks = append(chunks, piece)
+			}
+			blockStart = block[1]
+		}
+		if blockStart < section.end {
+			add(blockStart, section.end, section.path)
+		}
+		packStart, packEnd = section.end, section.end
+	}
+	flush()
+
+	return chunks
+}
+
+// splitSections splits documentation into sections at its headings. Lines
+// inside fenced code blocks are never headings.
+func splitSections(lines []string, language string) []docSection {
+	var sections []docSection
+	var titles [6]string // Current title at each level
+	rstLevels := []string{}
+
+	current := docSection{}
+	startSection := func(start, level int, title string) {
+		if start > current.start {
+			current.end = start
+			sections = append(sections, current)
+		}
+		if level > len(titles) {
+			level = len(titles)
+		}
+		titles[level-1] = title
+		for i := level; i < len(titles); i++ {
+			titles[i] = ""
+		}
+		var path []string
+		for _, t := range titles[:level] {
+			if t != "" {
+				path = append(path, t)
+			}
+		}
+		current = docSection{path: path, start: start}
+	}
+
+	fence := ""
+	for i := frontMatterEnd(lines, language); i < len(lines); i++ {
+		line := lines[i]
+
+		if language == "restructuredtext" {
+			// Title with an underline, optionally also overlined
+			if isRSTAdornment(line) && i+2 < len(lines) &&
+				strings.TrimSpace(lines[i+1]) != "" && strings.TrimRight(lines[i+2], " \t") == strings.TrimRight(line, " \t") {
+				style := "over" + line[:1]
+				startSection(i, rstLevel(&rstLevels, style), strings.TrimSpace(lines[i+1]))
+				i += 2
+				continue
+			}
+			title := strings.TrimSpace(line)
+			if title != "" && !isRSTAdornment(line) && i+1 < len(lines) &&
+				isRSTAdornment(lines[i+1]) && len(strings.TrimSpace(lines[i+1])) >= len(title) {
+				startSection(i, rstLevel(&rstLevels, lines[i+1][:1]), title)
+				i++
+			}
+			continue
+		}
+
+		// Markdown
+		if fence != "" {
+			if closesFence(line, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if m := fenceOpen.FindStringSubmatch(line); m != nil {
+			fence = m[1]
+			continue
+		}
+		if m := atxHeading.FindStringSubmatch(line); m != nil {
+			startSection(i, len(m[1]), strings.TrimSpace(m[2]))
+			continue
+		}
+		if i+1 < len(lines) && isSetextTitle(line) {
+			if m := setextUnderline.FindStringSubmatch(lines[i+1]); m != nil {
+				level := 1
+				if m[1][0] == '-' {
+					level = 2
+				}
+				startSection(i, level, strings.TrimSpace(line))
+				i++
+			}
+		}
+	}
+
+	current.end = len(lines)
+	if current.end > current.start {
+		sections = append(sections, current)
+	}
+	return sections
+}
+
+// frontMatterEnd returns the index of the first line after a markdown file's
+// YAML front matter, whose closing "---" would otherwise underline a heading
+func frontMatterEnd(lines []string, language string) int {
+	if language != "markdown" || len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return 0
+	}
+	for i := 1; i < len(lines); i++ {
+		if trimmed := strings.TrimSpace(lines[i]); trimmed == "---" || trimmed == "..." {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// closesFence reports whether line closes a fenced code block opened with
+// fence: the same character, at least as many times, and nothing else
+func closesFence(line, fence string) bool {
+	trimmed := strings.TrimSpace(line)
+	return len(trimmed) >= len(fence) && strings.Trim(trimmed, fence[:1]) == ""
+}
+
+// isSetextTitle reports whether a markdown line can be the text of a heading
+// underlined with "===" or "---", rather than a list item, quote or table row
+// followed by a thematic break
+func isSetextTitle(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed != "" && !strings.HasPrefix(line, "    ") && !strings.ContainsAny(trimmed[:1], "-*+>|#")
+}
+
+// rstAdornmentChars are the punctuation characters reStructuredText section
+// titles are underlined with
+const rstAdornmentChars = "=-~^\"'`#*+:._"
+
+// isRSTAdornment reports whether line is a reStructuredText section underline
+// or overline: one adornment character repeated at least three times
+func isRSTAdornment(line string) bool {
+	line = strings.TrimRight(line, " \t")
+	return len(line) >= 3 && strings.ContainsRune(rstAdornmentChars, rune(line[0])) &&
+		strings.Trim(line, line[:1]) == ""
+}
+
+// rstLevel returns the level of a reStructuredText adornment style; levels
+// are assigned in the order styles first appear
+func rstLevel(levels *[]string, style string) int {
+	for i, s := range *levels {
+		if s == style {
+			return i + 1
+		}
+	}
+	*levels = append(*levels, style)
+	return len(*levels)
+}
+
+// docBlocks splits lines [start, end) into blocks that are kept whole where
+// possible: paragraphs, and fenced code blocks (markdown) or literal blocks
+// (reStructuredText) including their blank lines. Blank lines belong to the
+// block before them.
+func docBlocks(lines []string, language string, start, end int) [][2]int {
+	var blocks [][2]int
+	blockStart := start
+	fence := ""
+	literal := false // In an indented reStructuredText literal block
+
+	for i := start; i < end; i++ {
+		line := lines[i]
+		blank := strings.TrimSpace(line) == ""
+
+		switch {
+		case fence != "":
+			if closesFence(line, fence) {
+				fence = ""
+			}
+			continue
+		case literal:
+			if blank || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+				continue
+			}
+			literal = false
+		}
+
+		if language != "restructuredtext" {
+			if m := fenceOpen.FindStringSubmatch(line); m != nil {
+				if i > blockStart {
+					blocks = append(blocks, [2]int{blockStart, i})
+					blockStart = i
+				}
+				fence = m[1]
+				continue
+			}
+		} else if strings.HasSuffix(strings.TrimSpace(line), "::") {
+			literal = true
+			continue
+		}
+
+		// A paragraph ends at the blank lines after it
+		if blank && i+1 < end && strings.TrimSpace(lines[i+1]) != "" {
+			blocks = append(blocks, [2]int{blockStart, i + 1})
+			blockStart = i + 1
+		}
+	}
+
+	if end > blockStart {
+		blocks = append(blocks, [2]int{blockStart, end})
+	}
+	return blocks
+}
+
+// sumTokens totals lineTokens over lines [start, end)
+func sumTokens(lineTokens []int, start, end int) int {
+	total := 0
+	for _, tokens := range lineTokens[start:end] {
+		total += tokens
+	}
+	return total
+}
@@ -0,0 +1,173 @@
+package vectorstore
+
+import (
+	"strings"
+	"testing"
+)
+
+// docChunk is the part of a CodeChunk the doc chunker tests check
+type docChunk struct {
+	start, end int
+	header     string
+}
+
+func checkDocChunks(t *testing.T, chunks []CodeChunk, want []docChunk) {
+	t.Helper()
+	if len(chunks) != len(want) {
+		for _, c := range chunks {
+			t.Logf("chunk lines %d-%d: %s", c.StartLine, c.EndLine, c.Header)
+		}
+		t.Fatalf("Expected %d chunks, got %d", len(want), len(chunks))
+	}
+	for i, c := range chunks {
+		if c.StartLine != want[i].start || c.EndLine != want[i].end || c.Header != want[i].header {
+			t.Errorf("chunk %d = lines %d-%d %q, want lines %d-%d %q",
+				i, c.StartLine, c.EndLine, c.Header, want[i].start, want[i].end, want[i].header)
+		}
+		if c.ChunkIndex != i {
+			t.Errorf("chunk %d has index %d", i, c.ChunkIndex)
+		}
+	}
+}
+
+func TestChunkByHeadings_Sections(t *testing.T) {
+	lines := []string{
+		"# Guide",
+		"intro",
+		"## Install",
+		"```sh",
+		"# not a heading",
+		"```",
+		"## Usage",
+		"text",
+	}
+	// Guide and Install together exceed MaxTokensPerChunk, as do Install and Usage
+	tokens := []int{10, 2000, 10, 10, 1500, 10, 10, 2000}
+
+	chunks := chunkByHeadings("README.md", "markdown", lines, tokens)
+	checkDocChunks(t, chunks, []docChunk{
+		{1, 2, "README.md :: markdown :: Guide"},
+		{3, 6, "README.md :: markdown :: Guide > Install"},
+		{7, 8, "README.md :: markdown :: Guide > Usage"},
+	})
+	if !strings.Contains(chunks[1].Content, "```sh\n# not a heading\n```\n") {
+		t.Errorf("Expected the fenced block intact, got %q", chunks[1].Content)
+	}
+}
+
+func TestChunkByHeadings_PacksSmallSections(t *testing.T) {
+	lines := []string{"# A", "a", "## B", "b", "# C", "c"}
+	tokens := []int{1, 1, 1, 1, 1, 1}
+
+	checkDocChunks(t, chunkByHeadings("doc.md", "markdown", lines, tokens), []docChunk{
+		{1, 6, "doc.md :: markdown :: A"},
+	})
+}
+
+func TestChunkByHeadings_OversizedSection(t *testing.T) {
+	lines := []string{
+		"# Big",
+		"",
+		"first paragraph",
+		"",
+		"second paragraph",
+		"",
+		"```go",
+		"code",
+		"```",
+		"last paragraph",
+	}
+	tokens := []int{1, 1, 2000, 1, 2000, 1, 1, 2000, 1, 1}
+
+	// Split between paragraphs, with the code block kept whole
+	checkDocChunks(t, chunkByHeadings("doc.md", "markdown", lines, tokens), []docChunk{
+		{1, 4, "doc.md :: markdown :: Big"},
+		{5, 6, "doc.md :: markdown :: Big"},
+		{7, 10, "doc.md :: markdown :: Big"},
+	})
+}
+
+func TestChunkByHeadings_OversizedBlock(t *testing.T) {
+	lines := []string{"# Dump", "```"}
+	tokens := []int{1, 1}
+	for i := 0; i < 100; i++ {
+		lines = append(lines, "line")
+		tokens = append(tokens, 100)
+	}
+	lines = append(lines, "```")
+	tokens = append(tokens, 1)
+
+	chunks := chunkByHeadings("doc.md", "markdown", lines, tokens)
+	if len(chunks) < 3 {
+		t.Fatalf("Expected the block split by lines, got %d chunks", len(chunks))
+	}
+	for i, c := range chunks {
+		if c.Header != "doc.md :: markdown :: Dump" {
+			t.Errorf("chunk %d header = %q", i, c.Header)
+		}
+		if c.ChunkIndex != i {
+			t.Errorf("chunk %d has index %d", i, c.ChunkIndex)
+		}
+	}
+	if last := chunks[len(chunks)-1]; last.EndLine != len(lines) {
+		t.Errorf("Expected the last chunk to end at line %d, got %d", len(lines), last.EndLine)
+	}
+}
+
+func TestChunkByHeadings_ReStructuredText(t *testing.T) {
+	lines := []string{
+		"=====",
+		"Title",
+		"=====",
+		"intro",
+		"Setup",
+		"-----",
+		"text",
+		"Run",
+		"---",
+		"more",
+	}
+	tokens := []int{1, 1, 1, 2000, 1, 1, 2000, 1, 1, 2000}
+
+	checkDocChunks(t, chunkByHeadings("index.rst", "restructuredtext", lines, tokens), []docChunk{
+		{1, 4, "index.rst :: restructuredtext :: Title"},
+		{5, 7, "index.rst :: restructuredtext :: Title > Setup"},
+		{8, 10, "index.rst :: restructuredtext :: Title > Run"},
+	})
+}
+
+func TestSplitSections(t *testing.T) {
+	lines := []string{
+		"---",
+		"title: x",
+		"---",
+		"Intro",
+		"=====",
+		"text",
+		"Sub",
+		"---",
+		"- item",
+		"---",
+		"#### Deep",
+	}
+
+	want := []struct {
+		start int
+		path  string
+	}{
+		{0, ""}, // Front matter
+		{3, "Intro"},
+		{6, "Intro > Sub"}, // The list item's thematic break is no heading
+		{10, "Intro > Sub > Deep"},
+	}
+
+	sections := splitSections(lines, "markdown")
+	if len(sections) != len(want) {
+		t.Fatalf("Expected %d sections, got %+v", len(want), sections)
+	}
+	for i, s := range sections {
+		if s.start != want[i].start || strings.Join(s.path, " > ") != want[i].path {
+			t.Errorf("section %d = %d %v, want %d %q", i, s.start, s.path, want[i].start, want[i].path)
+		}
+	}
+}
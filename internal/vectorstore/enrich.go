@@ -0,0 +1,302 @@
+package vectorstore
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/First008/mesh/internal/filetypes"
+)
+
+// EmbedContext is what an embed template renders the text embedded for a
+// chunk from
+type EmbedContext struct {
+	Path      string   // File path relative to the repository root
+	Language  string   // Language of the file
+	Package   string   // Package, module or namespace the file declares ("" if none)
+	Imports   []string // What the file imports, up to maxEmbedImports
+	Symbol    string   // Declaration the chunk starts with, or its heading path in documentation
+	Enclosing string   // Declaration a chunk starting mid-body belongs to ("" otherwise)
+	Header    string   // The chunker's "path :: language :: symbol" line
+	Content   string   // The chunk, as stored for the LLM
+}
+
+// EmbedTemplateRaw is the embed template setting that embeds chunk content
+// as is, as before embeddings were enriched
+const EmbedTemplateRaw = "raw"
+
+// DefaultEmbedTemplate renders the header, the enclosing declaration, the
+// package and imports above the chunk
+const DefaultEmbedTemplate = `{{.Header}}{{with .Enclosing}} (in {{.}}){{end}}
+{{with .Package}}package {{.}}
+{{end}}{{with .Imports}}imports: {{join . ", "}}
+{{end}}
+{{.Content}}`
+
+// maxEmbedImports caps the imports listed in embedded text, so a long
+// import block doesn't drown out the chunk
+const maxEmbedImports = 10
+
+// EmbedTemplate renders the text embedded for each chunk. Only the embedding
+// sees it: the payload keeps the raw chunk, which is what the LLM is shown.
+// A nil EmbedTemplate is the default one.
+type EmbedTemplate struct {
+	tmpl        *template.Template // nil embeds content as is
+	fingerprint string             // Identifies the template ("" for raw)
+}
+
+var defaultEmbedTemplate = func() *EmbedTemplate {
+	t, err := parseEmbedTemplate(DefaultEmbedTemplate)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}()
+
+// embedFuncs are the functions embed templates can call besides the
+// text/template built-ins
+var embedFuncs = template.FuncMap{"join": strings.Join}
+
+// ParseEmbedTemplate parses a configured embed template: "" is the default,
+// "raw" embeds content as is, anything else is a text/template over
+// EmbedContext
+func ParseEmbedTemplate(source string) (*EmbedTemplate, error) {
+	switch source {
+	case "":
+		return defaultEmbedTemplate, nil
+	case EmbedTemplateRaw:
+		return &EmbedTemplate{}, nil
+	}
+	return parseEmbedTemplate(source)
+}
+
+// parseEmbedTemplate parses and test-renders a text/template over EmbedContext
+func parseEmbedTemplate(source string) (*EmbedTemplate, error) {
+	tmpl, err := template.New("embed").Funcs(embedFuncs).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("embed_template: %w", err)
+	}
+	// Catch references to unknown fields now rather than for every chunk
+	sample := EmbedContext{Path: "a.go", Language: "go", Package: "a", Imports: []string{"fmt"}, Header: "a.go :: go", Content: "\n"}
+	if err := tmpl.Execute(&strings.Builder{}, sample); err != nil {
+		return nil, fmt.Errorf("embed_template: %w", err)
+	}
+	return &EmbedTemplate{tmpl: tmpl, fingerprint: fmt.Sprintf("%x", sha256.Sum256([]byte(source)))[:16]}, nil
+}
+
+// Fingerprint identifies the template, so a change can trigger re-embedding.
+// Returns "" for raw, which is how branches indexed before enrichment are
+// recorded.
+func (t *EmbedTemplate) Fingerprint() string {
+	if t == nil {
+		t = defaultEmbedTemplate
+	}
+	return t.fingerprint
+}
+
+// Render returns the text to embed for a chunk
+func (t *EmbedTemplate) Render(c EmbedContext) (string, error) {
+	if t == nil {
+		t = defaultEmbedTemplate
+	}
+	if t.tmpl == nil {
+		return c.Content, nil
+	}
+	var text strings.Builder
+	if err := t.tmpl.Execute(&text, c); err != nil {
+		return "", err
+	}
+	return text.String(), nil
+}
+
+// SetEmbedTemplate sets the text embedded for each chunk; nil means the
+// default template. Indexed branches are re-embedded when it changes.
+func (idx *Indexer) SetEmbedTemplate(t *EmbedTemplate) {
+	idx.embedTemplate = t
+}
+
+// embedFingerprint identifies how chunks are embedded: the template's
+// fingerprint, or "" when they are embedded as is. Stores that aren't
+// ChunkIndexers only get chunk content.
+func (idx *Indexer) embedFingerprint() string {
+	if _, ok := idx.store.(ChunkIndexer); !ok {
+		return ""
+	}
+	return idx.embedTemplate.Fingerprint()
+}
+
+// embedTexts renders the embedding text of each chunk of a file from the
+// file's outline; chunks whose template fails are embedded as is
+func (idx *Indexer) embedTexts(relPath, language, content string, chunks []CodeChunk) []string {
+	if idx.embedFingerprint() == "" {
+		return make([]string, len(chunks))
+	}
+
+	lines := strings.Split(content, "\n")
+	pkg, imports := fileOutline(language, lines)
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		c := EmbedContext{
+			Path:     relPath,
+			Language: language,
+			Package:  pkg,
+			Imports:  imports,
+			Symbol:   chunk.Symbol,
+			Header:   chunk.Header,
+			Content:  chunk.Content,
+		}
+		if !filetypes.IsDocLanguage(language) {
+			c.Enclosing = enclosingSymbol(lines, chunk.StartLine-1)
+		}
+
+		text, err := idx.embedTemplate.Render(c)
+		if err != nil {
+			idx.logger.Warn().Err(err).Str("path", relPath).Msg("Embed template failed, embedding chunk as is")
+			continue
+		}
+		texts[i] = text
+	}
+	return texts
+}
+
+// outlinePatterns find a file's package and imports for one family of
+// languages; the first submatch is the name
+type outlinePatterns struct {
+	pkg     *regexp.Regexp
+	imports []*regexp.Regexp
+}
+
+var (
+	goOutline = outlinePatterns{
+		pkg: regexp.MustCompile(`^package\s+(\w+)`),
+		imports: []*regexp.Regexp{
+			regexp.MustCompile(`^import\s+(?:[\w.]+\s+)?"([^"]+)"`),
+			regexp.MustCompile(`^\s+(?:[\w.]+\s+)?"([^"]+)"\s*(?://.*)?$`), // Inside import ( ... )
+		},
+	}
+	jvmOutline = outlinePatterns{
+		pkg:     regexp.MustCompile(`^package\s+([\w.]+)`),
+		imports: []*regexp.Regexp{regexp.MustCompile(`^import\s+(?:static\s+)?([\w.*]+)`)},
+	}
+
+	// languageOutlines map languages to their outline patterns
+	languageOutlines = map[string]outlinePatterns{
+		"go":     goOutline,
+		"java":   jvmOutline,
+		"kotlin": jvmOutline,
+		"scala":  jvmOutline,
+		"python": {imports: []*regexp.Regexp{
+			regexp.MustCompile(`^import\s+([\w.]+)`),
+			regexp.MustCompile(`^from\s+([\w.]+)\s+import\b`),
+		}},
+		"typescript": {imports: []*regexp.Regexp{
+			regexp.MustCompile(`^import\s.*\bfrom\s+['"]([^'"]+)['"]`),
+			regexp.MustCompile(`^import\s+['"]([^'"]+)['"]`),
+			regexp.MustCompile(`^\}\s*from\s+['"]([^'"]+)['"]`), // End of a multi-line import
+			regexp.MustCompile(`\brequire\(\s*['"]([^'"]+)['"]\s*\)`),
+		}},
+		"rust": {imports: []*regexp.Regexp{regexp.MustCompile(`^(?:pub\s+)?use\s+([\w:]+)`)}},
+		"csharp": {
+			pkg:     regexp.MustCompile(`^namespace\s+([\w.]+)`),
+			imports: []*regexp.Regexp{regexp.MustCompile(`^using\s+(?:static\s+)?([\w.]+)\s*;`)},
+		},
+	}
+)
+
+// fileOutline returns the package a file declares and up to maxEmbedImports
+// of its imports
+func fileOutline(language string, lines []string) (string, []string) {
+	if language == "javascript" {
+		language = "typescript"
+	}
+	patterns, ok := languageOutlines[language]
+	if !ok {
+		return "", nil
+	}
+
+	pkg := ""
+	var imports []string
+	seen := make(map[string]bool)
+	inImportBlock := false // Go's import ( ... )
+	for _, line := range lines {
+		if pkg == "" && patterns.pkg != nil {
+			if m := patterns.pkg.FindStringSubmatch(line); m != nil {
+				pkg = m[1]
+				continue
+			}
+		}
+
+		if language == "go" {
+			switch {
+			case strings.HasPrefix(line, "import ("):
+				inImportBlock = true
+				continue
+			case inImportBlock && strings.HasPrefix(line, ")"):
+				inImportBlock = false
+				continue
+			case !inImportBlock && !strings.HasPrefix(line, "import "):
+				continue
+			}
+		}
+
+		for _, re := range patterns.imports {
+			m := re.FindStringSubmatch(line)
+			if m == nil || seen[m[1]] {
+				continue
+			}
+			seen[m[1]] = true
+			if len(imports) < maxEmbedImports {
+				imports = append(imports, m[1])
+			}
+			break
+		}
+	}
+	return pkg, imports
+}
+
+// declarationPattern matches declarations of languages extractSymbol doesn't
+// know: "def name", "pub fn name", "public class Name", ...
+var declarationPattern = regexp.MustCompile(`^\s*(?:(?:export|default|pub(?:\([\w:]+\))?|public|private|protected|internal|static|abstract|final|async|open|data|sealed)\s+)*` +
+	`(def|class|fn|fun|func|function|struct|enum|trait|impl|interface|object|module)\s+([A-Za-z_]\w*)`)
+
+// declarationSymbol returns the symbol a line declares, or ""
+func declarationSymbol(line string) string {
+	if m := declarationPattern.FindStringSubmatch(line); m != nil {
+		return m[1] + " " + m[2]
+	}
+	return extractSymbol(line)
+}
+
+// enclosingSymbol returns the declaration the line at index start is inside
+// of, looking back to the nearest declaration. A chunk starting at a
+// declaration, or after a line back at the top level ("}" closing the
+// previous one), has none.
+func enclosingSymbol(lines []string, start int) string {
+	if start <= 0 || start >= len(lines) || declarationSymbol(lines[start]) != "" {
+		return ""
+	}
+	for i := start - 1; i >= 0; i-- {
+		line := lines[i]
+		if symbol := declarationSymbol(line); symbol != "" {
+			return symbol
+		}
+		// Back at the top level without finding a declaration
+		if line != "" && line[0] != ' ' && line[0] != '\t' && !isCommentLine(line) {
+			return ""
+		}
+	}
+	return ""
+}
+
+// isCommentLine reports whether a line is a comment or decorator, which sit
+// at the top level above declarations
+func isCommentLine(line string) bool {
+	for _, prefix := range []string{"//", "/*", "*", "#", "@", "--"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,204 @@
+package vectorstore
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseEmbedTemplate(t *testing.T) {
+	def, err := ParseEmbedTemplate("")
+	if err != nil || def.Fingerprint() == "" {
+		t.Fatalf("Expected the default template with a fingerprint, got %q, %v", def.Fingerprint(), err)
+	}
+	if (*EmbedTemplate)(nil).Fingerprint() != def.Fingerprint() {
+		t.Error("Expected nil to be the default template")
+	}
+
+	raw, _ := ParseEmbedTemplate("raw")
+	if raw.Fingerprint() != "" {
+		t.Errorf("Expected raw to fingerprint as unenriched, got %q", raw.Fingerprint())
+	}
+
+	custom, err := ParseEmbedTemplate("{{.Path}}\n{{.Content}}")
+	if err != nil || custom.Fingerprint() == "" || custom.Fingerprint() == def.Fingerprint() {
+		t.Errorf("Expected a distinct fingerprint for a custom template, got %q, %v", custom.Fingerprint(), err)
+	}
+
+	for _, source := range []string{"{{.Path", "{{.Module}}", "{{upper .Path}}"} {
+		if _, err := ParseEmbedTemplate(source); err == nil {
+			t.Errorf("Expected ParseEmbedTemplate(%q) to fail", source)
+		}
+	}
+}
+
+func TestEmbedTemplate_Render(t *testing.T) {
+	c := EmbedContext{
+		Path:      "internal/api/handler.go",
+		Language:  "go",
+		Package:   "api",
+		Imports:   []string{"context", "net/http"},
+		Symbol:    "func handle",
+		Enclosing: "func (s *Server) routes",
+		Header:    "internal/api/handler.go :: go :: func handle",
+		Content:   "func handle() {}\n",
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"default", "", "internal/api/handler.go :: go :: func handle (in func (s *Server) routes)\n" +
+			"package api\nimports: context, net/http\n\nfunc handle() {}\n"},
+		{"raw", "raw", "func handle() {}\n"},
+		{"custom", "{{.Path}} {{.Symbol}}\n{{.Content}}", "internal/api/handler.go func handle\nfunc handle() {}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := ParseEmbedTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("ParseEmbedTemplate() error = %v", err)
+			}
+			got, err := tmpl.Render(c)
+			if err != nil || got != tt.want {
+				t.Errorf("Render() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	// Empty fields leave no lines behind
+	got, _ := (*EmbedTemplate)(nil).Render(EmbedContext{Header: "notes.md :: markdown", Content: "text\n"})
+	if got != "notes.md :: markdown\n\ntext\n" {
+		t.Errorf("Render() = %q", got)
+	}
+}
+
+func TestFileOutline(t *testing.T) {
+	tests := []struct {
+		language    string
+		content     string
+		wantPackage string
+		wantImports []string
+	}{
+		{"go", "package api\n\nimport (\n\t\"context\"\n\tchi \"github.com/go-chi/chi/v5\" // router\n)\n\nfunc f() { x := \"not an import\" }\n",
+			"api", []string{"context", "github.com/go-chi/chi/v5"}},
+		{"go", "package main\n\nimport \"fmt\"\n", "main", []string{"fmt"}},
+		{"python", "import os\nfrom app.models import User\nimport os\n", "", []string{"os", "app.models"}},
+		{"javascript", "import {\n  a,\n} from './a'\nimport './styles.css'\nconst b = require('b')\n", "", []string{"./a", "./styles.css", "b"}},
+		{"java", "package com.example.api;\n\nimport java.util.List;\nimport static org.junit.Assert.*;\n",
+			"com.example.api", []string{"java.util.List", "org.junit.Assert.*"}},
+		{"ruby", "require 'json'\n", "", nil},
+	}
+
+	for _, tt := range tests {
+		pkg, imports := fileOutline(tt.language, strings.Split(tt.content, "\n"))
+		if pkg != tt.wantPackage || strings.Join(imports, ",") != strings.Join(tt.wantImports, ",") {
+			t.Errorf("fileOutline(%s) = %q, %v; want %q, %v", tt.language, pkg, imports, tt.wantPackage, tt.wantImports)
+		}
+	}
+
+	var many []string
+	for i := 0; i < 2*maxEmbedImports; i++ {
+		many = append(many, "import mod"+strings.Repeat("x", i))
+	}
+	if _, imports := fileOutline("python", many); len(imports) != maxEmbedImports {
+		t.Errorf("Expected imports capped at %d, got %d", maxEmbedImports, len(imports))
+	}
+}
+
+func TestEnclosingSymbol(t *testing.T) {
+	goLines := strings.Split(`package api
+
+// Server serves the API
+type Server struct {
+	mux *http.ServeMux
+}
+
+func (s *Server) routes() {
+	s.mux.HandleFunc("/", s.index)
+
+	s.mux.HandleFunc("/users", s.users)
+}
+
+// index serves the home page
+func (s *Server) index() {}
+`, "\n")
+	pyLines := strings.Split(`class Repo:
+    @property
+    def name(self):
+        value = self._name
+
+        return value
+`, "\n")
+
+	tests := []struct {
+		name  string
+		lines []string
+		start int
+		want  string
+	}{
+		{"inside method", goLines, 10, "func routes"},
+		{"inside struct", goLines, 4, "type Server"},
+		{"at declaration", goLines, 7, ""},
+		{"between declarations", goLines, 13, ""},
+		{"first line", goLines, 0, ""},
+		{"python method", pyLines, 5, "def name"},
+	}
+
+	for _, tt := range tests {
+		if got := enclosingSymbol(tt.lines, tt.start); got != tt.want {
+			t.Errorf("%s: enclosingSymbol(%d) = %q, want %q", tt.name, tt.start, got, tt.want)
+		}
+	}
+}
+
+func TestIndexIncremental_EmbedTemplate(t *testing.T) {
+	src, _ := initBareRepo(t)
+
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	store := newChunkStore()
+	index := func(template string) {
+		t.Helper()
+		tmpl, err := ParseEmbedTemplate(template)
+		if err != nil {
+			t.Fatalf("ParseEmbedTemplate failed: %v", err)
+		}
+		indexer := NewIndexerWithBranch(store, src, "repo", "feature", testLogger())
+		indexer.SetEmbedTemplate(tmpl)
+		if err := indexer.IndexIncremental(context.Background()); err != nil {
+			t.Fatalf("IndexIncremental failed: %v", err)
+		}
+	}
+
+	// The embedding gets the header and package, the payload the raw file
+	index("")
+	if got := store.embeds["b.go"]; got != "b.go :: go\npackage b\n\npackage b\n" {
+		t.Errorf("Expected enriched embedding text, got %q", got)
+	}
+	if got := store.indexed["b.go"]; got != "package b\n" {
+		t.Errorf("Expected raw content to be stored, got %q", got)
+	}
+
+	// Same template: nothing to do
+	indexed := store.indexCnt
+	index("")
+	if store.indexCnt != indexed {
+		t.Errorf("Expected no re-embedding with the same template, got %d index calls", store.indexCnt-indexed)
+	}
+
+	// A new template re-embeds every file, though none changed
+	index("raw")
+	if got := store.indexCnt - indexed; got != len(store.indexed) {
+		t.Errorf("Expected all %d files re-embedded, got %d index calls", len(store.indexed), got)
+	}
+	if got := store.embeds["b.go"]; got != "" {
+		t.Errorf("Expected raw content to be embedded, got %q", got)
+	}
+}
@@ -0,0 +1,185 @@
+package vectorstore
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/First008/mesh/internal/filetypes"
+	"github.com/First008/mesh/internal/ignore"
+	"github.com/rs/zerolog"
+)
+
+// MeshIgnoreFile is a repository-level ignore file, in .gitignore syntax, for
+// files that are tracked but should not be indexed
+const MeshIgnoreFile = ".meshignore"
+
+// pathFilter decides which files of a run are indexed
+type pathFilter struct {
+	ignored  *ignore.Matcher     // .gitignore and .meshignore files
+	excluded *ignore.Matcher     // Configured exclude patterns, which ignore files can't override
+	focus    *ignore.Matcher     // Focus paths; empty means the whole repository
+	types    *filetypes.Registry // Which files are code (nil = built-in)
+	hash     string              // Fingerprint of all rules ("" when there are none)
+}
+
+// newPathFilter creates a filter with the configured patterns and file types
+// and no ignore files
+func newPathFilter(exclude, focus []string, types *filetypes.Registry) *pathFilter {
+	filter := &pathFilter{ignored: ignore.New(), excluded: ignore.New(), focus: ignore.New(), types: types}
+	filter.excluded.AddPatterns("", exclude)
+	filter.focus.AddPatterns("", anchorFocusPaths(focus))
+	return filter
+}
+
+// indexable reports whether relPath is a code file that passes the filter.
+// Extension-less files pass as possible scripts; their shebang decides once
+// they are read (see Indexer.fileChunks).
+func (f *pathFilter) indexable(relPath string) bool {
+	if f == nil {
+		return isCodeFile(relPath)
+	}
+	if !f.types.MaybeCode(relPath) {
+		return false
+	}
+	if f.ignored.Match(relPath) || f.excluded.Match(relPath) {
+		return false
+	}
+	return f.focus.Empty() || f.focus.Match(relPath)
+}
+
+// skipsDir reports whether no file below the directory relPath can pass
+func (f *pathFilter) skipsDir(relPath string) bool {
+	return f.ignored.MatchDir(relPath) || f.excluded.MatchDir(relPath)
+}
+
+// SetPatterns sets the configured exclude patterns and focus paths applied at
+// index time, on top of .gitignore and .meshignore files. Both use .gitignore
+// syntax; focus paths are relative to the repository root.
+func (idx *Indexer) SetPatterns(exclude, focus []string) {
+	idx.excludePatterns = exclude
+	idx.focusPaths = focus
+}
+
+// SetFileTypes sets which files are code and in which language; nil means
+// the built-in registry. A change alters the filter hash, so the next run
+// reconciles.
+func (idx *Indexer) SetFileTypes(types *filetypes.Registry) {
+	idx.fileTypes = types
+}
+
+// loadFilter builds the filter for a run from the ignore files in src and the
+// configured patterns. files is the listing of src.
+func (idx *Indexer) loadFilter(src fileSource, files []string) *pathFilter {
+	filter := newPathFilter(idx.excludePatterns, idx.focusPaths, idx.fileTypes)
+	fingerprint := sha256.New()
+
+	addFile := func(relPath string) {
+		content, err := src.ReadFile(relPath)
+		if err != nil {
+			idx.logger.Warn().Err(err).Str("path", relPath).Msg("Failed to read ignore file")
+			return
+		}
+		filter.ignored.AddFile(ignoreFileDir(relPath), content)
+		fmt.Fprintf(fingerprint, "%s\x00%x\x00", relPath, sha256.Sum256(content))
+	}
+
+	// Nested .gitignore files, then .meshignore, which takes precedence at the root
+	hasMeshIgnore := false
+	for _, relPath := range files {
+		switch {
+		case path.Base(relPath) == ".gitignore":
+			addFile(relPath)
+		case relPath == MeshIgnoreFile:
+			hasMeshIgnore = true
+		}
+	}
+	if hasMeshIgnore {
+		addFile(MeshIgnoreFile)
+	}
+
+	fmt.Fprintf(fingerprint, "exclude\x00%s\x00focus\x00%s",
+		strings.Join(idx.excludePatterns, "\x00"), strings.Join(idx.focusPaths, "\x00"))
+	if types := idx.fileTypes.Fingerprint(); types != "" {
+		fmt.Fprintf(fingerprint, "\x00types\x00%s", types)
+	}
+
+	if !filter.ignored.Empty() || !filter.excluded.Empty() || !filter.focus.Empty() || idx.fileTypes.Fingerprint() != "" {
+		filter.hash = fmt.Sprintf("%x", fingerprint.Sum(nil))
+	}
+	return filter
+}
+
+// startFilter loads the filter for a run reading from src
+func (idx *Indexer) startFilter(src fileSource) error {
+	files, err := src.ListFiles()
+	if err != nil {
+		return fmt.Errorf("list files: %w", err)
+	}
+	idx.filter = idx.loadFilter(src, files)
+	return nil
+}
+
+// indexableFiles lists the files of src that pass the run's filter
+func (idx *Indexer) indexableFiles(src fileSource) ([]string, error) {
+	files, err := src.ListFiles()
+	if err != nil {
+		return nil, fmt.Errorf("list files: %w", err)
+	}
+
+	indexable := files[:0]
+	for _, relPath := range files {
+		if idx.filter.indexable(relPath) {
+			indexable = append(indexable, relPath)
+		}
+	}
+	return indexable, nil
+}
+
+// loadDiskFilter builds a filter from the ignore files at the root of the
+// checkout at repoPath. Nested .gitignore files are added with
+// addDiskIgnoreFile as a walk enters their directory.
+func loadDiskFilter(repoPath string, exclude, focus []string, types *filetypes.Registry, logger zerolog.Logger) *pathFilter {
+	filter := newPathFilter(exclude, focus, types)
+	filter.addDiskIgnoreFile(repoPath, ".gitignore", logger)
+	filter.addDiskIgnoreFile(repoPath, MeshIgnoreFile, logger)
+	return filter
+}
+
+// addDiskIgnoreFile adds the ignore file at relPath in the checkout, if any
+func (f *pathFilter) addDiskIgnoreFile(repoPath, relPath string, logger zerolog.Logger) {
+	content, err := os.ReadFile(filepath.Join(repoPath, filepath.FromSlash(relPath)))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warn().Err(err).Str("path", relPath).Msg("Failed to read ignore file")
+		}
+		return
+	}
+	f.ignored.AddFile(ignoreFileDir(relPath), content)
+}
+
+// ignoreFileDir returns the directory an ignore file's rules apply below
+func ignoreFileDir(relPath string) string {
+	dir := path.Dir(relPath)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// anchorFocusPaths anchors focus paths to the repository root, so "src/**"
+// does not also match a nested "lib/src" directory
+func anchorFocusPaths(focus []string) []string {
+	anchored := make([]string, 0, len(focus))
+	for _, pattern := range focus {
+		if pattern == "" || strings.HasPrefix(pattern, "/") || strings.HasPrefix(pattern, "**") {
+			anchored = append(anchored, pattern)
+			continue
+		}
+		anchored = append(anchored, "/"+pattern)
+	}
+	return anchored
+}
@@ -0,0 +1,262 @@
+package vectorstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/First008/mesh/internal/filetypes"
+)
+
+// mapSource serves files from memory
+type mapSource map[string]string
+
+func (s mapSource) ListFiles() ([]string, error) {
+	files := make([]string, 0, len(s))
+	for relPath := range s {
+		files = append(files, relPath)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func (s mapSource) ReadFile(relPath string) ([]byte, error) {
+	content, ok := s[relPath]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return []byte(content), nil
+}
+
+func (s mapSource) Close() error {
+	return nil
+}
+
+func TestLoadFilter(t *testing.T) {
+	src := mapSource{
+		".gitignore":          "*.gen.go\n",
+		"api/.gitignore":      "!keep.gen.go\nlocal/\n",
+		MeshIgnoreFile:        "testdata/\n",
+		"main.go":             "package main",
+		"x.gen.go":            "package main",
+		"api/keep.gen.go":     "package api",
+		"api/local/a.go":      "package local",
+		"api/handler.go":      "package api",
+		"api/handler_test.go": "package api",
+		"testdata/fixture.go": "package testdata",
+		"internal/core.go":    "package internal",
+		"lib/api/nested.go":   "package api",
+		"README.md":           "# readme",
+	}
+
+	tests := []struct {
+		name    string
+		exclude []string
+		focus   []string
+		path    string
+		want    bool
+	}{
+		{"plain file", nil, nil, "main.go", true},
+		{"root gitignore", nil, nil, "x.gen.go", false},
+		{"nested negation", nil, nil, "api/keep.gen.go", true},
+		{"nested directory", nil, nil, "api/local/a.go", false},
+		{"meshignore", nil, nil, "testdata/fixture.go", false},
+		{"exclude pattern", []string{"**/*_test.go"}, nil, "api/handler_test.go", false},
+		{"exclude beats gitignore negation", []string{"*.gen.go"}, nil, "api/keep.gen.go", false},
+		{"inside focus", nil, []string{"api/**"}, "api/handler.go", true},
+		{"outside focus", nil, []string{"api/**"}, "internal/core.go", false},
+		{"focus is anchored", nil, []string{"api/"}, "lib/api/nested.go", false},
+		{"focus double star", nil, []string{"**/api/"}, "lib/api/nested.go", true},
+		{"ignored inside focus", nil, []string{"api/"}, "api/local/a.go", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := NewIndexer(newMockStore(), "", testLogger())
+			idx.SetPatterns(tt.exclude, tt.focus)
+			files, _ := src.ListFiles()
+			filter := idx.loadFilter(src, files)
+			if got := filter.indexable(tt.path); got != tt.want {
+				t.Errorf("indexable(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFilter_Hash(t *testing.T) {
+	idx := NewIndexer(newMockStore(), "", testLogger())
+	src := mapSource{"main.go": "package main"}
+	files, _ := src.ListFiles()
+
+	if hash := idx.loadFilter(src, files).hash; hash != "" {
+		t.Errorf("Expected no fingerprint without rules, got %q", hash)
+	}
+
+	idx.SetPatterns([]string{"*.pb.go"}, nil)
+	withPattern := idx.loadFilter(src, files).hash
+	if withPattern == "" {
+		t.Fatal("Expected a fingerprint with exclude patterns")
+	}
+
+	src[MeshIgnoreFile] = "gen/\n"
+	files, _ = src.ListFiles()
+	if hash := idx.loadFilter(src, files).hash; hash == withPattern {
+		t.Error("Expected .meshignore to change the fingerprint")
+	}
+}
+
+func TestIndexIncremental_PurgesExcludedFiles(t *testing.T) {
+	src, _ := initBareRepo(t)
+
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	store := newMockStore()
+	index := func(exclude []string) {
+		t.Helper()
+		indexer := NewIndexerWithBranch(store, src, "repo", "feature", testLogger())
+		indexer.SetPatterns(exclude, nil)
+		if err := indexer.IndexIncremental(context.Background()); err != nil {
+			t.Fatalf("IndexIncremental failed: %v", err)
+		}
+	}
+
+	index(nil)
+	if _, ok := store.indexed["b.go"]; !ok {
+		t.Fatal("Expected b.go to be indexed")
+	}
+
+	// A new exclude pattern purges the file without a new commit
+	index([]string{"b.go"})
+	if _, ok := store.indexed["b.go"]; ok {
+		t.Error("Expected excluded b.go to be purged")
+	}
+	if _, ok := store.indexed["a.go"]; !ok {
+		t.Error("Expected a.go to stay indexed")
+	}
+
+	// A committed .meshignore excludes files too
+	gitCmd(t, src, "checkout", "-q", "feature")
+	os.WriteFile(filepath.Join(src, MeshIgnoreFile), []byte("a.go\n"), 0644)
+	gitCmd(t, src, "add", MeshIgnoreFile)
+	gitCmd(t, src, "commit", "-m", "ignore a.go")
+
+	index([]string{"b.go"})
+	if _, ok := store.indexed["a.go"]; ok {
+		t.Error("Expected a.go to be purged by .meshignore")
+	}
+
+	// Dropping the pattern brings b.go back
+	index(nil)
+	if _, ok := store.indexed["b.go"]; !ok {
+		t.Error("Expected b.go to be re-indexed once no longer excluded")
+	}
+}
+
+func TestIndexRepository_HonorsIgnoreFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		".gitignore":         "/gen/\n",
+		MeshIgnoreFile:       "*.pb.go\n",
+		"main.go":            "package main",
+		"gen/out.go":         "package gen",
+		"api/api.pb.go":      "package api",
+		"api/.gitignore":     "scratch.go\n",
+		"api/scratch.go":     "package api",
+		"api/handler.go":     "package api",
+		"other/scratch.go":   "package other",
+		"other/generated.go": "package other",
+	}
+	for relPath, content := range files {
+		path := filepath.Join(tmpDir, relPath)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", relPath, err)
+		}
+	}
+
+	store := newMockStore()
+	indexer := NewIndexer(store, tmpDir, testLogger())
+	indexer.SetPatterns([]string{"generated.go"}, nil)
+	if err := indexer.IndexRepository(context.Background()); err != nil {
+		t.Fatalf("IndexRepository failed: %v", err)
+	}
+
+	var indexed []string
+	for relPath := range store.indexed {
+		indexed = append(indexed, relPath)
+	}
+	sort.Strings(indexed)
+
+	want := []string{"api/handler.go", "main.go", "other/scratch.go"}
+	if len(indexed) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, indexed)
+	}
+	for i := range want {
+		if indexed[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, indexed)
+			break
+		}
+	}
+}
+
+func TestIndexIncremental_FileTypes(t *testing.T) {
+	src, _ := initBareRepo(t)
+
+	gitCmd(t, src, "checkout", "-q", "feature")
+	os.MkdirAll(filepath.Join(src, "bin"), 0755)
+	os.WriteFile(filepath.Join(src, "bin", "deploy"), []byte("#!/usr/bin/env bash\nset -e\n"), 0644)
+	os.WriteFile(filepath.Join(src, "LICENSE"), []byte("MIT License\n"), 0644)
+	os.WriteFile(filepath.Join(src, "page.tpl"), []byte("{{ .Title }}\n"), 0644)
+	gitCmd(t, src, "add", ".")
+	gitCmd(t, src, "commit", "-m", "add scripts and templates")
+
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	store := newChunkStore()
+	index := func(cfg filetypes.Config) {
+		t.Helper()
+		types, err := filetypes.New(cfg)
+		if err != nil {
+			t.Fatalf("filetypes.New failed: %v", err)
+		}
+		indexer := NewIndexerWithBranch(store, src, "repo", "feature", testLogger())
+		indexer.SetFileTypes(types)
+		if err := indexer.IndexIncremental(context.Background()); err != nil {
+			t.Fatalf("IndexIncremental failed: %v", err)
+		}
+	}
+
+	// Built-in types: the script by its shebang, not the license or template
+	index(filetypes.Config{})
+	if got := store.languages["bin/deploy"]; got != "bash" {
+		t.Errorf("Expected bin/deploy indexed as bash, got %q", got)
+	}
+	for _, path := range []string{"LICENSE", "page.tpl"} {
+		if _, ok := store.indexed[path]; ok {
+			t.Errorf("Expected %s not to be indexed", path)
+		}
+	}
+
+	// Configured types reconcile without a new commit
+	index(filetypes.Config{
+		Extensions:   map[string]string{".tpl": "gotemplate"},
+		Interpreters: map[string]string{"bash": ""},
+	})
+	if got := store.languages["page.tpl"]; got != "gotemplate" {
+		t.Errorf("Expected page.tpl indexed as gotemplate, got %q", got)
+	}
+	if _, ok := store.indexed["bin/deploy"]; ok {
+		t.Error("Expected bin/deploy to be purged once bash scripts are not code")
+	}
+	if got := store.languages["b.go"]; got != "go" {
+		t.Errorf("Expected b.go indexed as go, got %q", got)
+	}
+}
@@ -0,0 +1,113 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/First008/mesh/internal/filetypes"
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// GeneratedMode is what happens to generated, vendored, minified and binary
+// files (anything filetypes.Classify doesn't class as source)
+type GeneratedMode string
+
+const (
+	GeneratedDownweight GeneratedMode = "downweight" // Index them, ranked below source files
+	GeneratedSkip       GeneratedMode = "skip"       // Leave them out of the index
+	GeneratedOff        GeneratedMode = "off"        // Treat them like source files
+)
+
+// ParseGeneratedMode validates a configured mode; "" means downweight
+func ParseGeneratedMode(s string) (GeneratedMode, error) {
+	switch GeneratedMode(s) {
+	case "":
+		return GeneratedDownweight, nil
+	case GeneratedDownweight, GeneratedSkip, GeneratedOff:
+		return GeneratedMode(s), nil
+	}
+	return "", fmt.Errorf("unknown generated mode %q (want downweight, skip or off)", s)
+}
+
+// generatedWeight scales the search score of non-source chunks in
+// downweight mode, so a protobuf stub only outranks the implementation when
+// it is a much closer match
+const generatedWeight = 0.5
+
+// ChunkIndexer is implemented by stores that record what the indexer knows
+// about the file a chunk belongs to: its class, for ranking, and its language
+type ChunkIndexer interface {
+	IndexChunk(ctx context.Context, chunk IndexJob) error
+}
+
+// SetGeneratedMode sets how generated, vendored, minified and binary files
+// are handled: indexed and down-weighted (the default), skipped, or indexed
+// like source files
+func (idx *Indexer) SetGeneratedMode(mode GeneratedMode) {
+	idx.generatedMode = mode
+}
+
+// skipsClass reports whether files of class are left out of the index.
+// Binary files are only indexed when detection is off.
+func (idx *Indexer) skipsClass(class filetypes.Class) bool {
+	switch {
+	case class == filetypes.ClassSource || idx.generatedMode == GeneratedOff:
+		return false
+	case class == filetypes.ClassBinary:
+		return true
+	}
+	return idx.generatedMode == GeneratedSkip
+}
+
+// indexChunk stores a chunk, with its file's class and language when the
+// store records them
+func (idx *Indexer) indexChunk(ctx context.Context, chunk IndexJob) error {
+	if ci, ok := idx.store.(ChunkIndexer); ok {
+		return ci.IndexChunk(ctx, chunk)
+	}
+	return idx.store.IndexFile(ctx, chunk.RelPath, chunk.Content)
+}
+
+// SetGeneratedMode sets how search ranks chunks of generated, vendored,
+// minified and binary files; only downweight changes their scores
+func (qs *QdrantStore) SetGeneratedMode(mode GeneratedMode) {
+	qs.generatedMode = mode
+}
+
+// downweights reports whether search scores depend on file class. Skipped
+// files aren't in the index to rank.
+func (qs *QdrantStore) downweights() bool {
+	return qs.generatedMode != GeneratedOff && qs.generatedMode != GeneratedSkip
+}
+
+// classWeight returns the factor applied to the search score of a chunk of
+// a file of class
+func (qs *QdrantStore) classWeight(class filetypes.Class) float32 {
+	if class == filetypes.ClassSource || !qs.downweights() {
+		return 1
+	}
+	return generatedWeight
+}
+
+// weightResults applies class weights to results and re-ranks them by
+// weighted score, keeping at most limit. classes holds each result's class.
+func (qs *QdrantStore) weightResults(results []SearchResult, classes []filetypes.Class, limit int) []SearchResult {
+	for i := range results {
+		results[i].Score *= qs.classWeight(classes[i])
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// pointClass returns the class of the file a point belongs to. Points indexed
+// before classes were recorded are classified from their path and chunk.
+func pointClass(payload map[string]*qdrant.Value) filetypes.Class {
+	if class := getStringValue(payload, "file_class"); class != "" {
+		return filetypes.Class(class)
+	}
+	return filetypes.Classify(getStringValue(payload, "base_path"), getStringValue(payload, "content"))
+}
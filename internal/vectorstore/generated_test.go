@@ -0,0 +1,145 @@
+package vectorstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/First008/mesh/internal/filetypes"
+)
+
+// chunkStore is a mockStore that records the class, language and embedded
+// text of indexed chunks
+type chunkStore struct {
+	*mockStore
+	classes   map[string]filetypes.Class
+	languages map[string]string
+	embeds    map[string]string
+}
+
+func newChunkStore() *chunkStore {
+	return &chunkStore{
+		mockStore: newMockStore(),
+		classes:   make(map[string]filetypes.Class),
+		languages: make(map[string]string),
+		embeds:    make(map[string]string),
+	}
+}
+
+func (s *chunkStore) IndexChunk(ctx context.Context, chunk IndexJob) error {
+	s.mu.Lock()
+	s.classes[chunk.RelPath] = chunk.Class
+	s.languages[chunk.RelPath] = chunk.Language
+	s.embeds[chunk.RelPath] = chunk.Embed
+	s.mu.Unlock()
+	return s.IndexFile(ctx, chunk.RelPath, chunk.Content)
+}
+
+func TestIndexIncremental_GeneratedMode(t *testing.T) {
+	src, _ := initBareRepo(t)
+
+	gitCmd(t, src, "checkout", "-q", "feature")
+	stub := "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage api\n"
+	os.WriteFile(filepath.Join(src, "api.pb.go"), []byte(stub), 0644)
+	gitCmd(t, src, "add", "api.pb.go")
+	gitCmd(t, src, "commit", "-m", "add stub")
+
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	store := newChunkStore()
+	index := func(mode GeneratedMode) {
+		t.Helper()
+		indexer := NewIndexerWithBranch(store, src, "repo", "feature", testLogger())
+		indexer.SetGeneratedMode(mode)
+		if err := indexer.IndexIncremental(context.Background()); err != nil {
+			t.Fatalf("IndexIncremental failed: %v", err)
+		}
+	}
+
+	// Down-weighted by default: indexed, with its class recorded for search
+	index(GeneratedDownweight)
+	if _, ok := store.indexed["api.pb.go"]; !ok {
+		t.Error("Expected api.pb.go to be indexed in downweight mode")
+	}
+	if got := store.classes["api.pb.go"]; got != filetypes.ClassGenerated {
+		t.Errorf("Expected api.pb.go classed generated, got %q", got)
+	}
+	if got := store.classes["b.go"]; got != filetypes.ClassSource {
+		t.Errorf("Expected b.go classed source, got %q", got)
+	}
+
+	// Switching to skip reconciles and drops generated and vendored files
+	index(GeneratedSkip)
+	for _, path := range []string{"api.pb.go", "vendor/v.go"} {
+		if _, ok := store.indexed[path]; ok {
+			t.Errorf("Expected %s to be dropped in skip mode", path)
+		}
+	}
+	if _, ok := store.indexed["b.go"]; !ok {
+		t.Error("Expected b.go to stay indexed")
+	}
+
+	// And back again
+	index(GeneratedOff)
+	if _, ok := store.indexed["api.pb.go"]; !ok {
+		t.Error("Expected api.pb.go to be indexed again with detection off")
+	}
+}
+
+func TestWeightResults(t *testing.T) {
+	results := func() []SearchResult {
+		return []SearchResult{
+			{FilePath: "api/service.pb.go", Score: 0.9},
+			{FilePath: "api/service.go", Score: 0.6},
+			{FilePath: "api/client.go", Score: 0.4},
+		}
+	}
+	classes := []filetypes.Class{filetypes.ClassGenerated, filetypes.ClassSource, filetypes.ClassSource}
+
+	tests := []struct {
+		mode GeneratedMode
+		want []string
+	}{
+		{GeneratedDownweight, []string{"api/service.go", "api/service.pb.go"}},
+		{"", []string{"api/service.go", "api/service.pb.go"}}, // Zero value down-weights
+		{GeneratedOff, []string{"api/service.pb.go", "api/service.go"}},
+	}
+
+	for _, tt := range tests {
+		qs := &QdrantStore{generatedMode: tt.mode}
+		got := qs.weightResults(results(), classes, 2)
+		if len(got) != len(tt.want) {
+			t.Fatalf("mode %q: expected %d results, got %d", tt.mode, len(tt.want), len(got))
+		}
+		for i, r := range got {
+			if r.FilePath != tt.want[i] {
+				t.Errorf("mode %q: result %d = %s, want %s", tt.mode, i, r.FilePath, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestParseGeneratedMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    GeneratedMode
+		wantErr bool
+	}{
+		{"", GeneratedDownweight, false},
+		{"downweight", GeneratedDownweight, false},
+		{"skip", GeneratedSkip, false},
+		{"off", GeneratedOff, false},
+		{"hide", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseGeneratedMode(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseGeneratedMode(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
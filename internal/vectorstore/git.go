@@ -3,7 +3,9 @@ package vectorstore
 import (
 	"fmt"
 	"os/exec"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Git command wrappers for branch-aware indexing
@@ -31,19 +33,84 @@ func GetHeadCommit(repoPath string) (string, error) {
 // GetChangedFilesSince returns a list of files that changed between fromCommit and HEAD
 // This is used for incremental indexing after git pull
 func GetChangedFilesSince(repoPath, fromCommit string) ([]string, error) {
-	// Get files changed between fromCommit and HEAD
-	cmd := exec.Command("git", "-C", repoPath, "diff", "--name-only", fromCommit, "HEAD")
+	return GetChangedFilesBetween(repoPath, fromCommit, "HEAD")
+}
+
+// GetChangedFilesBetween returns every path touched between two commits,
+// including both the old and new path of renamed files
+func GetChangedFilesBetween(repoPath, fromCommit, toCommit string) ([]string, error) {
+	changes, err := GetFileChangesBetween(repoPath, fromCommit, toCommit)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, change := range changes {
+		if change.OldPath != "" && change.Status == ChangeRenamed {
+			files = append(files, change.OldPath)
+		}
+		files = append(files, change.Path)
+	}
+	return files, nil
+}
+
+// Change statuses reported by GetFileChangesBetween (first letter of git's --name-status)
+const (
+	ChangeAdded    = "A"
+	ChangeModified = "M"
+	ChangeDeleted  = "D"
+	ChangeRenamed  = "R"
+	ChangeCopied   = "C"
+	ChangeType     = "T"
+)
+
+// FileChange is a single entry of a name-status diff
+type FileChange struct {
+	Status  string // One of the Change* constants
+	Path    string // Path at toCommit (the removed path for deletions)
+	OldPath string // Source path for renames and copies
+}
+
+// GetFileChangesBetween returns file changes between two commits with rename
+// detection, so renamed files report their old path as well
+func GetFileChangesBetween(repoPath, fromCommit, toCommit string) ([]FileChange, error) {
+	cmd := exec.Command("git", "-C", repoPath, "diff", "--name-status", "-z", "-M", fromCommit, toCommit)
 	out, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("get changed files: %w", err)
 	}
 
-	if len(out) == 0 {
-		return []string{}, nil
+	// -z output: "<status>\0<path>\0", renames/copies carry two paths
+	fields := strings.Split(string(out), "\x00")
+	var changes []FileChange
+	for i := 0; i+1 < len(fields); {
+		status := fields[i]
+		if status == "" {
+			break
+		}
+		change := FileChange{Status: status[:1]}
+
+		if change.Status == ChangeRenamed || change.Status == ChangeCopied {
+			if i+2 >= len(fields) {
+				return nil, fmt.Errorf("malformed diff entry for %s", fields[i+1])
+			}
+			change.OldPath = fields[i+1]
+			change.Path = fields[i+2]
+			i += 3
+		} else {
+			change.Path = fields[i+1]
+			i += 2
+		}
+		changes = append(changes, change)
 	}
+	return changes, nil
+}
 
-	files := strings.Split(strings.TrimSpace(string(out)), "\n")
-	return files, nil
+// CommitExists reports whether commit is present in the repository's object
+// database (it may be gone after a force push followed by gc or a fresh clone)
+func CommitExists(repoPath, commit string) bool {
+	cmd := exec.Command("git", "-C", repoPath, "cat-file", "-e", commit+"^{commit}")
+	return cmd.Run() == nil
 }
 
 // IsGitRepo checks if the given path is a git repository
@@ -68,6 +135,30 @@ func GetAllBranches(repoPath string) ([]string, error) {
 	return branches, nil
 }
 
+// GetBranchCommitTimes returns every local branch with the committer date of its head
+func GetBranchCommitTimes(repoPath string) (map[string]time.Time, error) {
+	cmd := exec.Command("git", "-C", repoPath, "for-each-ref",
+		"--format=%(committerdate:unix) %(refname:short)", "refs/heads")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("get branch commit times: %w", err)
+	}
+
+	branches := make(map[string]time.Time)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		unix, branch, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		seconds, err := strconv.ParseInt(unix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse commit time of %s: %w", branch, err)
+		}
+		branches[branch] = time.Unix(seconds, 0)
+	}
+	return branches, nil
+}
+
 // GetBranchCommit returns the HEAD commit SHA for a specific branch
 func GetBranchCommit(repoPath, branch string) (string, error) {
 	cmd := exec.Command("git", "-C", repoPath, "rev-parse", branch)
@@ -78,6 +169,30 @@ func GetBranchCommit(repoPath, branch string) (string, error) {
 	return strings.TrimSpace(string(out)), nil
 }
 
+// ValidateBranchName rejects names git wouldn't accept as a branch (the rules
+// of `git check-ref-format --branch`), so a name taken from a webhook or API
+// request can't address paths outside its own metadata directory
+func ValidateBranchName(branch string) error {
+	if branch == "" || branch == "@" || strings.HasPrefix(branch, "-") {
+		return fmt.Errorf("invalid branch name %q", branch)
+	}
+	if strings.Contains(branch, "..") || strings.Contains(branch, "@{") || strings.Contains(branch, "//") ||
+		strings.HasPrefix(branch, "/") || strings.HasSuffix(branch, "/") || strings.HasSuffix(branch, ".") {
+		return fmt.Errorf("invalid branch name %q", branch)
+	}
+	for _, r := range branch {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(" ~^:?*[\\", r) {
+			return fmt.Errorf("invalid branch name %q: contains %q", branch, r)
+		}
+	}
+	for _, component := range strings.Split(branch, "/") {
+		if strings.HasPrefix(component, ".") || strings.HasSuffix(component, ".lock") {
+			return fmt.Errorf("invalid branch name %q", branch)
+		}
+	}
+	return nil
+}
+
 // SanitizeBranchName converts a branch name to a filesystem-safe string
 // Example: "feature/auth-v2" -> "feature-auth-v2"
 func SanitizeBranchName(branch string) string {
@@ -87,3 +202,31 @@ func SanitizeBranchName(branch string) string {
 	sanitized = strings.ReplaceAll(sanitized, ":", "-")
 	return sanitized
 }
+
+// GetWorkingTreeChanges returns files that differ from HEAD in the working tree:
+// modified, staged, untracked (not ignored), deleted, and both sides of renames
+func GetWorkingTreeChanges(repoPath string) ([]string, error) {
+	cmd := exec.Command("git", "-C", repoPath, "status", "--porcelain=v1", "-z", "--untracked-files=all")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("get working tree changes: %w", err)
+	}
+
+	// Entries are "XY path\0"; renames and copies append "orig_path\0"
+	var files []string
+	entries := strings.Split(string(out), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		files = append(files, entry[3:])
+		if entry[0] == 'R' || entry[0] == 'C' {
+			if i+1 < len(entries) && entries[i+1] != "" {
+				files = append(files, entries[i+1])
+			}
+			i++
+		}
+	}
+	return files, nil
+}
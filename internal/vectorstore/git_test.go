@@ -5,6 +5,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestSanitizeBranchName(t *testing.T) {
@@ -31,6 +32,49 @@ func TestSanitizeBranchName(t *testing.T) {
 	}
 }
 
+func TestValidateBranchName(t *testing.T) {
+	testCases := []struct {
+		branch string
+		valid  bool
+	}{
+		{"main", true},
+		{"feature/auth-v2", true},
+		{"release-1.2", true},
+		{"", false},
+		{".", false},
+		{"..", false},
+		{"../other", false},
+		{"feature/../main", false},
+		{"feature/.hidden", false},
+		{"/main", false},
+		{"main/", false},
+		{"feature//x", false},
+		{"main.", false},
+		{"main.lock", false},
+		{"@", false},
+		{"main@{1}", false},
+		{"-main", false},
+		{"has space", false},
+		{"a~b", false},
+		{"a^b", false},
+		{"a:b", false},
+		{"a?b", false},
+		{"a*b", false},
+		{"a[b", false},
+		{"a\\b", false},
+		{"a\x7fb", false},
+		{"a\nb", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.branch, func(t *testing.T) {
+			if err := ValidateBranchName(tc.branch); (err == nil) != tc.valid {
+				t.Errorf("ValidateBranchName(%q) = %v, want valid %v", tc.branch, err, tc.valid)
+			}
+		})
+	}
+}
+
 func TestSanitizeBranchName_NoSpecialChars(t *testing.T) {
 	// Branches without special chars should be unchanged
 	branches := []string{"main", "develop", "production", "staging"}
@@ -178,3 +222,95 @@ func TestGetChangedFilesSince_NonGitRepo(t *testing.T) {
 		t.Error("Expected error for non-git repository")
 	}
 }
+
+// gitCmd runs a git command in dir, failing the test on error
+func gitCmd(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v: %s", args, err, out)
+	}
+}
+
+func TestGetFileChangesBetween(t *testing.T) {
+	src, _ := initBareRepo(t)
+	from, _ := GetHeadCommit(src)
+
+	os.WriteFile(filepath.Join(src, "c.go"), []byte("package c\n"), 0644)
+	gitCmd(t, src, "mv", "a.go", "renamed.go")
+	gitCmd(t, src, "rm", "-q", "vendor/v.go")
+	gitCmd(t, src, "add", ".")
+	gitCmd(t, src, "commit", "-m", "rename, delete, add")
+	to, _ := GetHeadCommit(src)
+
+	changes, err := GetFileChangesBetween(src, from, to)
+	if err != nil {
+		t.Fatalf("GetFileChangesBetween failed: %v", err)
+	}
+
+	got := make(map[string]FileChange)
+	for _, change := range changes {
+		got[change.Path] = change
+	}
+
+	expected := map[string]FileChange{
+		"renamed.go":  {Status: ChangeRenamed, Path: "renamed.go", OldPath: "a.go"},
+		"vendor/v.go": {Status: ChangeDeleted, Path: "vendor/v.go"},
+		"c.go":        {Status: ChangeAdded, Path: "c.go"},
+	}
+	if len(got) != len(expected) {
+		t.Errorf("Expected %d changes, got %+v", len(expected), changes)
+	}
+	for path, want := range expected {
+		if got[path] != want {
+			t.Errorf("Change for %s: got %+v, want %+v", path, got[path], want)
+		}
+	}
+
+	files, err := GetChangedFilesBetween(src, from, to)
+	if err != nil {
+		t.Fatalf("GetChangedFilesBetween failed: %v", err)
+	}
+	if len(files) != 4 {
+		t.Errorf("Expected old and new rename paths plus 2 files, got %v", files)
+	}
+}
+
+func TestCommitExists(t *testing.T) {
+	src, _ := initBareRepo(t)
+	head, _ := GetHeadCommit(src)
+
+	if !CommitExists(src, head) {
+		t.Error("Expected HEAD commit to exist")
+	}
+	if CommitExists(src, "0123456789abcdef0123456789abcdef01234567") {
+		t.Error("Expected unknown commit not to exist")
+	}
+}
+
+func TestGetBranchCommitTimes(t *testing.T) {
+	src, _ := initBareRepo(t)
+
+	cmd := exec.Command("git", "-C", src, "commit", "--allow-empty", "-m", "old", "-q")
+	cmd.Env = append(os.Environ(), "GIT_COMMITTER_DATE=2020-01-02T03:04:05Z")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("commit failed: %v: %s", err, out)
+	}
+
+	times, err := GetBranchCommitTimes(src)
+	if err != nil {
+		t.Fatalf("GetBranchCommitTimes failed: %v", err)
+	}
+	if len(times) != 2 {
+		t.Fatalf("Expected main and feature, got %v", times)
+	}
+	if want := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC); !times["main"].Equal(want) {
+		t.Errorf("Expected main at %v, got %v", want, times["main"])
+	}
+	if times["feature"].Before(times["main"]) {
+		t.Errorf("Expected feature to be newer than main, got %v", times)
+	}
+
+	if _, err := GetBranchCommitTimes(t.TempDir()); err == nil {
+		t.Error("Expected error for non-git directory")
+	}
+}
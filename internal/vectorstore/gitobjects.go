@@ -0,0 +1,123 @@
+package vectorstore
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Reading files straight from git objects, so a branch can be indexed
+// without being checked out (or without any working tree at all)
+
+// IsBareRepo reports whether repoPath is a bare repository (no working tree)
+func IsBareRepo(repoPath string) bool {
+	cmd := exec.Command("git", "-C", repoPath, "rev-parse", "--is-bare-repository")
+	out, err := cmd.Output()
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+// ListFilesAtCommit returns the paths of all files in the tree of commit
+func ListFilesAtCommit(repoPath, commit string) ([]string, error) {
+	cmd := exec.Command("git", "-C", repoPath, "ls-tree", "-r", "-z", "--name-only", commit)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("list files at %s: %w", commit, err)
+	}
+
+	var files []string
+	for _, path := range strings.Split(string(out), "\x00") {
+		if path != "" {
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
+
+// BlobReader reads file contents at arbitrary commits through a single
+// long-running `git cat-file --batch` process. Safe for concurrent use.
+type BlobReader struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	mu     sync.Mutex
+}
+
+// NewBlobReader starts a cat-file process for repoPath
+func NewBlobReader(repoPath string) (*BlobReader, error) {
+	cmd := exec.Command("git", "-C", repoPath, "cat-file", "--batch")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("cat-file stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("cat-file stdout: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start cat-file: %w", err)
+	}
+
+	return &BlobReader{
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: bufio.NewReader(stdout),
+	}, nil
+}
+
+// ReadFile returns the content of relPath at commit.
+// Missing paths return an error satisfying os.IsNotExist.
+func (r *BlobReader) ReadFile(commit, relPath string) ([]byte, error) {
+	if strings.ContainsAny(relPath, "\n") {
+		return nil, fmt.Errorf("unsupported path %q", relPath)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := fmt.Fprintf(r.stdin, "%s:%s\n", commit, relPath); err != nil {
+		return nil, fmt.Errorf("cat-file request: %w", err)
+	}
+
+	// Header: "<sha> <type> <size>" or "<object> missing"
+	header, err := r.stdout.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("cat-file header: %w", err)
+	}
+	fields := strings.Fields(header)
+	if len(fields) == 2 && fields[1] == "missing" {
+		return nil, &os.PathError{Op: "read", Path: commit + ":" + relPath, Err: os.ErrNotExist}
+	}
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("unexpected cat-file header %q", strings.TrimSpace(header))
+	}
+
+	size, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("unexpected cat-file size %q", fields[2])
+	}
+
+	// Content is followed by a single newline
+	content := make([]byte, size+1)
+	if _, err := io.ReadFull(r.stdout, content); err != nil {
+		return nil, fmt.Errorf("cat-file content: %w", err)
+	}
+
+	if fields[1] != "blob" {
+		return nil, &os.PathError{Op: "read", Path: commit + ":" + relPath, Err: os.ErrNotExist}
+	}
+	return content[:size], nil
+}
+
+// Close stops the cat-file process
+func (r *BlobReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stdin.Close()
+	return r.cmd.Wait()
+}
@@ -0,0 +1,177 @@
+package vectorstore
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// initBareRepo creates a repo with main (a.go, vendor/v.go) and feature
+// (adds b.go) branches, and returns a bare clone of it
+func initBareRepo(t *testing.T) (string, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "src")
+
+	if exec.Command("git", "init", "-b", "main", src).Run() != nil {
+		t.Skip("Skipping test: git not available")
+	}
+	git := func(args ...string) {
+		if out, err := exec.Command("git", append([]string{"-C", src}, args...)...).CombinedOutput(); err != nil {
+			t.Skipf("Skipping test: git %v failed: %v: %s", args, err, out)
+		}
+	}
+	git("config", "user.email", "test@example.com")
+	git("config", "user.name", "Test User")
+
+	os.MkdirAll(filepath.Join(src, "vendor"), 0755)
+	os.WriteFile(filepath.Join(src, "a.go"), []byte("package a\n"), 0644)
+	os.WriteFile(filepath.Join(src, "vendor", "v.go"), []byte("package v\n"), 0644)
+	git("add", ".")
+	git("commit", "-m", "main")
+
+	git("checkout", "-b", "feature")
+	os.WriteFile(filepath.Join(src, "b.go"), []byte("package b\n"), 0644)
+	git("add", ".")
+	git("commit", "-m", "feature")
+	git("checkout", "main")
+
+	bare := filepath.Join(tmpDir, "bare.git")
+	if err := exec.Command("git", "clone", "--bare", src, bare).Run(); err != nil {
+		t.Skipf("Skipping test: bare clone failed: %v", err)
+	}
+	return src, bare
+}
+
+func TestIsBareRepo(t *testing.T) {
+	src, bare := initBareRepo(t)
+
+	if !IsBareRepo(bare) {
+		t.Error("Expected bare clone to be detected as bare")
+	}
+	if IsBareRepo(src) {
+		t.Error("Expected working tree clone not to be bare")
+	}
+}
+
+func TestListFilesAtCommit(t *testing.T) {
+	_, bare := initBareRepo(t)
+
+	files, err := ListFilesAtCommit(bare, "feature")
+	if err != nil {
+		t.Fatalf("ListFilesAtCommit failed: %v", err)
+	}
+	if len(files) != 3 {
+		t.Errorf("Expected 3 files on feature, got %v", files)
+	}
+}
+
+func TestBlobReader_ReadFile(t *testing.T) {
+	_, bare := initBareRepo(t)
+
+	reader, err := NewBlobReader(bare)
+	if err != nil {
+		t.Fatalf("NewBlobReader failed: %v", err)
+	}
+	defer reader.Close()
+
+	content, err := reader.ReadFile("feature", "b.go")
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if string(content) != "package b\n" {
+		t.Errorf("Unexpected content: %q", content)
+	}
+
+	if _, err := reader.ReadFile("main", "b.go"); !os.IsNotExist(err) {
+		t.Errorf("Expected not-exist error for file missing on main, got %v", err)
+	}
+
+	// The reader stays usable after a miss
+	content, err = reader.ReadFile("main", "a.go")
+	if err != nil || string(content) != "package a\n" {
+		t.Errorf("Expected a.go after miss, got %q (%v)", content, err)
+	}
+
+	// Directories are not files
+	if _, err := reader.ReadFile("main", "vendor"); !os.IsNotExist(err) {
+		t.Errorf("Expected not-exist error for directory, got %v", err)
+	}
+}
+
+func TestIndexIncremental_BareRepo(t *testing.T) {
+	_, bare := initBareRepo(t)
+
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	store := newMockStore()
+	indexer := NewIndexerWithBranch(store, bare, "bare-repo", "feature", testLogger())
+	if err := indexer.IndexIncremental(context.Background()); err != nil {
+		t.Fatalf("IndexIncremental failed: %v", err)
+	}
+
+	if _, ok := store.indexed["b.go"]; !ok {
+		t.Error("Expected feature-only file b.go to be indexed from git objects")
+	}
+	if _, ok := store.indexed["vendor/v.go"]; ok {
+		t.Error("Expected vendor directory to be skipped")
+	}
+
+	meta, err := LoadMetadata("bare-repo", "feature")
+	if err != nil || meta == nil {
+		t.Fatalf("Expected metadata to be saved, got %v (%v)", meta, err)
+	}
+	commit, _ := GetBranchCommit(bare, "feature")
+	if meta.CommitSHA != commit {
+		t.Errorf("Expected metadata commit %s, got %s", commit, meta.CommitSHA)
+	}
+}
+
+func TestIndexIncremental_NonCheckedOutBranch(t *testing.T) {
+	src, _ := initBareRepo(t)
+
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	// Uncommitted edits on the checked-out branch must not leak into feature
+	os.WriteFile(filepath.Join(src, "a.go"), []byte("package dirty\n"), 0644)
+
+	store := newMockStore()
+	indexer := NewIndexerWithBranch(store, src, "repo", "feature", testLogger())
+	if err := indexer.IndexIncremental(context.Background()); err != nil {
+		t.Fatalf("IndexIncremental failed: %v", err)
+	}
+
+	if _, ok := store.indexed["b.go"]; !ok {
+		t.Error("Expected feature-only file b.go to be indexed while main is checked out")
+	}
+	if store.indexed["a.go"] != "package a\n" {
+		t.Errorf("Expected committed a.go content, got %q", store.indexed["a.go"])
+	}
+
+	// Advance feature and switch back to main; only its diff is re-indexed
+	git := func(args ...string) {
+		if out, err := exec.Command("git", append([]string{"-C", src}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, out)
+		}
+	}
+	git("stash")
+	git("checkout", "feature")
+	os.WriteFile(filepath.Join(src, "b.go"), []byte("package b2\n"), 0644)
+	git("commit", "-am", "update b")
+	git("checkout", "main")
+
+	if err := indexer.IndexIncremental(context.Background()); err != nil {
+		t.Fatalf("Second IndexIncremental failed: %v", err)
+	}
+	if store.indexed["b.go"] != "package b2\n" {
+		t.Errorf("Expected updated b.go content, got %q", store.indexed["b.go"])
+	}
+}
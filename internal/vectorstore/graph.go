@@ -0,0 +1,163 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/First008/mesh/internal/symbols"
+)
+
+// relatedScoreDecay is the share of a selection's score a file it depends on
+// gets, per hop
+const relatedScoreDecay = 0.5
+
+// DependencyGraph is the files each of a branch's indexed files depends on,
+// built from its symbol index at the end of every indexing run
+type DependencyGraph struct {
+	RepoName  string        `json:"repo_name"`
+	Branch    string        `json:"branch"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	Files     symbols.Graph `json:"files"` // File path -> dependencies
+}
+
+// GetDependencyGraphPath returns path to the dependency graph for repo+branch
+// Example: .mesh/my-repo/main/graph.json
+func GetDependencyGraphPath(repoName, branch string) string {
+	return filepath.Join(filepath.Dir(GetMetadataPath(repoName, branch)), "graph.json")
+}
+
+// LoadDependencyGraph loads the dependency graph for a repo+branch
+// Returns nil if the branch has not been indexed with imports
+func LoadDependencyGraph(repoName, branch string) (*DependencyGraph, error) {
+	data, err := os.ReadFile(GetDependencyGraphPath(repoName, branch))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var graph DependencyGraph
+	if err := json.Unmarshal(data, &graph); err != nil {
+		return nil, err
+	}
+	if graph.Files == nil {
+		graph.Files = make(symbols.Graph)
+	}
+	return &graph, nil
+}
+
+// SaveDependencyGraph atomically writes the dependency graph for a repo+branch
+func SaveDependencyGraph(graph *DependencyGraph) error {
+	data, err := json.Marshal(graph)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(GetDependencyGraphPath(graph.RepoName, graph.Branch), data)
+}
+
+// loadGraph returns a repo+branch's dependency graph, cached until the next
+// indexing run rewrites it; nil if there is none
+func loadGraph(repoName, branch string) (symbols.Graph, error) {
+	value, err := artifacts.get(GetDependencyGraphPath(repoName, branch), func() (interface{}, error) {
+		graph, err := LoadDependencyGraph(repoName, branch)
+		if err != nil || graph == nil {
+			return nil, err
+		}
+		return graph.Files, nil
+	})
+	if value == nil {
+		return nil, err
+	}
+	return value.(symbols.Graph), nil
+}
+
+// SetDependencyGraph makes aggregated searches add the files the selected
+// files depend on, up to hops edges away in the repo+branch's dependency
+// graph (0 disables the expansion)
+func (qs *QdrantStore) SetDependencyGraph(repoName, branch string, hops int) {
+	qs.graphRepo = repoName
+	qs.graphBranch = branch
+	qs.graphHops = hops
+}
+
+// expandSelections adds the files selections depend on while the token budget
+// remains, fetching their chunks to count them
+func (qs *QdrantStore) expandSelections(ctx context.Context, selections []*FileSelection, config *SearchConfig) []*FileSelection {
+	if qs.graphHops <= 0 || config.MaxRelatedFiles <= 0 {
+		return selections
+	}
+	graph, err := loadGraph(qs.graphRepo, qs.graphBranch)
+	if err != nil {
+		qs.logger.Warn().Err(err).Msg("Failed to load dependency graph")
+		return selections
+	}
+
+	expanded := expandWithGraph(selections, graph, qs.graphHops, config, func(basePath string) (int, string, bool) {
+		chunks, err := qs.FetchAllChunks(ctx, basePath)
+		if err != nil || len(chunks) == 0 {
+			return 0, "", false // Not indexed (excluded, or deleted since)
+		}
+		tokens := 0
+		for _, chunk := range chunks {
+			tokens += qs.tokenizer.Count(chunk.Content)
+		}
+		return tokens, chunks[0].Language, true
+	})
+
+	if added := len(expanded) - len(selections); added > 0 {
+		qs.logger.Info().
+			Int("related_files", added).
+			Int("hops", qs.graphHops).
+			Msg("Added dependencies of selected files")
+	}
+	return expanded
+}
+
+// expandWithGraph appends to selections the files they depend on, nearest
+// first, up to config.MaxRelatedFiles and while they fit the token budget;
+// size returns a file's tokens and language, or false if it isn't indexed.
+// Related files score the selection they were reached from, decayed per hop.
+func expandWithGraph(selections []*FileSelection, graph symbols.Graph, hops int, config *SearchConfig,
+	size func(basePath string) (int, string, bool)) []*FileSelection {
+	if len(graph) == 0 || len(selections) == 0 {
+		return selections
+	}
+
+	usedTokens := 0
+	scores := make(map[string]float32, len(selections))
+	roots := make([]string, len(selections))
+	for i, selection := range selections {
+		usedTokens += selection.EstimatedTokens
+		scores[selection.BasePath] = selection.Score
+		roots[i] = selection.BasePath
+	}
+	maxTokens := config.EffectiveTokenBudget()
+
+	added := 0
+	for _, related := range graph.Related(roots, hops) {
+		if added == config.MaxRelatedFiles || usedTokens >= maxTokens {
+			break
+		}
+		// Scored before sizing, for files reached through it
+		score := scores[related.From] * relatedScoreDecay
+		scores[related.Path] = score
+
+		tokens, language, ok := size(related.Path)
+		if !ok || usedTokens+tokens > maxTokens {
+			continue
+		}
+		selections = append(selections, &FileSelection{
+			BasePath:        related.Path,
+			Language:        language,
+			Score:           score,
+			EstimatedTokens: tokens,
+		})
+		usedTokens += tokens
+		added++
+	}
+	return selections
+}
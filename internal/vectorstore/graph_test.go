@@ -0,0 +1,95 @@
+package vectorstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/First008/mesh/internal/symbols"
+)
+
+func TestExpandWithGraph(t *testing.T) {
+	graph := symbols.Graph{
+		"handler.go": {{Path: "service.go", Weight: 4}, {Path: "big.go", Weight: 2}, {Path: "excluded.go", Weight: 1}, {Path: "types.go", Weight: 1}},
+		"service.go": {{Path: "store.go", Weight: 3}},
+	}
+	tokens := map[string]int{"service.go": 100, "big.go": 10000, "types.go": 50, "store.go": 80}
+	size := func(basePath string) (int, string, bool) {
+		n, ok := tokens[basePath]
+		return n, "go", ok
+	}
+	config := &SearchConfig{MaxTokenBudget: 1500, ReserveTokens: 500, MaxRelatedFiles: 5}
+
+	type selection struct {
+		path  string
+		score float32
+	}
+	tests := []struct {
+		name       string
+		hops       int
+		maxRelated int
+		want       []selection
+	}{
+		{"disabled", 0, 5, []selection{{"handler.go", 0.8}}},
+		// big.go doesn't fit the budget and excluded.go isn't indexed
+		{"direct", 1, 5, []selection{{"handler.go", 0.8}, {"service.go", 0.4}, {"types.go", 0.4}}},
+		{"two hops", 2, 5, []selection{{"handler.go", 0.8}, {"service.go", 0.4}, {"types.go", 0.4}, {"store.go", 0.2}}},
+		{"file limit", 2, 1, []selection{{"handler.go", 0.8}, {"service.go", 0.4}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.MaxRelatedFiles = tt.maxRelated
+			selections := []*FileSelection{{BasePath: "handler.go", Score: 0.8, EstimatedTokens: 700}}
+			var got []selection
+			for _, s := range expandWithGraph(selections, graph, tt.hops, config, size) {
+				got = append(got, selection{s.BasePath, s.Score})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandWithGraph = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexIncremental_DependencyGraph(t *testing.T) {
+	src, _ := initBareRepo(t)
+
+	gitCmd(t, src, "checkout", "-q", "feature")
+	os.WriteFile(filepath.Join(src, "server.go"), []byte("package b\n\ntype Server struct{}\n"), 0644)
+	os.WriteFile(filepath.Join(src, "client.go"), []byte("package b\n\nfunc Dial() *Server { return &Server{} }\n"), 0644)
+	gitCmd(t, src, "add", "server.go", "client.go")
+	gitCmd(t, src, "commit", "-m", "add server and client")
+
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	store := newMockStore()
+	index := func() {
+		t.Helper()
+		indexer := NewIndexerWithBranch(store, src, "repo", "feature", testLogger())
+		if err := indexer.IndexIncremental(context.Background()); err != nil {
+			t.Fatalf("IndexIncremental failed: %v", err)
+		}
+	}
+
+	index()
+	graph, err := loadGraph("repo", "feature")
+	if err != nil {
+		t.Fatalf("loadGraph failed: %v", err)
+	}
+	if deps := graph["client.go"]; len(deps) != 1 || deps[0].Path != "server.go" || deps[0].Weight != 1 {
+		t.Errorf("Expected client.go to depend on server.go, got %v", deps)
+	}
+
+	// Branches indexed before the graph was built get a full pass
+	os.Remove(GetDependencyGraphPath("repo", "feature"))
+	index()
+	if graph, _ := LoadDependencyGraph("repo", "feature"); graph == nil || len(graph.Files["client.go"]) != 1 {
+		t.Errorf("Expected the dependency graph rebuilt, got %+v", graph)
+	}
+}
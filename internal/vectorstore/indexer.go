@@ -11,10 +11,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/First008/mesh/internal/filetypes"
+	"github.com/First008/mesh/internal/secrets"
+	"github.com/First008/mesh/internal/symbols"
+	"github.com/First008/mesh/internal/tokenizer"
 	"github.com/rs/zerolog"
 )
 
@@ -24,15 +28,44 @@ type Indexer struct {
 	repoPath   string
 	repoName   string
 	branch     string
-	fileHashes map[string]string // file path -> SHA256 hash
+	fileHashes map[string]string   // file path -> SHA256 hash
+	progress   ProgressFunc        // Optional progress callback
+	checkpoint *runCheckpoint      // Progress of the current run, if checkpointed
+	tokenizer  tokenizer.Tokenizer // Counts chunk tokens for the embedding model
+	filter     *pathFilter         // Files indexed by the current run
+	redactions *runRedactions      // Secrets found by the current run, if reported
+	symbols    *runSymbols         // Symbols of the current run's files, if indexed
+	summary    *runSummary         // Files of the current run, for the repository summary
+	secretMode secrets.Mode        // How files containing secrets are handled
 	mu         sync.RWMutex
 	logger     zerolog.Logger
+
+	excludePatterns []string // Configured exclude patterns (see SetPatterns)
+	focusPaths      []string // Configured focus paths (see SetPatterns)
+
+	generatedMode GeneratedMode       // How generated and vendored files are handled
+	fileTypes     *filetypes.Registry // Which files are code (nil = built-in, see SetFileTypes)
+	embedTemplate *EmbedTemplate      // Text embedded per chunk (nil = default, see SetEmbedTemplate)
+	summarizer    Summarizer          // Writes package summaries (nil = none, see SetSummarizer)
 }
 
+// maxIndexFileSize is the largest file indexed (>500KB is likely generated, minified, or binary)
+const maxIndexFileSize = 500000
+
+// ProgressFunc receives indexing progress: files processed so far (indexed or
+// failed), total files queued for this run, and how many of them failed
+type ProgressFunc func(done, total, errors int)
+
 // IndexJob represents a file indexing job for the worker pool
 type IndexJob struct {
-	RelPath string
-	Content string
+	RelPath  string
+	Content  string
+	Class    filetypes.Class // Class of the file the chunk belongs to ("" = unknown)
+	Language string          // Language of the file the chunk belongs to ("" = from its path)
+	Embed    string          // Text embedded for the chunk, with its context ("" = Content)
+
+	// File already scrubbed and chunked (see diffAgainstTree); nil = chunk Content
+	chunked *chunkedFile
 }
 
 // IndexStats tracks indexing statistics (thread-safe)
@@ -40,6 +73,7 @@ type IndexStats struct {
 	Indexed int
 	Skipped int
 	Errors  int
+	failed  []string
 	mu      sync.Mutex
 }
 
@@ -61,6 +95,21 @@ func (s *IndexStats) incErrors() {
 	s.mu.Unlock()
 }
 
+// incFailed counts an error and records the file for retry
+func (s *IndexStats) incFailed(relPath string) {
+	s.mu.Lock()
+	s.Errors++
+	s.failed = append(s.failed, relPath)
+	s.mu.Unlock()
+}
+
+// failedFiles returns the files that failed to index
+func (s *IndexStats) failedFiles() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.failed...)
+}
+
 func (s *IndexStats) get() (int, int, int) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -73,7 +122,11 @@ func NewIndexer(store VectorStore, repoPath string, logger zerolog.Logger) *Inde
 		store:      store,
 		repoPath:   repoPath,
 		fileHashes: make(map[string]string),
+		tokenizer:  tokenizer.Default(),
+		secretMode: secrets.ModeRedact,
 		logger:     logger,
+
+		generatedMode: GeneratedDownweight,
 	}
 }
 
@@ -85,16 +138,41 @@ func NewIndexerWithBranch(store VectorStore, repoPath, repoName, branch string,
 		repoName:   repoName,
 		branch:     branch,
 		fileHashes: make(map[string]string),
+		tokenizer:  tokenizer.Default(),
+		secretMode: secrets.ModeRedact,
 		logger:     logger,
+
+		generatedMode: GeneratedDownweight,
 	}
 }
 
+// SetTokenizer sets the tokenizer used to size chunks for the embedding model
+func (idx *Indexer) SetTokenizer(tok tokenizer.Tokenizer) {
+	idx.tokenizer = tok
+}
+
+// SetProgressFunc registers a callback invoked as files are processed
+func (idx *Indexer) SetProgressFunc(fn ProgressFunc) {
+	idx.progress = fn
+}
+
+// reportProgress forwards current stats to the progress callback, if any
+func (idx *Indexer) reportProgress(stats *IndexStats, total int) {
+	if idx.progress == nil {
+		return
+	}
+	indexed, _, errors := stats.get()
+	idx.progress(indexed+errors, total, errors)
+}
+
 // IndexRepository indexes all code files in the repository
 // Uses incremental indexing - only re-indexes files that have changed
 // Now with parallel workers and chunking support
 func (idx *Indexer) IndexRepository(ctx context.Context) error {
 	idx.logger.Info().Str("repo_path", idx.repoPath).Msg("Starting repository indexing")
 
+	filter := loadDiskFilter(idx.repoPath, idx.excludePatterns, idx.focusPaths, idx.fileTypes, idx.logger)
+
 	// Collect all files first
 	var filesToIndex []IndexJob
 	err := filepath.Walk(idx.repoPath, func(path string, info os.FileInfo, err error) error {
@@ -102,15 +180,26 @@ func (idx *Indexer) IndexRepository(ctx context.Context) error {
 			return nil
 		}
 
+		relPath, err := filepath.Rel(idx.repoPath, path)
+		if err != nil {
+			relPath = path
+		}
+		relPath = filepath.ToSlash(relPath)
+
 		if info.IsDir() {
-			// Skip common directories (delegates to filetypes)
-			if filetypes.ShouldSkipDirectory(info.Name()) {
+			if relPath == "." {
+				return nil
+			}
+			// Skip common directories (delegates to filetypes) and ignored ones
+			if filetypes.ShouldSkipDirectory(info.Name()) || filter.skipsDir(relPath) {
 				return filepath.SkipDir
 			}
+			// Walk visits a directory before its contents
+			filter.addDiskIgnoreFile(idx.repoPath, relPath+"/.gitignore", idx.logger)
 			return nil
 		}
 
-		if !isCodeFile(path) {
+		if !filter.indexable(relPath) {
 			return nil
 		}
 
@@ -121,10 +210,6 @@ func (idx *Indexer) IndexRepository(ctx context.Context) error {
 		}
 
 		currentHash := computeFileHash(content)
-		relPath, err := filepath.Rel(idx.repoPath, path)
-		if err != nil {
-			relPath = path
-		}
 
 		// Check if file has changed
 		idx.mu.RLock()
@@ -136,7 +221,7 @@ func (idx *Indexer) IndexRepository(ctx context.Context) error {
 		}
 
 		// Skip extremely large files (>500KB)
-		if len(content) > 500000 {
+		if len(content) > maxIndexFileSize {
 			idx.logger.Debug().
 				Str("path", relPath).
 				Int("size", len(content)).
@@ -176,6 +261,7 @@ func (idx *Indexer) IndexRepository(ctx context.Context) error {
 func (idx *Indexer) indexFilesParallel(ctx context.Context, jobs []IndexJob) *IndexStats {
 	stats := &IndexStats{}
 
+	idx.reportProgress(stats, len(jobs))
 	if len(jobs) == 0 {
 		return stats
 	}
@@ -195,7 +281,7 @@ func (idx *Indexer) indexFilesParallel(ctx context.Context, jobs []IndexJob) *In
 		wg.Add(1)
 		go func(workerID int) {
 			defer wg.Done()
-			idx.indexWorker(ctx, workerID, jobsChan, stats)
+			idx.indexWorker(ctx, workerID, jobsChan, stats, len(jobs))
 		}(w)
 	}
 
@@ -212,17 +298,23 @@ func (idx *Indexer) indexFilesParallel(ctx context.Context, jobs []IndexJob) *In
 }
 
 // indexWorker processes files from the job queue
-func (idx *Indexer) indexWorker(ctx context.Context, workerID int, jobs <-chan IndexJob, stats *IndexStats) {
+func (idx *Indexer) indexWorker(ctx context.Context, workerID int, jobs <-chan IndexJob, stats *IndexStats, total int) {
 	for job := range jobs {
-		if err := idx.indexFileOrChunks(ctx, job.RelPath, job.Content); err != nil {
+		// Stop picking up work once cancelled (the channel is fully buffered)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err := idx.indexJob(ctx, job); err != nil {
 			idx.logger.Error().
 				Err(err).
 				Int("worker", workerID).
 				Str("path", job.RelPath).
 				Msg("Failed to index file")
-			stats.incErrors()
+			stats.incFailed(job.RelPath)
 		} else {
 			stats.incIndexed()
+			idx.checkpoint.markDone(idx, job.RelPath)
 
 			// Log progress every 10 files
 			indexed, _, _ := stats.get()
@@ -233,40 +325,129 @@ func (idx *Indexer) indexWorker(ctx context.Context, workerID int, jobs <-chan I
 					Msg("Indexing progress")
 			}
 		}
+		idx.reportProgress(stats, total)
+	}
+}
+
+// indexJob indexes a file, reusing its chunks when they were computed already
+func (idx *Indexer) indexJob(ctx context.Context, job IndexJob) error {
+	if job.chunked != nil {
+		return idx.indexChunks(ctx, job.RelPath, idx.chunkJobs(job.RelPath, job.chunked))
 	}
+	return idx.indexFileOrChunks(ctx, job.RelPath, job.Content)
 }
 
 // indexFileOrChunks indexes a file using token-aware chunking
 // Always chunks files that might exceed model token limits
 func (idx *Indexer) indexFileOrChunks(ctx context.Context, relPath, content string) error {
-	// Use token-aware chunking - ChunkFile decides whether to chunk based on token budget
-	language := detectLanguage(relPath)
-	chunks := ChunkFile(relPath, content, language)
+	return idx.indexChunks(ctx, relPath, idx.fileChunks(relPath, content))
+}
 
+// indexChunks stores the chunks of a file
+func (idx *Indexer) indexChunks(ctx context.Context, relPath string, chunks []IndexJob) error {
 	if len(chunks) > 1 {
 		idx.logger.Debug().
 			Str("path", relPath).
-			Int("size", len(content)).
 			Int("chunks", len(chunks)).
 			Msg("File chunked for token budget")
 	}
 
-	for _, chunk := range chunks {
-		// Build chunk path: "path/file.go#chunk0", "path/file.go#chunk1", etc.
-		chunkPath := relPath
-		if len(chunks) > 1 {
-			chunkPath = fmt.Sprintf("%s#chunk%d", relPath, chunk.ChunkIndex)
+	for i, chunk := range chunks {
+		// The header and file context only go into the embedding (see
+		// EmbedTemplate); stored content stays raw, since headers would
+		// confuse LLMs by appearing as code
+		if err := idx.indexChunk(ctx, chunk); err != nil {
+			return fmt.Errorf("chunk %d: %w", i, err)
 		}
+	}
+
+	return nil
+}
+
+// chunkedFile is a file scrubbed and split into chunks, before its chunks'
+// embedding texts are rendered
+type chunkedFile struct {
+	content  string // Scrubbed
+	language string
+	class    filetypes.Class
+	chunks   []CodeChunk
+}
+
+// fileChunks splits a file into the points stored for it, keyed by chunk path:
+// "path/file.go" for single-chunk files, "path/file.go#chunk0", "path/file.go#chunk1", etc.
+// Extension-less files without a known shebang, files skipped as generated,
+// vendored or binary, and files skipped for their secrets have no chunks;
+// secrets are scrubbed from the others first.
+func (idx *Indexer) fileChunks(relPath, content string) []IndexJob {
+	return idx.chunkJobs(relPath, idx.chunkFile(relPath, content))
+}
+
+// chunkFile scrubs and chunks a file, recording its symbols and summary hash
+// (see fileChunks); nil if the file has no chunks
+func (idx *Indexer) chunkFile(relPath, content string) *chunkedFile {
+	language := idx.fileTypes.Detect(relPath, content)
+	if language == "" {
+		return nil
+	}
+
+	class := filetypes.Classify(relPath, content)
+	if idx.skipsClass(class) {
+		idx.logger.Debug().Str("path", relPath).Str("class", string(class)).Msg("Skipping non-source file")
+		idx.redactions.record(relPath, nil)
+		idx.symbols.record(relPath, nil)
+		idx.summary.record(relPath, "")
+		return nil
+	}
 
-		// Index chunk content directly WITHOUT header
-		// The header would confuse LLMs by appearing as code
-		// Chunk path already provides context via file_path field
-		if err := idx.store.IndexFile(ctx, chunkPath, chunk.Content); err != nil {
-			return fmt.Errorf("chunk %d: %w", chunk.ChunkIndex, err)
+	content, ok := idx.scrub(relPath, content)
+	if !ok {
+		idx.symbols.record(relPath, nil)
+		idx.summary.record(relPath, "")
+		return nil
+	}
+	if idx.symbols != nil {
+		idx.symbols.record(relPath, symbols.Extract(relPath, language, content))
+	}
+	if idx.summary != nil {
+		idx.summary.record(relPath, computeFileHash([]byte(content)))
+	}
+
+	// Use token-aware chunking - ChunkFile decides whether to chunk based on token budget
+	chunks := ChunkFileWithTokenizer(relPath, content, language, idx.tokenizer)
+	if len(chunks) == 0 {
+		return nil
+	}
+	return &chunkedFile{content: content, language: language, class: class, chunks: chunks}
+}
+
+// chunkJobs renders the embedding texts of a chunked file's chunks and
+// returns the points stored for it
+func (idx *Indexer) chunkJobs(relPath string, file *chunkedFile) []IndexJob {
+	if file == nil {
+		return nil
+	}
+
+	embeds := idx.embedTexts(relPath, file.language, file.content, file.chunks)
+
+	jobs := make([]IndexJob, len(file.chunks))
+	for i, chunk := range file.chunks {
+		jobs[i] = IndexJob{
+			RelPath:  chunkPath(relPath, chunk, len(file.chunks)),
+			Content:  chunk.Content,
+			Class:    file.class,
+			Language: file.language,
+			Embed:    embeds[i],
 		}
 	}
+	return jobs
+}
 
-	return nil
+// chunkPath returns the path a chunk of a file is stored under
+func chunkPath(relPath string, chunk CodeChunk, chunks int) string {
+	if chunks > 1 {
+		return fmt.Sprintf("%s#chunk%d", relPath, chunk.ChunkIndex)
+	}
+	return relPath
 }
 
 // isCodeFile checks if a file should be indexed
@@ -300,65 +481,150 @@ func (idx *Indexer) IndexIncremental(ctx context.Context) error {
 		return fmt.Errorf("check reindexing: %w", err)
 	}
 
-	if !needsReindex {
-		idx.logger.Info().Msg("No changes detected, skipping indexing")
-		return nil
-	}
-
 	// Load previous metadata
 	meta, err := LoadMetadata(idx.repoName, idx.branch)
 	if err != nil {
 		return fmt.Errorf("load metadata: %w", err)
 	}
 
-	var indexed, errors int
+	src, err := idx.openSource(currentCommit)
+	if err != nil {
+		return fmt.Errorf("open file source: %w", err)
+	}
+	defer src.Close()
+
+	if err := idx.startFilter(src); err != nil {
+		return err
+	}
+	idx.startRedactions()
+	idx.startSymbols()
+	idx.startSummary()
+	defer func() { idx.redactions, idx.symbols, idx.summary = nil, nil, nil }()
+
+	// Metadata written before secret scanning or file classes has no mode, so
+	// the first run after an upgrade reconciles, redacting secrets already
+	// embedded and dropping binary files
+	filterChanged := meta != nil &&
+		(meta.FilterHash != idx.filter.hash || meta.SecretMode != idx.secretMode ||
+			meta.GeneratedMode != idx.generatedMode)
+
+	// Chunks embedded with another template keep their content hashes, so
+	// only a reconcile that re-embeds every file brings them up to date
+	reembed := meta != nil && meta.EmbedTemplate != idx.embedFingerprint()
+
+	// Branches indexed before symbols and imports were extracted, or before
+	// they were summarized, have every file to visit
+	indexesMissing := meta != nil && (idx.symbols.needsRebuild() || idx.summary.needsRebuild())
+	if !needsReindex && !filterChanged && !reembed && !indexesMissing {
+		idx.logger.Info().Msg("No changes detected, skipping indexing")
+		idx.summarizePending(ctx)
+		return nil
+	}
 
 	if meta == nil {
 		// First time indexing this branch - index everything
 		idx.logger.Info().Msg("First time indexing this branch, indexing all files")
-		return idx.indexAllFiles(ctx, currentCommit)
+		return idx.indexAllFiles(ctx, src, currentCommit)
+	}
+
+	// A force push followed by gc (or a fresh clone) can drop the indexed
+	// commit, leaving nothing to diff against
+	if !CommitExists(idx.repoPath, meta.CommitSHA) {
+		idx.logger.Warn().
+			Str("from_commit", meta.CommitSHA[:8]).
+			Str("to_commit", currentCommit[:8]).
+			Msg("Indexed commit is unreachable, reconciling collection against tree")
+		return idx.reconcile(ctx, src, meta, currentCommit, reembed)
+	}
+
+	// Changed ignore files, patterns or generated mode can exclude indexed
+	// files or include unchanged ones, and a changed secret mode alters
+	// unchanged files' content, none of which a diff of the commits covers
+	if filterChanged {
+		idx.logger.Info().Msg("Ignore rules, secret or generated mode changed, reconciling collection against tree")
+		return idx.reconcile(ctx, src, meta, currentCommit, reembed)
+	}
+	if reembed {
+		idx.logger.Info().Msg("Embed template changed, re-embedding collection")
+		return idx.reconcile(ctx, src, meta, currentCommit, true)
+	}
+	if indexesMissing {
+		idx.logger.Info().Msg("No symbol index, dependency graph or repository summary for branch, reconciling collection against tree")
+		return idx.reconcile(ctx, src, meta, currentCommit, false)
 	}
 
-	// Get changed files since last commit
-	changedFiles, err := GetChangedFilesSince(idx.repoPath, meta.CommitSHA)
+	// Get changed files between the indexed commit and the branch's commit
+	changes, err := GetFileChangesBetween(idx.repoPath, meta.CommitSHA, currentCommit)
 	if err != nil {
 		return fmt.Errorf("get changed files: %w", err)
 	}
 
+	// Files that failed are retried at a new commit; at the same one, only
+	// those whose backoff elapsed, and the rest keep waiting
+	retries, waiting := meta.FailedFiles, []string(nil)
+	if meta.CommitSHA == currentCommit {
+		retries, waiting = meta.splitRetries(time.Now())
+	}
+
 	idx.logger.Info().
-		Int("changed_files", len(changedFiles)).
+		Int("changed_files", len(changes)).
+		Int("retry_files", len(retries)).
 		Str("from_commit", meta.CommitSHA[:8]).
 		Str("to_commit", currentCommit[:8]).
 		Msg("Detected changed files")
 
+	changes = withRetries(changes, retries)
+
+	checkpoint := idx.startCheckpoint(meta.CommitSHA, currentCommit)
+	defer idx.suspendCheckpoint()
+
 	// Collect files and content for parallel indexing
 	var jobsToIndex []IndexJob
-	for _, file := range changedFiles {
-		fullPath := filepath.Join(idx.repoPath, file)
-		if !isCodeFile(fullPath) {
+	var failed []string
+	for _, change := range changes {
+		// Renamed files leave their old path behind
+		if change.Status == ChangeRenamed && idx.filter.indexable(change.OldPath) {
+			if err := idx.deleteFile(ctx, change.OldPath); err != nil {
+				idx.logger.Error().Err(err).Str("path", change.OldPath).Msg("Failed to delete renamed file from index")
+				failed = append(failed, change.OldPath)
+			} else {
+				idx.logger.Debug().
+					Str("from", change.OldPath).
+					Str("to", change.Path).
+					Msg("Renamed file removed from index under old path")
+			}
+		}
+
+		file := change.Path
+		if !idx.filter.indexable(file) || checkpoint.isDone(file) {
 			continue
 		}
 
-		// Check if file still exists (might have been deleted)
-		content, err := os.ReadFile(fullPath)
+		// Deleted files have no content at the new commit
+		var content []byte
+		if change.Status == ChangeDeleted {
+			err = os.ErrNotExist
+		} else {
+			content, err = src.ReadFile(file)
+		}
 		if err != nil {
 			if os.IsNotExist(err) {
 				// File was deleted, remove from index (deletes all chunks)
-				if err := idx.store.DeleteFile(ctx, file); err != nil {
+				if err := idx.deleteFile(ctx, file); err != nil {
 					idx.logger.Error().Err(err).Str("path", file).Msg("Failed to delete file from index")
-					errors++
+					failed = append(failed, file)
 				} else {
 					idx.logger.Debug().Str("path", file).Msg("File deleted from index")
 				}
 				continue
 			}
 			idx.logger.Warn().Err(err).Str("path", file).Msg("Failed to read file")
-			errors++
+			failed = append(failed, file)
 			continue
 		}
 
 		// Skip extremely large files (>500KB)
-		if len(content) > 500000 {
+		if len(content) > maxIndexFileSize {
 			idx.logger.Debug().
 				Str("path", file).
 				Int("size", len(content)).
@@ -379,10 +645,13 @@ func (idx *Indexer) IndexIncremental(ctx context.Context) error {
 	}
 
 	// Index files in parallel with chunking support
-	if len(jobsToIndex) > 0 {
-		stats := idx.indexFilesParallel(ctx, jobsToIndex)
-		indexed = stats.Indexed
-		errors += stats.Errors
+	stats := idx.indexFilesParallel(ctx, jobsToIndex)
+	failed = append(failed, stats.failedFiles()...)
+
+	// A cancelled run must not advance the indexed commit; the checkpoint
+	// lets the next run pick up where this one stopped
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("indexing cancelled: %w", err)
 	}
 
 	// Update metadata
@@ -391,101 +660,317 @@ func (idx *Indexer) IndexIncremental(ctx context.Context) error {
 		indexedAt = ctxTime
 	}
 
-	newMeta := &BranchMetadata{
-		RepoName:  idx.repoName,
-		Branch:    idx.branch,
-		CommitSHA: currentCommit,
-		IndexedAt: indexedAt,
-		FileCount: indexed,
-	}
-
-	if err := SaveMetadata(newMeta); err != nil {
-		return fmt.Errorf("save metadata: %w", err)
+	failures := nextFailures(meta, currentCommit, failed, waiting, time.Now())
+	if err := idx.finishRun(ctx, currentCommit, indexedAt, stats.Indexed, failures); err != nil {
+		return err
 	}
 
 	idx.logger.Info().
-		Int("indexed", indexed).
-		Int("errors", errors).
+		Int("indexed", stats.Indexed).
+		Int("errors", len(failed)).
 		Str("commit", currentCommit[:8]).
 		Msg("Incremental indexing completed")
 
 	return nil
 }
 
-// indexAllFiles indexes all files in the repository (used for first-time indexing)
-func (idx *Indexer) indexAllFiles(ctx context.Context, currentCommit string) error {
-	// Collect all files first
-	var filesToIndex []IndexJob
-
-	err := filepath.Walk(idx.repoPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
+// withRetries appends previously failed files to changes as modifications,
+// unless the diff already covers them
+func withRetries(changes []FileChange, failedFiles []string) []FileChange {
+	seen := make(map[string]bool, len(changes))
+	for _, change := range changes {
+		seen[change.Path] = true
+	}
+	for _, path := range failedFiles {
+		if !seen[path] {
+			changes = append(changes, FileChange{Status: ChangeModified, Path: path})
+			seen[path] = true
 		}
+	}
+	return changes
+}
 
-		if info.IsDir() {
-			// Skip common directories (delegates to filetypes)
-			if filetypes.ShouldSkipDirectory(info.Name()) {
-				return filepath.SkipDir
-			}
-			return nil
-		}
+// finishRun records a completed run: the branch advances to commit, files
+// that failed are kept in the metadata for retry, and the checkpoint is dropped
+func (idx *Indexer) finishRun(ctx context.Context, commit string, indexedAt time.Time, fileCount int, failures map[string]FailedFile) error {
+	failed := make([]string, 0, len(failures))
+	for relPath := range failures {
+		failed = append(failed, relPath)
+	}
+	sort.Strings(failed)
 
-		if !isCodeFile(path) {
-			return nil
-		}
+	meta := &BranchMetadata{
+		RepoName:       idx.repoName,
+		Branch:         idx.branch,
+		CommitSHA:      commit,
+		IndexedAt:      indexedAt,
+		FileCount:      fileCount,
+		FailedAttempts: failures,
+	}
+	if len(failed) > 0 {
+		meta.FailedFiles = failed
+	}
+	if idx.filter != nil {
+		meta.FilterHash = idx.filter.hash
+	}
+	meta.SecretMode = idx.secretMode
+	meta.GeneratedMode = idx.generatedMode
+	meta.EmbedTemplate = idx.embedFingerprint()
+	idx.saveRedactions()
+	idx.saveSymbols()
 
-		content, err := os.ReadFile(path)
-		if err != nil {
-			idx.logger.Warn().Err(err).Str("path", path).Msg("Failed to read file")
-			return nil
+	if err := SaveMetadata(meta); err != nil {
+		return fmt.Errorf("save metadata: %w", err)
+	}
+
+	// Summaries can take a while to write, so they come after the metadata: a
+	// run cancelled meanwhile still keeps its index, and the rest are retried
+	idx.saveSummary(ctx)
+
+	if len(failed) > 0 {
+		idx.logger.Warn().
+			Int("failed", len(failed)).
+			Msg("Some files failed to index; they will be retried with backoff")
+	}
+
+	idx.checkpoint = nil
+	if err := DeleteCheckpoint(idx.repoName, idx.branch); err != nil {
+		idx.logger.Warn().Err(err).Msg("Failed to remove indexing checkpoint")
+	}
+	return nil
+}
+
+// indexAllFiles indexes all files in the repository (used for first-time indexing)
+func (idx *Indexer) indexAllFiles(ctx context.Context, src fileSource, currentCommit string) error {
+	files, err := idx.indexableFiles(src)
+	if err != nil {
+		return err
+	}
+
+	checkpoint := idx.startCheckpoint("", currentCommit)
+	defer idx.suspendCheckpoint()
+
+	// Every file is visited, so what an interrupted run over another commit
+	// saved is dropped; a resumed run keeps that of its completed files
+	if !checkpoint.resumed() {
+		idx.redactions.reset()
+		idx.symbols.reset()
+		idx.summary.reset()
+	}
+
+	// Collect all files first
+	var filesToIndex []IndexJob
+	var failed []string
+	for _, relPath := range files {
+		if checkpoint.isDone(relPath) {
+			continue
 		}
 
-		relPath, err := filepath.Rel(idx.repoPath, path)
+		content, err := src.ReadFile(relPath)
 		if err != nil {
-			relPath = path
+			idx.logger.Warn().Err(err).Str("path", relPath).Msg("Failed to read file")
+			failed = append(failed, relPath)
+			continue
 		}
 
 		// Skip extremely large files (>500KB - likely generated, minified, or binary)
-		if len(content) > 500000 {
+		if len(content) > maxIndexFileSize {
 			idx.logger.Debug().
 				Str("path", relPath).
 				Int("size", len(content)).
 				Msg("File too large, skipping (>500KB)")
-			return nil
+			continue
 		}
 
 		filesToIndex = append(filesToIndex, IndexJob{
 			RelPath: relPath,
 			Content: string(content),
 		})
-
-		return nil
-	})
-	if err != nil {
-		return fmt.Errorf("walk error: %w", err)
 	}
 
 	// Index files in parallel
 	stats := idx.indexFilesParallel(ctx, filesToIndex)
+	failed = append(failed, stats.failedFiles()...)
 
-	// Save metadata
-	meta := &BranchMetadata{
-		RepoName:  idx.repoName,
-		Branch:    idx.branch,
-		CommitSHA: currentCommit,
-		IndexedAt: time.Now(),
-		FileCount: stats.Indexed,
+	// A cancelled run must not advance the indexed commit; the checkpoint
+	// lets the next run pick up where this one stopped
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("indexing cancelled: %w", err)
 	}
 
-	if err := SaveMetadata(meta); err != nil {
-		return fmt.Errorf("save metadata: %w", err)
+	failures := nextFailures(nil, currentCommit, failed, nil, time.Now())
+	if err := idx.finishRun(ctx, currentCommit, time.Now(), stats.Indexed, failures); err != nil {
+		return err
 	}
 
 	idx.logger.Info().
 		Int("indexed", stats.Indexed).
 		Int("skipped", stats.Skipped).
-		Int("errors", stats.Errors).
+		Int("errors", len(failed)).
 		Msg("Initial indexing completed")
 
 	return nil
 }
+
+// reconcile brings the collection in line with the tree at currentCommit
+// without a diff: stale and missing files are re-indexed and files no longer
+// in the tree removed (see diffAgainstTree). With reembed, unchanged files
+// are re-indexed too.
+func (idx *Indexer) reconcile(ctx context.Context, src fileSource, meta *BranchMetadata, currentCommit string, reembed bool) error {
+	// Every file is scanned again, so files gone from the tree (including
+	// ones skipped for their secrets, which were never stored) drop out
+	idx.redactions.reset()
+	idx.symbols.reset()
+	idx.summary.reset()
+
+	diff, err := idx.diffAgainstTree(ctx, src, reembed)
+	if err != nil {
+		return err
+	}
+
+	stats, deleteErrors := idx.applyTreeDiff(ctx, diff)
+
+	// A cancelled run must not advance the indexed commit
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("indexing cancelled: %w", err)
+	}
+
+	// Unreadable files and failed embeddings are retried on the next run;
+	// orphans that failed to delete are left for verification to catch
+	failed := append(diff.unread, stats.failedFiles()...)
+	failures := nextFailures(meta, currentCommit, failed, nil, time.Now())
+	if err := idx.finishRun(ctx, currentCommit, time.Now(), stats.Indexed, failures); err != nil {
+		return err
+	}
+
+	idx.logger.Info().
+		Int("indexed", stats.Indexed).
+		Int("unchanged", diff.unchanged).
+		Int("deleted", len(diff.orphaned)-deleteErrors).
+		Int("errors", len(failed)+deleteErrors).
+		Str("commit", currentCommit[:8]).
+		Msg("Reconciliation completed")
+
+	return nil
+}
+
+// treeDiff is how the stored chunks of a collection differ from a tree
+type treeDiff struct {
+	missing   []string   // In the tree, not indexed
+	stale     []string   // Indexed with chunk hashes that don't match the tree
+	orphaned  []string   // Indexed, but no longer in the tree
+	jobs      []IndexJob // Missing and stale files with their tree content, already chunked
+	unread    []string   // Files that could not be read from the tree
+	unchanged int
+}
+
+// diffAgainstTree compares stored chunk hashes against freshly chunked files
+// from src. Oversized, non-code and excluded files count as absent from the
+// tree. With reembed, every indexed file counts as stale. Each file is read,
+// scrubbed and chunked once: jobs carry the chunks of the files that differ,
+// and embedding texts are only rendered when they are indexed.
+func (idx *Indexer) diffAgainstTree(ctx context.Context, src fileSource, reembed bool) (*treeDiff, error) {
+	stored, err := idx.store.ListIndexedChunks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list indexed chunks: %w", err)
+	}
+
+	// Group stored chunks by file
+	storedFiles := make(map[string]map[string]string)
+	for chunkPath, hash := range stored {
+		basePath := extractBasePath(chunkPath)
+		if storedFiles[basePath] == nil {
+			storedFiles[basePath] = make(map[string]string)
+		}
+		storedFiles[basePath][chunkPath] = hash
+	}
+
+	files, err := idx.indexableFiles(src)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &treeDiff{}
+	for _, relPath := range files {
+		content, err := src.ReadFile(relPath)
+		if err != nil {
+			idx.logger.Warn().Err(err).Str("path", relPath).Msg("Failed to read file")
+			// Unknown content; don't treat the indexed copy as orphaned
+			delete(storedFiles, relPath)
+			diff.unread = append(diff.unread, relPath)
+			continue
+		}
+
+		// Oversized files stay in storedFiles and count as orphaned
+		if len(content) > maxIndexFileSize {
+			continue
+		}
+
+		existing, indexed := storedFiles[relPath]
+		delete(storedFiles, relPath)
+
+		file := idx.chunkFile(relPath, string(content))
+		if chunksMatch(existing, relPath, file) && (!reembed || file == nil) {
+			diff.unchanged++
+			continue
+		}
+
+		if indexed {
+			diff.stale = append(diff.stale, relPath)
+		} else {
+			diff.missing = append(diff.missing, relPath)
+		}
+		// Files without chunks any more only need their stale chunks deleted
+		if file != nil {
+			diff.jobs = append(diff.jobs, IndexJob{RelPath: relPath, chunked: file})
+		}
+	}
+
+	// Whatever is left is no longer part of the tree
+	for basePath := range storedFiles {
+		diff.orphaned = append(diff.orphaned, basePath)
+	}
+
+	sort.Strings(diff.missing)
+	sort.Strings(diff.stale)
+	sort.Strings(diff.orphaned)
+	return diff, nil
+}
+
+// applyTreeDiff removes stale and orphaned files from the collection and
+// indexes missing and stale ones. Returns index stats and failed deletions.
+func (idx *Indexer) applyTreeDiff(ctx context.Context, diff *treeDiff) (*IndexStats, int) {
+	var deleteErrors int
+
+	for _, relPath := range diff.stale {
+		if err := idx.store.DeleteFile(ctx, relPath); err != nil {
+			idx.logger.Warn().Err(err).Str("path", relPath).Msg("Failed to delete old chunks before re-indexing")
+		}
+	}
+
+	for _, relPath := range diff.orphaned {
+		if err := idx.deleteFile(ctx, relPath); err != nil {
+			idx.logger.Error().Err(err).Str("path", relPath).Msg("Failed to delete orphaned file from index")
+			deleteErrors++
+		}
+	}
+
+	return idx.indexFilesParallel(ctx, diff.jobs), deleteErrors
+}
+
+// chunksMatch reports whether the stored chunk hashes of a file equal the
+// hashes of its freshly computed chunks (none for a nil file)
+func chunksMatch(stored map[string]string, relPath string, file *chunkedFile) bool {
+	if file == nil {
+		return len(stored) == 0
+	}
+	if len(stored) != len(file.chunks) {
+		return false
+	}
+	for _, chunk := range file.chunks {
+		if stored[chunkPath(relPath, chunk, len(file.chunks))] != computeHash(chunk.Content) {
+			return false
+		}
+	}
+	return true
+}
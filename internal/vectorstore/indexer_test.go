@@ -44,11 +44,25 @@ func (m *mockStore) Search(ctx context.Context, query string, limit int) ([]Sear
 func (m *mockStore) DeleteFile(ctx context.Context, filePath string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	delete(m.indexed, filePath)
+	for path := range m.indexed {
+		if extractBasePath(path) == filePath {
+			delete(m.indexed, path)
+		}
+	}
 	m.deleteCnt++
 	return nil
 }
 
+func (m *mockStore) ListIndexedChunks(ctx context.Context) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hashes := make(map[string]string, len(m.indexed))
+	for path, content := range m.indexed {
+		hashes[path] = computeHash(content)
+	}
+	return hashes, nil
+}
+
 func (m *mockStore) DeleteCollection(ctx context.Context) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -489,3 +503,80 @@ func TestIndexRepository_NestedDirectories(t *testing.T) {
 		}
 	}
 }
+
+func TestIndexIncremental_RenameAndDelete(t *testing.T) {
+	src, _ := initBareRepo(t)
+
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	store := newMockStore()
+	indexer := NewIndexerWithBranch(store, src, "repo", "feature", testLogger())
+	if err := indexer.IndexIncremental(context.Background()); err != nil {
+		t.Fatalf("IndexIncremental failed: %v", err)
+	}
+
+	gitCmd(t, src, "checkout", "-q", "feature")
+	gitCmd(t, src, "mv", "a.go", "moved.go")
+	gitCmd(t, src, "rm", "-q", "b.go")
+	gitCmd(t, src, "commit", "-m", "rename and delete")
+
+	if err := indexer.IndexIncremental(context.Background()); err != nil {
+		t.Fatalf("Second IndexIncremental failed: %v", err)
+	}
+
+	if _, ok := store.indexed["a.go"]; ok {
+		t.Error("Expected old path of renamed file to be removed")
+	}
+	if _, ok := store.indexed["moved.go"]; !ok {
+		t.Error("Expected renamed file to be indexed under its new path")
+	}
+	if _, ok := store.indexed["b.go"]; ok {
+		t.Error("Expected deleted file to be removed")
+	}
+}
+
+func TestIndexIncremental_ReconcilesUnreachableCommit(t *testing.T) {
+	src, _ := initBareRepo(t)
+
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	// Metadata points at a commit that no longer exists (rewritten history)
+	SaveMetadata(&BranchMetadata{
+		RepoName:  "repo",
+		Branch:    "feature",
+		CommitSHA: "0123456789abcdef0123456789abcdef01234567",
+	})
+
+	store := newMockStore()
+	store.indexed["a.go"] = "package a\n"   // up to date
+	store.indexed["b.go"] = "package old\n" // stale
+	store.indexed["gone.go#chunk0"] = "x"   // no longer in the tree
+	store.indexed["gone.go#chunk1"] = "y"
+
+	indexer := NewIndexerWithBranch(store, src, "repo", "feature", testLogger())
+	if err := indexer.IndexIncremental(context.Background()); err != nil {
+		t.Fatalf("IndexIncremental failed: %v", err)
+	}
+
+	if store.indexCnt != 1 {
+		t.Errorf("Expected only the stale file to be re-indexed, got %d index calls", store.indexCnt)
+	}
+	if store.indexed["b.go"] != "package b\n" {
+		t.Errorf("Expected stale b.go to be refreshed, got %q", store.indexed["b.go"])
+	}
+	if len(store.indexed) != 2 {
+		t.Errorf("Expected orphaned chunks to be removed, got %v", store.indexed)
+	}
+
+	meta, _ := LoadMetadata("repo", "feature")
+	commit, _ := GetBranchCommit(src, "feature")
+	if meta == nil || meta.CommitSHA != commit {
+		t.Errorf("Expected metadata to advance to %s, got %+v", commit, meta)
+	}
+}
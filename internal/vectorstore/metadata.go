@@ -2,9 +2,13 @@ package vectorstore
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
+
+	"github.com/First008/mesh/internal/secrets"
 )
 
 // BranchMetadata tracks indexing state per branch
@@ -15,6 +19,97 @@ type BranchMetadata struct {
 	CommitSHA string    `json:"commit_sha"`
 	IndexedAt time.Time `json:"indexed_at"`
 	FileCount int       `json:"file_count"`
+
+	// FailedFiles failed to index at CommitSHA and are retried with backoff
+	// (see FailedAttempts), and again once the branch moves
+	FailedFiles []string `json:"failed_files,omitempty"`
+
+	// FailedAttempts is the retry state of each of FailedFiles at CommitSHA
+	FailedAttempts map[string]FailedFile `json:"failed_attempts,omitempty"`
+
+	// FilterHash fingerprints the ignore files and patterns the index was
+	// built with; a change triggers a reconcile that purges excluded files
+	FilterHash string `json:"filter_hash,omitempty"`
+
+	// SecretMode is how secrets were scrubbed from indexed content; a change
+	// triggers a reconcile so unchanged files are scrubbed the new way
+	SecretMode secrets.Mode `json:"secret_mode,omitempty"`
+
+	// GeneratedMode is how generated and vendored files were handled; a
+	// change triggers a reconcile that adds or drops them
+	GeneratedMode GeneratedMode `json:"generated_mode,omitempty"`
+
+	// EmbedTemplate fingerprints the template chunks were embedded with
+	// ("" for raw content); a change re-embeds every file
+	EmbedTemplate string `json:"embed_template,omitempty"`
+}
+
+const (
+	// maxFailedAttempts is how often a failing file is tried at one commit;
+	// after that it waits for the branch to move
+	maxFailedAttempts = 5
+
+	// failedRetryDelay is the wait after a file's first failure, doubling
+	// with each further attempt
+	failedRetryDelay = time.Minute
+)
+
+// FailedFile is the retry state of a file that failed to index
+type FailedFile struct {
+	Attempts    int       `json:"attempts"` // Failed attempts at the indexed commit
+	LastAttempt time.Time `json:"last_attempt"`
+}
+
+// retryDue reports whether the file's backoff elapsed and it has attempts left
+func (f FailedFile) retryDue(now time.Time) bool {
+	if f.Attempts >= maxFailedAttempts {
+		return false
+	}
+	attempts := max(f.Attempts, 1)
+	return !now.Before(f.LastAttempt.Add(failedRetryDelay << (attempts - 1)))
+}
+
+// failure returns the retry state of a failed file; metadata written before
+// attempts were recorded counts one attempt, at IndexedAt
+func (m *BranchMetadata) failure(relPath string) FailedFile {
+	if f, ok := m.FailedAttempts[relPath]; ok {
+		return f
+	}
+	return FailedFile{Attempts: 1, LastAttempt: m.IndexedAt}
+}
+
+// splitRetries splits the failed files into those whose retry at the indexed
+// commit is due and those still waiting
+func (m *BranchMetadata) splitRetries(now time.Time) (due, waiting []string) {
+	for _, relPath := range m.FailedFiles {
+		if m.failure(relPath).retryDue(now) {
+			due = append(due, relPath)
+		} else {
+			waiting = append(waiting, relPath)
+		}
+	}
+	return due, waiting
+}
+
+// nextFailures returns the retry state after a run at commit: files failing
+// again at the same commit count another attempt, new failures their first,
+// and waiting files, which the run did not try, keep theirs
+func nextFailures(prev *BranchMetadata, commit string, failed, waiting []string, now time.Time) map[string]FailedFile {
+	if len(failed) == 0 && len(waiting) == 0 {
+		return nil
+	}
+	failures := make(map[string]FailedFile, len(failed)+len(waiting))
+	for _, relPath := range waiting {
+		failures[relPath] = prev.failure(relPath)
+	}
+	for _, relPath := range failed {
+		f := FailedFile{Attempts: 1, LastAttempt: now}
+		if prev != nil && prev.CommitSHA == commit && slices.Contains(prev.FailedFiles, relPath) {
+			f.Attempts = prev.failure(relPath).Attempts + 1
+		}
+		failures[relPath] = f
+	}
+	return failures
 }
 
 // GetMetadataPath returns path to metadata file for repo+branch
@@ -46,19 +141,48 @@ func LoadMetadata(repoName, branch string) (*BranchMetadata, error) {
 
 // SaveMetadata saves the metadata for a repo+branch combination
 func SaveMetadata(meta *BranchMetadata) error {
-	path := GetMetadataPath(meta.RepoName, meta.Branch)
-
-	// Create directory if it doesn't exist
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
 		return err
 	}
 
-	data, err := json.MarshalIndent(meta, "", "  ")
-	if err != nil {
+	return writeFileAtomic(GetMetadataPath(meta.RepoName, meta.Branch), data)
+}
+
+// DeleteMetadata removes the metadata and other index files of a repo+branch
+// combination, and their directory once empty. Only the files indexing writes
+// are removed, and only from a directory directly under .mesh/{repo}/.
+// Missing metadata is not an error
+func DeleteMetadata(repoName, branch string) error {
+	if err := ValidateBranchName(branch); err != nil {
 		return err
 	}
+	dir := filepath.Dir(GetMetadataPath(repoName, branch))
+	if rel, err := filepath.Rel(filepath.Join(".mesh", repoName), dir); err != nil || rel == "." || rel != filepath.Base(dir) {
+		return fmt.Errorf("metadata directory %s is outside .mesh/%s", dir, repoName)
+	}
+
+	for _, path := range []string{
+		GetMetadataPath(repoName, branch),
+		GetCheckpointPath(repoName, branch),
+		GetRedactionReportPath(repoName, branch),
+		GetSymbolIndexPath(repoName, branch),
+		GetDependencyGraphPath(repoName, branch),
+		GetRepoSummaryPath(repoName, branch),
+	} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
 
-	return os.WriteFile(path, data, 0644)
+	// Left in place if anything else was put there
+	if err := os.Remove(dir); err != nil && !os.IsNotExist(err) {
+		if entries, _ := os.ReadDir(dir); len(entries) > 0 {
+			return nil
+		}
+		return err
+	}
+	return nil
 }
 
 // GetKnownBranches returns a list of branches that have been indexed
@@ -107,6 +231,7 @@ func GetKnownBranches(repoName string) ([]string, error) {
 // Returns true if:
 // - No metadata exists (never indexed)
 // - Current commit differs from last indexed commit
+// - Files failed to index and their retry is due (see FailedFile)
 func NeedsReindexing(repoPath, repoName, branch string) (bool, string, error) {
 	// Get current commit for this branch
 	currentCommit, err := GetBranchCommit(repoPath, branch)
@@ -125,6 +250,10 @@ func NeedsReindexing(repoPath, repoName, branch string) (bool, string, error) {
 		return true, currentCommit, nil
 	}
 
-	// Check if commit changed
-	return meta.CommitSHA != currentCommit, currentCommit, nil
+	// Check if commit changed or failed files are due for a retry
+	if meta.CommitSHA != currentCommit {
+		return true, currentCommit, nil
+	}
+	due, _ := meta.splitRetries(time.Now())
+	return len(due) > 0, currentCommit, nil
 }
@@ -3,6 +3,7 @@ package vectorstore
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -250,3 +251,134 @@ func contains(s, substr string) bool {
 	}
 	return false
 }
+
+func TestDeleteMetadata(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	for _, path := range []string{
+		GetMetadataPath("repo", "feature/x"),
+		GetSymbolIndexPath("repo", "feature/x"),
+		GetRepoSummaryPath("repo", "feature/x"),
+		GetMetadataPath("repo", "main"),
+		GetMetadataPath("other", "main"),
+	} {
+		if err := writeFileAtomic(path, []byte("{}")); err != nil {
+			t.Fatalf("writeFileAtomic failed: %v", err)
+		}
+	}
+
+	for _, branch := range []string{"..", ".", "", "../other"} {
+		if err := DeleteMetadata("repo", branch); err == nil {
+			t.Errorf("Expected DeleteMetadata to reject branch %q", branch)
+		}
+	}
+	if _, err := os.Stat(GetMetadataPath("other", "main")); err != nil {
+		t.Errorf("Expected other repos' metadata to survive: %v", err)
+	}
+
+	if err := DeleteMetadata("repo", "feature/x"); err != nil {
+		t.Fatalf("DeleteMetadata failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Dir(GetMetadataPath("repo", "feature/x"))); !os.IsNotExist(err) {
+		t.Errorf("Expected the branch directory removed, got %v", err)
+	}
+	if _, err := os.Stat(GetMetadataPath("repo", "main")); err != nil {
+		t.Errorf("Expected other branches' metadata to survive: %v", err)
+	}
+
+	// Files indexing didn't write are left alone, with their directory
+	unknown := filepath.Join(filepath.Dir(GetMetadataPath("repo", "main")), "notes.txt")
+	os.WriteFile(unknown, []byte("keep"), 0644)
+	if err := DeleteMetadata("repo", "main"); err != nil {
+		t.Fatalf("DeleteMetadata failed: %v", err)
+	}
+	if _, err := os.Stat(GetMetadataPath("repo", "main")); !os.IsNotExist(err) {
+		t.Errorf("Expected metadata.json removed, got %v", err)
+	}
+	if _, err := os.Stat(unknown); err != nil {
+		t.Errorf("Expected unknown file kept: %v", err)
+	}
+}
+
+func TestFailedFile_RetryDue(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name string
+		file FailedFile
+		want bool
+	}{
+		{"first failure during backoff", FailedFile{Attempts: 1, LastAttempt: now.Add(-30 * time.Second)}, false},
+		{"first failure after backoff", FailedFile{Attempts: 1, LastAttempt: now.Add(-failedRetryDelay)}, true},
+		{"backoff doubles per attempt", FailedFile{Attempts: 3, LastAttempt: now.Add(-3 * failedRetryDelay)}, false},
+		{"third failure after backoff", FailedFile{Attempts: 3, LastAttempt: now.Add(-4 * failedRetryDelay)}, true},
+		{"attempts exhausted", FailedFile{Attempts: maxFailedAttempts, LastAttempt: now.Add(-24 * time.Hour)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.file.retryDue(now); got != tt.want {
+				t.Errorf("retryDue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextFailures(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+	prev := &BranchMetadata{
+		CommitSHA:      "abc",
+		IndexedAt:      earlier,
+		FailedFiles:    []string{"a.go", "b.go", "legacy.go"},
+		FailedAttempts: map[string]FailedFile{"a.go": {Attempts: 2, LastAttempt: earlier}, "b.go": {Attempts: 4, LastAttempt: earlier}},
+	}
+
+	tests := []struct {
+		name    string
+		commit  string
+		failed  []string
+		waiting []string
+		want    map[string]FailedFile
+	}{
+		{
+			name:    "failing again at the same commit counts another attempt",
+			commit:  "abc",
+			failed:  []string{"a.go", "new.go"},
+			waiting: []string{"b.go"},
+			want: map[string]FailedFile{
+				"a.go":   {Attempts: 3, LastAttempt: now},
+				"new.go": {Attempts: 1, LastAttempt: now},
+				"b.go":   {Attempts: 4, LastAttempt: earlier},
+			},
+		},
+		{
+			name:   "metadata without attempts counts one",
+			commit: "abc",
+			failed: []string{"legacy.go"},
+			want:   map[string]FailedFile{"legacy.go": {Attempts: 2, LastAttempt: now}},
+		},
+		{
+			name:   "a new commit starts over",
+			commit: "def",
+			failed: []string{"b.go"},
+			want:   map[string]FailedFile{"b.go": {Attempts: 1, LastAttempt: now}},
+		},
+		{
+			name:   "no failures",
+			commit: "abc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nextFailures(prev, tt.commit, tt.failed, tt.waiting, now)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("nextFailures() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -38,6 +38,7 @@ func NewOpenAIEmbeddingProvider(apiKey, model string, logger zerolog.Logger) (*O
 
 	client := openai.NewClient(
 		option.WithAPIKey(apiKey),
+		option.WithMaxRetries(0), // Retries are handled by the resilience guard
 	)
 
 	logger.Info().
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/First008/mesh/internal/filetypes"
+	"github.com/First008/mesh/internal/tokenizer"
 	"github.com/qdrant/go-client/qdrant"
 	"github.com/rs/zerolog"
 )
@@ -18,7 +19,14 @@ type QdrantStore struct {
 	embeddingProvider EmbeddingProvider
 	collectionName    string
 	logger            zerolog.Logger
-	searchConfig      *SearchConfig // Configuration for smart file selection
+	searchConfig      *SearchConfig       // Configuration for smart file selection
+	tokenizer         tokenizer.Tokenizer // Counts result tokens against the search budget
+	generatedMode     GeneratedMode       // How non-source files rank ("" = downweight)
+
+	// Dependency graph expanding aggregated results (see SetDependencyGraph)
+	graphRepo   string
+	graphBranch string
+	graphHops   int
 }
 
 // NewQdrantStore creates a new Qdrant vector store with an embedding provider
@@ -30,6 +38,17 @@ func NewQdrantStore(qdrantURL string, embeddingProvider EmbeddingProvider, repoN
 // NewQdrantStoreWithBranch creates a new Qdrant vector store with branch support
 // Collection name format: mesh-{repo}-{branch}-v1
 func NewQdrantStoreWithBranch(qdrantURL string, embeddingProvider EmbeddingProvider, repoName, branch string, logger zerolog.Logger) (*QdrantStore, error) {
+	return newQdrantStore(qdrantURL, embeddingProvider, BranchCollectionName(repoName, branch), logger)
+}
+
+// NewWorkingTreeStore creates the overlay store holding uncommitted working-tree
+// changes for a repo+branch (see WorkingTree)
+func NewWorkingTreeStore(qdrantURL string, embeddingProvider EmbeddingProvider, repoName, branch string, logger zerolog.Logger) (*QdrantStore, error) {
+	return newQdrantStore(qdrantURL, embeddingProvider, WorkingTreeCollectionName(repoName, branch), logger)
+}
+
+// newQdrantStore connects to Qdrant and ensures the named collection exists
+func newQdrantStore(qdrantURL string, embeddingProvider EmbeddingProvider, collectionName string, logger zerolog.Logger) (*QdrantStore, error) {
 	if qdrantURL == "" {
 		return nil, fmt.Errorf("qdrant URL is required")
 	}
@@ -51,17 +70,13 @@ func NewQdrantStoreWithBranch(qdrantURL string, embeddingProvider EmbeddingProvi
 		return nil, fmt.Errorf("failed to create qdrant client: %w", err)
 	}
 
-	// Collection name: mesh-{repo-name}-{branch}-v1
-	// Sanitize branch name for collection (replace / with -)
-	safeBranch := SanitizeBranchName(branch)
-	collectionName := fmt.Sprintf("mesh-%s-%s-v1", repoName, safeBranch)
-
 	store := &QdrantStore{
 		client:            qdrantClient,
 		embeddingProvider: embeddingProvider,
 		collectionName:    collectionName,
 		logger:            logger,
 		searchConfig:      DefaultSearchConfig(), // Use default smart search config
+		tokenizer:         tokenizer.Default(),
 	}
 
 	// Ensure collection exists
@@ -77,6 +92,57 @@ func NewQdrantStoreWithBranch(qdrantURL string, embeddingProvider EmbeddingProvi
 	return store, nil
 }
 
+// BranchCollectionName returns the Qdrant collection name for a repo+branch
+// Format: mesh-{repo-name}-{branch}-v1 (branch sanitized, / replaced with -)
+func BranchCollectionName(repoName, branch string) string {
+	return fmt.Sprintf("mesh-%s-%s-v1", repoName, SanitizeBranchName(branch))
+}
+
+// WorkingTreeCollectionName returns the overlay collection name for a repo+branch
+// Format: mesh-{repo-name}-{branch}~worktree-v1. Branch names can't contain
+// "~", so the overlay never shares a name with a branch's own collection.
+func WorkingTreeCollectionName(repoName, branch string) string {
+	return fmt.Sprintf("mesh-%s-%s~worktree-v1", repoName, SanitizeBranchName(branch))
+}
+
+// DeleteBranchCollection drops the collection for a repo+branch without
+// needing an embedding provider. Missing collections are not an error.
+func DeleteBranchCollection(ctx context.Context, qdrantURL, repoName, branch string) error {
+	return deleteCollectionIfExists(ctx, qdrantURL, BranchCollectionName(repoName, branch))
+}
+
+// DeleteWorkingTreeCollection drops the working-tree overlay for a repo+branch.
+// Missing collections are not an error.
+func DeleteWorkingTreeCollection(ctx context.Context, qdrantURL, repoName, branch string) error {
+	return deleteCollectionIfExists(ctx, qdrantURL, WorkingTreeCollectionName(repoName, branch))
+}
+
+// deleteCollectionIfExists drops a collection by name using a short-lived client
+func deleteCollectionIfExists(ctx context.Context, qdrantURL, collectionName string) error {
+	host, port := parseQdrantURL(qdrantURL)
+	client, err := qdrant.NewClient(&qdrant.Config{
+		Host: host,
+		Port: port,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create qdrant client: %w", err)
+	}
+	defer client.Close()
+
+	exists, err := client.CollectionExists(ctx, collectionName)
+	if err != nil {
+		return fmt.Errorf("failed to check collection: %w", err)
+	}
+	if !exists {
+		return nil
+	}
+
+	if err := client.DeleteCollection(ctx, collectionName); err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	return nil
+}
+
 // ensureCollection creates the collection if it doesn't exist
 func (qs *QdrantStore) ensureCollection(ctx context.Context) error {
 	// Check if collection exists
@@ -87,6 +153,10 @@ func (qs *QdrantStore) ensureCollection(ctx context.Context) error {
 
 	if exists {
 		qs.logger.Debug().Str("collection", qs.collectionName).Msg("Collection already exists")
+		// Unfiltered search keeps working without the filter payload
+		if err := qs.ensureFilterPayload(ctx); err != nil {
+			qs.logger.Warn().Err(err).Str("collection", qs.collectionName).Msg("Search filters may miss older points")
+		}
 		return nil
 	}
 
@@ -117,16 +187,39 @@ func (qs *QdrantStore) ensureCollection(ctx context.Context) error {
 	}
 
 	qs.logger.Info().Str("collection", qs.collectionName).Msg("Collection created")
+	if err := qs.createFilterIndexes(ctx); err != nil {
+		qs.logger.Warn().Err(err).Str("collection", qs.collectionName).Msg("Search filters will scan payloads")
+	}
 	return nil
 }
 
 // IndexFile indexes a file by creating an embedding and storing it in Qdrant
 func (qs *QdrantStore) IndexFile(ctx context.Context, filePath, content string) error {
+	// Only the chunk is at hand; the indexer classifies whole files
+	return qs.IndexChunk(ctx, IndexJob{RelPath: filePath, Content: content})
+}
+
+// IndexChunk indexes a chunk with the class and language of its file,
+// deriving them from the chunk when unknown
+func (qs *QdrantStore) IndexChunk(ctx context.Context, chunk IndexJob) error {
+	filePath, content := chunk.RelPath, chunk.Content
+	class, language := chunk.Class, chunk.Language
+	if class == "" {
+		class = filetypes.Classify(extractBasePath(filePath), content)
+	}
+	if language == "" {
+		language = detectLanguage(extractBasePath(filePath))
+	}
+
 	// Create file hash for change detection
 	fileHash := computeHash(content)
 
-	// Create embedding
-	embedding, err := qs.createEmbedding(ctx, content)
+	// Embed the chunk with its context, store it raw
+	embedText := chunk.Embed
+	if embedText == "" {
+		embedText = content
+	}
+	embedding, err := qs.createEmbedding(ctx, embedText)
 	if err != nil {
 		return fmt.Errorf("failed to create embedding: %w", err)
 	}
@@ -137,6 +230,19 @@ func (qs *QdrantStore) IndexFile(ctx context.Context, filePath, content string)
 	// Extract base path (without #chunkN suffix) for deletion
 	basePath := strings.Split(filePath, "#")[0]
 
+	payload := map[string]any{
+		"file_path":   filePath,
+		"base_path":   basePath, // For deleting all chunks of a file
+		"content":     content,
+		"file_hash":   fileHash,
+		"language":    language,
+		"chunk_index": extractChunkIndex(filePath), // For ordering chunks during reconstruction
+		"file_class":  string(class),               // For ranking generated files below source
+	}
+	for key, value := range filterPayload(basePath, language) {
+		payload[key] = value
+	}
+
 	// Upsert to Qdrant
 	_, err = qs.client.Upsert(ctx, &qdrant.UpsertPoints{
 		CollectionName: qs.collectionName,
@@ -144,14 +250,7 @@ func (qs *QdrantStore) IndexFile(ctx context.Context, filePath, content string)
 			{
 				Id:      qdrant.NewIDNum(pointID),
 				Vectors: qdrant.NewVectors(embedding...),
-				Payload: qdrant.NewValueMap(map[string]any{
-					"file_path":   filePath,
-					"base_path":   basePath, // For deleting all chunks of a file
-					"content":     content,
-					"file_hash":   fileHash,
-					"language":    detectLanguage(filePath),
-					"chunk_index": extractChunkIndex(filePath), // For ordering chunks during reconstruction
-				}),
+				Payload: qdrant.NewValueMap(payload),
 			},
 		},
 	})
@@ -169,6 +268,12 @@ func (qs *QdrantStore) IndexFile(ctx context.Context, filePath, content string)
 
 // Search performs semantic search for relevant code
 func (qs *QdrantStore) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	return qs.SearchFiltered(ctx, query, limit, SearchFilter{})
+}
+
+// SearchFiltered performs semantic search over the files matching filter,
+// applied as a Qdrant payload filter so the limit counts only matching chunks
+func (qs *QdrantStore) SearchFiltered(ctx context.Context, query string, limit int, filter SearchFilter) ([]SearchResult, error) {
 	qs.logger.Info().
 		Str("query", truncate(query, 60)).
 		Int("limit", limit).
@@ -195,10 +300,17 @@ func (qs *QdrantStore) Search(ctx context.Context, query string, limit int) ([]S
 		Int("limit", limit).
 		Msg("Querying Qdrant")
 
+	// Down-weighted chunks make room for source chunks ranked below them
+	fetch := limit
+	if qs.downweights() {
+		fetch = 2 * limit
+	}
+
 	searchResult, err := qs.client.Query(ctx, &qdrant.QueryPoints{
 		CollectionName: qs.collectionName,
 		Query:          qdrant.NewQuery(embedding...),
-		Limit:          uintPtr(uint64(limit)),
+		Filter:         filter.qdrantFilter(),
+		Limit:          uintPtr(uint64(fetch)),
 		WithPayload:    qdrant.NewWithPayload(true),
 	})
 	if err != nil {
@@ -211,9 +323,15 @@ func (qs *QdrantStore) Search(ctx context.Context, query string, limit int) ([]S
 
 	// Convert to SearchResult
 	var results []SearchResult
+	var classes []filetypes.Class
 	for _, point := range searchResult {
 		payload := point.Payload
 
+		// Glob paths are only approximated by prefixes in Qdrant
+		if !filter.MatchesLanguage(getStringValue(payload, "file_path"), getStringValue(payload, "language")) {
+			continue
+		}
+
 		results = append(results, SearchResult{
 			FilePath: getStringValue(payload, "file_path"),
 			Content:  getStringValue(payload, "content"),
@@ -221,7 +339,9 @@ func (qs *QdrantStore) Search(ctx context.Context, query string, limit int) ([]S
 			Language: getStringValue(payload, "language"),
 			FileHash: getStringValue(payload, "file_hash"),
 		})
+		classes = append(classes, pointClass(payload))
 	}
+	results = qs.weightResults(results, classes, limit)
 
 	qs.logger.Info().
 		Int("result_count", len(results)).
@@ -292,13 +412,13 @@ func (qs *QdrantStore) buildFileCandidates(ctx context.Context, rawResults []Sea
 				BestChunkScore:  r.Score,
 				TopKChunkScores: []float32{r.Score},
 				ChunkCount:      1,
-				EstimatedTokens: estimateTokens(r.Content),
+				EstimatedTokens: qs.tokenizer.Count(r.Content),
 			}
 			fileMap[basePath] = candidate
 		} else {
 			// Update existing candidate
 			candidate.ChunkCount++
-			candidate.EstimatedTokens += estimateTokens(r.Content)
+			candidate.EstimatedTokens += qs.tokenizer.Count(r.Content)
 
 			if r.Score > candidate.BestChunkScore {
 				candidate.BestChunkScore = r.Score
@@ -640,6 +760,18 @@ func (qs *QdrantStore) reconstructFiles(ctx context.Context, selections []*FileS
 	return results
 }
 
+// SetSearchConfig replaces the smart file selection configuration, e.g. to fit
+// the token budget of the model that will consume the results
+func (qs *QdrantStore) SetSearchConfig(config *SearchConfig) {
+	qs.searchConfig = config
+}
+
+// SetTokenizer sets the tokenizer used to count results against the search
+// budget; it should match the model that will consume the results
+func (qs *QdrantStore) SetTokenizer(tok tokenizer.Tokenizer) {
+	qs.tokenizer = tok
+}
+
 // SearchWithAggregation searches and reconstructs complete files from chunks
 // Uses adaptive scoring, token budget management, and hybrid ranking for improved recall
 func (qs *QdrantStore) SearchWithAggregation(ctx context.Context, query string, maxFiles int) ([]SearchResult, error) {
@@ -713,7 +845,10 @@ func (qs *QdrantStore) SearchWithAggregation(ctx context.Context, query string,
 		return nil, nil
 	}
 
-	// 8. Reconstruct files (complete or partial)
+	// 8. Add files the selected ones depend on while budget remains
+	selections = qs.expandSelections(ctx, selections, config)
+
+	// 9. Reconstruct files (complete or partial)
 	results := qs.reconstructFiles(ctx, selections)
 
 	// Log final results
@@ -764,6 +899,33 @@ func (qs *QdrantStore) DeleteFile(ctx context.Context, filePath string) error {
 	return nil
 }
 
+// ListIndexedChunks scrolls the whole collection and returns chunk path -> file_hash
+func (qs *QdrantStore) ListIndexedChunks(ctx context.Context) (map[string]string, error) {
+	hashes := make(map[string]string)
+
+	var offset *qdrant.PointId
+	for {
+		points, next, err := qs.client.ScrollAndOffset(ctx, &qdrant.ScrollPoints{
+			CollectionName: qs.collectionName,
+			Offset:         offset,
+			WithPayload:    qdrant.NewWithPayloadInclude("file_path", "file_hash"),
+			Limit:          uint32Ptr(1000),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("qdrant scroll failed: %w", err)
+		}
+
+		for _, point := range points {
+			hashes[getStringValue(point.Payload, "file_path")] = getStringValue(point.Payload, "file_hash")
+		}
+
+		if next == nil {
+			return hashes, nil
+		}
+		offset = next
+	}
+}
+
 // DeleteCollection removes the entire collection
 func (qs *QdrantStore) DeleteCollection(ctx context.Context) error {
 	err := qs.client.DeleteCollection(ctx, qs.collectionName)
@@ -876,6 +1038,10 @@ func uint32Ptr(u uint32) *uint32 {
 	return &u
 }
 
+func boolPtr(b bool) *bool {
+	return &b
+}
+
 func parseQdrantURL(url string) (host string, port int) {
 	// Default port for Qdrant gRPC
 	port = 6334
@@ -0,0 +1,163 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/First008/mesh/internal/secrets"
+)
+
+// RedactionReport is the audit trail of secrets found in a branch: which
+// files had content redacted (or were skipped) and by which rules
+type RedactionReport struct {
+	RepoName  string                       `json:"repo_name"`
+	Branch    string                       `json:"branch"`
+	Mode      secrets.Mode                 `json:"mode"`
+	UpdatedAt time.Time                    `json:"updated_at"`
+	Files     map[string][]secrets.Finding `json:"files"` // File path -> findings
+}
+
+// GetRedactionReportPath returns path to the redaction report for repo+branch
+// Example: .mesh/my-repo/main/redactions.json
+func GetRedactionReportPath(repoName, branch string) string {
+	return filepath.Join(filepath.Dir(GetMetadataPath(repoName, branch)), "redactions.json")
+}
+
+// LoadRedactionReport loads the redaction report for a repo+branch
+// Returns nil if the branch has not been indexed with scanning
+func LoadRedactionReport(repoName, branch string) (*RedactionReport, error) {
+	data, err := os.ReadFile(GetRedactionReportPath(repoName, branch))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var report RedactionReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+	if report.Files == nil {
+		report.Files = make(map[string][]secrets.Finding)
+	}
+	return &report, nil
+}
+
+// SaveRedactionReport atomically writes the redaction report for a repo+branch
+func SaveRedactionReport(report *RedactionReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(GetRedactionReportPath(report.RepoName, report.Branch), data)
+}
+
+// runRedactions collects the findings of a run into the branch's report
+// (thread-safe)
+type runRedactions struct {
+	report *RedactionReport
+	mu     sync.Mutex
+}
+
+// SetSecretMode sets how files containing secrets are handled: redacted
+// (the default), skipped, or indexed as-is
+func (idx *Indexer) SetSecretMode(mode secrets.Mode) {
+	idx.secretMode = mode
+}
+
+// scrub applies the secret mode to a file's content. Returns false if the
+// file must not be indexed.
+func (idx *Indexer) scrub(relPath, content string) (string, bool) {
+	var findings []secrets.Finding
+	switch idx.secretMode {
+	case secrets.ModeOff:
+		return content, true
+	case secrets.ModeSkip:
+		findings = secrets.Scan(content)
+	default:
+		content, findings = secrets.Redact(content)
+	}
+
+	idx.redactions.record(relPath, findings)
+	if len(findings) > 0 {
+		idx.logger.Debug().
+			Str("path", relPath).
+			Int("secrets", len(findings)).
+			Str("mode", string(idx.secretMode)).
+			Msg("Secrets found in file")
+	}
+	return content, idx.secretMode != secrets.ModeSkip || len(findings) == 0
+}
+
+// startRedactions loads the branch's redaction report for a run to update
+func (idx *Indexer) startRedactions() {
+	report, err := LoadRedactionReport(idx.repoName, idx.branch)
+	if err != nil {
+		idx.logger.Warn().Err(err).Msg("Ignoring unreadable redaction report")
+	}
+	if report == nil {
+		report = &RedactionReport{
+			RepoName: idx.repoName,
+			Branch:   idx.branch,
+			Files:    make(map[string][]secrets.Finding),
+		}
+	}
+	report.Mode = idx.secretMode
+	idx.redactions = &runRedactions{report: report}
+}
+
+// saveRedactions writes the run's redaction report; failures only cost the
+// audit trail until the file is indexed again, so they are logged
+func (idx *Indexer) saveRedactions() {
+	if idx.redactions == nil {
+		return
+	}
+	idx.redactions.mu.Lock()
+	defer idx.redactions.mu.Unlock()
+
+	idx.redactions.report.UpdatedAt = time.Now()
+	if err := SaveRedactionReport(idx.redactions.report); err != nil {
+		idx.logger.Warn().Err(err).Msg("Failed to write redaction report")
+	}
+}
+
+// record replaces the findings of a file; no findings clears its entry
+func (r *runRedactions) record(relPath string, findings []secrets.Finding) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(findings) == 0 {
+		delete(r.report.Files, relPath)
+		return
+	}
+	r.report.Files[relPath] = findings
+}
+
+// reset drops all findings, before a run that scans every file
+func (r *runRedactions) reset() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report.Files = make(map[string][]secrets.Finding)
+}
+
+// deleteFile removes a file from the store, the redaction report, the symbol
+// index and the repository summary
+func (idx *Indexer) deleteFile(ctx context.Context, relPath string) error {
+	if err := idx.store.DeleteFile(ctx, relPath); err != nil {
+		return err
+	}
+	idx.redactions.record(relPath, nil)
+	idx.symbols.record(relPath, nil)
+	idx.summary.record(relPath, "")
+	return nil
+}
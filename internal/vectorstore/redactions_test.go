@@ -0,0 +1,75 @@
+package vectorstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/First008/mesh/internal/secrets"
+)
+
+func TestIndexIncremental_ScrubsSecrets(t *testing.T) {
+	src, _ := initBareRepo(t)
+
+	// Assembled at runtime so the repository doesn't trip secret scanners
+	key := "AKIA" + "IOSFODNN7EXAMPLE"
+	gitCmd(t, src, "checkout", "-q", "feature")
+	os.WriteFile(filepath.Join(src, "config.go"), []byte("package config\n\nconst key = \""+key+"\"\n"), 0644)
+	gitCmd(t, src, "add", "config.go")
+	gitCmd(t, src, "commit", "-m", "add config")
+
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	store := newMockStore()
+	index := func(mode secrets.Mode) *RedactionReport {
+		t.Helper()
+		indexer := NewIndexerWithBranch(store, src, "repo", "feature", testLogger())
+		indexer.SetSecretMode(mode)
+		if err := indexer.IndexIncremental(context.Background()); err != nil {
+			t.Fatalf("IndexIncremental failed: %v", err)
+		}
+		report, err := LoadRedactionReport("repo", "feature")
+		if err != nil || report == nil {
+			t.Fatalf("Expected a redaction report, got %v, %v", report, err)
+		}
+		return report
+	}
+
+	// Redacted by default, with the finding in the report
+	report := index(secrets.ModeRedact)
+	content, ok := store.indexed["config.go"]
+	if !ok || strings.Contains(content, key) || !strings.Contains(content, "[REDACTED:aws-access-key]") {
+		t.Errorf("Expected config.go to be indexed with the key redacted, got %q", content)
+	}
+	findings := report.Files["config.go"]
+	if len(findings) != 1 || findings[0].Rule != "aws-access-key" || findings[0].Line != 3 {
+		t.Errorf("Expected one aws-access-key finding on line 3, got %+v", findings)
+	}
+	if len(report.Files) != 1 {
+		t.Errorf("Expected only config.go in the report, got %v", report.Files)
+	}
+
+	// Switching to skip reconciles and drops the file from the index
+	report = index(secrets.ModeSkip)
+	if _, ok := store.indexed["config.go"]; ok {
+		t.Error("Expected config.go to be dropped in skip mode")
+	}
+	if _, ok := store.indexed["a.go"]; !ok {
+		t.Error("Expected a.go to stay indexed")
+	}
+	if report.Mode != secrets.ModeSkip || len(report.Files["config.go"]) != 1 {
+		t.Errorf("Expected skipped config.go in the report, got %+v", report)
+	}
+
+	// Deleting the file clears its entry
+	gitCmd(t, src, "rm", "-q", "config.go")
+	gitCmd(t, src, "commit", "-m", "remove config")
+	if report := index(secrets.ModeSkip); len(report.Files) != 0 {
+		t.Errorf("Expected an empty report after deletion, got %v", report.Files)
+	}
+}
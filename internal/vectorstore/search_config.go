@@ -18,6 +18,7 @@ type SearchConfig struct {
 	// Search limits
 	InitialChunkLimit int // Initial search limit (default: 50)
 	MaxFilesLimit     int // Maximum files to return (default: 15)
+	MaxRelatedFiles   int // Dependencies of selected files added on top (default: 5)
 
 	// Hybrid scoring weights (dynamically adjusted)
 	// These weights should sum to 1.0 for proper normalization
@@ -45,6 +46,7 @@ func DefaultSearchConfig() *SearchConfig {
 		// Search parameters: Moderate tuning
 		InitialChunkLimit: 50, // Keep at 50 - proven to work
 		MaxFilesLimit:     10, // Down from 15 for faster processing
+		MaxRelatedFiles:   5,  // Dependency graph expansion, budget permitting
 
 		// Hybrid weights: semantic-heavy to avoid false keyword boosts
 		SemanticWeight:  0.70, // Primary signal: vector similarity
@@ -54,6 +56,18 @@ func DefaultSearchConfig() *SearchConfig {
 	}
 }
 
+// SearchConfigForInputTokens returns the default configuration with its token
+// budget shrunk to fit a model's input window, keeping the reserve proportional.
+// Windows larger than the default budget keep the default for response speed.
+func SearchConfigForInputTokens(inputTokens int) *SearchConfig {
+	config := DefaultSearchConfig()
+	if inputTokens > 0 && inputTokens < config.MaxTokenBudget {
+		config.ReserveTokens = inputTokens * config.ReserveTokens / config.MaxTokenBudget
+		config.MaxTokenBudget = inputTokens
+	}
+	return config
+}
+
 // Validate checks if the configuration is valid and returns an error if not.
 func (c *SearchConfig) Validate() error {
 	if c.MaxTokenBudget <= c.ReserveTokens {
@@ -68,6 +82,9 @@ func (c *SearchConfig) Validate() error {
 	if c.MinFilesAfterThreshold < 1 {
 		return fmt.Errorf("invalid config: MinFilesAfterThreshold must be at least 1")
 	}
+	if c.MaxRelatedFiles < 0 {
+		return fmt.Errorf("invalid config: MaxRelatedFiles must not be negative")
+	}
 	if c.InitialChunkLimit < c.MaxFilesLimit {
 		return fmt.Errorf("invalid config: InitialChunkLimit should be at least MaxFilesLimit")
 	}
@@ -108,3 +108,23 @@ func TestEffectiveTokenBudget(t *testing.T) {
 		t.Errorf("Expected EffectiveTokenBudget=%d, got %d", expected, config.EffectiveTokenBudget())
 	}
 }
+
+func TestSearchConfigForInputTokens(t *testing.T) {
+	defaults := DefaultSearchConfig()
+
+	large := SearchConfigForInputTokens(136000)
+	if large.MaxTokenBudget != defaults.MaxTokenBudget {
+		t.Errorf("Expected large window to keep default budget %d, got %d", defaults.MaxTokenBudget, large.MaxTokenBudget)
+	}
+
+	small := SearchConfigForInputTokens(24000)
+	if small.MaxTokenBudget != 24000 {
+		t.Errorf("Expected budget 24000, got %d", small.MaxTokenBudget)
+	}
+	if small.ReserveTokens != 10000 {
+		t.Errorf("Expected proportional reserve 10000, got %d", small.ReserveTokens)
+	}
+	if err := small.Validate(); err != nil {
+		t.Errorf("Expected valid config, got %v", err)
+	}
+}
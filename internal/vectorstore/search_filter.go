@@ -0,0 +1,269 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/First008/mesh/internal/filetypes"
+	"github.com/First008/mesh/internal/ignore"
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// SearchFilter restricts a search to matching files before results are ranked,
+// so excluded files don't use up the result limit
+type SearchFilter struct {
+	// Paths are patterns in focus_paths syntax (.gitignore syntax anchored to
+	// the repository root); a file must match one of them
+	Paths []string
+
+	// Languages a file must be written in, as reported by filetypes.GetLanguage
+	Languages []string
+
+	// ExcludeTests drops test files (see filetypes.IsTestFile)
+	ExcludeTests bool
+
+	// DocTypes a file must have, as reported by filetypes.GetDocType: "code"
+	// for code only, or kinds of documentation such as "readme" and "adr"
+	DocTypes []string
+}
+
+// FilteredSearcher is implemented by stores that can apply a SearchFilter
+// inside the vector search
+type FilteredSearcher interface {
+	SearchFiltered(ctx context.Context, query string, limit int, filter SearchFilter) ([]SearchResult, error)
+}
+
+// SearchWithFilter searches store for files matching filter, inside the
+// vector search when the store supports it and by dropping results otherwise
+func SearchWithFilter(ctx context.Context, store VectorStore, query string, limit int, filter SearchFilter) ([]SearchResult, error) {
+	if filter.Empty() {
+		return store.Search(ctx, query, limit)
+	}
+	if fs, ok := store.(FilteredSearcher); ok {
+		return fs.SearchFiltered(ctx, query, limit, filter)
+	}
+
+	results, err := store.Search(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	matching := results[:0]
+	for _, r := range results {
+		if filter.Matches(r.FilePath) {
+			matching = append(matching, r)
+		}
+	}
+	return matching, nil
+}
+
+// Empty reports whether the filter matches every file
+func (f SearchFilter) Empty() bool {
+	return len(f.Paths) == 0 && len(f.Languages) == 0 && !f.ExcludeTests && len(f.DocTypes) == 0
+}
+
+// Matches reports whether a file passes the filter. Stores that can only
+// approximate the filter (glob paths become prefixes) are checked with it.
+func (f SearchFilter) Matches(filePath string) bool {
+	return f.MatchesLanguage(filePath, detectLanguage(extractBasePath(filePath)))
+}
+
+// MatchesLanguage is Matches for a file whose language is known, e.g. from a
+// repository's own file types
+func (f SearchFilter) MatchesLanguage(filePath, language string) bool {
+	filePath = extractBasePath(filePath)
+
+	if len(f.Paths) > 0 {
+		paths := ignore.New()
+		paths.AddPatterns("", anchorFocusPaths(f.Paths))
+		if !paths.Match(filePath) {
+			return false
+		}
+	}
+	if len(f.Languages) > 0 && !containsFold(f.Languages, language) {
+		return false
+	}
+	if f.ExcludeTests && filetypes.IsTestFile(filePath) {
+		return false
+	}
+	if len(f.DocTypes) > 0 && !containsFold(f.DocTypes, string(filetypes.GetDocType(filePath, language))) {
+		return false
+	}
+	return true
+}
+
+// qdrantFilter translates the filter into Qdrant payload conditions, or nil
+// when it matches everything. Path patterns are reduced to their literal
+// leading directories; callers check results with Matches for the rest.
+func (f SearchFilter) qdrantFilter() *qdrant.Filter {
+	filter := &qdrant.Filter{}
+
+	if prefixes := pathPrefixes(f.Paths); len(prefixes) > 0 {
+		var should []*qdrant.Condition
+		for _, prefix := range prefixes {
+			should = append(should,
+				qdrant.NewMatchKeyword("dirs", prefix),      // Files below a directory
+				qdrant.NewMatchKeyword("base_path", prefix), // Or the file itself
+			)
+		}
+		filter.Must = append(filter.Must, qdrant.NewFilterAsCondition(&qdrant.Filter{Should: should}))
+	}
+
+	if len(f.Languages) > 0 {
+		languages := make([]string, len(f.Languages))
+		for i, language := range f.Languages {
+			languages[i] = strings.ToLower(language)
+		}
+		filter.Must = append(filter.Must, qdrant.NewMatchKeywords("language", languages...))
+	}
+
+	if f.ExcludeTests {
+		filter.MustNot = append(filter.MustNot, qdrant.NewMatchBool("is_test", true))
+	}
+
+	if len(f.DocTypes) > 0 {
+		docTypes := make([]string, len(f.DocTypes))
+		for i, docType := range f.DocTypes {
+			docTypes[i] = strings.ToLower(docType)
+		}
+		filter.Must = append(filter.Must, qdrant.NewMatchKeywords("doc_type", docTypes...))
+	}
+
+	if len(filter.Must) == 0 && len(filter.MustNot) == 0 {
+		return nil
+	}
+	return filter
+}
+
+// pathPrefixes returns the literal leading directories of path patterns, e.g.
+// "internal/api" for "internal/api/**". Returns nil if any pattern starts with
+// a wildcard, since then no prefix covers every match.
+func pathPrefixes(patterns []string) []string {
+	var prefixes []string
+	for _, pattern := range patterns {
+		var literal []string
+		for _, segment := range strings.Split(strings.Trim(pattern, "/"), "/") {
+			if segment == "" || strings.ContainsAny(segment, `*?[\`) {
+				break
+			}
+			literal = append(literal, segment)
+		}
+		if len(literal) == 0 {
+			return nil
+		}
+		prefixes = append(prefixes, strings.Join(literal, "/"))
+	}
+	return prefixes
+}
+
+// filterPayload returns the payload fields search filters match on
+func filterPayload(basePath, language string) map[string]any {
+	dirs := []any{}
+	for dir := path.Dir(basePath); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		dirs = append(dirs, dir)
+	}
+	return map[string]any{
+		"dirs":     dirs, // Every parent directory, for prefix filters
+		"is_test":  filetypes.IsTestFile(basePath),
+		"doc_type": string(filetypes.GetDocType(basePath, language)),
+	}
+}
+
+// filterIndexes are the payload fields filtered on, with their index types
+var filterIndexes = map[string]qdrant.FieldType{
+	"dirs":     qdrant.FieldType_FieldTypeKeyword,
+	"language": qdrant.FieldType_FieldTypeKeyword,
+	"is_test":  qdrant.FieldType_FieldTypeBool,
+	"doc_type": qdrant.FieldType_FieldTypeKeyword,
+}
+
+// createFilterIndexes indexes the payload fields search filters use
+func (qs *QdrantStore) createFilterIndexes(ctx context.Context) error {
+	for field, fieldType := range filterIndexes {
+		_, err := qs.client.CreateFieldIndex(ctx, &qdrant.CreateFieldIndexCollection{
+			CollectionName: qs.collectionName,
+			FieldName:      field,
+			FieldType:      fieldType.Enum(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to index payload field %s: %w", field, err)
+		}
+	}
+	return nil
+}
+
+// ensureFilterPayload prepares a collection created before search filters:
+// the payload indexes are created and chunks missing the filter fields get
+// them, derived from their path and language, without re-embedding
+func (qs *QdrantStore) ensureFilterPayload(ctx context.Context) error {
+	missing := &qdrant.Filter{Should: []*qdrant.Condition{
+		qdrant.NewIsEmpty("is_test"),
+		qdrant.NewIsEmpty("doc_type"), // Added after the other fields
+	}}
+	count, err := qs.client.Count(ctx, &qdrant.CountPoints{
+		CollectionName: qs.collectionName,
+		Filter:         missing,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to count points without filter payload: %w", err)
+	}
+	if count == 0 {
+		return nil
+	}
+
+	qs.logger.Info().
+		Str("collection", qs.collectionName).
+		Uint64("points", count).
+		Msg("Adding search filter payload to existing points")
+
+	if err := qs.createFilterIndexes(ctx); err != nil {
+		return err
+	}
+
+	// Updated points stop matching the filter, so each scroll starts over
+	for {
+		points, err := qs.client.Scroll(ctx, &qdrant.ScrollPoints{
+			CollectionName: qs.collectionName,
+			Filter:         missing,
+			WithPayload:    qdrant.NewWithPayloadInclude("base_path", "language"),
+			Limit:          uint32Ptr(256),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to scroll points without filter payload: %w", err)
+		}
+		if len(points) == 0 {
+			return nil
+		}
+
+		// Chunks of a file share its payload
+		byFile := make(map[string][]*qdrant.PointId)
+		languages := make(map[string]string)
+		for _, point := range points {
+			basePath := getStringValue(point.Payload, "base_path")
+			byFile[basePath] = append(byFile[basePath], point.Id)
+			languages[basePath] = getStringValue(point.Payload, "language")
+		}
+		for basePath, ids := range byFile {
+			_, err := qs.client.SetPayload(ctx, &qdrant.SetPayloadPoints{
+				CollectionName: qs.collectionName,
+				Wait:           boolPtr(true),
+				Payload:        qdrant.NewValueMap(filterPayload(basePath, languages[basePath])),
+				PointsSelector: qdrant.NewPointsSelector(ids...),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to set filter payload for %s: %w", basePath, err)
+			}
+		}
+	}
+}
+
+// containsFold reports whether values contains s, ignoring case
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
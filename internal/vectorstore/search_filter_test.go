@@ -0,0 +1,131 @@
+package vectorstore
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSearchFilter_Matches(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter SearchFilter
+		path   string
+		want   bool
+	}{
+		{"empty filter", SearchFilter{}, "any/file.go", true},
+		{"under path", SearchFilter{Paths: []string{"internal/api/**"}}, "internal/api/h.go", true},
+		{"outside path", SearchFilter{Paths: []string{"internal/api/**"}}, "internal/db/q.go", false},
+		{"path is anchored", SearchFilter{Paths: []string{"api"}}, "lib/api/h.go", false},
+		{"glob path", SearchFilter{Paths: []string{"internal/*/handler.go"}}, "internal/api/handler.go", true},
+		{"chunk suffix", SearchFilter{Paths: []string{"internal/"}}, "internal/big.go#chunk3", true},
+		{"language", SearchFilter{Languages: []string{"Go"}}, "main.go", true},
+		{"other language", SearchFilter{Languages: []string{"go"}}, "app.ts", false},
+		{"go test", SearchFilter{ExcludeTests: true}, "pkg/a_test.go", false},
+		{"ts spec", SearchFilter{ExcludeTests: true}, "web/app.spec.ts", false},
+		{"python test", SearchFilter{ExcludeTests: true}, "tests/helpers.py", false},
+		{"java test", SearchFilter{ExcludeTests: true}, "src/UserServiceTest.java", false},
+		{"not a test", SearchFilter{ExcludeTests: true}, "pkg/testing.go", true},
+		{"code only", SearchFilter{DocTypes: []string{"code"}}, "main.go", true},
+		{"code only drops docs", SearchFilter{DocTypes: []string{"code"}}, "docs/setup.md", false},
+		{"doc type", SearchFilter{DocTypes: []string{"ADR"}}, "docs/adr/0001-use-qdrant.md#chunk2", true},
+		{"other doc type", SearchFilter{DocTypes: []string{"adr", "design"}}, "README.md", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(tt.path); got != tt.want {
+				t.Errorf("Matches(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathPrefixes(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		want     []string
+	}{
+		{[]string{"internal/api/**"}, []string{"internal/api"}},
+		{[]string{"/cmd/", "pkg/*.go"}, []string{"cmd", "pkg"}},
+		{[]string{"main.go"}, []string{"main.go"}},
+		{[]string{"internal/**", "**/handlers"}, nil}, // No prefix covers every match
+		{nil, nil},
+	}
+
+	for _, tt := range tests {
+		got := pathPrefixes(tt.patterns)
+		if len(got) != len(tt.want) {
+			t.Errorf("pathPrefixes(%v) = %v, want %v", tt.patterns, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("pathPrefixes(%v) = %v, want %v", tt.patterns, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestSearchFilter_QdrantFilter(t *testing.T) {
+	if f := (SearchFilter{}).qdrantFilter(); f != nil {
+		t.Errorf("Expected no Qdrant filter for an empty filter, got %v", f)
+	}
+
+	f := SearchFilter{Paths: []string{"internal/**"}, Languages: []string{"Go"}, ExcludeTests: true}.qdrantFilter()
+	if f == nil {
+		t.Fatal("Expected a Qdrant filter")
+	}
+	if len(f.Must) != 2 || len(f.MustNot) != 1 {
+		t.Fatalf("Expected 2 must and 1 must-not conditions, got %v", f)
+	}
+	if keywords := f.Must[1].GetField().GetMatch().GetKeywords().GetStrings(); len(keywords) != 1 || keywords[0] != "go" {
+		t.Errorf("Expected lower-cased language keyword, got %v", keywords)
+	}
+
+	f = SearchFilter{DocTypes: []string{"ADR"}}.qdrantFilter()
+	if keywords := f.GetMust()[0].GetField().GetMatch().GetKeywords().GetStrings(); len(keywords) != 1 || keywords[0] != "adr" {
+		t.Errorf("Expected lower-cased doc type keyword, got %v", keywords)
+	}
+
+	// Wildcard-only paths can't be pushed down
+	if f := (SearchFilter{Paths: []string{"**/api/**"}}).qdrantFilter(); f != nil {
+		t.Errorf("Expected wildcard paths to be checked after the search, got %v", f)
+	}
+}
+
+func TestFilterPayload(t *testing.T) {
+	payload := filterPayload("internal/api/handler_test.go", "go")
+	dirs, _ := payload["dirs"].([]any)
+	if len(dirs) != 2 || dirs[0] != "internal/api" || dirs[1] != "internal" {
+		t.Errorf("Expected parent directories, got %v", dirs)
+	}
+	if payload["is_test"] != true {
+		t.Error("Expected test file to be flagged")
+	}
+	if payload["doc_type"] != "code" {
+		t.Errorf("Expected doc_type code, got %v", payload["doc_type"])
+	}
+	if dirs, _ := filterPayload("main.go", "go")["dirs"].([]any); len(dirs) != 0 {
+		t.Errorf("Expected no directories for a root file, got %v", dirs)
+	}
+	if docType := filterPayload("docs/decisions/0002.md", "markdown")["doc_type"]; docType != "adr" {
+		t.Errorf("Expected doc_type adr, got %v", docType)
+	}
+}
+
+func TestSearchWithFilter_WithoutStoreSupport(t *testing.T) {
+	store := &resultStore{mockStore: newMockStore(), results: []SearchResult{
+		{FilePath: "api/handler.go", Score: 0.9},
+		{FilePath: "api/handler_test.go", Score: 0.8},
+		{FilePath: "db/query.go", Score: 0.7},
+	}}
+
+	results, err := SearchWithFilter(context.Background(), store, "q", 10, SearchFilter{Paths: []string{"api/"}, ExcludeTests: true})
+	if err != nil {
+		t.Fatalf("SearchWithFilter failed: %v", err)
+	}
+	if len(results) != 1 || results[0].FilePath != "api/handler.go" {
+		t.Errorf("Expected only api/handler.go, got %v", results)
+	}
+}
@@ -0,0 +1,72 @@
+package vectorstore
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/First008/mesh/internal/filetypes"
+)
+
+// fileSource provides repository files to an indexing run
+type fileSource interface {
+	// ListFiles returns all candidate file paths relative to the repository root,
+	// excluding directories that indexing always skips
+	ListFiles() ([]string, error)
+
+	// ReadFile returns a file's content; missing files satisfy os.IsNotExist
+	ReadFile(relPath string) ([]byte, error)
+
+	// Close releases resources held by the source
+	Close() error
+}
+
+// openSource reads files from git objects at commit, so a branch collection
+// reflects that branch's content regardless of what is checked out
+func (idx *Indexer) openSource(commit string) (fileSource, error) {
+	blobs, err := NewBlobReader(idx.repoPath)
+	if err != nil {
+		return nil, err
+	}
+	return &commitSource{repoPath: idx.repoPath, commit: commit, blobs: blobs}, nil
+}
+
+// commitSource reads files from the tree of a commit via git objects
+type commitSource struct {
+	repoPath string
+	commit   string
+	blobs    *BlobReader
+}
+
+func (s *commitSource) ListFiles() ([]string, error) {
+	all, err := ListFilesAtCommit(s.repoPath, s.commit)
+	if err != nil {
+		return nil, err
+	}
+
+	files := all[:0]
+	for _, relPath := range all {
+		if !inSkippedDirectory(relPath) {
+			files = append(files, relPath)
+		}
+	}
+	return files, nil
+}
+
+func (s *commitSource) ReadFile(relPath string) ([]byte, error) {
+	return s.blobs.ReadFile(s.commit, relPath)
+}
+
+func (s *commitSource) Close() error {
+	return s.blobs.Close()
+}
+
+// inSkippedDirectory reports whether any parent directory of relPath is skipped
+func inSkippedDirectory(relPath string) bool {
+	dirs := strings.Split(filepath.ToSlash(relPath), "/")
+	for _, dir := range dirs[:len(dirs)-1] {
+		if filetypes.ShouldSkipDirectory(dir) {
+			return true
+		}
+	}
+	return false
+}
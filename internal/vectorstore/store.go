@@ -21,6 +21,10 @@ type VectorStore interface {
 	// DeleteFile removes a file from the index
 	DeleteFile(ctx context.Context, filePath string) error
 
+	// ListIndexedChunks returns the content hash of every indexed chunk, keyed by
+	// chunk path ("file.go" or "file.go#chunkN")
+	ListIndexedChunks(ctx context.Context) (map[string]string, error)
+
 	// DeleteCollection removes an entire collection (repository)
 	DeleteCollection(ctx context.Context) error
 
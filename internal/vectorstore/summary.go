@@ -0,0 +1,426 @@
+package vectorstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/First008/mesh/internal/symbols"
+)
+
+const (
+	maxPackageSummariesPerRun = 40  // LLM calls per run; later runs summarize the rest
+	maxDigestDeclarations     = 60  // Declarations shown to the LLM per package
+	maxDigestImports          = 20  // Imports shown to the LLM per package
+	maxDigestFiles            = 50  // File names shown to the LLM per package
+	maxEntryPoints            = 20  // Entry points kept per branch
+	maxSummaryChars           = 600 // Longer summaries are cut
+)
+
+// entryPointFiles are file names programs conventionally start from; Go
+// entry points are found by their main function instead
+var entryPointFiles = map[string]bool{
+	"__main__.py": true, "main.py": true, "manage.py": true, "app.py": true, "wsgi.py": true, "asgi.py": true,
+	"main.rs": true, "main.ts": true, "main.js": true, "index.ts": true, "index.js": true,
+	"server.ts": true, "server.js": true, "server.py": true,
+	"Program.cs": true, "Main.java": true, "Application.java": true, "Main.kt": true, "Application.kt": true,
+	"main.c": true, "main.cpp": true, "main.swift": true, "config.ru": true,
+}
+
+// maxEntryPointDepth is how deep conventional entry files are looked for
+// ("src/bin/main.rs")
+const maxEntryPointDepth = 3
+
+// Summarizer writes the prose of a repository summary, e.g. with an LLM
+type Summarizer interface {
+	Summarize(ctx context.Context, prompt string) (string, error)
+}
+
+// RepoSummary orients an LLM in a branch: its indexed files, where programs
+// start, and what each package (directory of indexed files) does
+type RepoSummary struct {
+	RepoName    string                     `json:"repo_name"`
+	Branch      string                     `json:"branch"`
+	UpdatedAt   time.Time                  `json:"updated_at"`
+	Files       map[string]string          `json:"files"`                  // File path -> content hash
+	Packages    map[string]*PackageSummary `json:"packages,omitempty"`     // Directory -> summary
+	EntryPoints []string                   `json:"entry_points,omitempty"` // Files programs start from
+}
+
+// PackageSummary is the written summary of a directory's files, current while
+// the hash of their content is unchanged
+type PackageSummary struct {
+	Hash    string `json:"hash"`
+	Summary string `json:"summary"`
+}
+
+// GetRepoSummaryPath returns path to the repository summary for repo+branch
+// Example: .mesh/my-repo/main/summary.json
+func GetRepoSummaryPath(repoName, branch string) string {
+	return filepath.Join(filepath.Dir(GetMetadataPath(repoName, branch)), "summary.json")
+}
+
+// LoadRepoSummary loads the repository summary for a repo+branch
+// Returns nil if the branch has not been summarized
+func LoadRepoSummary(repoName, branch string) (*RepoSummary, error) {
+	data, err := os.ReadFile(GetRepoSummaryPath(repoName, branch))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var summary RepoSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, err
+	}
+	if summary.Files == nil {
+		summary.Files = make(map[string]string)
+	}
+	if summary.Packages == nil {
+		summary.Packages = make(map[string]*PackageSummary)
+	}
+	return &summary, nil
+}
+
+// SaveRepoSummary atomically writes the repository summary for a repo+branch
+func SaveRepoSummary(summary *RepoSummary) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(GetRepoSummaryPath(summary.RepoName, summary.Branch), data)
+}
+
+// CachedRepoSummary returns a repo+branch's summary, cached until the next
+// indexing run rewrites it; callers must not modify it
+// Returns nil if the branch has not been summarized
+func CachedRepoSummary(repoName, branch string) (*RepoSummary, error) {
+	value, err := artifacts.get(GetRepoSummaryPath(repoName, branch), func() (interface{}, error) {
+		summary, err := LoadRepoSummary(repoName, branch)
+		if err != nil || summary == nil {
+			return nil, err
+		}
+		return summary, nil
+	})
+	if value == nil {
+		return nil, err
+	}
+	return value.(*RepoSummary), nil
+}
+
+// SetSummarizer sets what writes package summaries at the end of each run
+// (nil = the summary has the tree and entry points only)
+func (idx *Indexer) SetSummarizer(summarizer Summarizer) {
+	idx.summarizer = summarizer
+}
+
+// runSummary collects the files of a run into the branch's summary
+// (thread-safe)
+type runSummary struct {
+	summary *RepoSummary
+	missing bool // No summary was saved before: every file must be visited
+	mu      sync.Mutex
+}
+
+// startSummary loads the branch's summary for a run to update
+func (idx *Indexer) startSummary() {
+	summary, err := LoadRepoSummary(idx.repoName, idx.branch)
+	if err != nil {
+		idx.logger.Warn().Err(err).Msg("Ignoring unreadable repository summary")
+	}
+	run := &runSummary{summary: summary}
+	if summary == nil {
+		run.missing = true
+		run.summary = &RepoSummary{
+			RepoName: idx.repoName,
+			Branch:   idx.branch,
+			Files:    make(map[string]string),
+			Packages: make(map[string]*PackageSummary),
+		}
+	}
+	idx.summary = run
+}
+
+// saveSummary brings the run's summary up to date, summarizing packages whose
+// files changed, and writes it; failures are logged, and packages left
+// unsummarized are retried by the next run
+func (idx *Indexer) saveSummary(ctx context.Context) {
+	if idx.summary == nil {
+		return
+	}
+	idx.summary.mu.Lock()
+	defer idx.summary.mu.Unlock()
+
+	summary := idx.summary.summary
+	summary.EntryPoints = entryPoints(summary.Files, idx.symbolFiles())
+	idx.summarizePackages(ctx, summary)
+	idx.writeSummary(summary)
+}
+
+// suspendSummary writes the run's files and entry points without summarizing
+// packages, for a run that did not finish; packages whose files changed keep
+// their old summary until a run finishes
+func (idx *Indexer) suspendSummary() {
+	if idx.summary == nil {
+		return
+	}
+	idx.summary.mu.Lock()
+	defer idx.summary.mu.Unlock()
+
+	summary := idx.summary.summary
+	summary.EntryPoints = entryPoints(summary.Files, idx.symbolFiles())
+	idx.writeSummary(summary)
+}
+
+// summarizePending summarizes packages a previous run left unsummarized, for
+// runs that otherwise have nothing to do
+func (idx *Indexer) summarizePending(ctx context.Context) {
+	if idx.summary == nil || idx.summarizer == nil {
+		return
+	}
+	idx.summary.mu.Lock()
+	defer idx.summary.mu.Unlock()
+
+	if idx.summarizePackages(ctx, idx.summary.summary) > 0 {
+		idx.writeSummary(idx.summary.summary)
+	}
+}
+
+// writeSummary saves a summary; failures are logged
+func (idx *Indexer) writeSummary(summary *RepoSummary) {
+	summary.UpdatedAt = time.Now()
+	if err := SaveRepoSummary(summary); err != nil {
+		idx.logger.Warn().Err(err).Msg("Failed to write repository summary")
+	}
+}
+
+// summarizePackages drops the summaries of packages gone from the tree and
+// writes those of packages whose content changed, returning how many it wrote
+func (idx *Indexer) summarizePackages(ctx context.Context, summary *RepoSummary) int {
+	hashes, files := packageHashes(summary.Files)
+	for dir := range summary.Packages {
+		if _, ok := hashes[dir]; !ok {
+			delete(summary.Packages, dir)
+		}
+	}
+	if idx.summarizer == nil {
+		return 0
+	}
+
+	var stale []string
+	for dir, hash := range hashes {
+		if p := summary.Packages[dir]; p == nil || p.Hash != hash {
+			stale = append(stale, dir)
+		}
+	}
+	sort.Strings(stale)
+
+	symbolFiles := idx.symbolFiles()
+	written := 0
+	for _, dir := range stale {
+		if written == maxPackageSummariesPerRun || ctx.Err() != nil {
+			break
+		}
+		text, err := idx.summarizer.Summarize(ctx, packagePrompt(idx.repoName, dir, files[dir], symbolFiles))
+		if err != nil {
+			// The provider is likely down or out of budget; the next run retries
+			idx.logger.Warn().Err(err).Str("package", dir).Msg("Failed to summarize package, retrying on the next run")
+			break
+		}
+		text = truncateSummary(strings.TrimSpace(text))
+		// A changed package keeps its old summary until the new one is written
+		summary.Packages[dir] = &PackageSummary{Hash: hashes[dir], Summary: text}
+		written++
+	}
+
+	if len(stale) > 0 {
+		idx.logger.Info().
+			Int("summarized", written).
+			Int("pending", len(stale)-written).
+			Msg("Summarized packages")
+	}
+	return written
+}
+
+// symbolFiles returns the symbols of the run's files, if extracted
+func (idx *Indexer) symbolFiles() map[string]*symbols.File {
+	if idx.symbols == nil {
+		return nil
+	}
+	idx.symbols.mu.Lock()
+	defer idx.symbols.mu.Unlock()
+	return idx.symbols.index.Files
+}
+
+// record sets the content hash of an indexed file; "" drops it
+func (r *runSummary) record(relPath, hash string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if hash == "" {
+		delete(r.summary.Files, relPath)
+		return
+	}
+	r.summary.Files[relPath] = hash
+}
+
+// reset drops all files, before a run that visits every file
+func (r *runSummary) reset() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.summary.Files = make(map[string]string)
+}
+
+// needsRebuild reports whether the branch has no summary yet, so the run must
+// visit every file rather than the changed ones
+func (r *runSummary) needsRebuild() bool {
+	return r != nil && r.missing
+}
+
+// packageHashes groups files by directory, returning a hash of each
+// directory's file names and contents, and its files in order
+func packageHashes(files map[string]string) (map[string]string, map[string][]string) {
+	byDir := make(map[string][]string)
+	for relPath := range files {
+		dir := path.Dir(relPath)
+		byDir[dir] = append(byDir[dir], relPath)
+	}
+
+	hashes := make(map[string]string, len(byDir))
+	for dir, paths := range byDir {
+		sort.Strings(paths)
+		h := sha256.New()
+		for _, relPath := range paths {
+			fmt.Fprintf(h, "%s %s\n", relPath, files[relPath])
+		}
+		hashes[dir] = fmt.Sprintf("%x", h.Sum(nil))
+	}
+	return hashes, byDir
+}
+
+// packagePrompt asks for the summary of a directory, from its file names,
+// imports and declarations
+func packagePrompt(repoName, dir string, files []string, symbolFiles map[string]*symbols.File) string {
+	var sb strings.Builder
+	name := dir
+	if dir == "." {
+		name = "(repository root)"
+	}
+	fmt.Fprintf(&sb, "Summarize what the directory `%s` of the %s repository does, in one or two sentences, for a map of the codebase. ", name, repoName)
+	sb.WriteString("Name its responsibilities and main types or functions; don't list files. Answer with the summary only.\n\n")
+
+	sb.WriteString("Files:\n")
+	for i, relPath := range files {
+		if i == maxDigestFiles {
+			fmt.Fprintf(&sb, "- ... (%d more)\n", len(files)-i)
+			break
+		}
+		fmt.Fprintf(&sb, "- %s\n", path.Base(relPath))
+	}
+
+	var imports, declarations []string
+	seen := make(map[string]bool)
+	for _, relPath := range files {
+		f := symbolFiles[relPath]
+		if f == nil {
+			continue
+		}
+		for _, imp := range f.Imports {
+			if !seen[imp] && len(imports) < maxDigestImports {
+				seen[imp] = true
+				imports = append(imports, imp)
+			}
+		}
+		for _, def := range f.Definitions {
+			if len(declarations) == maxDigestDeclarations {
+				break
+			}
+			// The declaration without its body: "func Open() error { ... }"
+			declaration, _, _ := strings.Cut(strings.TrimSpace(def.Signature), " {")
+			if declaration == "" {
+				declaration = string(def.Kind) + " " + def.Name
+			}
+			declarations = append(declarations, fmt.Sprintf("%s (%s)", strings.TrimSpace(declaration), path.Base(relPath)))
+		}
+	}
+
+	if len(imports) > 0 {
+		sb.WriteString("\nImports:\n")
+		for _, imp := range imports {
+			fmt.Fprintf(&sb, "- %s\n", imp)
+		}
+	}
+	if len(declarations) > 0 {
+		sb.WriteString("\nDeclarations:\n")
+		for _, declaration := range declarations {
+			fmt.Fprintf(&sb, "- %s\n", declaration)
+		}
+	}
+	return sb.String()
+}
+
+// entryPoints returns the files programs start from: Go files declaring
+// func main in package main, and conventional entry files near the root
+func entryPoints(files map[string]string, symbolFiles map[string]*symbols.File) []string {
+	var entries []string
+	for relPath := range files {
+		if isEntryPoint(relPath, symbolFiles[relPath]) {
+			entries = append(entries, relPath)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		// Shallow ones first: "main.go" before "tools/gen/main.go"
+		di, dj := strings.Count(entries[i], "/"), strings.Count(entries[j], "/")
+		if di != dj {
+			return di < dj
+		}
+		return entries[i] < entries[j]
+	})
+	if len(entries) > maxEntryPoints {
+		entries = entries[:maxEntryPoints]
+	}
+	return entries
+}
+
+func isEntryPoint(relPath string, f *symbols.File) bool {
+	if strings.HasSuffix(relPath, ".go") {
+		if f == nil || strings.HasSuffix(relPath, "_test.go") {
+			return false
+		}
+		for _, def := range f.Definitions {
+			if def.Name == "main" && def.Kind == symbols.KindFunction && def.Package == "main" {
+				return true
+			}
+		}
+		return false
+	}
+	return entryPointFiles[path.Base(relPath)] && strings.Count(relPath, "/") < maxEntryPointDepth
+}
+
+// truncateSummary cuts text to maxSummaryChars bytes at a rune boundary
+func truncateSummary(text string) string {
+	if len(text) <= maxSummaryChars {
+		return text
+	}
+	end := maxSummaryChars
+	for end > 0 && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	return text[:end] + "..."
+}
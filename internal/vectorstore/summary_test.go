@@ -0,0 +1,150 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// fakeSummarizer summarizes a package as the first file name in its prompt
+type fakeSummarizer struct {
+	prompts []string
+	err     error
+}
+
+func (f *fakeSummarizer) Summarize(ctx context.Context, prompt string) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	f.prompts = append(f.prompts, prompt)
+	_, files, _ := strings.Cut(prompt, "Files:\n- ")
+	name, _, _ := strings.Cut(files, "\n")
+	return "Package with " + name, nil
+}
+
+func TestIndexIncremental_RepoSummary(t *testing.T) {
+	src, _ := initBareRepo(t)
+
+	gitCmd(t, src, "checkout", "-q", "feature")
+	os.MkdirAll(filepath.Join(src, "cmd", "api"), 0755)
+	os.MkdirAll(filepath.Join(src, "internal", "store"), 0755)
+	os.WriteFile(filepath.Join(src, "cmd", "api", "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+	os.WriteFile(filepath.Join(src, "internal", "store", "store.go"), []byte("package store\n\n// Open opens the store\nfunc Open(path string) error { return nil }\n"), 0644)
+	gitCmd(t, src, "add", ".")
+	gitCmd(t, src, "commit", "-m", "add api and store")
+
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	store := newMockStore()
+	summarizer := &fakeSummarizer{}
+	index := func() {
+		t.Helper()
+		indexer := NewIndexerWithBranch(store, src, "repo", "feature", testLogger())
+		indexer.SetSummarizer(summarizer)
+		if err := indexer.IndexIncremental(context.Background()); err != nil {
+			t.Fatalf("IndexIncremental failed: %v", err)
+		}
+	}
+	load := func() *RepoSummary {
+		t.Helper()
+		summary, err := LoadRepoSummary("repo", "feature")
+		if err != nil || summary == nil {
+			t.Fatalf("Expected a repository summary, got %v, %v", summary, err)
+		}
+		return summary
+	}
+	packages := func(summary *RepoSummary) map[string]string {
+		texts := make(map[string]string)
+		for dir, p := range summary.Packages {
+			texts[dir] = p.Summary
+		}
+		return texts
+	}
+
+	index()
+	summary := load()
+	if len(summary.Files) != 4 {
+		t.Errorf("Expected 4 files in the summary, got %v", summary.Files)
+	}
+	if !reflect.DeepEqual(summary.EntryPoints, []string{"cmd/api/main.go"}) {
+		t.Errorf("EntryPoints = %v", summary.EntryPoints)
+	}
+	want := map[string]string{".": "Package with a.go", "cmd/api": "Package with main.go", "internal/store": "Package with store.go"}
+	if got := packages(summary); !reflect.DeepEqual(got, want) {
+		t.Errorf("Packages = %v, want %v", got, want)
+	}
+	if prompt := summarizer.prompts[2]; !strings.Contains(prompt, "`internal/store`") || !strings.Contains(prompt, "func Open(path string) error (store.go)") {
+		t.Errorf("Expected the package's declarations in the prompt, got:\n%s", prompt)
+	}
+
+	// Only packages whose files changed are summarized again
+	summarizer.prompts = nil
+	os.WriteFile(filepath.Join(src, "internal", "store", "cache.go"), []byte("package store\n"), 0644)
+	gitCmd(t, src, "add", ".")
+	gitCmd(t, src, "commit", "-m", "add cache")
+	index()
+	if len(summarizer.prompts) != 1 || !strings.Contains(summarizer.prompts[0], "`internal/store`") {
+		t.Errorf("Expected internal/store summarized again, got %d prompts", len(summarizer.prompts))
+	}
+	if got := packages(load())["internal/store"]; got != "Package with cache.go" {
+		t.Errorf("Expected the new summary, got %q", got)
+	}
+
+	// Packages a failing provider left unsummarized are retried without changes
+	gitCmd(t, src, "rm", "-q", "cmd/api/main.go")
+	os.MkdirAll(filepath.Join(src, "web"), 0755)
+	os.WriteFile(filepath.Join(src, "web", "index.ts"), []byte("export const app = 1\n"), 0644)
+	gitCmd(t, src, "add", ".")
+	gitCmd(t, src, "commit", "-m", "replace api")
+	summarizer.err = errors.New("provider down")
+	index()
+	summary = load()
+	if _, ok := summary.Packages["cmd/api"]; ok {
+		t.Error("Expected the summary of a deleted package to be dropped")
+	}
+	if _, ok := summary.Packages["web"]; ok {
+		t.Error("Expected web to be unsummarized while the provider fails")
+	}
+	if !reflect.DeepEqual(summary.EntryPoints, []string{"web/index.ts"}) {
+		t.Errorf("EntryPoints = %v", summary.EntryPoints)
+	}
+
+	summarizer.err = nil
+	index()
+	if got := packages(load())["web"]; got != "Package with index.ts" {
+		t.Errorf("Expected web summarized by the next run, got %q", got)
+	}
+}
+
+func TestTruncateSummary(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"short", "Persists users.", "Persists users."},
+		{"ascii", strings.Repeat("a", maxSummaryChars+10), strings.Repeat("a", maxSummaryChars) + "..."},
+		{"rune across the limit", strings.Repeat("a", maxSummaryChars-1) + "日本", strings.Repeat("a", maxSummaryChars-1) + "..."},
+		{"multibyte", strings.Repeat("é", maxSummaryChars), strings.Repeat("é", maxSummaryChars/2) + "..."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateSummary(tt.text)
+			if got != tt.want {
+				t.Errorf("truncateSummary() = %q, want %q", got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("truncateSummary() returned invalid UTF-8: %q", got)
+			}
+		})
+	}
+}
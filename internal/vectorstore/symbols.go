@@ -0,0 +1,199 @@
+package vectorstore
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/First008/mesh/internal/symbols"
+)
+
+// SymbolIndex is the definitions and references of identifiers in a branch's
+// indexed files
+type SymbolIndex struct {
+	RepoName  string                   `json:"repo_name"`
+	Branch    string                   `json:"branch"`
+	UpdatedAt time.Time                `json:"updated_at"`
+	Files     map[string]*symbols.File `json:"files"` // File path -> symbols
+}
+
+// GetSymbolIndexPath returns path to the symbol index for repo+branch
+// Example: .mesh/my-repo/main/symbols.json
+func GetSymbolIndexPath(repoName, branch string) string {
+	return filepath.Join(filepath.Dir(GetMetadataPath(repoName, branch)), "symbols.json")
+}
+
+// LoadSymbolIndex loads the symbol index for a repo+branch
+// Returns nil if the branch has not been indexed with symbols
+func LoadSymbolIndex(repoName, branch string) (*SymbolIndex, error) {
+	data, err := os.ReadFile(GetSymbolIndexPath(repoName, branch))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var index SymbolIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, err
+	}
+	if index.Files == nil {
+		index.Files = make(map[string]*symbols.File)
+	}
+	return &index, nil
+}
+
+// SaveSymbolIndex atomically writes the symbol index for a repo+branch
+func SaveSymbolIndex(index *SymbolIndex) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(GetSymbolIndexPath(index.RepoName, index.Branch), data)
+}
+
+// artifacts caches values derived from per-branch artifact files (symbol
+// tables, dependency graphs) by path, until the file is rewritten
+var artifacts = &artifactCache{entries: make(map[string]artifactEntry)}
+
+type artifactCache struct {
+	mu      sync.Mutex
+	entries map[string]artifactEntry
+}
+
+type artifactEntry struct {
+	modTime time.Time
+	size    int64
+	value   interface{}
+}
+
+// get returns the value derived from the file at path, loading it again when
+// the file changed; nil if the file doesn't exist
+func (c *artifactCache) get(path string, load func() (interface{}, error)) (interface{}, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[path]; ok && entry.modTime.Equal(info.ModTime()) && entry.size == info.Size() {
+		return entry.value, nil
+	}
+
+	value, err := load()
+	if err != nil || value == nil {
+		return nil, err
+	}
+	c.entries[path] = artifactEntry{modTime: info.ModTime(), size: info.Size(), value: value}
+	return value, nil
+}
+
+// LoadSymbolTable returns the lookup table of a repo+branch's symbol index,
+// cached until the next indexing run rewrites it
+// Returns nil if the branch has not been indexed with symbols
+func LoadSymbolTable(repoName, branch string) (*symbols.Table, error) {
+	value, err := artifacts.get(GetSymbolIndexPath(repoName, branch), func() (interface{}, error) {
+		index, err := LoadSymbolIndex(repoName, branch)
+		if err != nil || index == nil {
+			return nil, err
+		}
+		return symbols.NewTable(index.Files), nil
+	})
+	if value == nil {
+		return nil, err
+	}
+	return value.(*symbols.Table), nil
+}
+
+// runSymbols collects the symbols of a run's files into the branch's index
+// (thread-safe)
+type runSymbols struct {
+	index   *SymbolIndex
+	missing bool // No index or graph was saved before: every file must be extracted
+	mu      sync.Mutex
+}
+
+// startSymbols loads the branch's symbol index for a run to update
+func (idx *Indexer) startSymbols() {
+	index, err := LoadSymbolIndex(idx.repoName, idx.branch)
+	if err != nil {
+		idx.logger.Warn().Err(err).Msg("Ignoring unreadable symbol index")
+	}
+	run := &runSymbols{index: index}
+	if _, err := os.Stat(GetDependencyGraphPath(idx.repoName, idx.branch)); err != nil {
+		run.missing = true // Indexed before imports were extracted
+	}
+	if index == nil {
+		run.missing = true
+		run.index = &SymbolIndex{
+			RepoName: idx.repoName,
+			Branch:   idx.branch,
+			Files:    make(map[string]*symbols.File),
+		}
+	}
+	idx.symbols = run
+}
+
+// saveSymbols writes the run's symbol index and the dependency graph built
+// from it; failures only cost lookups until the file is indexed again, so
+// they are logged
+func (idx *Indexer) saveSymbols() {
+	if idx.symbols == nil {
+		return
+	}
+	idx.symbols.mu.Lock()
+	defer idx.symbols.mu.Unlock()
+
+	idx.symbols.index.UpdatedAt = time.Now()
+	if err := SaveSymbolIndex(idx.symbols.index); err != nil {
+		idx.logger.Warn().Err(err).Msg("Failed to write symbol index")
+		return
+	}
+
+	graph := &DependencyGraph{
+		RepoName:  idx.repoName,
+		Branch:    idx.branch,
+		UpdatedAt: idx.symbols.index.UpdatedAt,
+		Files:     symbols.BuildGraph(idx.symbols.index.Files),
+	}
+	if err := SaveDependencyGraph(graph); err != nil {
+		idx.logger.Warn().Err(err).Msg("Failed to write dependency graph")
+	}
+}
+
+// record replaces the symbols of a file; nil clears its entry
+func (r *runSymbols) record(relPath string, file *symbols.File) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if file == nil {
+		delete(r.index.Files, relPath)
+		return
+	}
+	r.index.Files[relPath] = file
+}
+
+// reset drops all symbols, before a run that extracts every file
+func (r *runSymbols) reset() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.index.Files = make(map[string]*symbols.File)
+}
+
+// needsRebuild reports whether the branch has no symbol index yet, so the run
+// must visit every file rather than the changed ones
+func (r *runSymbols) needsRebuild() bool {
+	return r != nil && r.missing
+}
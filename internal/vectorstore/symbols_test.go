@@ -0,0 +1,72 @@
+package vectorstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIndexIncremental_Symbols(t *testing.T) {
+	src, _ := initBareRepo(t)
+
+	gitCmd(t, src, "checkout", "-q", "feature")
+	os.WriteFile(filepath.Join(src, "server.go"), []byte("package b\n\ntype Server struct{}\n\nfunc NewServer() *Server {\n\treturn &Server{}\n}\n"), 0644)
+	gitCmd(t, src, "add", "server.go")
+	gitCmd(t, src, "commit", "-m", "add server")
+
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	store := newMockStore()
+	index := func() {
+		t.Helper()
+		indexer := NewIndexerWithBranch(store, src, "repo", "feature", testLogger())
+		if err := indexer.IndexIncremental(context.Background()); err != nil {
+			t.Fatalf("IndexIncremental failed: %v", err)
+		}
+	}
+	definitions := func(name string) []string {
+		t.Helper()
+		table, err := LoadSymbolTable("repo", "feature")
+		if err != nil || table == nil {
+			t.Fatalf("Expected a symbol table, got %v, %v", table, err)
+		}
+		var paths []string
+		for _, def := range table.Definitions(name) {
+			paths = append(paths, def.Path)
+		}
+		return paths
+	}
+
+	index()
+	if got := definitions("Server"); len(got) != 1 || got[0] != "server.go" {
+		t.Errorf("Expected Server defined in server.go, got %v", got)
+	}
+
+	// Changed files are extracted again, deleted ones dropped
+	os.WriteFile(filepath.Join(src, "client.go"), []byte("package b\n\nfunc Dial() *Server { return NewServer() }\n"), 0644)
+	gitCmd(t, src, "add", "client.go")
+	gitCmd(t, src, "rm", "-q", "server.go")
+	gitCmd(t, src, "commit", "-m", "replace server")
+	index()
+	if got := definitions("Server"); len(got) != 0 {
+		t.Errorf("Expected Server to be gone, got %v", got)
+	}
+	if got := definitions("Dial"); len(got) != 1 || got[0] != "client.go" {
+		t.Errorf("Expected Dial defined in client.go, got %v", got)
+	}
+
+	// Branches indexed before symbols were extracted get a full pass
+	os.Remove(GetSymbolIndexPath("repo", "feature"))
+	index()
+	if got := definitions("Dial"); len(got) != 1 {
+		t.Errorf("Expected the symbol index rebuilt, got %v", got)
+	}
+	table, _ := LoadSymbolTable("repo", "feature")
+	if refs := table.References("NewServer"); len(refs) != 1 || refs[0].Path != "client.go" || refs[0].Line != 3 {
+		t.Errorf("Expected one reference to NewServer, got %v", refs)
+	}
+}
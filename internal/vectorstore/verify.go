@@ -0,0 +1,105 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+)
+
+// VerifyReport describes how a branch collection deviates from the git tree
+// at its indexed commit
+type VerifyReport struct {
+	Repo      string   `json:"repo"`
+	Branch    string   `json:"branch"`
+	Commit    string   `json:"commit"`
+	Files     int      `json:"files"`    // Indexable files in the tree
+	Missing   []string `json:"missing"`  // In the tree, not indexed
+	Stale     []string `json:"stale"`    // Indexed content differs from the tree
+	Orphaned  []string `json:"orphaned"` // Indexed, but no longer in the tree
+	Unread    int      `json:"unread"`   // Tree files that could not be read
+	Repaired  bool     `json:"repaired"`
+	Reindexed int      `json:"reindexed,omitempty"`
+	Removed   int      `json:"removed,omitempty"`
+	Errors    int      `json:"errors,omitempty"` // Failures during repair
+}
+
+// Consistent reports whether the collection matched the tree when checked
+func (r *VerifyReport) Consistent() bool {
+	return len(r.Missing) == 0 && len(r.Stale) == 0 && len(r.Orphaned) == 0
+}
+
+// Verify compares the branch collection against the tree at the commit
+// recorded in its metadata. With repair, missing and stale files are
+// re-indexed and orphaned ones removed; the recorded commit is unchanged.
+func (idx *Indexer) Verify(ctx context.Context, repair bool) (*VerifyReport, error) {
+	if idx.repoName == "" || idx.branch == "" {
+		return nil, fmt.Errorf("verification requires repoName and branch to be set")
+	}
+
+	meta, err := LoadMetadata(idx.repoName, idx.branch)
+	if err != nil {
+		return nil, fmt.Errorf("load metadata: %w", err)
+	}
+	if meta == nil {
+		return nil, fmt.Errorf("branch %s of %s has not been indexed", idx.branch, idx.repoName)
+	}
+
+	if !CommitExists(idx.repoPath, meta.CommitSHA) {
+		return nil, fmt.Errorf("indexed commit %s is unreachable; re-index the branch to reconcile", meta.CommitSHA)
+	}
+
+	src, err := idx.openSource(meta.CommitSHA)
+	if err != nil {
+		return nil, fmt.Errorf("open file source: %w", err)
+	}
+	defer src.Close()
+
+	if err := idx.startFilter(src); err != nil {
+		return nil, err
+	}
+
+	diff, err := idx.diffAgainstTree(ctx, src, false)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &VerifyReport{
+		Repo:     idx.repoName,
+		Branch:   idx.branch,
+		Commit:   meta.CommitSHA,
+		Files:    diff.unchanged + len(diff.missing) + len(diff.stale),
+		Missing:  diff.missing,
+		Stale:    diff.stale,
+		Orphaned: diff.orphaned,
+		Unread:   len(diff.unread),
+	}
+
+	idx.logger.Info().
+		Str("commit", meta.CommitSHA[:8]).
+		Int("files", report.Files).
+		Int("missing", len(report.Missing)).
+		Int("stale", len(report.Stale)).
+		Int("orphaned", len(report.Orphaned)).
+		Msg("Verified collection against tree")
+
+	if !repair || report.Consistent() {
+		return report, nil
+	}
+
+	stats, deleteErrors := idx.applyTreeDiff(ctx, diff)
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("repair cancelled: %w", err)
+	}
+
+	report.Repaired = true
+	report.Reindexed = stats.Indexed
+	report.Removed = len(diff.orphaned) - deleteErrors
+	report.Errors = stats.Errors + deleteErrors
+
+	idx.logger.Info().
+		Int("reindexed", report.Reindexed).
+		Int("removed", report.Removed).
+		Int("errors", report.Errors).
+		Msg("Repaired collection")
+
+	return report, nil
+}
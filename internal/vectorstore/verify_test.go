@@ -0,0 +1,98 @@
+package vectorstore
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/First008/mesh/internal/tokenizer"
+)
+
+// countingTokenizer counts the lines of each package clause it is asked about,
+// showing how often a file was chunked
+type countingTokenizer struct {
+	tokenizer.Tokenizer
+	mu    sync.Mutex
+	lines map[string]int
+}
+
+func (c *countingTokenizer) Count(text string) int {
+	if strings.HasPrefix(text, "package ") {
+		c.mu.Lock()
+		c.lines[text]++
+		c.mu.Unlock()
+	}
+	return c.Tokenizer.Count(text)
+}
+
+func TestVerify(t *testing.T) {
+	src, _ := initBareRepo(t)
+
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	store := newMockStore()
+	indexer := NewIndexerWithBranch(store, src, "repo", "feature", testLogger())
+
+	if _, err := indexer.Verify(context.Background(), false); err == nil {
+		t.Error("Expected error verifying a branch that was never indexed")
+	}
+
+	if err := indexer.IndexIncremental(context.Background()); err != nil {
+		t.Fatalf("IndexIncremental failed: %v", err)
+	}
+
+	report, err := indexer.Verify(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !report.Consistent() || report.Files != 2 {
+		t.Errorf("Expected consistent report over 2 files, got %+v", report)
+	}
+
+	// Simulate a crash mid-index and a deleted file that lingered
+	delete(store.indexed, "a.go")
+	store.indexed["b.go"] = "package partial\n"
+	store.indexed["old.go"] = "package old\n"
+	indexCnt := store.indexCnt
+
+	report, err = indexer.Verify(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !reflect.DeepEqual(report.Missing, []string{"a.go"}) ||
+		!reflect.DeepEqual(report.Stale, []string{"b.go"}) ||
+		!reflect.DeepEqual(report.Orphaned, []string{"old.go"}) {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if report.Repaired || store.indexCnt != indexCnt {
+		t.Error("Verify without repair must not modify the collection")
+	}
+
+	counter := &countingTokenizer{Tokenizer: tokenizer.Default(), lines: make(map[string]int)}
+	indexer.SetTokenizer(counter)
+	report, err = indexer.Verify(context.Background(), true)
+	if err != nil {
+		t.Fatalf("Verify with repair failed: %v", err)
+	}
+	if !report.Repaired || report.Reindexed != 2 || report.Removed != 1 {
+		t.Errorf("Unexpected repair results: %+v", report)
+	}
+	// Repaired files are indexed from the chunks the comparison computed
+	if want := map[string]int{"package a": 1, "package b": 1}; !reflect.DeepEqual(counter.lines, want) {
+		t.Errorf("Expected each file chunked once, got %v", counter.lines)
+	}
+
+	report, err = indexer.Verify(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Verify after repair failed: %v", err)
+	}
+	if !report.Consistent() {
+		t.Errorf("Expected consistent collection after repair, got %+v", report)
+	}
+}
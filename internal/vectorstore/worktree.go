@@ -0,0 +1,356 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/First008/mesh/internal/filetypes"
+	"github.com/First008/mesh/internal/secrets"
+	"github.com/rs/zerolog"
+)
+
+// WorkingTree mirrors uncommitted changes of a repository into an overlay
+// collection. Files that differ from HEAD are indexed into the overlay (or
+// recorded as deleted) so queries can prefer them over the branch collection,
+// which only reflects committed code.
+type WorkingTree struct {
+	store    VectorStore       // Overlay collection
+	indexer  *Indexer          // Chunks files into the overlay
+	repoPath string            // Repository root
+	files    map[string]string // rel path -> content hash ("" when deleted)
+	exclude  []string          // Configured exclude patterns
+	focus    []string          // Configured focus paths
+	mu       sync.RWMutex      // Guards files and patterns
+	syncMu   sync.Mutex        // Serializes Sync
+	logger   zerolog.Logger
+}
+
+// WorkingTreeSyncStats summarizes one Sync pass
+type WorkingTreeSyncStats struct {
+	Indexed int // Files (re-)indexed into the overlay
+	Deleted int // Files deleted in the working tree
+	Removed int // Files no longer differing from HEAD, dropped from the overlay
+	Errors  int
+}
+
+// NewWorkingTree creates a working-tree overlay backed by store
+func NewWorkingTree(store VectorStore, repoPath string, logger zerolog.Logger) *WorkingTree {
+	return &WorkingTree{
+		store:    store,
+		indexer:  NewIndexer(store, repoPath, logger),
+		repoPath: repoPath,
+		files:    make(map[string]string),
+		logger:   logger,
+	}
+}
+
+// SetPatterns sets the exclude patterns and focus paths applied, together
+// with the checkout's .gitignore and .meshignore, from the next Sync on
+func (wt *WorkingTree) SetPatterns(exclude, focus []string) {
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+	wt.exclude = exclude
+	wt.focus = focus
+}
+
+// SetSecretMode sets how files containing secrets are handled. Files already
+// in the overlay are re-indexed by the next Sync when the mode changes.
+func (wt *WorkingTree) SetSecretMode(mode secrets.Mode) {
+	wt.syncMu.Lock()
+	defer wt.syncMu.Unlock()
+	if wt.indexer.secretMode == mode {
+		return
+	}
+	wt.indexer.SetSecretMode(mode)
+	wt.forgetHashes()
+}
+
+// SetGeneratedMode sets how generated, vendored, minified and binary files
+// are handled. Files already in the overlay are re-indexed by the next Sync
+// when the mode changes.
+func (wt *WorkingTree) SetGeneratedMode(mode GeneratedMode) {
+	wt.syncMu.Lock()
+	defer wt.syncMu.Unlock()
+	if wt.indexer.generatedMode == mode {
+		return
+	}
+	wt.indexer.SetGeneratedMode(mode)
+	wt.forgetHashes()
+}
+
+// SetFileTypes sets which files are code and in which language. Files
+// already in the overlay are re-indexed by the next Sync, and ones that are
+// no longer code are dropped.
+func (wt *WorkingTree) SetFileTypes(types *filetypes.Registry) {
+	wt.syncMu.Lock()
+	defer wt.syncMu.Unlock()
+	if wt.indexer.fileTypes.Fingerprint() == types.Fingerprint() {
+		return
+	}
+	wt.indexer.SetFileTypes(types)
+	wt.forgetHashes()
+}
+
+// SetEmbedTemplate sets the text embedded for each chunk. Files already in
+// the overlay are re-embedded by the next Sync when the template changes.
+func (wt *WorkingTree) SetEmbedTemplate(t *EmbedTemplate) {
+	wt.syncMu.Lock()
+	defer wt.syncMu.Unlock()
+	if wt.indexer.embedTemplate.Fingerprint() == t.Fingerprint() {
+		return
+	}
+	wt.indexer.SetEmbedTemplate(t)
+	wt.forgetHashes()
+}
+
+// forgetHashes makes the next Sync re-index every file in the overlay.
+// Deleted files keep their empty hash; changed ones no longer match.
+func (wt *WorkingTree) forgetHashes() {
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+	for relPath := range wt.files {
+		wt.files[relPath] = ""
+	}
+}
+
+// Sync reconciles the overlay with `git status`: changed files are re-indexed
+// when their content differs from the last sync, and files that match HEAD
+// again (committed, reverted, or checked out) are removed from the overlay.
+func (wt *WorkingTree) Sync(ctx context.Context) (WorkingTreeSyncStats, error) {
+	wt.syncMu.Lock()
+	defer wt.syncMu.Unlock()
+
+	var stats WorkingTreeSyncStats
+
+	changed, err := GetWorkingTreeChanges(wt.repoPath)
+	if err != nil {
+		return stats, err
+	}
+
+	// Files that become excluded are dropped below, like reverted ones
+	filter := wt.loadFilter()
+
+	dirty := make(map[string]bool, len(changed))
+	for _, relPath := range changed {
+		if ctx.Err() != nil {
+			return stats, ctx.Err()
+		}
+		if !filter.indexable(relPath) {
+			continue
+		}
+		dirty[relPath] = true
+
+		hash, content, err := wt.readFile(relPath)
+		if err != nil {
+			wt.logger.Warn().Err(err).Str("path", relPath).Msg("Failed to read working tree file")
+			stats.Errors++
+			continue
+		}
+
+		wt.mu.RLock()
+		prev, known := wt.files[relPath]
+		wt.mu.RUnlock()
+		if known && prev == hash {
+			continue
+		}
+
+		if err := wt.store.DeleteFile(ctx, relPath); err != nil {
+			wt.logger.Warn().Err(err).Str("path", relPath).Msg("Failed to clear overlay file")
+			stats.Errors++
+			continue
+		}
+
+		if hash != "" {
+			if err := wt.indexer.indexFileOrChunks(ctx, relPath, content); err != nil {
+				wt.logger.Warn().Err(err).Str("path", relPath).Msg("Failed to index working tree file")
+				stats.Errors++
+				continue
+			}
+			stats.Indexed++
+		} else {
+			stats.Deleted++
+		}
+
+		wt.mu.Lock()
+		wt.files[relPath] = hash
+		wt.mu.Unlock()
+	}
+
+	// Drop files that no longer differ from HEAD
+	for _, relPath := range wt.ChangedFiles() {
+		if dirty[relPath] {
+			continue
+		}
+		if err := wt.store.DeleteFile(ctx, relPath); err != nil {
+			wt.logger.Warn().Err(err).Str("path", relPath).Msg("Failed to remove overlay file")
+			stats.Errors++
+			continue
+		}
+		wt.mu.Lock()
+		delete(wt.files, relPath)
+		wt.mu.Unlock()
+		stats.Removed++
+	}
+
+	return stats, nil
+}
+
+// loadFilter builds the filter for one Sync from the current patterns and
+// the root ignore files on disk
+func (wt *WorkingTree) loadFilter() *pathFilter {
+	wt.mu.RLock()
+	exclude, focus := wt.exclude, wt.focus
+	wt.mu.RUnlock()
+
+	// File types only change under syncMu, which Sync holds
+	return loadDiskFilter(wt.repoPath, exclude, focus, wt.indexer.fileTypes, wt.logger)
+}
+
+// readFile returns the content hash and content of a working tree file.
+// Deleted files return an empty hash; oversized files are treated as deleted
+// so stale committed content is hidden rather than served.
+func (wt *WorkingTree) readFile(relPath string) (string, string, error) {
+	content, err := os.ReadFile(filepath.Join(wt.repoPath, relPath))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if len(content) > maxIndexFileSize {
+		return "", "", nil
+	}
+	return computeFileHash(content), string(content), nil
+}
+
+// Shadows reports whether relPath has uncommitted changes, meaning results for
+// it from the branch collection are stale
+func (wt *WorkingTree) Shadows(relPath string) bool {
+	wt.mu.RLock()
+	defer wt.mu.RUnlock()
+	_, ok := wt.files[extractBasePath(relPath)]
+	return ok
+}
+
+// ChangedFiles returns the files currently held in (or hidden by) the overlay
+func (wt *WorkingTree) ChangedFiles() []string {
+	wt.mu.RLock()
+	defer wt.mu.RUnlock()
+
+	files := make([]string, 0, len(wt.files))
+	for relPath := range wt.files {
+		files = append(files, relPath)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Store returns the overlay collection
+func (wt *WorkingTree) Store() VectorStore {
+	return wt.store
+}
+
+// OverlayStore layers a WorkingTree over a branch collection. Searches return
+// overlay results plus branch results for files without uncommitted changes;
+// writes go to the overlay.
+type OverlayStore struct {
+	base VectorStore // Branch collection (may be nil)
+	tree *WorkingTree
+}
+
+// NewOverlayStore creates a read view combining base with tree
+func NewOverlayStore(base VectorStore, tree *WorkingTree) *OverlayStore {
+	return &OverlayStore{base: base, tree: tree}
+}
+
+// IndexFile indexes into the overlay collection
+func (ov *OverlayStore) IndexFile(ctx context.Context, filePath, content string) error {
+	return ov.tree.store.IndexFile(ctx, filePath, content)
+}
+
+// Search merges overlay and unshadowed branch results by score
+func (ov *OverlayStore) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	return ov.merge(limit, func(store VectorStore, n int) ([]SearchResult, error) {
+		return store.Search(ctx, query, n)
+	})
+}
+
+// SearchFiltered merges filtered overlay and branch results by score
+func (ov *OverlayStore) SearchFiltered(ctx context.Context, query string, limit int, filter SearchFilter) ([]SearchResult, error) {
+	return ov.merge(limit, func(store VectorStore, n int) ([]SearchResult, error) {
+		return SearchWithFilter(ctx, store, query, n, filter)
+	})
+}
+
+// SearchWithAggregation merges aggregated overlay and branch results by score
+func (ov *OverlayStore) SearchWithAggregation(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	return ov.merge(limit, func(store VectorStore, n int) ([]SearchResult, error) {
+		return store.SearchWithAggregation(ctx, query, n)
+	})
+}
+
+// merge runs search against both stores and drops stale branch results
+func (ov *OverlayStore) merge(limit int, search func(VectorStore, int) ([]SearchResult, error)) ([]SearchResult, error) {
+	overlayResults, err := search(ov.tree.store, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if ov.base == nil {
+		return overlayResults, nil
+	}
+
+	// Over-fetch so shadowed results don't starve the branch side
+	baseResults, err := search(ov.base, limit+len(ov.tree.ChangedFiles()))
+	if err != nil {
+		return nil, err
+	}
+
+	results := overlayResults
+	for _, r := range baseResults {
+		if !ov.tree.Shadows(r.FilePath) {
+			results = append(results, r)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// DeleteFile removes a file from the overlay collection
+func (ov *OverlayStore) DeleteFile(ctx context.Context, filePath string) error {
+	return ov.tree.store.DeleteFile(ctx, filePath)
+}
+
+// ListIndexedChunks lists the overlay collection's chunks
+func (ov *OverlayStore) ListIndexedChunks(ctx context.Context) (map[string]string, error) {
+	return ov.tree.store.ListIndexedChunks(ctx)
+}
+
+// DeleteCollection removes the overlay collection; the branch collection is untouched
+func (ov *OverlayStore) DeleteCollection(ctx context.Context) error {
+	return ov.tree.store.DeleteCollection(ctx)
+}
+
+// GetStats returns statistics for the branch collection
+func (ov *OverlayStore) GetStats(ctx context.Context) (*Stats, error) {
+	if ov.base == nil {
+		return ov.tree.store.GetStats(ctx)
+	}
+	return ov.base.GetStats(ctx)
+}
+
+// Close is a no-op; the underlying stores are owned by their creators
+func (ov *OverlayStore) Close() error {
+	return nil
+}
@@ -0,0 +1,197 @@
+package vectorstore
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// resultStore is a mockStore that returns canned search results
+type resultStore struct {
+	*mockStore
+	results []SearchResult
+}
+
+func (r *resultStore) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	if limit < len(r.results) {
+		return r.results[:limit], nil
+	}
+	return r.results, nil
+}
+
+func (r *resultStore) SearchWithAggregation(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	return r.Search(ctx, query, limit)
+}
+
+// initWorkingTreeRepo creates a repo with a.go and b.go committed
+func initWorkingTreeRepo(t *testing.T) string {
+	t.Helper()
+	tmpDir := t.TempDir()
+
+	if exec.Command("git", "init", tmpDir).Run() != nil {
+		t.Skip("Skipping test: git not available")
+	}
+	exec.Command("git", "-C", tmpDir, "config", "user.email", "test@example.com").Run()
+	exec.Command("git", "-C", tmpDir, "config", "user.name", "Test User").Run()
+
+	os.WriteFile(filepath.Join(tmpDir, "a.go"), []byte("package a\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "b.go"), []byte("package b\n"), 0644)
+	exec.Command("git", "-C", tmpDir, "add", ".").Run()
+	if err := exec.Command("git", "-C", tmpDir, "commit", "-m", "Initial commit").Run(); err != nil {
+		t.Skipf("Skipping test: commit failed: %v", err)
+	}
+	return tmpDir
+}
+
+func TestWorkingTreeCollectionName(t *testing.T) {
+	overlay := WorkingTreeCollectionName("api", "main")
+	for _, branch := range []string{"main", "main-worktree", "main/worktree"} {
+		if BranchCollectionName("api", branch) == overlay {
+			t.Errorf("Overlay collection %s clashes with the collection of branch %q", overlay, branch)
+		}
+	}
+}
+
+func TestGetWorkingTreeChanges(t *testing.T) {
+	repo := initWorkingTreeRepo(t)
+
+	os.WriteFile(filepath.Join(repo, "a.go"), []byte("package a\n\nfunc A() {}\n"), 0644)
+	os.WriteFile(filepath.Join(repo, "c.go"), []byte("package c\n"), 0644)
+	exec.Command("git", "-C", repo, "mv", "b.go", "renamed.go").Run()
+
+	files, err := GetWorkingTreeChanges(repo)
+	if err != nil {
+		t.Fatalf("GetWorkingTreeChanges failed: %v", err)
+	}
+
+	got := make(map[string]bool)
+	for _, f := range files {
+		got[f] = true
+	}
+	for _, want := range []string{"a.go", "b.go", "c.go", "renamed.go"} {
+		if !got[want] {
+			t.Errorf("Expected %s in working tree changes, got %v", want, files)
+		}
+	}
+}
+
+func TestWorkingTree_Sync(t *testing.T) {
+	repo := initWorkingTreeRepo(t)
+	store := newMockStore()
+	wt := NewWorkingTree(store, repo, testLogger())
+	ctx := context.Background()
+
+	// Modify a.go, delete b.go, add c.go
+	os.WriteFile(filepath.Join(repo, "a.go"), []byte("package a\n\nfunc A() {}\n"), 0644)
+	os.Remove(filepath.Join(repo, "b.go"))
+	os.WriteFile(filepath.Join(repo, "c.go"), []byte("package c\n"), 0644)
+
+	stats, err := wt.Sync(ctx)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if stats.Indexed != 2 || stats.Deleted != 1 {
+		t.Errorf("Expected 2 indexed and 1 deleted, got %+v", stats)
+	}
+	if _, ok := store.indexed["a.go"]; !ok {
+		t.Error("Modified file should be indexed into the overlay")
+	}
+	if _, ok := store.indexed["b.go"]; ok {
+		t.Error("Deleted file should not be in the overlay")
+	}
+	for _, path := range []string{"a.go", "b.go", "c.go", "a.go#chunk1"} {
+		if !wt.Shadows(path) {
+			t.Errorf("Expected %s to be shadowed", path)
+		}
+	}
+
+	// Unchanged content is not re-indexed
+	before := store.indexCnt
+	if _, err := wt.Sync(ctx); err != nil {
+		t.Fatalf("Second sync failed: %v", err)
+	}
+	if store.indexCnt != before {
+		t.Errorf("Unchanged files should not be re-indexed (%d -> %d)", before, store.indexCnt)
+	}
+
+	// Reverting a.go drops it from the overlay
+	exec.Command("git", "-C", repo, "checkout", "--", "a.go").Run()
+	stats, err = wt.Sync(ctx)
+	if err != nil {
+		t.Fatalf("Third sync failed: %v", err)
+	}
+	if stats.Removed != 1 {
+		t.Errorf("Expected 1 removed file, got %+v", stats)
+	}
+	if wt.Shadows("a.go") {
+		t.Error("Reverted file should no longer be shadowed")
+	}
+}
+
+func TestWorkingTree_SyncExcludedFiles(t *testing.T) {
+	repo := initWorkingTreeRepo(t)
+	store := newMockStore()
+	wt := NewWorkingTree(store, repo, testLogger())
+	ctx := context.Background()
+
+	os.WriteFile(filepath.Join(repo, "a.go"), []byte("package a\n\nfunc A() {}\n"), 0644)
+	os.WriteFile(filepath.Join(repo, "c.go"), []byte("package c\n"), 0644)
+	os.WriteFile(filepath.Join(repo, MeshIgnoreFile), []byte("c.go\n"), 0644)
+
+	if _, err := wt.Sync(ctx); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if _, ok := store.indexed["c.go"]; ok {
+		t.Error("File ignored by .meshignore should not be indexed into the overlay")
+	}
+	if !wt.Shadows("a.go") {
+		t.Error("Expected a.go to be shadowed")
+	}
+
+	// Files that become excluded leave the overlay
+	wt.SetPatterns([]string{"a.go"}, nil)
+	stats, err := wt.Sync(ctx)
+	if err != nil {
+		t.Fatalf("Second sync failed: %v", err)
+	}
+	if stats.Removed != 1 || wt.Shadows("a.go") {
+		t.Errorf("Expected excluded a.go to be removed, got %+v", stats)
+	}
+}
+
+func TestOverlayStore_Search(t *testing.T) {
+	repo := initWorkingTreeRepo(t)
+
+	overlay := &resultStore{mockStore: newMockStore(), results: []SearchResult{
+		{FilePath: "a.go", Content: "package a // edited", Score: 0.7},
+	}}
+	base := &resultStore{mockStore: newMockStore(), results: []SearchResult{
+		{FilePath: "a.go", Content: "package a", Score: 0.9},
+		{FilePath: "b.go#chunk0", Content: "package b", Score: 0.8},
+		{FilePath: "z.go", Content: "package z", Score: 0.5},
+	}}
+
+	wt := NewWorkingTree(overlay, repo, testLogger())
+	os.WriteFile(filepath.Join(repo, "a.go"), []byte("package a // edited\n"), 0644)
+	os.Remove(filepath.Join(repo, "b.go"))
+	if _, err := wt.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	results, err := NewOverlayStore(base, wt).Search(context.Background(), "package", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results (overlay a.go, base z.go), got %+v", results)
+	}
+	if results[0].Content != "package a // edited" {
+		t.Errorf("Expected overlay version of a.go, got %q", results[0].Content)
+	}
+	if results[1].FilePath != "z.go" {
+		t.Errorf("Expected unshadowed base result z.go, got %s", results[1].FilePath)
+	}
+}
@@ -53,6 +53,7 @@ type CostTracker struct {
 	dailyOutputTokens int64
 	dailyCachedTokens int64
 	dailyRequestCount int
+	dailyLimitHit     bool // A request was refused by the daily limit
 	lastResetDate     string
 
 	// Overall tracking
@@ -62,6 +63,8 @@ type CostTracker struct {
 	totalCachedTokens int64
 	totalRequestCount int
 
+	pricing func(model string) (PricingTable, bool) // Overrides AnthropicPricing when set
+
 	logger zerolog.Logger
 }
 
@@ -85,7 +88,7 @@ func (ct *CostTracker) RecordRequest(model string, inputTokens, outputTokens, ca
 	ct.checkDailyReset()
 
 	// Get pricing for model
-	pricing, ok := AnthropicPricing[model]
+	pricing, ok := ct.lookupPricing(model)
 	if !ok {
 		return 0, fmt.Errorf("unknown model: %s", model)
 	}
@@ -99,6 +102,7 @@ func (ct *CostTracker) RecordRequest(model string, inputTokens, outputTokens, ca
 
 	// Check if this would exceed daily limit
 	if ct.dailySpend+totalCost > ct.dailyMaxUSD {
+		ct.dailyLimitHit = true
 		return 0, fmt.Errorf("daily cost limit exceeded: current=$%.2f, limit=$%.2f, this request=$%.2f",
 			ct.dailySpend, ct.dailyMaxUSD, totalCost)
 	}
@@ -146,6 +150,22 @@ func (ct *CostTracker) RecordRequest(model string, inputTokens, outputTokens, ca
 	return totalCost, nil
 }
 
+// SetPricing replaces the built-in pricing table with a lookup function
+func (ct *CostTracker) SetPricing(lookup func(model string) (PricingTable, bool)) {
+	ct.mu.Lock()
+	ct.pricing = lookup
+	ct.mu.Unlock()
+}
+
+// lookupPricing returns the pricing for a model
+func (ct *CostTracker) lookupPricing(model string) (PricingTable, bool) {
+	if ct.pricing != nil {
+		return ct.pricing(model)
+	}
+	pricing, ok := AnthropicPricing[model]
+	return pricing, ok
+}
+
 // checkDailyReset resets daily counters if the date has changed
 func (ct *CostTracker) checkDailyReset() {
 	today := time.Now().Format("2006-01-02")
@@ -160,10 +180,25 @@ func (ct *CostTracker) checkDailyReset() {
 		ct.dailyOutputTokens = 0
 		ct.dailyCachedTokens = 0
 		ct.dailyRequestCount = 0
+		ct.dailyLimitHit = false
 		ct.lastResetDate = today
 	}
 }
 
+// BudgetExhausted reports whether today's spend has reached the daily limit.
+// A limit of 0 or less is treated as unset.
+func (ct *CostTracker) BudgetExhausted() bool {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	ct.checkDailyReset()
+
+	if ct.dailyMaxUSD <= 0 {
+		return false
+	}
+	return ct.dailyLimitHit || ct.dailySpend >= ct.dailyMaxUSD
+}
+
 // GetDailyStats returns current daily statistics
 func (ct *CostTracker) GetDailyStats() DailyStats {
 	ct.mu.Lock()
@@ -165,6 +165,23 @@ func TestRecordRequest_ExceedsDailyLimit(t *testing.T) {
 	}
 }
 
+func TestBudgetExhausted(t *testing.T) {
+	tracker := NewCostTracker(0.10, 0.08, 100000, testLogger())
+	if tracker.BudgetExhausted() {
+		t.Fatal("Expected fresh tracker to have budget left")
+	}
+
+	// A refused request marks the budget as exhausted for the day
+	tracker.RecordRequest("claude-sonnet-4-5-20250929", 10000, 5000, 0)
+	if !tracker.BudgetExhausted() {
+		t.Error("Expected budget exhausted after a request over the limit")
+	}
+
+	if NewCostTracker(0, 0, 100000, testLogger()).BudgetExhausted() {
+		t.Error("Expected unset limit never to be exhausted")
+	}
+}
+
 func TestRecordRequest_MultipleRequestsApproachLimit(t *testing.T) {
 	// Daily limit: $0.20, alert at $0.16
 	tracker := NewCostTracker(0.20, 0.16, 100000, testLogger())